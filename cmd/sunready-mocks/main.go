@@ -0,0 +1,40 @@
+// Command sunready-mocks serves fake LightFusion, Genability, Twilio and
+// SendGrid APIs, so the app runs the lead, 3D design and proposal flow
+// offline. See package mocks for the URLs to point the app at.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/mocks"
+)
+
+func main() {
+	addr := flag.String("addr", envOr("MOCKS_ADDR", ":8090"), "address to listen on")
+	fixturesPath := flag.String("fixtures", os.Getenv("MOCKS_FIXTURES"), "JSON fixtures file; built-in fixtures when empty")
+	flag.Parse()
+
+	fixtures := mocks.DefaultFixtures()
+	if *fixturesPath != "" {
+		var err error
+		if fixtures, err = mocks.LoadFixtures(*fixturesPath); err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+		log.Printf("Loaded fixtures from %s", *fixturesPath)
+	}
+
+	log.Printf("Mock APIs listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, mocks.NewServer(fixtures)); err != nil {
+		log.Fatalf("Mock server failed: %v", err)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -5,13 +5,18 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/database"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/handler"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/production"
+	appmiddleware "github.com/Bilal-Cplusoft/sun_ready/internal/middleware"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -70,8 +75,20 @@ func main() {
 	quoteRepo := repo.NewQuoteRepo(db)
 	leadRepo := repo.NewLeadRepo(db)
 	houseRepo := repo.NewHouseRepo(db)
+	adderRepo := repo.NewAdderRepo(db)
+	hardwareRepo := repo.NewHardwareRepo(db)
+	proposalRepo := repo.NewProposalRepo(db)
+	proposalOptionRepo := repo.NewProposalOptionRepo(db)
+	notificationRepo := repo.NewNotificationRepo(db)
+	signatureRepo := repo.NewSignatureRepo(db)
+	financingRepo := repo.NewFinancingRepo(db)
+	incentiveRepo := repo.NewIncentiveRepo(db)
+	leadUsageRepo := repo.NewLeadUsageRepo(db)
 
 	lightFusionClient,twilioClient,sendGridClient := client.NewLightFusionClient(lightFusionURL, lightFusionAPIKey),client.InitializeTwilio(),client.InitializeSendGrid()
+	if meshURL := os.Getenv("LIGHTFUSION_MESH_URL"); meshURL != "" {
+		lightFusionClient.SetMeshURL(meshURL)
+	}
 
 	if lightFusionEmail != "" && lightFusionPassword != "" {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -90,19 +107,137 @@ func main() {
 	userService := service.NewUserService(userRepo)
 	companyService := service.NewCompanyService(companyRepo)
 	projectService := service.NewProjectService(projectRepo)
-	dealService := service.NewDealService(dealRepo)
-	quoteService := service.NewQuoteService(quoteRepo)
-	leadService := service.NewLeadService(leadRepo,houseRepo)
+	hardwareService := service.NewHardwareService(hardwareRepo, lightFusionClient)
+	dealService := service.NewDealService(dealRepo, hardwareService)
+	adderService := service.NewAdderService(adderRepo, leadRepo, dealRepo)
+	financingService := service.NewFinancingService(financingRepo)
+	incentiveService := service.NewIncentiveService(incentiveRepo)
+	txManager := repo.NewTxManager(db)
+	pricingService := service.NewPricingService(txManager, dealRepo, companyRepo, adderService, hardwareService, financingService)
+	usageService := service.NewUsageService(leadUsageRepo, leadRepo)
+	quoteService := service.NewQuoteService(quoteRepo, leadRepo, financingService, incentiveService, usageService)
+	genabilityAgent, err := client.NewAgent()
+	if err != nil {
+		log.Printf("Warning: Genability is unavailable: %v", err)
+	} else {
+		genabilityCacheTTL := client.DefaultCacheTTL
+		if v := os.Getenv("GENABILITY_CACHE_TTL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				genabilityCacheTTL = d
+			} else {
+				log.Printf("Warning: invalid GENABILITY_CACHE_TTL %q, using %s", v, genabilityCacheTTL)
+			}
+		}
+		genabilityCacheRepo := repo.NewGenabilityCacheRepo(db)
+		genabilityAgent.UseCache(genabilityCacheRepo, genabilityCacheTTL)
+		go func() {
+			if _, err := genabilityCacheRepo.DeleteExpired(context.Background()); err != nil {
+				log.Printf("Warning: failed to clear expired Genability cache entries: %v", err)
+			}
+		}()
+	}
+	leadService := service.NewLeadService(leadRepo,houseRepo,hardwareService,genabilityAgent)
+	weatherDir := os.Getenv("WEATHER_DIR")
+	if weatherDir == "" {
+		weatherDir = "./data/weather"
+	}
+	weatherLibrary, err := production.OpenLibrary(weatherDir)
+	if err != nil {
+		log.Fatalf("Failed to load weather files: %v", err)
+	}
+	if weatherLibrary.Len() == 0 {
+		log.Printf("Warning: no weather files in %s; production estimates are unavailable", weatherDir)
+	}
+	productionService := service.NewProductionService(weatherLibrary, leadRepo, hardwareService)
+	sizingService := service.NewSizingService(leadRepo, hardwareService, productionService, usageService)
+	designService := service.NewDesignService(leadRepo, dealRepo, hardwareService, weatherLibrary)
+	genabilityService := service.NewGenabilityService(genabilityAgent)
+	// Documents hold signed contracts and unreleased proposals, so they must
+	// never be under ./media, which is served to anyone.
+	documentsDir := os.Getenv("DOCUMENTS_DIR")
+	if documentsDir == "" {
+		documentsDir = "./data/documents"
+		if _, err := os.Stat(documentsDir); os.IsNotExist(err) {
+			if _, err := os.Stat("./media/documents"); err == nil {
+				if err := os.MkdirAll(filepath.Dir(documentsDir), 0o755); err != nil {
+					log.Fatalf("Failed to create %s: %v", filepath.Dir(documentsDir), err)
+				}
+				if err := os.Rename("./media/documents", documentsDir); err != nil {
+					log.Fatalf("Failed to move documents out of ./media: %v", err)
+				}
+				log.Printf("Moved documents from ./media/documents to %s", documentsDir)
+			}
+		}
+	}
+	if rel, err := filepath.Rel("./media", documentsDir); err == nil && !strings.HasPrefix(rel, "..") {
+		log.Fatalf("DOCUMENTS_DIR %s must not be inside ./media, which is served publicly", documentsDir)
+	}
+	documentStore, err := storage.NewLocalDocumentStore(documentsDir, "")
+	if err != nil {
+		log.Fatalf("Failed to initialize document store: %v", err)
+	}
+	proposalDocumentService := service.NewProposalDocumentService(proposalRepo, companyRepo, hardwareService, documentStore)
+	proposalConversionService := service.NewProposalConversionService(txManager, proposalRepo, proposalOptionRepo, dealRepo, adderRepo, pricingService)
+	proposalService := service.NewProposalService(proposalRepo, proposalOptionRepo, leadRepo, companyRepo, quoteService, proposalDocumentService, proposalConversionService)
+	proposalOptionService := service.NewProposalOptionService(proposalOptionRepo, proposalRepo, proposalService, hardwareService)
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:" + port
+	}
+	proposalFollowUpService := service.NewProposalFollowUpService(proposalRepo, notificationRepo, userRepo, companyRepo, sendGridClient, twilioClient, publicURL)
+	signatureService := service.NewSignatureService(txManager, signatureRepo, dealRepo, proposalRepo, companyRepo, userRepo, hardwareService, documentStore, sendGridClient, twilioClient, publicURL)
 
 	authHandler := handler.NewAuthHandler(authService,sendGridClient)
 	userHandler := handler.NewUserHandler(userService)
 	companyHandler := handler.NewCompanyHandler(companyService, userService)
 	projectHandler := handler.NewProjectHandler(projectService)
-	project3DHandler := handler.NewProject3DHandler(lightFusionClient, leadRepo)
-	dealHandler := handler.NewDealHandler(dealService)
+	project3DHandler := handler.NewProject3DHandler(lightFusionClient, leadRepo, hardwareService)
+	dealHandler := handler.NewDealHandler(dealService, pricingService)
 	quoteHandler := handler.NewQuoteHandler(quoteService)
+	productionHandler := handler.NewProductionHandler(productionService)
+	sizingHandler := handler.NewSizingHandler(sizingService)
+	designHandler := handler.NewDesignHandler(designService)
+	usageHandler := handler.NewUsageHandler(usageService)
+	genabilityHandler := handler.NewGenabilityHandler(genabilityService)
 	leadHandler := handler.NewLeadHandler(leadRepo, lightFusionClient,leadService,userRepo)
 	otpHandler := handler.NewOtpHandler(twilioClient)
+	adderHandler := handler.NewAdderHandler(adderService)
+	financingHandler := handler.NewFinancingHandler(financingService)
+	incentiveHandler := handler.NewIncentiveHandler(incentiveService)
+	hardwareHandler := handler.NewHardwareHandler(hardwareService)
+	proposalHandler := handler.NewProposalHandler(proposalService)
+	proposalFollowUpHandler := handler.NewProposalFollowUpHandler(proposalFollowUpService)
+	proposalOptionHandler := handler.NewProposalOptionHandler(proposalOptionService)
+	signatureHandler := handler.NewSignatureHandler(signatureService)
+
+	hardwareSyncInterval := 24 * time.Hour
+	if v := os.Getenv("HARDWARE_SYNC_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			hardwareSyncInterval = d
+		} else {
+			log.Printf("Warning: invalid HARDWARE_SYNC_INTERVAL %q, using %s", v, hardwareSyncInterval)
+		}
+	}
+	go hardwareService.RunSync(context.Background(), hardwareSyncInterval)
+
+	proposalFollowUpInterval := 15 * time.Minute
+	if v := os.Getenv("PROPOSAL_FOLLOW_UP_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			proposalFollowUpInterval = d
+		} else {
+			log.Printf("Warning: invalid PROPOSAL_FOLLOW_UP_INTERVAL %q, using %s", v, proposalFollowUpInterval)
+		}
+	}
+	go proposalFollowUpService.Run(context.Background(), proposalFollowUpInterval)
+	go signatureService.RunCodeCleanup(context.Background(), time.Hour)
+
+	// Forwarding headers are only believed from these proxies, so clients
+	// cannot pick their own IP for the per-IP rate limits.
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		if err := appmiddleware.SetTrustedProxies(strings.Split(v, ",")); err != nil {
+			log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+		}
+	}
 
 	r := chi.NewRouter()
 
@@ -132,6 +267,10 @@ func main() {
 	r.Get("/api/companies/{id}", companyHandler.GetByID)
 	r.Put("/api/companies/{id}", companyHandler.Update)
 	r.Delete("/api/companies/{id}", companyHandler.Delete)
+	r.Get("/api/companies/{id}/hardware", hardwareHandler.GetCompanyHardware)
+	r.Put("/api/companies/{id}/hardware", hardwareHandler.SetCompanyHardware)
+	r.Get("/api/companies/{id}/proposal-reminders", proposalFollowUpHandler.GetSettings)
+	r.Put("/api/companies/{id}/proposal-reminders", proposalFollowUpHandler.UpdateSettings)
 	r.Get("/api/companies", companyHandler.List)
 
 	r.Post("/api/projects", projectHandler.Create)
@@ -150,13 +289,113 @@ func main() {
 	r.Delete("/api/deals/{id}", dealHandler.Delete)
 	r.Post("/api/deals/{id}/archive", dealHandler.Archive)
 	r.Post("/api/deals/{id}/unarchive", dealHandler.Unarchive)
+	r.Post("/api/deals/{id}/price", dealHandler.Price)
+	r.Post("/api/deals/{id}/design-check", designHandler.CheckDeal)
+	r.Get("/api/deals/{id}/adders", adderHandler.ListDealAdders)
+	r.Post("/api/deals/{id}/signature-requests", signatureHandler.Create)
+	r.Get("/api/deals/{id}/signature-requests", signatureHandler.ListByDeal)
 	r.Get("/api/deals", dealHandler.List)
 
 	r.Post("/api/projects/external", project3DHandler.Create3DProject)
 	r.Get("/api/projects/external/{id}", project3DHandler.GetProjectStatus)
 	r.Get("/api/projects/external/{id}/files", project3DHandler.GetProjectFiles3D)
 
+	r.Post("/api/adder-categories", adderHandler.CreateCategory)
+	r.Get("/api/adder-categories", adderHandler.ListCategories)
+	r.Put("/api/adder-categories/{id}", adderHandler.UpdateCategory)
+	r.Delete("/api/adder-categories/{id}", adderHandler.DeleteCategory)
+
+	r.Post("/api/adders", adderHandler.Create)
+	r.Get("/api/adders", adderHandler.List)
+	r.Get("/api/adders/applicable", adderHandler.Applicable)
+	r.Get("/api/adders/{id}", adderHandler.GetByID)
+	r.Put("/api/adders/{id}", adderHandler.Update)
+	r.Delete("/api/adders/{id}", adderHandler.Delete)
+	r.Get("/api/adders/{id}/versions", adderHandler.ListVersions)
+
+	r.Post("/api/financing-providers", financingHandler.CreateProvider)
+	r.Get("/api/financing-providers", financingHandler.ListProviders)
+	r.Get("/api/financing-providers/{id}", financingHandler.GetProvider)
+	r.Put("/api/financing-providers/{id}", financingHandler.UpdateProvider)
+	r.Delete("/api/financing-providers/{id}", financingHandler.DeleteProvider)
+
+	r.Post("/api/financing-options", financingHandler.CreateOption)
+	r.Get("/api/financing-options", financingHandler.ListOptions)
+	r.Get("/api/financing-options/{id}", financingHandler.GetOption)
+	r.Put("/api/financing-options/{id}", financingHandler.UpdateOption)
+	r.Delete("/api/financing-options/{id}", financingHandler.DeleteOption)
+
+	r.Post("/api/incentives", incentiveHandler.Create)
+	r.Get("/api/incentives", incentiveHandler.List)
+	r.Get("/api/incentives/{id}", incentiveHandler.GetByID)
+	r.Put("/api/incentives/{id}", incentiveHandler.Update)
+	r.Delete("/api/incentives/{id}", incentiveHandler.Delete)
+
+	r.Post("/api/hardware/panels", hardwareHandler.CreatePanel)
+	r.Get("/api/hardware/panels", hardwareHandler.ListPanels)
+	r.Get("/api/hardware/panels/{id}", hardwareHandler.GetPanel)
+	r.Put("/api/hardware/panels/{id}", hardwareHandler.UpdatePanel)
+	r.Delete("/api/hardware/panels/{id}", hardwareHandler.DeletePanel)
+	r.Post("/api/hardware/inverters", hardwareHandler.CreateInverter)
+	r.Get("/api/hardware/inverters", hardwareHandler.ListInverters)
+	r.Get("/api/hardware/inverters/{id}", hardwareHandler.GetInverter)
+	r.Put("/api/hardware/inverters/{id}", hardwareHandler.UpdateInverter)
+	r.Delete("/api/hardware/inverters/{id}", hardwareHandler.DeleteInverter)
+	r.Post("/api/hardware/batteries", hardwareHandler.CreateBattery)
+	r.Get("/api/hardware/batteries", hardwareHandler.ListBatteries)
+	r.Get("/api/hardware/batteries/{id}", hardwareHandler.GetBattery)
+	r.Put("/api/hardware/batteries/{id}", hardwareHandler.UpdateBattery)
+	r.Delete("/api/hardware/batteries/{id}", hardwareHandler.DeleteBattery)
+	r.Post("/api/hardware/sync", hardwareHandler.Sync)
+
+	r.Post("/api/proposals", proposalHandler.Create)
+	r.Get("/api/proposals", proposalHandler.List)
+	r.Get("/api/proposals/{id}", proposalHandler.GetByID)
+	r.Delete("/api/proposals/{id}", proposalHandler.Delete)
+	r.Post("/api/proposals/{id}/send", proposalHandler.Send)
+	r.Post("/api/proposals/{id}/accept", proposalHandler.Accept)
+	r.Post("/api/proposals/{id}/reject", proposalHandler.Reject)
+	r.Get("/api/proposals/{id}/views", proposalHandler.ListViews)
+	r.Get("/api/proposals/{id}/notifications", proposalFollowUpHandler.ListNotifications)
+	r.Post("/api/proposals/{id}/options", proposalOptionHandler.Create)
+	r.Get("/api/proposals/{id}/options", proposalOptionHandler.List)
+	r.Get("/api/proposals/{id}/options/compare", proposalOptionHandler.Compare)
+	r.Put("/api/proposals/{id}/options/{optionId}", proposalOptionHandler.Update)
+	r.Get("/api/proposals/{id}/options/{optionId}/versions", proposalOptionHandler.Versions)
+	r.Post("/api/proposals/{id}/options/{optionId}/accept", proposalOptionHandler.Accept)
+	r.Get("/api/proposals/{id}/pdf", proposalHandler.Document)
+	r.Post("/api/proposals/{id}/pdf", proposalHandler.RegenerateDocument)
+
+	// Public homeowner proposal link. Codes are the only credential, so
+	// lookups are rate limited per IP to keep them from being enumerated.
+	proposalLimiter := appmiddleware.NewRateLimiter(0.5, 10)
+	r.With(proposalLimiter.Middleware).Get("/p/{code}", proposalHandler.View)
+	r.With(proposalLimiter.Middleware).Get("/p/{code}/pdf", proposalHandler.ViewDocument)
+
+	r.Get("/api/signature-requests/{id}", signatureHandler.Get)
+	r.Get("/api/signature-requests/{id}/document", signatureHandler.Document)
+	r.Get("/api/signature-requests/{id}/certificate", signatureHandler.Certificate)
+	r.Get("/api/signature-requests/{id}/verify", signatureHandler.Verify)
+
+	// Public signing link. Like proposal codes, the token is the only
+	// credential, so every signing route is rate limited per IP.
+	signingLimiter := appmiddleware.NewRateLimiter(0.5, 10)
+	r.Route("/sign/{token}", func(r chi.Router) {
+		r.Use(signingLimiter.Middleware)
+		r.Get("/", signatureHandler.View)
+		r.Get("/document", signatureHandler.ViewDocument)
+		r.Post("/code", signatureHandler.SendCode)
+		r.Post("/verify", signatureHandler.VerifyCode)
+		r.Post("/sign", signatureHandler.Sign)
+	})
+
 	r.Post("/api/quote", quoteHandler.GetQuote)
+	r.Post("/api/production/estimate", productionHandler.Estimate)
+	r.Post("/api/sizing", sizingHandler.Size)
+	r.Post("/api/quotes", quoteHandler.Create)
+	r.Get("/api/quotes", quoteHandler.List)
+	r.Get("/api/quotes/{id}", quoteHandler.GetByID)
+	r.Post("/api/quotes/{id}/recompute", quoteHandler.Recompute)
 
 	// Lead routes
 	r.Post("/api/leads", leadHandler.CreateLead)
@@ -165,6 +404,15 @@ func main() {
 	r.Put("/api/leads/{id}", leadHandler.UpdateLead)
 	r.Delete("/api/leads/{id}", leadHandler.DeleteLead)
 	r.Post("/api/leads/{id}/sync-3d-status", leadHandler.SyncLead3DStatus)
+	r.Post("/api/leads/{id}/design-check", designHandler.CheckLead)
+	r.Post("/api/leads/{id}/usage", usageHandler.Upload)
+	r.Get("/api/leads/{id}/usage", usageHandler.Get)
+	r.Delete("/api/leads/{id}/usage", usageHandler.Delete)
+	r.Post("/api/usage/synthesize", usageHandler.Synthesize)
+	r.Get("/api/genability/lses", genabilityHandler.ListLSEs)
+	r.Get("/api/genability/tariffs", genabilityHandler.SearchTariffs)
+	r.Put("/api/genability/accounts/{accountId}/properties", genabilityHandler.SetAccountProperties)
+	r.Post("/api/genability/calculate", genabilityHandler.CalculateBills)
 
 	r.Get("/api/otp/send",otpHandler.SendOTP)
 	r.Get("/api/otp/verify",otpHandler.VerifyOTP)
@@ -97,6 +97,7 @@ CREATE TABLE IF NOT EXISTS leads (
     panel_id INTEGER,
     inverter_id INTEGER,
     inverter_count INTEGER DEFAULT 1,
+    battery_id INTEGER,
     battery_count INTEGER DEFAULT 0,
 
     -- Utility information
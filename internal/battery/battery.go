@@ -0,0 +1,187 @@
+// Package battery simulates home battery dispatch hour by hour alongside
+// solar, and sizes batteries for backup.
+package battery
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidBattery = errors.New("invalid battery")
+
+// Dispatch modes.
+const (
+	// ModeSelfConsumption stores solar that would be exported and uses it
+	// as soon as the home needs more than the panels produce.
+	ModeSelfConsumption = "self_consumption"
+	// ModeTOUArbitrage stores solar like self-consumption but only
+	// discharges in each day's most expensive hours.
+	ModeTOUArbitrage = "tou_arbitrage"
+	// ModeBackup keeps the battery full for outages and never discharges
+	// to serve everyday load.
+	ModeBackup = "backup"
+)
+
+const defaultRoundTripEfficiency = 0.90
+
+// Config describes the installed batteries and how they are run. Capacity
+// and power are per battery.
+type Config struct {
+	Count             int     `json:"count" example:"1"`
+	UsableCapacityKWh float64 `json:"usable_capacity_kwh" example:"13.5"`
+	PowerKW           float64 `json:"power_kw" example:"5"`
+	// RoundTripEfficiency is a fraction; zero means 90%.
+	RoundTripEfficiency float64 `json:"round_trip_efficiency,omitempty" example:"0.90"`
+	Mode                string  `json:"mode" example:"self_consumption"`
+	// ReservePct is the fraction of capacity held back for outages in the
+	// everyday modes.
+	ReservePct float64 `json:"reserve_pct,omitempty" example:"0.20"`
+	// AllowGridCharging lets TOU arbitrage charge from the grid in each
+	// day's cheapest hours when that pays after losses. Batteries charged
+	// from the grid may not qualify for the solar tax credit.
+	AllowGridCharging bool `json:"allow_grid_charging,omitempty" example:"false"`
+}
+
+// Validate checks the configuration. Count may be zero when the caller
+// fills it in from a recommendation.
+func (c *Config) Validate() error {
+	if c.Count < 0 || c.UsableCapacityKWh <= 0 || c.PowerKW <= 0 {
+		return fmt.Errorf("%w: capacity and power must be greater than 0", ErrInvalidBattery)
+	}
+	if c.RoundTripEfficiency < 0 || c.RoundTripEfficiency > 1 {
+		return fmt.Errorf("%w: round-trip efficiency must be between 0 and 1", ErrInvalidBattery)
+	}
+	if c.ReservePct < 0 || c.ReservePct > 1 {
+		return fmt.Errorf("%w: reserve must be between 0 and 1", ErrInvalidBattery)
+	}
+	switch c.Mode {
+	case ModeSelfConsumption, ModeTOUArbitrage, ModeBackup:
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidBattery, c.Mode)
+	}
+	return nil
+}
+
+func (c *Config) efficiency() float64 {
+	if c.RoundTripEfficiency == 0 {
+		return defaultRoundTripEfficiency
+	}
+	return c.RoundTripEfficiency
+}
+
+// Result is a year of dispatch. Supply is what the home gets from solar and
+// the battery each hour: production plus discharge less charging, negative
+// when the battery charges from the grid. Billing load against Supply gives
+// the bill with storage.
+type Result struct {
+	Supply           []float64 `json:"-"`
+	ChargedKWh       float64   `json:"charged_kwh" example:"3100"`
+	GridChargedKWh   float64   `json:"grid_charged_kwh" example:"0"`
+	DischargedKWh    float64   `json:"discharged_kwh" example:"2790"`
+	EquivalentCycles float64   `json:"equivalent_cycles" example:"206.7"`
+	// ReserveKWh is the energy held for outages.
+	ReserveKWh float64 `json:"reserve_kwh" example:"2.7"`
+}
+
+// Dispatch runs the batteries over a year of hourly load and production.
+// importRates are the hourly import rates used by TOU arbitrage; they may
+// be nil for the other modes.
+func Dispatch(c Config, load, production, importRates []float64) (*Result, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if len(load) != len(production) || len(load)%24 != 0 {
+		return nil, fmt.Errorf("%w: load and production must cover the same whole days", ErrInvalidBattery)
+	}
+	if c.Mode == ModeTOUArbitrage && len(importRates) != len(load) {
+		return nil, fmt.Errorf("%w: TOU arbitrage needs an import rate for every hour", ErrInvalidBattery)
+	}
+
+	capacity := float64(c.Count) * c.UsableCapacityKWh
+	power := float64(c.Count) * c.PowerKW
+	// Losses are split evenly between charging and discharging.
+	oneWay := math.Sqrt(c.efficiency())
+	reserve := capacity * c.ReservePct
+	if c.Mode == ModeBackup {
+		reserve = capacity
+	}
+
+	r := &Result{Supply: make([]float64, len(load)), ReserveKWh: round2(reserve)}
+	soc := reserve
+	for day := 0; day < len(load); day += 24 {
+		peak, offPeak := math.Inf(-1), math.Inf(1)
+		if c.Mode == ModeTOUArbitrage {
+			for h := day; h < day+24; h++ {
+				peak = math.Max(peak, importRates[h])
+				offPeak = math.Min(offPeak, importRates[h])
+			}
+		}
+
+		for h := day; h < day+24; h++ {
+			surplus := production[h] - load[h]
+			supply := production[h]
+
+			if surplus > 0 {
+				// Store solar that would otherwise be exported. Backup
+				// batteries top up from solar too.
+				charge := math.Min(math.Min(surplus, power), (capacity-soc)/oneWay)
+				soc += charge * oneWay
+				supply -= charge
+				r.ChargedKWh += charge
+			} else if c.Mode != ModeBackup {
+				discharge := c.Mode == ModeSelfConsumption ||
+					peak == offPeak || importRates[h] >= peak
+				if discharge {
+					out := math.Min(math.Min(-surplus, power), math.Max(0, soc-reserve)*oneWay)
+					soc -= out / oneWay
+					supply += out
+					r.DischargedKWh += out
+				}
+			}
+
+			if c.Mode == ModeTOUArbitrage && c.AllowGridCharging && importRates[h] == offPeak &&
+				peak*c.efficiency() > offPeak {
+				charge := math.Min(power, (capacity-soc)/oneWay)
+				if charge > 0 {
+					soc += charge * oneWay
+					supply -= charge
+					r.ChargedKWh += charge
+					r.GridChargedKWh += charge
+				}
+			}
+			r.Supply[h] = supply
+		}
+	}
+
+	if capacity > 0 {
+		r.EquivalentCycles = math.Round(r.DischargedKWh/capacity*10) / 10
+	}
+	r.ChargedKWh = round2(r.ChargedKWh)
+	r.GridChargedKWh = round2(r.GridChargedKWh)
+	r.DischargedKWh = round2(r.DischargedKWh)
+	return r, nil
+}
+
+// BackupHours returns how long energyKWh runs criticalLoadKW.
+func BackupHours(energyKWh, criticalLoadKW float64) float64 {
+	if criticalLoadKW <= 0 {
+		return 0
+	}
+	return math.Round(energyKWh/criticalLoadKW*10) / 10
+}
+
+// RecommendCount returns the fewest batteries of the configured model that
+// can run criticalLoadKW for hours from full, both in energy and in power.
+func RecommendCount(c Config, criticalLoadKW, hours float64) int {
+	if c.UsableCapacityKWh <= 0 || c.PowerKW <= 0 || criticalLoadKW <= 0 || hours <= 0 {
+		return 0
+	}
+	byEnergy := math.Ceil(criticalLoadKW * hours / c.UsableCapacityKWh)
+	byPower := math.Ceil(criticalLoadKW / c.PowerKW)
+	return int(math.Max(1, math.Max(byEnergy, byPower)))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
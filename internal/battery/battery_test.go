@@ -0,0 +1,196 @@
+package battery
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// day is a day of 1 kWh load an hour with 6 kWh of solar from 10:00 to
+// 14:00, leaving 20 kWh of surplus around midday.
+func day() (load, production []float64) {
+	load, production = make([]float64, 24), make([]float64, 24)
+	for h := range load {
+		load[h] = 1
+		if h >= 10 && h < 14 {
+			production[h] = 6
+		}
+	}
+	return load, production
+}
+
+// rates is 20c an hour with a 50c peak from 17:00 to 21:00.
+func rates() []float64 {
+	r := make([]float64, 24)
+	for h := range r {
+		r[h] = 0.20
+		if h >= 17 && h < 21 {
+			r[h] = 0.50
+		}
+	}
+	return r
+}
+
+func TestDispatch(t *testing.T) {
+	battery := Config{Count: 1, UsableCapacityKWh: 10, PowerKW: 5, RoundTripEfficiency: 1, Mode: ModeSelfConsumption}
+	tests := []struct {
+		name           string
+		edit           func(*Config)
+		wantCharged    float64
+		wantGrid       float64
+		wantDischarged float64
+		wantReserve    float64
+		// wantSupply is checked for the hours given.
+		wantSupply map[int]float64
+	}{
+		{
+			name:           "self-consumption stores midday solar for the evening",
+			edit:           func(*Config) {},
+			wantCharged:    10,
+			wantDischarged: 10,
+			wantSupply:     map[int]float64{9: 0, 10: 1, 11: 1, 12: 6, 14: 1, 23: 1},
+		},
+		{
+			name:           "reserve is held back",
+			edit:           func(c *Config) { c.ReservePct = 0.2 },
+			wantCharged:    8,
+			wantDischarged: 8,
+			wantReserve:    2,
+			wantSupply:     map[int]float64{11: 3, 21: 1, 22: 0},
+		},
+		{
+			name:           "losses split between charging and discharging",
+			edit:           func(c *Config) { c.RoundTripEfficiency = 0.81 },
+			wantCharged:    10 / 0.9,
+			wantDischarged: 9,
+			wantSupply:     map[int]float64{12: 6 - 1/0.9, 22: 1, 23: 0},
+		},
+		{
+			name:           "two batteries double capacity and power",
+			edit:           func(c *Config) { c.Count = 2 },
+			wantCharged:    20,
+			wantDischarged: 10,
+			wantSupply:     map[int]float64{10: 1, 12: 1, 14: 1},
+		},
+		{
+			name:        "backup stays full",
+			edit:        func(c *Config) { c.Mode = ModeBackup },
+			wantReserve: 10,
+			wantSupply:  map[int]float64{10: 6, 20: 0},
+		},
+		{
+			name:           "TOU arbitrage discharges only at the peak",
+			edit:           func(c *Config) { c.Mode = ModeTOUArbitrage },
+			wantCharged:    10,
+			wantDischarged: 4,
+			wantSupply:     map[int]float64{16: 0, 17: 1, 20: 1, 21: 0},
+		},
+		{
+			name:           "TOU arbitrage charges from the grid off-peak",
+			edit:           func(c *Config) { c.Mode, c.AllowGridCharging = ModeTOUArbitrage, true },
+			wantCharged:    14,
+			wantGrid:       14,
+			wantDischarged: 4,
+			wantSupply:     map[int]float64{0: -5, 1: -5, 2: 0, 10: 6, 17: 1, 21: -4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := battery
+			tt.edit(&c)
+			load, production := day()
+			r, err := Dispatch(c, load, production, rates())
+			if err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+			if !near(r.ChargedKWh, tt.wantCharged) || !near(r.GridChargedKWh, tt.wantGrid) || !near(r.DischargedKWh, tt.wantDischarged) {
+				t.Errorf("charged %v (%v from the grid) and discharged %v, want %v (%v) and %v",
+					r.ChargedKWh, r.GridChargedKWh, r.DischargedKWh, tt.wantCharged, tt.wantGrid, tt.wantDischarged)
+			}
+			if r.ReserveKWh != tt.wantReserve {
+				t.Errorf("reserve = %v, want %v", r.ReserveKWh, tt.wantReserve)
+			}
+			for h, want := range tt.wantSupply {
+				if !near(r.Supply[h], want) {
+					t.Errorf("supply at %d:00 = %v, want %v", h, r.Supply[h], want)
+				}
+			}
+			capacity := float64(c.Count) * c.UsableCapacityKWh
+			if want := math.Round(r.DischargedKWh/capacity*10) / 10; r.EquivalentCycles != want {
+				t.Errorf("cycles = %v, want %v", r.EquivalentCycles, want)
+			}
+		})
+	}
+}
+
+func TestDispatchInvalid(t *testing.T) {
+	valid := Config{Count: 1, UsableCapacityKWh: 13.5, PowerKW: 5, Mode: ModeSelfConsumption}
+	load, production := day()
+	tests := []struct {
+		name             string
+		edit             func(*Config)
+		load, production []float64
+		rates            []float64
+	}{
+		{"negative count", func(c *Config) { c.Count = -1 }, load, production, nil},
+		{"no capacity", func(c *Config) { c.UsableCapacityKWh = 0 }, load, production, nil},
+		{"no power", func(c *Config) { c.PowerKW = 0 }, load, production, nil},
+		{"efficiency over 1", func(c *Config) { c.RoundTripEfficiency = 1.1 }, load, production, nil},
+		{"negative reserve", func(c *Config) { c.ReservePct = -0.1 }, load, production, nil},
+		{"unknown mode", func(c *Config) { c.Mode = "peak_shaving" }, load, production, nil},
+		{"profiles differ", func(*Config) {}, load, production[:12], nil},
+		{"part of a day", func(*Config) {}, load[:12], production[:12], nil},
+		{"TOU without rates", func(c *Config) { c.Mode = ModeTOUArbitrage }, load, production, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.edit(&c)
+			if _, err := Dispatch(c, tt.load, tt.production, tt.rates); !errors.Is(err, ErrInvalidBattery) {
+				t.Errorf("err = %v, want ErrInvalidBattery", err)
+			}
+		})
+	}
+}
+
+func TestRecommendCount(t *testing.T) {
+	battery := Config{UsableCapacityKWh: 13.5, PowerKW: 5}
+	tests := []struct {
+		name   string
+		loadKW float64
+		hours  float64
+		want   int
+	}{
+		{"one is enough", 1, 12, 1},
+		{"sized by energy", 2, 24, 4},
+		{"sized by power", 12, 1, 3},
+		{"no critical load", 0, 24, 0},
+		{"no hours", 2, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecommendCount(battery, tt.loadKW, tt.hours); got != tt.want {
+				t.Errorf("RecommendCount(%v kW, %v h) = %d, want %d", tt.loadKW, tt.hours, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupHours(t *testing.T) {
+	tests := []struct {
+		energyKWh, loadKW, want float64
+	}{
+		{13.5, 1.5, 9},
+		{27, 2.2, 12.3},
+		{13.5, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := BackupHours(tt.energyKWh, tt.loadKW); got != tt.want {
+			t.Errorf("BackupHours(%v, %v) = %v, want %v", tt.energyKWh, tt.loadKW, got, tt.want)
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+)
+
+// DefaultCacheTTL is how long Genability lookups are cached. Tariffs and
+// utilities change a few times a year.
+const DefaultCacheTTL = 24 * time.Hour
+
+// Cache stores Genability responses as JSON. A shared cache, such as the
+// Postgres one in repo, lets replicas and restarts reuse lookups.
+type Cache interface {
+	// Get returns the value stored under key, or false when there is none
+	// or it has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the value stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryCache keeps responses in process memory.
+type MemoryCache struct {
+	store *cache.Cache
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{store: cache.New(DefaultCacheTTL, time.Hour)}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := c.store.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return value.([]byte), true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.store.Set(key, value, ttl)
+	return nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.store.Delete(key)
+	return nil
+}
+
+// cached reads key into out from the agent's cache. Cache failures are
+// logged and read as a miss, so lookups fall through to the API.
+func (a *Agent) cached(ctx context.Context, key string, out any) bool {
+	data, ok, err := a.cache.Get(ctx, key)
+	if err != nil {
+		log.Printf("Warning: genability cache get %s: %v", key, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		log.Printf("Warning: genability cache decode %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// store saves value under key in the agent's cache.
+func (a *Agent) store(ctx context.Context, key string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := a.cache.Set(ctx, key, data, a.cacheTTL); err != nil {
+		log.Printf("Warning: genability cache set %s: %v", key, err)
+	}
+}
+
+// forget removes key from the agent's cache.
+func (a *Agent) forget(ctx context.Context, key string) {
+	if err := a.cache.Delete(ctx, key); err != nil {
+		log.Printf("Warning: genability cache delete %s: %v", key, err)
+	}
+}
@@ -2,18 +2,18 @@ package client
 
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
-
-	cache "github.com/patrickmn/go-cache"
 )
 
 var (
@@ -29,10 +29,20 @@ type Credentials struct {
 	AppKey string
 }
 
+// Agent calls the Genability API. Each attempt is bounded by a timeout,
+// failed attempts at requests that are safe to repeat are retried by the
+// retry policy, and a circuit breaker stops calls while Genability keeps
+// failing. Lookups are cached in process memory unless UseCache gives a
+// shared cache.
 type Agent struct {
-	client *http.Client
-	creds  Credentials
-	base   string
+	client   *http.Client
+	creds    Credentials
+	base     string
+	timeout  time.Duration
+	retry    RetryPolicy
+	breaker  *breaker
+	cache    Cache
+	cacheTTL time.Duration
 }
 
 type AccountAddress struct {
@@ -47,8 +57,8 @@ type AccountAddress struct {
 }
 
 type KeyValue struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key   string `json:"keyName"`
+	Value string `json:"dataValue"`
 }
 
 type AccountProperty struct {
@@ -67,56 +77,173 @@ type Tariff struct {
 	ServiceType string  `json:"serviceType"`
 	IsActive    bool    `json:"isActive"`
 	MasterID    uint    `json:"masterTariffId"`
+	CustomerClass string `json:"customerClass,omitempty"`
+	TariffType    string `json:"tariffType,omitempty"`
 	CustomerLikelihood *float64 `json:"customerLikelihood,omitempty"`
 }
 
 type Tariffs struct {
 	Agent *Agent
-	cache *cache.Cache
 }
 
+// ErrMissingCredentials is returned by NewAgent when Genability is not
+// configured.
+var ErrMissingCredentials = errors.New("missing GENABILITY_ID or GENABILITY_KEY in environment")
 
+const (
+	// DefaultRequestTimeout bounds each attempt of a request.
+	DefaultRequestTimeout = 10 * time.Second
+	breakerThreshold      = 5
+	breakerCooldown       = 30 * time.Second
+)
 
-func NewAgent() *Agent {
+// NewAgent returns an agent with the credentials in GENABILITY_ID and
+// GENABILITY_KEY, or ErrMissingCredentials when they are not set.
+// GENABILITY_URL overrides the API's base URL.
+func NewAgent() (*Agent, error) {
 	appID := os.Getenv("GENABILITY_ID")
 	appKey := os.Getenv("GENABILITY_KEY")
 
 	if appID == "" || appKey == "" {
-		log.Fatal("missing GENABILITY_ID or GENABILITY_KEY in environment")
+		return nil, ErrMissingCredentials
 	}
 	Creds := Credentials{AppID: appID, AppKey: appKey}
+	base := "https://api.genability.com/rest/"
+	if v := os.Getenv("GENABILITY_URL"); v != "" {
+		base = strings.TrimSuffix(v, "/") + "/"
+	}
 	return &Agent{
-		client: &http.Client{Timeout: 10 * time.Second},
-		creds:  Creds,
-		base:   "https://api.genability.com/rest/",
+		client:   &http.Client{},
+		creds:    Creds,
+		base:     base,
+		timeout:  DefaultRequestTimeout,
+		retry:    DefaultRetryPolicy,
+		breaker:  newBreaker(breakerThreshold, breakerCooldown),
+		cache:    NewMemoryCache(),
+		cacheTTL: DefaultCacheTTL,
+	}, nil
+}
+
+// UseCache caches lookups in c for ttl instead of process memory.
+func (a *Agent) UseCache(c Cache, ttl time.Duration) {
+	a.cache = c
+	if ttl > 0 {
+		a.cacheTTL = ttl
+	}
+}
+
+// APIError is a failed Genability request, with the messages it returned.
+type APIError struct {
+	StatusCode int
+	Messages   []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("genability: request failed with status %d", e.StatusCode)
 	}
+	return fmt.Sprintf("genability: request failed with status %d: %s", e.StatusCode, strings.Join(e.Messages, "; "))
 }
 
+// doRequest sends a request and decodes the response into out. GET and PUT
+// requests are retried while they fail transiently; a POST may have
+// created something before it failed, so it is sent once.
 func (a *Agent) doRequest(ctx context.Context, method, path string, body any, out any) error {
-	url := a.base + path
+	return a.send(ctx, method, path, body, out, method == http.MethodGet || method == http.MethodPut)
+}
+
+// doIdempotentRequest is doRequest for a POST that is safe to repeat, such
+// as a calculation, and so is retried like a GET.
+func (a *Agent) doIdempotentRequest(ctx context.Context, method, path string, body any, out any) error {
+	return a.send(ctx, method, path, body, out, true)
+}
+
+func (a *Agent) send(ctx context.Context, method, path string, body any, out any, retry bool) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("genability: failed to marshal request body: %w", err)
+		}
+	}
+	if !a.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	for attempt := 1; ; attempt++ {
+		transient, wait, err := a.attempt(ctx, method, path, data, out)
+		if err != nil && ctx.Err() != nil {
+			// A caller that gave up says nothing about Genability.
+			a.breaker.abandon()
+			return err
+		}
+		if err == nil || !transient {
+			// Failures the caller caused do not trip the breaker.
+			a.breaker.record(true)
+			return err
+		}
+		if !retry || attempt >= a.retry.MaxAttempts {
+			a.breaker.record(false)
+			return err
+		}
+		timer := time.NewTimer(a.retry.backoff(attempt, wait))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			a.breaker.abandon()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+// attempt makes one try at a request. It reports whether a failure is
+// worth retrying, and how long a 429 asked to wait.
+func (a *Agent) attempt(ctx context.Context, method, path string, data []byte, out any) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.base+path, reqBody)
 	if err != nil {
-		return err
+		return false, 0, err
 	}
 
 	req.SetBasicAuth(a.creds.AppID, a.creds.AppKey)
 	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return err
+		return true, 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("genability: request failed with status %d", resp.StatusCode)
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Results []struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"results"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil {
+			for _, r := range errResp.Results {
+				apiErr.Messages = append(apiErr.Messages, r.Message)
+			}
+		}
+		return retryable(resp.StatusCode), retryAfter(resp), apiErr
 	}
 
 	if out != nil {
-		return json.NewDecoder(resp.Body).Decode(out)
+		return false, 0, json.NewDecoder(resp.Body).Decode(out)
 	}
-	return nil
+	return false, 0, nil
 }
 
 
@@ -171,17 +298,16 @@ func (a *Accounts) Show(ctx context.Context, id string) (*Account, error) {
 
 
 func NewTariffs(agent *Agent) *Tariffs {
-	return &Tariffs{
-		Agent: agent,
-		cache: cache.New(time.Hour, cache.NoExpiration),
-	}
+	return &Tariffs{Agent: agent}
 }
 
 func (t *Tariffs) fetch(ctx context.Context, v url.Values) ([]Tariff, error) {
 	page := 0
 	v.Set("isActive", "true")
 	v.Set("pageCount", "100")
-	v.Set("customerClasses", "RESIDENTIAL")
+	if v.Get("customerClasses") == "" {
+		v.Set("customerClasses", CustomerClassResidential)
+	}
 
 	var all []Tariff
 	
@@ -207,8 +333,9 @@ func (t *Tariffs) fetch(ctx context.Context, v url.Values) ([]Tariff, error) {
 
 func (t *Tariffs) Index(ctx context.Context, zipcode, country string) ([]Tariff, error) {
 	key := fmt.Sprintf("zip_%s_%s", zipcode, country)
-	if cached, ok := t.cache.Get(key); ok {
-		return cached.([]Tariff), nil
+	var cached []Tariff
+	if t.Agent.cached(ctx, key, &cached) {
+		return cached, nil
 	}
 	v := url.Values{}
 	v.Set("zipCode", zipcode)
@@ -216,16 +343,16 @@ func (t *Tariffs) Index(ctx context.Context, zipcode, country string) ([]Tariff,
 
 	data, err := t.fetch(ctx, v)
 	if err == nil {
-		t.cache.Set(key, data, cache.DefaultExpiration)
+		t.Agent.store(ctx, key, data)
 	}
 	return data, err
 }
 
 func (t *Tariffs) Show(ctx context.Context, masterID uint) (*Tariff, error) {
 	key := fmt.Sprintf("tariff_%d", masterID)
-	if cached, ok := t.cache.Get(key); ok {
-		tariff := cached.(Tariff)
-		return &tariff, nil
+	var cached Tariff
+	if t.Agent.cached(ctx, key, &cached) {
+		return &cached, nil
 	}
 	
 	var resp struct {
@@ -238,16 +365,16 @@ func (t *Tariffs) Show(ctx context.Context, masterID uint) (*Tariff, error) {
 		return nil, errors.New("tariff not found")
 	}
 	tariff := resp.Results[0]
-	t.cache.Set(key, tariff, cache.DefaultExpiration)
+	t.Agent.store(ctx, key, tariff)
 	return &tariff, nil
 }
 
 // GetCurrent retrieves the current tariff for a given account ID
 func (t *Tariffs) GetCurrent(ctx context.Context, accountID string) (*Tariff, error) {
-	key := fmt.Sprintf("account_tariff_%s", accountID)
-	if cached, ok := t.cache.Get(key); ok {
-		tariff := cached.(Tariff)
-		return &tariff, nil
+	key := accountTariffKey(accountID)
+	var cached Tariff
+	if t.Agent.cached(ctx, key, &cached) {
+		return &cached, nil
 	}
 	
 	var resp struct {
@@ -267,13 +394,260 @@ func (t *Tariffs) GetCurrent(ctx context.Context, accountID string) (*Tariff, er
 	// Return the first active tariff, or the first one if none are active
 	for _, tariff := range resp.Results {
 		if tariff.IsActive {
-			t.cache.Set(key, tariff, cache.DefaultExpiration)
+			t.Agent.store(ctx, key, tariff)
 			return &tariff, nil
 		}
 	}
 	
 	// If no active tariff found, return the first one
 	tariff := resp.Results[0]
-	t.cache.Set(key, tariff, cache.DefaultExpiration)
+	t.Agent.store(ctx, key, tariff)
 	return &tariff, nil
 }
+
+// accountTariffKey caches an account's current tariff, which depends on
+// the account's properties.
+func accountTariffKey(accountID string) string {
+	return fmt.Sprintf("account_tariff_%s", accountID)
+}
+
+// Customer classes tariffs are offered to, for tariff search.
+const (
+	CustomerClassResidential = "RESIDENTIAL"
+	CustomerClassGeneral     = "GENERAL"
+	CustomerClassSpecialUse  = "SPECIAL_USE"
+)
+
+// customerClassValues are the AccountPropertyCustomerClass values for each
+// customer class.
+var customerClassValues = map[string]string{
+	CustomerClassResidential: "1",
+	CustomerClassGeneral:     "2",
+	CustomerClassSpecialUse:  "4",
+}
+
+// NewCustomerClassProp returns the account property for a customer class,
+// or false when the class is not known.
+func NewCustomerClassProp(class string) (AccountProperty, bool) {
+	value, ok := customerClassValues[strings.ToUpper(class)]
+	return NewAccountProp(AccountPropertyCustomerClass, value), ok
+}
+
+// SetProperty sets one property on an account and returns the account.
+// The account's cached tariff is dropped, as the property may change it.
+func (a *Accounts) SetProperty(ctx context.Context, accountID string, prop AccountProperty) (*Account, error) {
+	var resp struct {
+		Results []Account `json:"results"`
+	}
+	path := fmt.Sprintf("v1/accounts/%s/properties", url.PathEscape(accountID))
+	err := a.Agent.doRequest(ctx, "PUT", path, prop.KeyValue, &resp)
+	// A failed PUT may still have reached Genability.
+	a.Agent.forget(ctx, accountTariffKey(accountID))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("account not found")
+	}
+	return &resp.Results[0], nil
+}
+
+// SetProperties sets properties on an account one at a time, as the API
+// takes them, and returns the account after the last.
+func (a *Accounts) SetProperties(ctx context.Context, accountID string, props []AccountProperty) (*Account, error) {
+	if len(props) == 0 {
+		return a.Show(ctx, accountID)
+	}
+	var account *Account
+	for _, prop := range props {
+		var err error
+		if account, err = a.SetProperty(ctx, accountID, prop); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", prop.Key, err)
+		}
+	}
+	return account, nil
+}
+
+// LoadServingEntity is a utility that serves electricity.
+type LoadServingEntity struct {
+	ID                   uint   `json:"lseId"`
+	Name                 string `json:"name"`
+	Code                 string `json:"code"`
+	WebsiteHome          string `json:"websiteHome,omitempty"`
+	ServiceTypes         string `json:"serviceTypes,omitempty"`
+	TotalCustomers       int    `json:"totalCustomers,omitempty"`
+	ResidentialCustomers int    `json:"residentialCustomers,omitempty"`
+}
+
+type LSEs struct {
+	Agent *Agent
+}
+
+func NewLSEs(agent *Agent) *LSEs {
+	return &LSEs{Agent: agent}
+}
+
+// Index lists the utilities serving residential electricity in a zip
+// code, the ones with the most customers first.
+func (l *LSEs) Index(ctx context.Context, zipcode, country string) ([]LoadServingEntity, error) {
+	key := fmt.Sprintf("lses_%s_%s", zipcode, country)
+	var cached []LoadServingEntity
+	if l.Agent.cached(ctx, key, &cached) {
+		return cached, nil
+	}
+	v := url.Values{}
+	v.Set("zipCode", zipcode)
+	v.Set("country", country)
+	v.Set("residentialServiceTypes", "ELECTRICITY")
+	v.Set("fields", "ext")
+	v.Set("sortOn", "totalCustomers")
+	v.Set("sortOrder", "DESC")
+	v.Set("pageCount", "100")
+
+	var all []LoadServingEntity
+	for page := 0; ; page++ {
+		v.Set("pageStart", strconv.Itoa(page*100))
+		var resp struct {
+			Results []LoadServingEntity `json:"results"`
+			Count   int                 `json:"count"`
+		}
+		if err := l.Agent.doRequest(ctx, "GET", "public/lses?"+v.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Results...)
+		if (page+1)*100 >= resp.Count {
+			break
+		}
+	}
+	l.Agent.store(ctx, key, all)
+	return all, nil
+}
+
+// TariffSearch narrows active tariffs by place, customer class and utility.
+// An empty CustomerClass searches residential tariffs.
+type TariffSearch struct {
+	ZipCode       string
+	Country       string
+	CustomerClass string
+	LseID         uint
+}
+
+// Search lists the active tariffs matching s.
+func (t *Tariffs) Search(ctx context.Context, s TariffSearch) ([]Tariff, error) {
+	key := fmt.Sprintf("search_%s_%s_%s_%d", s.ZipCode, s.Country, s.CustomerClass, s.LseID)
+	var cached []Tariff
+	if t.Agent.cached(ctx, key, &cached) {
+		return cached, nil
+	}
+	v := url.Values{}
+	if s.ZipCode != "" {
+		v.Set("zipCode", s.ZipCode)
+	}
+	if s.Country != "" {
+		v.Set("country", s.Country)
+	}
+	if s.CustomerClass != "" {
+		v.Set("customerClasses", strings.ToUpper(s.CustomerClass))
+	}
+	if s.LseID != 0 {
+		v.Set("lseId", strconv.FormatUint(uint64(s.LseID), 10))
+	}
+
+	data, err := t.fetch(ctx, v)
+	if err == nil {
+		t.Agent.store(ctx, key, data)
+	}
+	return data, err
+}
+
+// Property input keys for the calculator.
+const (
+	PropertyConsumption = "consumption"
+	PropertyTerritoryID = "territoryId"
+)
+
+// Calculator detail levels and groupings.
+const (
+	DetailLevelTotal      = "TOTAL"
+	DetailLevelChargeType = "CHARGE_TYPE"
+	DetailLevelRate       = "RATE"
+	GroupByMonth          = "MONTH"
+	GroupByYear           = "YEAR"
+)
+
+// PropertyInput is a value the calculator bills with: usage over a period
+// for PropertyConsumption, or a tariff property such as PropertyTerritoryID.
+// Dates are YYYY-MM-DD or RFC 3339.
+type PropertyInput struct {
+	Key   string `json:"keyName"`
+	Value string `json:"dataValue"`
+	From  string `json:"fromDateTime,omitempty"`
+	To    string `json:"toDateTime,omitempty"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// NewConsumptionInput returns kWh used between from and to.
+func NewConsumptionInput(from, to string, kwh float64) PropertyInput {
+	return PropertyInput{
+		Key:   PropertyConsumption,
+		Value: strconv.FormatFloat(kwh, 'f', -1, 64),
+		From:  from,
+		To:    to,
+		Unit:  "kWh",
+	}
+}
+
+// CalculateRequest is an on-demand bill calculation on a tariff.
+type CalculateRequest struct {
+	From           string          `json:"fromDateTime"`
+	To             string          `json:"toDateTime"`
+	MasterTariffID uint            `json:"masterTariffId"`
+	GroupBy        string          `json:"groupBy,omitempty"`
+	DetailLevel    string          `json:"detailLevel,omitempty"`
+	PropertyInputs []PropertyInput `json:"propertyInputs,omitempty"`
+}
+
+// CalculatedCostItem is one charge of a calculated bill.
+type CalculatedCostItem struct {
+	TariffRateID uint    `json:"tariffRateId,omitempty"`
+	RateName     string  `json:"rateName"`
+	ChargeType   string  `json:"chargeType"`
+	From         string  `json:"fromDateTime"`
+	To           string  `json:"toDateTime"`
+	QuantityKey  string  `json:"quantityKey,omitempty"`
+	RateAmount   float64 `json:"rateAmount"`
+	ItemQuantity float64 `json:"itemQuantity"`
+	Cost         float64 `json:"cost"`
+}
+
+// CalculatedCost is the cost of a calculation's period on its tariff.
+type CalculatedCost struct {
+	MasterTariffID uint                 `json:"masterTariffId"`
+	TariffName     string               `json:"tariffName"`
+	From           string               `json:"fromDateTime"`
+	To             string               `json:"toDateTime"`
+	TotalCost      float64              `json:"totalCost"`
+	Items          []CalculatedCostItem `json:"items"`
+}
+
+type Calculator struct {
+	Agent *Agent
+}
+
+func NewCalculator(agent *Agent) *Calculator {
+	return &Calculator{Agent: agent}
+}
+
+// Calculate prices usage on a tariff without an account.
+func (c *Calculator) Calculate(ctx context.Context, req CalculateRequest) (*CalculatedCost, error) {
+	var resp struct {
+		Results []CalculatedCost `json:"results"`
+	}
+	if err := c.Agent.doIdempotentRequest(ctx, "POST", "v1/ondemand/calculate", req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("no cost returned from Genability")
+	}
+	return &resp.Results[0], nil
+}
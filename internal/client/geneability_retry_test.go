@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter time.Duration
+		// The wait is random up to ceiling, or exactly exact when set.
+		ceiling, exact time.Duration
+	}{
+		{name: "first retry", attempt: 1, ceiling: 100 * time.Millisecond},
+		{name: "doubles", attempt: 3, ceiling: 400 * time.Millisecond},
+		{name: "capped", attempt: 6, ceiling: time.Second},
+		{name: "Retry-After", attempt: 1, retryAfter: 700 * time.Millisecond, exact: 700 * time.Millisecond},
+		{name: "Retry-After capped", attempt: 1, retryAfter: time.Minute, exact: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 200 {
+				wait := p.backoff(tt.attempt, tt.retryAfter)
+				if tt.exact > 0 && wait != tt.exact {
+					t.Fatalf("wait = %v, want %v", wait, tt.exact)
+				}
+				if tt.exact == 0 && (wait < 0 || wait > tt.ceiling) {
+					t.Fatalf("wait = %v, want up to %v", wait, tt.ceiling)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.header)
+		if got := retryAfter(resp); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	// cool ends the breaker's cooldown.
+	cool := func(b *breaker) { b.openUntil = time.Now().Add(-time.Millisecond) }
+	tests := []struct {
+		name  string
+		steps func(b *breaker)
+		// wantAllow is whether the next two requests may be made.
+		wantAllow [2]bool
+	}{
+		{"closed", func(*breaker) {}, [2]bool{true, true}},
+		{"under the threshold", func(b *breaker) {
+			b.record(false)
+			b.record(false)
+		}, [2]bool{true, true}},
+		{"a success resets the count", func(b *breaker) {
+			b.record(false)
+			b.record(false)
+			b.record(true)
+			b.record(false)
+		}, [2]bool{true, true}},
+		{"opens at the threshold", func(b *breaker) {
+			for range 3 {
+				b.record(false)
+			}
+		}, [2]bool{false, false}},
+		{"half-open lets one probe through", func(b *breaker) {
+			for range 3 {
+				b.record(false)
+			}
+			cool(b)
+		}, [2]bool{true, false}},
+		{"a successful probe closes it", func(b *breaker) {
+			for range 3 {
+				b.record(false)
+			}
+			cool(b)
+			b.allow()
+			b.record(true)
+		}, [2]bool{true, true}},
+		{"a failed probe opens it again", func(b *breaker) {
+			for range 3 {
+				b.record(false)
+			}
+			cool(b)
+			b.allow()
+			b.record(false)
+		}, [2]bool{false, false}},
+		{"an abandoned probe lets another through", func(b *breaker) {
+			for range 3 {
+				b.record(false)
+			}
+			cool(b)
+			b.allow()
+			b.abandon()
+		}, [2]bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(3, time.Minute)
+			tt.steps(b)
+			for i, want := range tt.wantAllow {
+				if got := b.allow(); got != want {
+					t.Errorf("request %d allowed = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+// testAgent returns an agent on a server answering with statuses in turn,
+// then 200, and a count of the requests it got.
+func testAgent(t *testing.T, retryAfter string, statuses ...int) (*Agent, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte(`{"results":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return &Agent{
+		client:  srv.Client(),
+		base:    srv.URL + "/",
+		timeout: time.Second,
+		retry:   RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		breaker: newBreaker(2, time.Minute),
+		cache:   NewMemoryCache(),
+	}, &calls
+}
+
+func TestAgentRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		wantCalls    int32
+		wantStatus   int
+		wantFailures int
+	}{
+		{"success", http.MethodGet, nil, 1, 0, 0},
+		{"GET retried until it succeeds", http.MethodGet, []int{503, 429}, 3, 0, 0},
+		{"GET gives up after MaxAttempts", http.MethodGet, []int{500, 502, 503}, 3, 503, 1},
+		{"PUT is retried", http.MethodPut, []int{503}, 2, 0, 0},
+		{"POST is sent once", http.MethodPost, []int{503}, 1, 503, 1},
+		{"client errors are not retried or counted", http.MethodGet, []int{404}, 1, 404, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, calls := testAgent(t, "", tt.statuses...)
+			err := a.doRequest(context.Background(), tt.method, "v1/test", nil, nil)
+			var apiErr *APIError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Errorf("err = %v, want success", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus):
+				t.Errorf("err = %v, want status %d", err, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			if a.breaker.failures != tt.wantFailures {
+				t.Errorf("breaker failures = %d, want %d", a.breaker.failures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestAgentIdempotentPostIsRetried(t *testing.T) {
+	a, calls := testAgent(t, "", 503)
+	if err := a.doIdempotentRequest(context.Background(), http.MethodPost, "v1/calculate", nil, nil); err != nil {
+		t.Fatalf("doIdempotentRequest: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("%d calls, want 2", got)
+	}
+}
+
+func TestAgentCircuitOpen(t *testing.T) {
+	a, calls := testAgent(t, "", 503, 503)
+	ctx := context.Background()
+	for range 2 {
+		a.doRequest(ctx, http.MethodPost, "v1/test", nil, nil)
+	}
+	if err := a.doRequest(ctx, http.MethodGet, "v1/test", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("%d calls, want 2, none while the breaker is open", got)
+	}
+}
+
+func TestAgentCancelledDuringBackoff(t *testing.T) {
+	// Retry-After holds the retry for a second, well past the deadline.
+	a, _ := testAgent(t, "1", 503)
+	a.retry.MaxBackoff = time.Second
+	// The breaker is half-open and this request is its probe.
+	a.breaker.failures = a.breaker.threshold
+	a.breaker.openUntil = time.Now().Add(-time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.doRequest(ctx, http.MethodGet, "v1/test", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline", err)
+	}
+	if a.breaker.failures != a.breaker.threshold || a.breaker.probing {
+		t.Errorf("breaker failures = %d and probing = %v, want %d and no probe", a.breaker.failures, a.breaker.probing, a.breaker.threshold)
+	}
+	if !a.breaker.allow() {
+		t.Error("the breaker let no new probe through")
+	}
+}
@@ -16,8 +16,12 @@ import (
 	"sync"
 )
 
+// DefaultMeshURL is where LightFusion publishes project meshes.
+const DefaultMeshURL = "https://storage.googleapis.com/lightfusiondev"
+
 type LightFusionClient struct {
 	baseURL    string
+	meshURL    string
 	httpClient *http.Client
 	apiKey     string
 }
@@ -25,11 +29,18 @@ type LightFusionClient struct {
 func NewLightFusionClient(baseURL, apiKey string) *LightFusionClient {
 	return &LightFusionClient{
 		baseURL:    baseURL,
+		meshURL:    DefaultMeshURL,
 		httpClient: &http.Client{},
 		apiKey:     apiKey,
 	}
 }
 
+// SetMeshURL downloads project meshes from meshURL instead of
+// DefaultMeshURL.
+func (c *LightFusionClient) SetMeshURL(meshURL string) {
+	c.meshURL = strings.TrimSuffix(meshURL, "/")
+}
+
 type LoginRequest struct {
 	Contact  string `json:"contact"`
 	Password string `json:"password"`
@@ -233,27 +244,56 @@ type CompanyInfo struct {
 }
 
 type Panel struct {
-    ID           int     `json:"ID"`
-    Manufacturer string  `json:"Manufacturer"`
-    Model        string  `json:"Model"`
-    DisplayName  string  `json:"DisplayName"`
-    Active       bool    `json:"Active"`
-    IsDefault    bool    `json:"IsDefault"`
-    Power        float64 `json:"Power"`
-    PricePerWatt float64 `json:"PricePerWatt"`
-    IsDomestic   bool    `json:"IsDomestic"`
+    ID            int     `json:"ID"`
+    Manufacturer  string  `json:"Manufacturer"`
+    Model         string  `json:"Model"`
+    DisplayName   string  `json:"DisplayName"`
+    Active        bool    `json:"Active"`
+    IsDefault     bool    `json:"IsDefault"`
+    Power         float64 `json:"Power"`
+    PricePerWatt  float64 `json:"PricePerWatt"`
+    IsDomestic    bool    `json:"IsDomestic"`
+    Voc           float64 `json:"Voc,omitempty"`
+    Isc           float64 `json:"Isc,omitempty"`
+    Vmp           float64 `json:"Vmp,omitempty"`
+    Imp           float64 `json:"Imp,omitempty"`
+    TempCoeffVoc  float64 `json:"TempCoeffVoc,omitempty"`
+    TempCoeffPmax float64 `json:"TempCoeffPmax,omitempty"`
+    NOCT          float64 `json:"NOCT,omitempty"`
+    Efficiency    float64 `json:"Efficiency,omitempty"`
 }
 
 type Inverter struct {
-    Name         string  `json:"Name"`
-    CostType     string  `json:"CostType"`
-    Category     string  `json:"Category"`
-    IsActive     bool    `json:"IsActive"`
-    Cost         float64 `json:"Cost"`
-    ID           int     `json:"ID"`
-    Manufacturer string  `json:"Manufacturer"`
-    Capacity     float64 `json:"Capacity"`
-    Quantity     int     `json:"Quantity"`
+    Name            string  `json:"Name"`
+    CostType        string  `json:"CostType"`
+    Category        string  `json:"Category"`
+    IsActive        bool    `json:"IsActive"`
+    Cost            float64 `json:"Cost"`
+    ID              int     `json:"ID"`
+    Manufacturer    string  `json:"Manufacturer"`
+    Capacity        float64 `json:"Capacity"`
+    Quantity        int     `json:"Quantity"`
+    Model           string  `json:"Model,omitempty"`
+    MaxDCVoltage    float64 `json:"MaxDCVoltage,omitempty"`
+    MPPTMinVoltage  float64 `json:"MPPTMinVoltage,omitempty"`
+    MPPTMaxVoltage  float64 `json:"MPPTMaxVoltage,omitempty"`
+    MPPTCount       int     `json:"MPPTCount,omitempty"`
+    MaxInputCurrent float64 `json:"MaxInputCurrent,omitempty"`
+    Efficiency      float64 `json:"Efficiency,omitempty"`
+}
+
+type Battery struct {
+    ID                  int     `json:"ID"`
+    Name                string  `json:"Name"`
+    Manufacturer        string  `json:"Manufacturer"`
+    Model               string  `json:"Model"`
+    Capacity            float64 `json:"Capacity"`
+    UsableCapacity      float64 `json:"UsableCapacity,omitempty"`
+    Power               float64 `json:"Power,omitempty"`
+    RoundTripEfficiency float64 `json:"RoundTripEfficiency,omitempty"`
+    Cost                float64 `json:"Cost"`
+    IsActive            bool    `json:"IsActive"`
+    IsDomestic          bool    `json:"IsDomestic"`
 }
 
 
@@ -570,7 +610,7 @@ func (c *LightFusionClient) GetProjectFiles(ctx context.Context, projectID int)
 				return
 			}
 
-			endpoint := fmt.Sprintf("https://storage.googleapis.com/lightfusiondev/leads/%d/mesh/%s", projectID, f.filename)
+			endpoint := fmt.Sprintf("%s/leads/%d/mesh/%s", c.meshURL, projectID, f.filename)
 			if err := c.downloadMeshFile(ctx, endpoint, filePath); err != nil {
 				errMsg := fmt.Sprintf("failed to download %s: %v", f.filename, err)
 				log.Printf("Warning: %s", errMsg)
@@ -644,3 +684,73 @@ func (c *LightFusionClient) downloadMeshFile(ctx context.Context, endpoint, dest
 
 	return nil
 }
+
+// ListPanels fetches the LightFusion panel catalog.
+func (c *LightFusionClient) ListPanels(ctx context.Context) ([]Panel, error) {
+	var resp struct {
+		Panels []Panel `json:"panels"`
+	}
+	if err := c.postV3(ctx, "hardware.ListPanels", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Panels, nil
+}
+
+// ListInverters fetches the LightFusion inverter catalog.
+func (c *LightFusionClient) ListInverters(ctx context.Context) ([]Inverter, error) {
+	var resp struct {
+		Inverters []Inverter `json:"inverters"`
+	}
+	if err := c.postV3(ctx, "hardware.ListInverters", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Inverters, nil
+}
+
+// ListBatteries fetches the LightFusion storage catalog.
+func (c *LightFusionClient) ListBatteries(ctx context.Context) ([]Battery, error) {
+	var resp struct {
+		Batteries []Battery `json:"batteries"`
+	}
+	if err := c.postV3(ctx, "hardware.ListBatteries", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Batteries, nil
+}
+
+func (c *LightFusionClient) postV3(ctx context.Context, method string, body any, out any) error {
+	if c.apiKey == "" {
+		return fmt.Errorf("not authenticated with LightFusion API")
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s", c.baseURL, method)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w, body: %s", err, string(bodyBytes))
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package client
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -23,6 +25,13 @@ func InitializeSendGrid() *SendGridClient {
 	}
 
 	client := sendgrid.NewSendClient(apiKey)
+	// SENDGRID_API_URL sends mail through another host, such as the local
+	// mock server.
+	if apiURL := os.Getenv("SENDGRID_API_URL"); apiURL != "" {
+		request := sendgrid.GetRequest(apiKey, "/v3/mail/send", apiURL)
+		request.Method = "POST"
+		client = &sendgrid.Client{Request: request}
+	}
 	from := mail.NewEmail("SunReady Team", fromEmail)
 
 	return &SendGridClient{
@@ -51,3 +60,21 @@ func (sg *SendGridClient) SendWelcomeEmail(toEmail, name string) error {
 	fmt.Printf("Welcome email sent to %s successfully\n", toEmail)
 	return nil
 }
+
+// SendEmail sends a plain text email. The HTML part is the same text with
+// line breaks kept.
+func (sg *SendGridClient) SendEmail(toEmail, name, subject, body string) error {
+	to := mail.NewEmail(name, toEmail)
+	htmlContent := strings.ReplaceAll(html.EscapeString(body), "\n", "<br>")
+
+	message := mail.NewSingleEmail(sg.from, subject, to, body, htmlContent)
+	response, err := sg.client.Send(message)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("failed to send email, status code: %d, body: %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
@@ -9,7 +9,10 @@ import (
 	"time"
     "os"
     "log"
+	"net/http"
+	"net/url"
 	"github.com/twilio/twilio-go"
+	twilioclient "github.com/twilio/twilio-go/client"
 	openapi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
@@ -34,10 +37,25 @@ func InitializeTwilio() *TwilioClient {
 	if accountSID == "" || authToken == "" || fromNumber == "" {
      log.Fatal("Twilio SID, Auth Token, or From Number not set in environment")
 	}
-	client := twilio.NewRestClientWithParams(twilio.ClientParams{
+	params := twilio.ClientParams{
 		Username: accountSID,
 		Password: authToken,
-	})
+	}
+	// TWILIO_API_URL sends requests to another host, such as the local
+	// mock server.
+	if apiURL := os.Getenv("TWILIO_API_URL"); apiURL != "" {
+		target, err := url.Parse(apiURL)
+		if err != nil {
+			log.Fatalf("invalid TWILIO_API_URL %q: %v", apiURL, err)
+		}
+		base := &twilioclient.Client{
+			Credentials: twilioclient.NewCredentials(accountSID, authToken),
+			HTTPClient:  &http.Client{Transport: &rewriteHost{target: target}},
+		}
+		base.SetAccountSid(accountSID)
+		params = twilio.ClientParams{Client: base}
+	}
+	client := twilio.NewRestClientWithParams(params)
 
 	return &TwilioClient{
 		client:        client,
@@ -48,7 +66,7 @@ func InitializeTwilio() *TwilioClient {
 }
 
 func (tc *TwilioClient) SendOTP(phoneNumber string) error {
-	otp, err := generateOTP(6)
+	otp, err := GenerateOTP(6)
 	if err != nil {
 		return fmt.Errorf("failed to generate OTP: %w", err)
 	}
@@ -79,6 +97,19 @@ func (tc *TwilioClient) SendOTP(phoneNumber string) error {
 	return nil
 }
 
+// SendSMS sends a text message to phoneNumber.
+func (tc *TwilioClient) SendSMS(phoneNumber, body string) error {
+	params := &openapi.CreateMessageParams{}
+	params.SetTo(phoneNumber)
+	params.SetFrom(tc.fromNumber)
+	params.SetBody(body)
+
+	if _, err := tc.client.Api.CreateMessage(params); err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	return nil
+}
+
 func (tc *TwilioClient) VerifyOTP(phoneNumber, otp string) error {
 	tc.mu.Lock()
 	defer tc.mu.Unlock()
@@ -106,7 +137,8 @@ func (tc *TwilioClient) VerifyOTP(phoneNumber, otp string) error {
 	return nil
 }
 
-func generateOTP(length int) (string, error) {
+// GenerateOTP returns a random code of length digits.
+func GenerateOTP(length int) (string, error) {
 	const digits = "0123456789"
 	otp := make([]byte, length)
 	for i := range otp {
@@ -130,3 +162,17 @@ func (tc *TwilioClient) CleanupExpiredOTPs() {
 		}
 	}
 }
+
+// rewriteHost sends requests to target's scheme and host, under its path.
+type rewriteHost struct {
+	target *url.URL
+}
+
+func (t *rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.URL.Path = t.target.Path + req.URL.Path
+	req.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -40,7 +40,29 @@ func runMigrations(db *gorm.DB) error {
 		{&models.Project{}, "projects"},
 		{&models.Lead{}, "leads"},
 		{&models.Deal{}, "deals"},
+		{&models.Quote{}, "quotes"},
 		{&models.Proposal{}, "proposals"},
+		{&models.ProposalView{}, "proposal_views"},
+		{&models.ProposalOption{}, "proposal_options"},
+		{&models.ProposalOptionVersion{}, "proposal_option_versions"},
+		{&models.ProposalReminderSettings{}, "proposal_reminder_settings"},
+		{&models.NotificationLog{}, "notification_logs"},
+		{&models.SignatureRequest{}, "signature_requests"},
+		{&models.SignatureEvent{}, "signature_events"},
+		{&models.SignatureCode{}, "signature_codes"},
+		{&models.AdderCategory{}, "adder_categories"},
+		{&models.Adder{}, "adders"},
+		{&models.AdderVersion{}, "adder_versions"},
+		{&models.DealAdder{}, "deal_adders"},
+		{&models.FinancingProvider{}, "financing_providers"},
+		{&models.FinancingOption{}, "financing_options"},
+		{&models.Incentive{}, "incentives"},
+		{&models.LeadUsage{}, "lead_usage"},
+		{&models.GenabilityCacheEntry{}, "genability_cache"},
+		{&models.Panel{}, "panels"},
+		{&models.Inverter{}, "inverters"},
+		{&models.Battery{}, "batteries"},
+		{&models.CompanyHardware{}, "company_hardware"},
 	}
 
 	for _, table := range tables {
@@ -54,6 +76,55 @@ func runMigrations(db *gorm.DB) error {
 		}
 	}
 
+	// Columns added to tables that already exist. AutoMigrate is skipped for
+	// existing tables above, so they are added one by one.
+	columns := []struct {
+		model interface{}
+		name  string
+	}{
+		{&models.Lead{}, "battery_id"},
+		{&models.Proposal{}, "rejected_at"},
+		{&models.Proposal{}, "rejection_reason"},
+		{&models.Proposal{}, "monthly_production"},
+		{&models.Proposal{}, "monthly_consumption"},
+		{&models.Proposal{}, "document_hash"},
+		{&models.Proposal{}, "accepted_option_id"},
+		{&models.Proposal{}, "loan_term_months"},
+		{&models.Proposal{}, "cash_flow"},
+		{&models.Deal{}, "proposal_id"},
+		{&models.SignatureRequest{}, "contract"},
+		{&models.FinancingProvider{}, "deleted_at"},
+		{&models.FinancingOption{}, "deleted_at"},
+	}
+
+	for _, column := range columns {
+		if !db.Migrator().HasColumn(column.model, column.name) {
+			log.Printf("Adding column: %s", column.name)
+			if err := db.Migrator().AddColumn(column.model, column.name); err != nil {
+				log.Printf("Error adding column %s: %v", column.name, err)
+			}
+		}
+	}
+
+	// Indexes on columns added above, named by struct field.
+	indexes := []struct {
+		model interface{}
+		field string
+	}{
+		{&models.Deal{}, "ProposalID"},
+		{&models.FinancingProvider{}, "DeletedAt"},
+		{&models.FinancingOption{}, "DeletedAt"},
+	}
+
+	for _, index := range indexes {
+		if !db.Migrator().HasIndex(index.model, index.field) {
+			log.Printf("Creating index on: %s", index.field)
+			if err := db.Migrator().CreateIndex(index.model, index.field); err != nil {
+				log.Printf("Error creating index on %s: %v", index.field, err)
+			}
+		}
+	}
+
 	log.Println("Database migrations completed")
 	return nil
 }
@@ -0,0 +1,467 @@
+// Package design checks a system's electrical design: string lengths
+// against the inverter's voltage limits and MPPT window at the site's
+// temperature extremes, input current, DC/AC ratio and clipping. Findings
+// follow the NEC articles permitting reviewers check.
+package design
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidDesign = errors.New("invalid design")
+
+// Finding severities. Errors block permitting; warnings cost production or
+// need a designer's judgment.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Finding codes.
+const (
+	CodeMissingSpecs      = "missing_specs"
+	CodeSystemVoltage     = "system_voltage"
+	CodeInverterVoltage   = "inverter_voltage"
+	CodeMPPTLow           = "mppt_window_low"
+	CodeMPPTHigh          = "mppt_window_high"
+	CodeInputCurrent      = "input_current"
+	CodeInverterDCPower   = "inverter_dc_power"
+	CodeNoStringLayout    = "no_string_layout"
+	CodeMismatchedStrings = "mismatched_strings"
+	CodeStringPanelCount  = "string_panel_count"
+	CodeDCACRatioHigh     = "dc_ac_ratio_high"
+	CodeDCACRatioLow      = "dc_ac_ratio_low"
+	CodeClipping          = "clipping"
+	CodeSiteTemperatures  = "site_temperatures"
+)
+
+// MaxDwellingVoltage is the highest PV system voltage NEC 690.7 allows on
+// one- and two-family dwellings.
+const MaxDwellingVoltage = 600
+
+// Thresholds for the DC/AC ratio and clipping findings.
+const (
+	MaxDCACRatio   = 1.5
+	MinDCACRatio   = 0.9
+	MaxClippingPct = 3
+)
+
+const (
+	defaultNOCT          = 45
+	defaultVmpTempCoeff  = -0.35
+	stcTemperature       = 25
+	continuousMultiplier = 1.25
+	conductorMultiplier  = 1.56
+)
+
+// Panel is the datasheet a design is checked with. Temperature
+// coefficients are in %/°C.
+type Panel struct {
+	PowerW        float64 `json:"power_w" example:"400"`
+	Voc           float64 `json:"voc" example:"48.5"`
+	Isc           float64 `json:"isc" example:"10.25"`
+	Vmp           float64 `json:"vmp" example:"41.2"`
+	Imp           float64 `json:"imp" example:"9.71"`
+	TempCoeffVoc  float64 `json:"temp_coeff_voc,omitempty" example:"-0.24"`
+	TempCoeffPmax float64 `json:"temp_coeff_pmax,omitempty" example:"-0.26"`
+	NOCT          float64 `json:"noct,omitempty" example:"44"`
+}
+
+// Inverter is the datasheet a design is checked with. Zero limits aren't
+// checked.
+type Inverter struct {
+	ACWatts                float64 `json:"ac_watts" example:"7600"`
+	MaxDCVoltage           float64 `json:"max_dc_voltage,omitempty" example:"480"`
+	MPPTMinVoltage         float64 `json:"mppt_min_voltage,omitempty" example:"380"`
+	MPPTMaxVoltage         float64 `json:"mppt_max_voltage,omitempty" example:"480"`
+	MPPTCount              int     `json:"mppt_count,omitempty" example:"1"`
+	MaxInputCurrentPerMPPT float64 `json:"max_input_current_per_mppt,omitempty" example:"20"`
+	MaxDCPower             float64 `json:"max_dc_power,omitempty" example:"11800"`
+	Micro                  bool    `json:"micro,omitempty" example:"false"`
+}
+
+// Site holds the ambient temperatures the design must work at: the
+// coldest, which sets open-circuit voltage, and the hottest, which sets
+// the lowest operating voltage.
+type Site struct {
+	MinTempC float64 `json:"min_temp_c" example:"-6"`
+	MaxTempC float64 `json:"max_temp_c" example:"34"`
+}
+
+// String is a series string of panels on an inverter's MPPT input.
+// Inverter and MPPT are zero-based.
+type String struct {
+	Inverter int `json:"inverter" example:"0"`
+	MPPT     int `json:"mppt" example:"0"`
+	Modules  int `json:"modules" example:"10"`
+}
+
+// Design is a system to check. Strings may be left out to lay strings out
+// automatically. ClippingLossKWh and AnnualKWh come from a production
+// estimate at the inverter's capacity; zero skips the clipping check.
+type Design struct {
+	Panel           Panel    `json:"panel"`
+	Inverter        Inverter `json:"inverter"`
+	PanelCount      int      `json:"panel_count" example:"24"`
+	InverterCount   int      `json:"inverter_count" example:"1"`
+	Strings         []String `json:"strings,omitempty"`
+	Site            Site     `json:"site"`
+	ClippingLossKWh float64  `json:"clipping_loss_kwh,omitempty" example:"85"`
+	AnnualKWh       float64  `json:"annual_kwh,omitempty" example:"13900"`
+}
+
+// Finding is one problem with a design. Rule cites the code section it
+// comes from, if any.
+type Finding struct {
+	Code     string `json:"code" example:"system_voltage"`
+	Severity string `json:"severity" example:"error"`
+	Rule     string `json:"rule,omitempty" example:"NEC 690.7"`
+	Message  string `json:"message" example:"String 1 on inverter 1 reaches 612.4 V at -10°C, over the 600 V limit"`
+}
+
+// StringCheck is a string's temperature-corrected voltages and currents.
+type StringCheck struct {
+	String
+	VocCold float64 `json:"voc_cold" example:"507.2"`
+	VmpCold float64 `json:"vmp_cold" example:"438.4"`
+	VmpHot  float64 `json:"vmp_hot" example:"372.9"`
+	// MaxCurrent is 125% of Isc (NEC 690.8(A)(1)); ConductorAmpacity is
+	// the 156% of Isc the string's conductors must carry (690.8(B)).
+	MaxCurrent        float64 `json:"max_current" example:"12.81"`
+	ConductorAmpacity float64 `json:"conductor_ampacity" example:"15.99"`
+}
+
+// Report is the outcome of a check. Valid is false when any finding is an
+// error.
+type Report struct {
+	Valid bool `json:"valid" example:"true"`
+	Site  Site `json:"site"`
+	// Per-module voltages at the site's extremes.
+	ModuleVocCold float64 `json:"module_voc_cold" example:"50.72"`
+	ModuleVmpHot  float64 `json:"module_vmp_hot" example:"37.29"`
+	// MinModules and MaxModules bound a string's length on the inverter.
+	MinModules      int           `json:"min_modules_per_string" example:"8"`
+	MaxModules      int           `json:"max_modules_per_string" example:"11"`
+	Strings         []StringCheck `json:"strings"`
+	DCKW            float64       `json:"dc_kw" example:"9.6"`
+	ACKW            float64       `json:"ac_kw" example:"7.6"`
+	DCACRatio       float64       `json:"dc_ac_ratio" example:"1.26"`
+	ClippingLossKWh float64       `json:"clipping_loss_kwh" example:"85"`
+	ClippingPct     float64       `json:"clipping_pct" example:"0.61"`
+	Findings        []Finding     `json:"findings"`
+}
+
+// Add adds a finding, marking the report invalid if it's an error.
+func (r *Report) Add(code, severity, rule, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{Code: code, Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	if severity == SeverityError {
+		r.Valid = false
+	}
+}
+
+func (d *Design) Validate() error {
+	if d.PanelCount <= 0 {
+		return fmt.Errorf("%w: panel count must be greater than 0", ErrInvalidDesign)
+	}
+	if d.Panel.PowerW <= 0 || d.Inverter.ACWatts <= 0 {
+		return fmt.Errorf("%w: panel and inverter power must be greater than 0", ErrInvalidDesign)
+	}
+	if d.InverterCount < 0 {
+		return fmt.Errorf("%w: inverter count cannot be negative", ErrInvalidDesign)
+	}
+	if d.Site.MinTempC > d.Site.MaxTempC {
+		return fmt.Errorf("%w: minimum temperature is above the maximum", ErrInvalidDesign)
+	}
+	for _, s := range d.Strings {
+		if s.Modules <= 0 || s.Inverter < 0 || s.MPPT < 0 {
+			return fmt.Errorf("%w: strings need at least one module", ErrInvalidDesign)
+		}
+	}
+	return nil
+}
+
+// Inverters returns the design's inverter count: one per panel for
+// micro-inverters, otherwise InverterCount or, when that's zero, enough
+// inverters for the DC on them.
+func (d *Design) Inverters() int {
+	switch {
+	case d.Inverter.Micro:
+		return d.PanelCount
+	case d.InverterCount > 0:
+		return d.InverterCount
+	case d.Inverter.MaxDCPower > 0:
+		return max(1, int(math.Ceil(float64(d.PanelCount)*d.Panel.PowerW/d.Inverter.MaxDCPower)))
+	default:
+		return 1
+	}
+}
+
+// Check checks the design. Micro-inverter designs are checked panel by
+// panel, as a string of one on its own inverter.
+func Check(d Design) (*Report, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Report{Valid: true, Site: d.Site, Strings: []StringCheck{}, Findings: []Finding{}}
+	p, inv := d.Panel, d.Inverter
+	mpptCount := max(inv.MPPTCount, 1)
+
+	inverterCount := d.Inverters()
+	r.DCKW = round2(float64(d.PanelCount) * p.PowerW / 1000)
+	r.ACKW = round2(float64(inverterCount) * inv.ACWatts / 1000)
+	r.DCACRatio = round2(r.DCKW / r.ACKW)
+
+	specsKnown := p.Voc > 0 && p.Vmp > 0 && p.Isc > 0
+	if !specsKnown {
+		r.Add(CodeMissingSpecs, SeverityWarning, "",
+			"The panel's datasheet voltages and currents are missing, so strings can't be checked")
+	} else {
+		r.ModuleVocCold = round2(vocCold(p, d.Site.MinTempC))
+		r.ModuleVmpHot = round2(vmpAt(p, cellTemp(p, d.Site.MaxTempC)))
+		voltageLimit := float64(MaxDwellingVoltage)
+		if inv.MaxDCVoltage > 0 {
+			voltageLimit = math.Min(voltageLimit, inv.MaxDCVoltage)
+		}
+		r.MaxModules = int(math.Floor(voltageLimit / r.ModuleVocCold))
+		r.MinModules = 1
+		if inv.MPPTMinVoltage > 0 {
+			r.MinModules = int(math.Ceil(inv.MPPTMinVoltage / r.ModuleVmpHot))
+		}
+	}
+
+	strs := d.Strings
+	switch {
+	case len(strs) > 0:
+		total := 0
+		for _, s := range strs {
+			total += s.Modules
+		}
+		if total != d.PanelCount {
+			r.Add(CodeStringPanelCount, SeverityError, "",
+				"Strings hold %d panels but the design has %d", total, d.PanelCount)
+		}
+	case inv.Micro:
+		for i := range d.PanelCount {
+			strs = append(strs, String{Inverter: i, Modules: 1})
+		}
+	case specsKnown:
+		var ok bool
+		// Parallel strings per MPPT input, as many as its current rating takes.
+		parallel := 0
+		if inv.MaxInputCurrentPerMPPT > 0 {
+			parallel = max(1, int(math.Floor(inv.MaxInputCurrentPerMPPT/(p.Isc*continuousMultiplier))))
+		}
+		if strs, ok = layout(d.PanelCount, inverterCount, mpptCount, parallel, r.MinModules, r.MaxModules); !ok {
+			r.Add(CodeNoStringLayout, SeverityError, "",
+				"%d panels can't be strung on %d inverter(s) with %d to %d modules per string within the inputs' current rating",
+				d.PanelCount, inverterCount, r.MinModules, r.MaxModules)
+		}
+	}
+
+	if specsKnown {
+		checkStrings(r, d, strs)
+	}
+	checkInverters(r, d, strs, inverterCount)
+	checkRatio(r, d)
+	return r, nil
+}
+
+// checkStrings checks each string's voltage against the code limit, the
+// inverter's limit and its MPPT window, and each MPPT input's current.
+func checkStrings(r *Report, d Design, strs []String) {
+	p, inv := d.Panel, d.Inverter
+	vmpCold := vmpAt(p, d.Site.MinTempC)
+	type input struct{ inverter, mppt int }
+	current := map[input]float64{}
+	lengths := map[input]int{}
+	for i, s := range strs {
+		n := float64(s.Modules)
+		c := StringCheck{
+			String:            s,
+			VocCold:           round2(n * r.ModuleVocCold),
+			VmpCold:           round2(n * vmpCold),
+			VmpHot:            round2(n * r.ModuleVmpHot),
+			MaxCurrent:        round2(p.Isc * continuousMultiplier),
+			ConductorAmpacity: round2(p.Isc * conductorMultiplier),
+		}
+		r.Strings = append(r.Strings, c)
+		name := fmt.Sprintf("String %d on inverter %d", i+1, s.Inverter+1)
+
+		if c.VocCold > MaxDwellingVoltage {
+			r.Add(CodeSystemVoltage, SeverityError, "NEC 690.7",
+				"%s reaches %.1f V at %.0f°C, over the %d V limit for dwellings", name, c.VocCold, d.Site.MinTempC, MaxDwellingVoltage)
+		}
+		if inv.MaxDCVoltage > 0 && c.VocCold > inv.MaxDCVoltage {
+			r.Add(CodeInverterVoltage, SeverityError, "NEC 110.3(B)",
+				"%s reaches %.1f V at %.0f°C, over the inverter's %.0f V maximum", name, c.VocCold, d.Site.MinTempC, inv.MaxDCVoltage)
+		}
+		if inv.MPPTMinVoltage > 0 && c.VmpHot < inv.MPPTMinVoltage {
+			r.Add(CodeMPPTLow, SeverityWarning, "",
+				"%s drops to %.1f V at %.0f°C, below the %.0f V MPPT minimum", name, c.VmpHot, d.Site.MaxTempC, inv.MPPTMinVoltage)
+		}
+		if inv.MPPTMaxVoltage > 0 && c.VmpCold > inv.MPPTMaxVoltage {
+			r.Add(CodeMPPTHigh, SeverityWarning, "",
+				"%s operates at %.1f V at %.0f°C, above the %.0f V MPPT maximum", name, c.VmpCold, d.Site.MinTempC, inv.MPPTMaxVoltage)
+		}
+
+		key := input{s.Inverter, s.MPPT}
+		current[key] += c.MaxCurrent
+		if l, ok := lengths[key]; ok && l != s.Modules {
+			r.Add(CodeMismatchedStrings, SeverityWarning, "",
+				"Inverter %d MPPT %d has strings of %d and %d modules in parallel", s.Inverter+1, s.MPPT+1, l, s.Modules)
+		}
+		lengths[key] = s.Modules
+	}
+
+	if inv.MaxInputCurrentPerMPPT > 0 {
+		for key, amps := range current {
+			if amps > inv.MaxInputCurrentPerMPPT {
+				r.Add(CodeInputCurrent, SeverityError, "NEC 690.8(A)",
+					"Inverter %d MPPT %d carries %.2f A at 125%% of Isc, over its %g A input rating",
+					key.inverter+1, key.mppt+1, amps, inv.MaxInputCurrentPerMPPT)
+			}
+		}
+	}
+}
+
+// checkInverters checks the DC on each inverter against its rating.
+func checkInverters(r *Report, d Design, strs []String, inverterCount int) {
+	if d.Inverter.MaxDCPower <= 0 {
+		return
+	}
+	dc := make([]float64, inverterCount)
+	if len(strs) == 0 {
+		for i := range dc {
+			dc[i] = float64(d.PanelCount) * d.Panel.PowerW / float64(inverterCount)
+		}
+	}
+	for _, s := range strs {
+		if s.Inverter < len(dc) {
+			dc[s.Inverter] += float64(s.Modules) * d.Panel.PowerW
+		}
+	}
+	for i, w := range dc {
+		if w > d.Inverter.MaxDCPower {
+			r.Add(CodeInverterDCPower, SeverityError, "NEC 110.3(B)",
+				"Inverter %d has %.0f W of panels, over its %.0f W DC rating", i+1, w, d.Inverter.MaxDCPower)
+		}
+	}
+}
+
+func checkRatio(r *Report, d Design) {
+	switch {
+	case r.DCACRatio > MaxDCACRatio:
+		r.Add(CodeDCACRatioHigh, SeverityWarning, "",
+			"DC/AC ratio of %.2f is above %.2f; expect heavy clipping", r.DCACRatio, MaxDCACRatio)
+	case r.DCACRatio < MinDCACRatio:
+		r.Add(CodeDCACRatioLow, SeverityInfo, "",
+			"DC/AC ratio of %.2f is below %.2f; the inverter is oversized", r.DCACRatio, MinDCACRatio)
+	}
+	if d.AnnualKWh > 0 {
+		r.ClippingLossKWh = round2(d.ClippingLossKWh)
+		r.ClippingPct = round2(d.ClippingLossKWh / (d.AnnualKWh + d.ClippingLossKWh) * 100)
+		if r.ClippingPct > MaxClippingPct {
+			r.Add(CodeClipping, SeverityWarning, "",
+				"The inverter clips %.0f kWh a year, %.1f%% of production", r.ClippingLossKWh, r.ClippingPct)
+		}
+	}
+}
+
+// layout spreads panels evenly over inverters and, on each, uses the
+// fewest strings that keep every string within the module bounds and no
+// more than parallel strings on an MPPT input (zero for no limit). Strings
+// go to MPPT inputs in turn.
+func layout(panels, inverters, mppts, parallel, minModules, maxModules int) ([]String, bool) {
+	if maxModules < 1 || minModules > maxModules {
+		return nil, false
+	}
+	var out []String
+	for i := range inverters {
+		n := panels / inverters
+		if i < panels%inverters {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		count := 0
+		for s := 1; s <= n; s++ {
+			if parallel > 0 && s > mppts*parallel {
+				break
+			}
+			if (n+s-1)/s <= maxModules && n/s >= minModules {
+				count = s
+				break
+			}
+		}
+		if count == 0 {
+			return nil, false
+		}
+		for s := range count {
+			modules := n / count
+			if s < n%count {
+				modules++
+			}
+			out = append(out, String{Inverter: i, MPPT: s % mppts, Modules: modules})
+		}
+	}
+	return out, true
+}
+
+// vocCold returns a module's open-circuit voltage at the site's lowest
+// temperature. Without a datasheet coefficient it uses the correction
+// factors of NEC Table 690.7(A).
+func vocCold(p Panel, minTempC float64) float64 {
+	if p.TempCoeffVoc != 0 {
+		return p.Voc * (1 + p.TempCoeffVoc/100*(minTempC-stcTemperature))
+	}
+	return p.Voc * table6907A(minTempC)
+}
+
+// vmpAt returns a module's maximum-power voltage at a cell temperature.
+// Datasheets rarely give a Vmp coefficient; the Pmax coefficient is close,
+// since current barely moves with temperature.
+func vmpAt(p Panel, cellTempC float64) float64 {
+	coeff := p.TempCoeffPmax
+	if coeff == 0 {
+		coeff = defaultVmpTempCoeff
+	}
+	return p.Vmp * (1 + coeff/100*(cellTempC-stcTemperature))
+}
+
+// cellTemp returns a module's cell temperature in full sun at an ambient
+// temperature, from its NOCT.
+func cellTemp(p Panel, ambientC float64) float64 {
+	noct := p.NOCT
+	if noct == 0 {
+		noct = defaultNOCT
+	}
+	return ambientC + (noct-20)*1000/800
+}
+
+// table6907A returns the NEC Table 690.7(A) voltage correction factor for
+// crystalline modules.
+func table6907A(tempC float64) float64 {
+	bands := []struct{ from, factor float64 }{
+		{20, 1.02}, {15, 1.04}, {10, 1.06}, {5, 1.08}, {0, 1.10},
+		{-5, 1.12}, {-10, 1.14}, {-15, 1.16}, {-20, 1.18}, {-25, 1.20},
+		{-30, 1.21}, {-35, 1.23}, {-40, 1.25},
+	}
+	t := math.Round(tempC)
+	if t >= 25 {
+		return 1
+	}
+	for _, b := range bands {
+		if t >= b.from {
+			return b.factor
+		}
+	}
+	return 1.25
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
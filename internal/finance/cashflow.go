@@ -0,0 +1,186 @@
+// Package finance models what a solar system costs and saves over its life.
+package finance
+
+import "math"
+
+// CashFlowInput describes a system, how it is paid for and what it saves.
+// Rates are annual fractions (0.005 is 0.5% a year). Dollar amounts are in
+// first-year dollars and escalate as described on each field.
+type CashFlowInput struct {
+	Years        int
+	SystemSizeKW float64
+	// UpfrontPayment is paid at signing, year 0: the full price for cash or
+	// the down payment for a loan.
+	UpfrontPayment float64
+	// AnnualPayments are financing payments for years 1..n.
+	AnnualPayments []float64
+	// TaxCredit is received in TaxCreditYear, usually year 1 when the
+	// owner files taxes for the install year. A year past the horizon
+	// falls in its last year, as incentive values do.
+	TaxCredit     float64
+	TaxCreditYear int
+	// Incentives are other incentives received in years 1..n, e.g.
+	// rebates, state credits and performance payments.
+	Incentives []float64
+
+	FirstYearProductionKWh float64
+	// FirstYearBillSavings falls with production as panels degrade and
+	// rises with utility rates.
+	FirstYearBillSavings float64
+	Degradation          float64
+	UtilityEscalation    float64
+
+	// OMCostPerKW is the yearly operations and maintenance cost per kW,
+	// escalated with Inflation, as is the inverter replacement.
+	OMCostPerKW             float64
+	InverterReplacementYear int
+	InverterReplacementCost float64
+	Inflation               float64
+
+	DiscountRate float64
+}
+
+// YearCashFlow is one year of the model. Costs are negative.
+type YearCashFlow struct {
+	Year               int     `json:"year" example:"1"`
+	ProductionKWh      float64 `json:"production_kwh" example:"11500"`
+	BillSavings        float64 `json:"bill_savings" example:"1800.00"`
+	FinancingPayment   float64 `json:"financing_payment" example:"-1612.44"`
+	TaxCredit          float64 `json:"tax_credit" example:"9360.00"`
+	Incentives         float64 `json:"incentives" example:"0"`
+	OMCost             float64 `json:"om_cost" example:"-120.00"`
+	InverterCost       float64 `json:"inverter_cost" example:"0"`
+	NetCashFlow        float64 `json:"net_cash_flow" example:"9427.56"`
+	CumulativeCashFlow float64 `json:"cumulative_cash_flow" example:"9427.56"`
+	DiscountedCashFlow float64 `json:"discounted_cash_flow" example:"8978.63"`
+}
+
+// CashFlow is the result of the model. IRR is nil when the cash flows
+// never change sign, e.g. a loan that saves money from the first year.
+// PaybackYear is the first year cumulative cash flow is not negative, or 0
+// if that never happens.
+type CashFlow struct {
+	UpfrontCost     float64        `json:"upfront_cost" example:"36000.00"`
+	NPV             float64        `json:"npv" example:"18250.40"`
+	IRR             *float64       `json:"irr,omitempty" example:"0.0912"`
+	LCOE            float64        `json:"lcoe" example:"0.0874"`
+	PaybackYear     int            `json:"payback_year" example:"9"`
+	TotalNetSavings float64        `json:"total_net_savings" example:"41200.75"`
+	Years           []YearCashFlow `json:"years"`
+}
+
+// Model runs the year-by-year cash flow.
+func Model(in CashFlowInput) *CashFlow {
+	out := &CashFlow{
+		UpfrontCost: round2(in.UpfrontPayment),
+		Years:       make([]YearCashFlow, 0, in.Years),
+	}
+	flows := []float64{-in.UpfrontPayment}
+	cumulative := -in.UpfrontPayment
+	pvCost, pvEnergy := in.UpfrontPayment, 0.0
+	taxCreditYear := min(max(in.TaxCreditYear, 1), in.Years)
+
+	for year := 1; year <= in.Years; year++ {
+		n := float64(year - 1)
+		discount := math.Pow(1+in.DiscountRate, float64(year))
+		inflation := math.Pow(1+in.Inflation, n)
+		degradation := math.Pow(1-in.Degradation, n)
+
+		y := YearCashFlow{
+			Year:          year,
+			ProductionKWh: in.FirstYearProductionKWh * degradation,
+			BillSavings:   in.FirstYearBillSavings * degradation * math.Pow(1+in.UtilityEscalation, n),
+			OMCost:        -in.OMCostPerKW * in.SystemSizeKW * inflation,
+		}
+		if year <= len(in.AnnualPayments) {
+			y.FinancingPayment = -in.AnnualPayments[year-1]
+		}
+		if year == taxCreditYear {
+			y.TaxCredit = in.TaxCredit
+		}
+		if year <= len(in.Incentives) {
+			y.Incentives = in.Incentives[year-1]
+		}
+		if year == in.InverterReplacementYear {
+			y.InverterCost = -in.InverterReplacementCost * inflation
+		}
+
+		y.NetCashFlow = y.BillSavings + y.FinancingPayment + y.TaxCredit + y.Incentives + y.OMCost + y.InverterCost
+		cumulative += y.NetCashFlow
+		y.CumulativeCashFlow = cumulative
+		y.DiscountedCashFlow = y.NetCashFlow / discount
+		if cumulative >= 0 && out.PaybackYear == 0 {
+			out.PaybackYear = year
+		}
+
+		flows = append(flows, y.NetCashFlow)
+		pvCost += -(y.FinancingPayment + y.TaxCredit + y.Incentives + y.OMCost + y.InverterCost) / discount
+		pvEnergy += y.ProductionKWh / discount
+		out.Years = append(out.Years, roundYear(y))
+	}
+
+	out.NPV = round2(NPV(in.DiscountRate, flows))
+	out.TotalNetSavings = round2(cumulative)
+	if pvEnergy > 0 {
+		out.LCOE = round4(pvCost / pvEnergy)
+	}
+	if irr, ok := IRR(flows); ok {
+		irr = round4(irr)
+		out.IRR = &irr
+	}
+	return out
+}
+
+// NPV discounts flows, where flows[0] is year 0, at rate.
+func NPV(rate float64, flows []float64) float64 {
+	npv := 0.0
+	for year, flow := range flows {
+		npv += flow / math.Pow(1+rate, float64(year))
+	}
+	return npv
+}
+
+// IRR finds the rate at which flows have an NPV of zero. It reports false
+// when NPV does not change sign between -99% and 1000%.
+func IRR(flows []float64) (float64, bool) {
+	lo, hi := -0.99, 10.0
+	npvLo, npvHi := NPV(lo, flows), NPV(hi, flows)
+	if math.IsNaN(npvLo) || math.IsNaN(npvHi) || npvLo*npvHi > 0 {
+		return 0, false
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		npvMid := NPV(mid, flows)
+		if math.Abs(npvMid) < 1e-7 || hi-lo < 1e-10 {
+			return mid, true
+		}
+		if npvLo*npvMid < 0 {
+			hi = mid
+		} else {
+			lo, npvLo = mid, npvMid
+		}
+	}
+	return (lo + hi) / 2, true
+}
+
+func roundYear(y YearCashFlow) YearCashFlow {
+	y.ProductionKWh = round2(y.ProductionKWh)
+	y.BillSavings = round2(y.BillSavings)
+	y.FinancingPayment = round2(y.FinancingPayment)
+	y.TaxCredit = round2(y.TaxCredit)
+	y.Incentives = round2(y.Incentives)
+	y.OMCost = round2(y.OMCost)
+	y.InverterCost = round2(y.InverterCost)
+	y.NetCashFlow = round2(y.NetCashFlow)
+	y.CumulativeCashFlow = round2(y.CumulativeCashFlow)
+	y.DiscountedCashFlow = round2(y.DiscountedCashFlow)
+	return y
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
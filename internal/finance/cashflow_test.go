@@ -0,0 +1,149 @@
+package finance
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// base is a 10 kW system that saves $1,800 in its first year.
+func base() CashFlowInput {
+	return CashFlowInput{
+		Years:                   25,
+		SystemSizeKW:            10,
+		TaxCreditYear:           1,
+		FirstYearProductionKWh:  14000,
+		FirstYearBillSavings:    1800,
+		Degradation:             0.005,
+		UtilityEscalation:       0.03,
+		OMCostPerKW:             15,
+		InverterReplacementYear: 12,
+		InverterReplacementCost: 2000,
+		Inflation:               0.025,
+		DiscountRate:            0.05,
+	}
+}
+
+func TestModelGolden(t *testing.T) {
+	cash := base()
+	cash.UpfrontPayment = 30000
+	cash.TaxCredit = 9000
+
+	loan := base()
+	loan.TaxCredit = 9000
+	loan.AnnualPayments = Loan{APR: 0.0699, TermMonths: 300, LoanFee: 0.2}.Terms(System{Price: 30000, TaxCreditRate: 0.3}).AnnualPayments
+
+	deferredCredit := base()
+	deferredCredit.UpfrontPayment = 30000
+	deferredCredit.TaxCredit = 9000
+	deferredCredit.TaxCreditYear = 2
+
+	noInverter := base()
+	noInverter.UpfrontPayment = 30000
+	noInverter.TaxCredit = 9000
+	noInverter.InverterReplacementYear = 0
+
+	tests := []struct {
+		name string
+		in   CashFlowInput
+	}{
+		{"model_cash", cash},
+		{"model_loan", loan},
+		{"model_tax_credit_year_2", deferredCredit},
+		{"model_no_inverter_replacement", noInverter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.name, Model(tt.in))
+		})
+	}
+}
+
+func TestModelTaxCreditYear(t *testing.T) {
+	tests := []struct {
+		name          string
+		years         int
+		taxCreditYear int
+		want          int
+	}{
+		{"unset", 25, 0, 1},
+		{"first year", 25, 1, 1},
+		{"deferred", 25, 2, 2},
+		{"past the horizon", 3, 5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := base()
+			in.Years = tt.years
+			in.TaxCredit = 9000
+			in.TaxCreditYear = tt.taxCreditYear
+			out := Model(in)
+			for _, y := range out.Years {
+				want := 0.0
+				if y.Year == tt.want {
+					want = 9000
+				}
+				if y.TaxCredit != want {
+					t.Errorf("year %d tax credit = %v, want %v", y.Year, y.TaxCredit, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNPVAndIRRGolden(t *testing.T) {
+	type result struct {
+		Flows []float64 `json:"flows"`
+		NPV   float64   `json:"npv"`
+		IRR   *float64  `json:"irr"`
+	}
+	flows := map[string][]float64{
+		"payback":    {-30000, 9000 + 1800, 1850, 1900, 1950, 2000, 2050, 2100, 2150, 2200, 2250},
+		"never_paid": {-30000, 1000, 1000, 1000},
+		"all_gains":  {0, 500, 500},
+	}
+	out := make(map[string]result, len(flows))
+	for name, f := range flows {
+		r := result{Flows: f, NPV: round2(NPV(0.05, f))}
+		if irr, ok := IRR(f); ok {
+			irr = round4(irr)
+			r.IRR = &irr
+		}
+		out[name] = r
+	}
+	assertGolden(t, "npv_irr", out)
+}
+
+// assertGolden compares got, as indented JSON, with testdata/name.golden.json,
+// rewriting the file instead when the tests run with -update.
+func assertGolden(t *testing.T, name string, got any) {
+	t.Helper()
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal %s: %v", name, err)
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s (run with -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(want, data) {
+		t.Errorf("%s differs from %s (run with -update to accept):\n%s", name, path, data)
+	}
+}
@@ -0,0 +1,316 @@
+package finance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidProduct = errors.New("invalid financing product")
+
+// Financing product types.
+const (
+	ProductCash  = "cash"
+	ProductLoan  = "loan"
+	ProductLease = "lease"
+	ProductPPA   = "ppa"
+)
+
+// Product is a way of paying for a system. Products that leave the system
+// with a third party (leases and PPAs) report OwnsSystem false in their
+// terms: the owner then takes the tax credit and pays for upkeep.
+type Product interface {
+	Type() string
+	Name() string
+	Terms(s System) Terms
+}
+
+// System is what a product is asked to finance.
+type System struct {
+	// Price is the cash price before dealer fees and incentives.
+	Price         float64
+	TaxCreditRate float64
+	// ProductionKWh is expected production by year, year 1 first.
+	ProductionKWh []float64
+}
+
+// Terms is what the homeowner pays under a product. AnnualPayments are for
+// years 1..n and include any lump sums paid during the year.
+type Terms struct {
+	ContractPrice  float64
+	UpfrontPayment float64
+	MonthlyPayment float64
+	AnnualPayments []float64
+	TaxCredit      float64
+	OwnsSystem     bool
+}
+
+// Cash is an outright purchase.
+type Cash struct {
+	Label string
+}
+
+func (p Cash) Type() string { return ProductCash }
+func (p Cash) Name() string { return nameOr(p.Label, "Cash") }
+
+func (p Cash) Terms(s System) Terms {
+	return Terms{
+		ContractPrice:  s.Price,
+		UpfrontPayment: s.Price,
+		TaxCredit:      s.Price * s.TaxCreditRate,
+		OwnsSystem:     true,
+	}
+}
+
+// Loan is an amortized loan. LoanFee is the lender's dealer fee as a
+// fraction of the contract price and LoanFeeFixed a flat fee on top, grossed
+// up the same way the pricing engine does for a financing option. When
+// ITCPaydownMonth is set, the homeowner pays the tax credit down against the
+// balance that month and the loan is re-amortized over the months left.
+type Loan struct {
+	Label           string
+	APR             float64
+	TermMonths      int
+	LoanFee         float64
+	LoanFeeFixed    float64
+	DownPayment     float64
+	ITCPaydownMonth int
+}
+
+func (p Loan) Type() string { return ProductLoan }
+func (p Loan) Name() string {
+	return nameOr(p.Label, fmt.Sprintf("%d-year loan at %.2f%%", p.TermMonths/12, p.APR*100))
+}
+
+func (p Loan) Terms(s System) Terms {
+	contract := s.Price
+	if p.LoanFee > 0 && p.LoanFee < 1 {
+		contract = contract / (1 - p.LoanFee)
+	}
+	contract += p.LoanFeeFixed
+
+	t := Terms{
+		ContractPrice:  contract,
+		UpfrontPayment: p.DownPayment,
+		TaxCredit:      contract * s.TaxCreditRate,
+		OwnsSystem:     true,
+		AnnualPayments: make([]float64, (p.TermMonths+11)/12),
+	}
+
+	balance := math.Max(0, contract-p.DownPayment)
+	rate := p.APR / 12
+	payment := amortize(balance, rate, p.TermMonths)
+	t.MonthlyPayment = payment
+	for month := 1; month <= p.TermMonths && balance > 0; month++ {
+		year := (month - 1) / 12
+		if month == p.ITCPaydownMonth {
+			paydown := math.Min(t.TaxCredit, balance)
+			balance -= paydown
+			t.AnnualPayments[year] += paydown
+			payment = amortize(balance, rate, p.TermMonths-month+1)
+		}
+		interest := balance * rate
+		paid := math.Min(payment, balance+interest)
+		balance -= paid - interest
+		t.AnnualPayments[year] += paid
+	}
+	return t
+}
+
+// Lease is a fixed monthly payment that rises by Escalator each year.
+type Lease struct {
+	Label          string
+	MonthlyPayment float64
+	Escalator      float64
+	TermYears      int
+	UpfrontPayment float64
+}
+
+func (p Lease) Type() string { return ProductLease }
+func (p Lease) Name() string {
+	return nameOr(p.Label, fmt.Sprintf("%d-year lease", p.TermYears))
+}
+
+func (p Lease) Terms(s System) Terms {
+	t := Terms{
+		UpfrontPayment: p.UpfrontPayment,
+		MonthlyPayment: p.MonthlyPayment,
+		AnnualPayments: make([]float64, p.TermYears),
+	}
+	for y := range t.AnnualPayments {
+		t.AnnualPayments[y] = p.MonthlyPayment * 12 * math.Pow(1+p.Escalator, float64(y))
+	}
+	return t
+}
+
+// PPA sells the system's production per kWh at a rate that rises by
+// Escalator each year.
+type PPA struct {
+	Label          string
+	RatePerKWh     float64
+	Escalator      float64
+	TermYears      int
+	UpfrontPayment float64
+}
+
+func (p PPA) Type() string { return ProductPPA }
+func (p PPA) Name() string {
+	return nameOr(p.Label, fmt.Sprintf("%d-year PPA", p.TermYears))
+}
+
+func (p PPA) Terms(s System) Terms {
+	t := Terms{
+		UpfrontPayment: p.UpfrontPayment,
+		AnnualPayments: make([]float64, p.TermYears),
+	}
+	for y := range t.AnnualPayments {
+		if y >= len(s.ProductionKWh) {
+			break
+		}
+		t.AnnualPayments[y] = s.ProductionKWh[y] * p.RatePerKWh * math.Pow(1+p.Escalator, float64(y))
+	}
+	t.MonthlyPayment = t.AnnualPayments[0] / 12
+	return t
+}
+
+// ProductSpec is the JSON form of a product. Type picks which fields apply.
+type ProductSpec struct {
+	Type            string  `json:"type" example:"loan"`
+	Name            string  `json:"name,omitempty" example:"25-year loan"`
+	APR             float64 `json:"apr,omitempty" example:"0.0699"`
+	TermMonths      int     `json:"term_months,omitempty" example:"300"`
+	LoanFee         float64 `json:"loan_fee,omitempty" example:"0.25"`
+	LoanFeeFixed    float64 `json:"loan_fee_fixed,omitempty" example:"0"`
+	DownPayment     float64 `json:"down_payment,omitempty" example:"0"`
+	ITCPaydownMonth int     `json:"itc_paydown_month,omitempty" example:"18"`
+	MonthlyPayment  float64 `json:"monthly_payment,omitempty" example:"135.00"`
+	RatePerKWh      float64 `json:"rate_per_kwh,omitempty" example:"0.16"`
+	Escalator       float64 `json:"escalator,omitempty" example:"0.029"`
+	TermYears       int     `json:"term_years,omitempty" example:"25"`
+	UpfrontPayment  float64 `json:"upfront_payment,omitempty" example:"0"`
+}
+
+// Product builds the product a spec describes.
+func (s ProductSpec) Product() (Product, error) {
+	switch s.Type {
+	case ProductCash:
+		return Cash{Label: s.Name}, nil
+	case ProductLoan:
+		if s.TermMonths <= 0 || s.APR < 0 || s.LoanFee < 0 || s.LoanFee >= 1 || s.LoanFeeFixed < 0 || s.DownPayment < 0 {
+			return nil, fmt.Errorf("%w: loan needs a term and non-negative rate and fees below 100%%", ErrInvalidProduct)
+		}
+		if s.ITCPaydownMonth < 0 || s.ITCPaydownMonth > s.TermMonths {
+			return nil, fmt.Errorf("%w: tax credit paydown must fall within the loan term", ErrInvalidProduct)
+		}
+		return Loan{
+			Label:           s.Name,
+			APR:             s.APR,
+			TermMonths:      s.TermMonths,
+			LoanFee:         s.LoanFee,
+			LoanFeeFixed:    s.LoanFeeFixed,
+			DownPayment:     s.DownPayment,
+			ITCPaydownMonth: s.ITCPaydownMonth,
+		}, nil
+	case ProductLease:
+		if s.TermYears <= 0 || s.MonthlyPayment <= 0 || s.UpfrontPayment < 0 {
+			return nil, fmt.Errorf("%w: lease needs a term and a monthly payment", ErrInvalidProduct)
+		}
+		return Lease{
+			Label:          s.Name,
+			MonthlyPayment: s.MonthlyPayment,
+			Escalator:      s.Escalator,
+			TermYears:      s.TermYears,
+			UpfrontPayment: s.UpfrontPayment,
+		}, nil
+	case ProductPPA:
+		if s.TermYears <= 0 || s.RatePerKWh <= 0 || s.UpfrontPayment < 0 {
+			return nil, fmt.Errorf("%w: PPA needs a term and a rate", ErrInvalidProduct)
+		}
+		return PPA{
+			Label:          s.Name,
+			RatePerKWh:     s.RatePerKWh,
+			Escalator:      s.Escalator,
+			TermYears:      s.TermYears,
+			UpfrontPayment: s.UpfrontPayment,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidProduct, s.Type)
+	}
+}
+
+// Offer is one product's terms and cash flow for a system.
+type Offer struct {
+	Type           string    `json:"type" example:"loan"`
+	Name           string    `json:"name" example:"25-year loan at 6.99%"`
+	OwnsSystem     bool      `json:"owns_system" example:"true"`
+	ContractPrice  float64   `json:"contract_price" example:"32000.00"`
+	UpfrontPayment float64   `json:"upfront_payment" example:"0"`
+	MonthlyPayment float64   `json:"monthly_payment" example:"226.17"`
+	TotalPayments  float64   `json:"total_payments" example:"67850.00"`
+	CashFlow       *CashFlow `json:"cash_flow"`
+}
+
+// Compare runs the cash flow for each product. base describes the system's
+// production, savings and upkeep; each product fills in the payments and
+// tax credit, and upkeep and incentives are dropped for products the
+// homeowner does not own.
+func Compare(base CashFlowInput, price, taxCreditRate float64, products []Product) []Offer {
+	s := System{
+		Price:         price,
+		TaxCreditRate: taxCreditRate,
+		ProductionKWh: make([]float64, base.Years),
+	}
+	for y := range s.ProductionKWh {
+		s.ProductionKWh[y] = base.FirstYearProductionKWh * math.Pow(1-base.Degradation, float64(y))
+	}
+
+	offers := make([]Offer, 0, len(products))
+	for _, p := range products {
+		t := p.Terms(s)
+		in := base
+		in.UpfrontPayment = t.UpfrontPayment
+		in.AnnualPayments = t.AnnualPayments
+		in.TaxCredit = t.TaxCredit
+		if !t.OwnsSystem {
+			in.OMCostPerKW = 0
+			in.InverterReplacementCost = 0
+			in.Incentives = nil
+		}
+
+		total := t.UpfrontPayment
+		for _, payment := range t.AnnualPayments {
+			total += payment
+		}
+		offers = append(offers, Offer{
+			Type:           p.Type(),
+			Name:           p.Name(),
+			OwnsSystem:     t.OwnsSystem,
+			ContractPrice:  round2(t.ContractPrice),
+			UpfrontPayment: round2(t.UpfrontPayment),
+			MonthlyPayment: round2(t.MonthlyPayment),
+			TotalPayments:  round2(total),
+			CashFlow:       Model(in),
+		})
+	}
+	return offers
+}
+
+// amortize returns the monthly payment that pays off principal over n
+// months at monthly rate r.
+func amortize(principal, r float64, n int) float64 {
+	if n <= 0 {
+		return principal
+	}
+	if r == 0 {
+		return principal / float64(n)
+	}
+	f := math.Pow(1+r, float64(n))
+	return principal * r * f / (f - 1)
+}
+
+func nameOr(name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	return fallback
+}
@@ -0,0 +1,497 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdderHandler struct {
+	adderService *service.AdderService
+}
+
+func NewAdderHandler(adderService *service.AdderService) *AdderHandler {
+	return &AdderHandler{adderService: adderService}
+}
+
+// AdderRequest represents the request body for creating or updating an adder
+type AdderRequest struct {
+	CompanyID     int      `json:"company_id" example:"1"`
+	CategoryID    *int     `json:"category_id,omitempty" example:"1"`
+	Name          string   `json:"name" example:"Main panel upgrade"`
+	Description   string   `json:"description,omitempty" example:"Upgrade to 200A service"`
+	Cost          float64  `json:"cost" example:"2500.00"`
+	CostType      string   `json:"cost_type" example:"fixed"`
+	States        []string `json:"states,omitempty" example:"CA,NV"`
+	Active        *bool    `json:"active,omitempty" example:"true"`
+	IsAutomatic   bool     `json:"is_automatic" example:"false"`
+	MinSystemSize float64  `json:"min_system_size,omitempty" example:"0"`
+	MaxSystemSize float64  `json:"max_system_size,omitempty" example:"0"`
+}
+
+// AdderCategoryRequest represents the request body for creating or updating an adder category
+type AdderCategoryRequest struct {
+	CompanyID   int    `json:"company_id" example:"1"`
+	Name        string `json:"name" example:"Roofing"`
+	Description string `json:"description,omitempty" example:"Roof work required before install"`
+	Position    int    `json:"position,omitempty" example:"0"`
+}
+
+// AdderResponse represents the response for adder operations
+type AdderResponse struct {
+	Adder *models.Adder `json:"adder"`
+}
+
+// AddersResponse represents the response for listing adders
+type AddersResponse struct {
+	Adders []*models.Adder `json:"adders"`
+	Total  int             `json:"total"`
+}
+
+// AdderCategoriesResponse represents the response for listing adder categories
+type AdderCategoriesResponse struct {
+	Categories []*models.AdderCategory `json:"categories"`
+	Total      int                     `json:"total"`
+}
+
+// CreateCategory godoc
+// @Summary Create an adder category
+// @Description Creates a company-owned adder category
+// @Tags adders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AdderCategoryRequest true "Category details"
+// @Success 201 {object} models.AdderCategory
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/adder-categories [post]
+func (h *AdderHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req AdderCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CompanyID == 0 {
+		respondError(w, http.StatusBadRequest, "Company ID is required")
+		return
+	}
+
+	category := &models.AdderCategory{
+		CompanyID:   req.CompanyID,
+		Name:        req.Name,
+		Description: req.Description,
+		Position:    req.Position,
+	}
+	if err := h.adderService.CreateCategory(r.Context(), category); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, category)
+}
+
+// ListCategories godoc
+// @Summary List adder categories
+// @Description Lists the adder categories of a company
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param company_id query int true "Company ID"
+// @Success 200 {object} AdderCategoriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/adder-categories [get]
+func (h *AdderHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(r.URL.Query().Get("company_id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	categories, err := h.adderService.ListCategories(r.Context(), companyID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch adder categories")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AdderCategoriesResponse{
+		Categories: categories,
+		Total:      len(categories),
+	})
+}
+
+// UpdateCategory godoc
+// @Summary Update an adder category
+// @Description Updates an adder category's name, description or position
+// @Tags adders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param request body AdderCategoryRequest true "Category details"
+// @Success 200 {object} models.AdderCategory
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/adder-categories/{id} [put]
+func (h *AdderHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	category, err := h.adderService.GetCategoryByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Adder category not found")
+		return
+	}
+
+	var req AdderCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	category.Name = req.Name
+	category.Description = req.Description
+	category.Position = req.Position
+
+	if err := h.adderService.UpdateCategory(r.Context(), category); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete an adder category
+// @Description Deletes an adder category; its adders become uncategorized
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/adder-categories/{id} [delete]
+func (h *AdderHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	if err := h.adderService.DeleteCategory(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete adder category")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// Create godoc
+// @Summary Create an adder
+// @Description Adds an adder to a company catalog
+// @Tags adders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AdderRequest true "Adder details"
+// @Success 201 {object} AdderResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/adders [post]
+func (h *AdderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req AdderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CompanyID == 0 {
+		respondError(w, http.StatusBadRequest, "Company ID is required")
+		return
+	}
+
+	adder := &models.Adder{CompanyID: req.CompanyID, Active: true}
+	applyAdderRequest(adder, req)
+
+	if err := h.adderService.Create(r.Context(), adder); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, AdderResponse{Adder: adder})
+}
+
+// GetByID godoc
+// @Summary Get adder by ID
+// @Description Get an adder by its ID
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Adder ID"
+// @Success 200 {object} AdderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/adders/{id} [get]
+func (h *AdderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid adder ID")
+		return
+	}
+
+	adder, err := h.adderService.GetByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Adder not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AdderResponse{Adder: adder})
+}
+
+// Update godoc
+// @Summary Update an adder
+// @Description Updates an adder and records the change as a new catalog version. Deals already priced keep the version they were sold with.
+// @Tags adders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Adder ID"
+// @Param request body AdderRequest true "Adder details"
+// @Success 200 {object} AdderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/adders/{id} [put]
+func (h *AdderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid adder ID")
+		return
+	}
+
+	adder, err := h.adderService.GetByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Adder not found")
+		return
+	}
+
+	var req AdderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	applyAdderRequest(adder, req)
+
+	if err := h.adderService.Update(r.Context(), adder); err != nil {
+		if errors.Is(err, models.ErrAdderNotFound) {
+			respondError(w, http.StatusNotFound, "Adder not found")
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AdderResponse{Adder: adder})
+}
+
+// Delete godoc
+// @Summary Delete an adder
+// @Description Removes an adder from the catalog. Version history and deal snapshots are kept.
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Adder ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/adders/{id} [delete]
+func (h *AdderHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid adder ID")
+		return
+	}
+
+	if err := h.adderService.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, models.ErrAdderNotFound) {
+			respondError(w, http.StatusNotFound, "Adder not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete adder")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// List godoc
+// @Summary List adders
+// @Description Lists a company's adder catalog, optionally only active adders available in a state
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param company_id query int true "Company ID"
+// @Param active query bool false "Only active adders"
+// @Param state query string false "Only adders available in this state"
+// @Success 200 {object} AddersResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/adders [get]
+func (h *AdderHandler) List(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(r.URL.Query().Get("company_id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+	state := r.URL.Query().Get("state")
+
+	adders, err := h.adderService.ListByCompany(r.Context(), companyID, activeOnly)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch adders")
+		return
+	}
+
+	if state != "" {
+		filtered := make([]*models.Adder, 0, len(adders))
+		for _, adder := range adders {
+			if adder.AvailableFor(state, 0) {
+				filtered = append(filtered, adder)
+			}
+		}
+		adders = filtered
+	}
+
+	respondJSON(w, http.StatusOK, AddersResponse{
+		Adders: adders,
+		Total:  len(adders),
+	})
+}
+
+// ListVersions godoc
+// @Summary List adder versions
+// @Description Lists every recorded version of an adder, newest first
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Adder ID"
+// @Success 200 {array} models.AdderVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/adders/{id}/versions [get]
+func (h *AdderHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid adder ID")
+		return
+	}
+
+	versions, err := h.adderService.ListVersions(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch adder versions")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, versions)
+}
+
+// Applicable godoc
+// @Summary Evaluate adders for a lead or deal
+// @Description Returns the automatic adders that apply to a lead or deal and the manual adders the rep may add, based on state and system size
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param lead_id query int false "Lead ID"
+// @Param deal_id query int false "Deal ID"
+// @Success 200 {object} service.ApplicableAdders
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/adders/applicable [get]
+func (h *AdderHandler) Applicable(w http.ResponseWriter, r *http.Request) {
+	var (
+		result *service.ApplicableAdders
+		err    error
+	)
+
+	if leadIDStr := r.URL.Query().Get("lead_id"); leadIDStr != "" {
+		leadID, convErr := strconv.Atoi(leadIDStr)
+		if convErr != nil {
+			respondError(w, http.StatusBadRequest, "Invalid lead ID")
+			return
+		}
+		result, err = h.adderService.ApplicableForLead(r.Context(), leadID)
+	} else if dealIDStr := r.URL.Query().Get("deal_id"); dealIDStr != "" {
+		dealID, convErr := strconv.Atoi(dealIDStr)
+		if convErr != nil {
+			respondError(w, http.StatusBadRequest, "Invalid deal ID")
+			return
+		}
+		result, err = h.adderService.ApplicableForDeal(r.Context(), dealID)
+	} else {
+		respondError(w, http.StatusBadRequest, "lead_id or deal_id is required")
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrLeadNotFound):
+			respondError(w, http.StatusNotFound, "Lead not found")
+		case errors.Is(err, models.ErrDealNotFound):
+			respondError(w, http.StatusNotFound, "Deal not found")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to evaluate adders")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// ListDealAdders godoc
+// @Summary List a deal's adders
+// @Description Lists the adders a deal was priced with, at the catalog version in force when it was sold
+// @Tags adders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deal ID"
+// @Success 200 {array} models.DealAdder
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/deals/{id}/adders [get]
+func (h *AdderHandler) ListDealAdders(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid deal ID")
+		return
+	}
+
+	adders, err := h.adderService.ListDealAdders(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch deal adders")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, adders)
+}
+
+func applyAdderRequest(adder *models.Adder, req AdderRequest) {
+	adder.CategoryID = req.CategoryID
+	adder.Name = req.Name
+	adder.Description = req.Description
+	adder.Cost = req.Cost
+	adder.CostType = req.CostType
+	adder.States = req.States
+	adder.IsAutomatic = req.IsAutomatic
+	adder.MinSystemSize = req.MinSystemSize
+	adder.MaxSystemSize = req.MaxSystemSize
+	if req.Active != nil {
+		adder.Active = *req.Active
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,11 +14,15 @@ import (
 )
 
 type DealHandler struct {
-	dealService *service.DealService
+	dealService    *service.DealService
+	pricingService *service.PricingService
 }
 
-func NewDealHandler(dealService *service.DealService) *DealHandler {
-	return &DealHandler{dealService: dealService}
+func NewDealHandler(dealService *service.DealService, pricingService *service.PricingService) *DealHandler {
+	return &DealHandler{
+		dealService:    dealService,
+		pricingService: pricingService,
+	}
 }
 
 
@@ -25,6 +30,8 @@ type CreateDealRequest struct {
 	ProjectID           int     `json:"project_id" example:"1"`
 	SystemSize          float64 `json:"system_size" example:"10.5"`
 	PanelCount          int     `json:"panel_count" example:"30"`
+	PanelID             *int    `json:"panel_id,omitempty" example:"1"`
+	InverterID          *int    `json:"inverter_id,omitempty" example:"1"`
 	SalesID             int     `json:"sales_id" example:"1"`
 	HomeownerID         int     `json:"homeowner_id" example:"2"`
 	DocumentID          *int    `json:"document_id,omitempty" example:"1"`
@@ -46,6 +53,8 @@ type CreateDealRequest struct {
 type UpdateDealRequest struct {
 	SystemSize          *float64    `json:"system_size,omitempty" example:"10.5"`
 	PanelCount          *int        `json:"panel_count,omitempty" example:"30"`
+	PanelID             *int        `json:"panel_id,omitempty" example:"1"`
+	InverterID          *int        `json:"inverter_id,omitempty" example:"1"`
 	DocumentID          *int        `json:"document_id,omitempty" example:"1"`
 	FinancingOptionID   *int        `json:"financing_option_id,omitempty" example:"1"`
 	FinancingProvider   *string     `json:"financing_provider,omitempty" example:"SunPower Financial"`
@@ -99,6 +108,8 @@ func (h *DealHandler) Create(w http.ResponseWriter, r *http.Request) {
 		ProjectID:           req.ProjectID,
 		SystemSize:          req.SystemSize,
 		PanelCount:          req.PanelCount,
+		PanelID:             req.PanelID,
+		InverterID:          req.InverterID,
 		SalesID:             req.SalesID,
 		HomeownerID:         req.HomeownerID,
 		DocumentID:          req.DocumentID,
@@ -217,6 +228,12 @@ func (h *DealHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.PanelCount != nil {
 		deal.PanelCount = *req.PanelCount
 	}
+	if req.PanelID != nil {
+		deal.PanelID = req.PanelID
+	}
+	if req.InverterID != nil {
+		deal.InverterID = req.InverterID
+	}
 	if req.DocumentID != nil {
 		deal.DocumentID = req.DocumentID
 	}
@@ -498,3 +515,49 @@ func (h *DealHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
 
 	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
+
+// Price godoc
+// @Summary Price a deal
+// @Description Builds a price breakdown for a deal from company pricing, adders, hardware and financing dealer fees, and writes back consistent cost and profit fields
+// @Tags deals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deal ID"
+// @Param request body service.PricingInput true "Pricing inputs"
+// @Success 200 {object} service.PricedDeal
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/deals/{id}/price [post]
+func (h *DealHandler) Price(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid deal ID")
+		return
+	}
+
+	var input service.PricingInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	priced, err := h.pricingService.PriceDeal(r.Context(), id, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDealNotFound):
+			respondError(w, http.StatusNotFound, "Deal not found")
+		case errors.Is(err, models.ErrCompanyNotFound):
+			respondError(w, http.StatusNotFound, "Company not found")
+		case errors.Is(err, models.ErrFinancingOptionNotFound):
+			respondError(w, http.StatusNotFound, "Financing option not found")
+		default:
+			respondError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, priced)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/design"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type DesignHandler struct {
+	designService *service.DesignService
+}
+
+func NewDesignHandler(designService *service.DesignService) *DesignHandler {
+	return &DesignHandler{designService: designService}
+}
+
+// CheckLead godoc
+// @Summary Check a lead's electrical design
+// @Description Checks string lengths against the inverter's voltage limits and MPPT window using Voc and Vmp corrected to the site's temperature extremes, MPPT input current, inverter DC rating, DC/AC ratio and clipping. Findings cite the NEC article they come from; any error finding makes the design invalid for permitting. The body is optional and overrides the site temperatures or string layout.
+// @Tags design
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Lead ID"
+// @Param request body service.DesignCheckInput false "Overrides"
+// @Success 200 {object} service.DesignCheck
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/design-check [post]
+func (h *DesignHandler) CheckLead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+	var input service.DesignCheckInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.designService.CheckLead(r.Context(), id, input)
+	if err != nil {
+		respondDesignError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// CheckDeal godoc
+// @Summary Check a deal's electrical design
+// @Description Runs the same checks as a lead's design check on a deal's panel, inverter and panel count. The deal's lead gives the site and inverter count.
+// @Tags design
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deal ID"
+// @Param request body service.DesignCheckInput false "Overrides"
+// @Success 200 {object} service.DesignCheck
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/deals/{id}/design-check [post]
+func (h *DesignHandler) CheckDeal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid deal ID")
+		return
+	}
+	var input service.DesignCheckInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.designService.CheckDeal(r.Context(), id, input)
+	if err != nil {
+		respondDesignError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+func respondDesignError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, design.ErrInvalidDesign):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrLeadNotFound),
+		errors.Is(err, models.ErrDealNotFound),
+		errors.Is(err, models.ErrPanelNotFound),
+		errors.Is(err, models.ErrInverterNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to check design")
+	}
+}
@@ -0,0 +1,438 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type FinancingHandler struct {
+	financingService *service.FinancingService
+}
+
+func NewFinancingHandler(financingService *service.FinancingService) *FinancingHandler {
+	return &FinancingHandler{financingService: financingService}
+}
+
+// FinancingProviderRequest represents the request body for creating or updating a financing provider
+type FinancingProviderRequest struct {
+	CompanyID   int    `json:"company_id" example:"1"`
+	Name        string `json:"name" example:"SunPower Financial"`
+	Description string `json:"description,omitempty" example:"Residential solar loans"`
+	Active      *bool  `json:"active,omitempty" example:"true"`
+}
+
+// FinancingOptionRequest represents the request body for creating or updating a financing option
+type FinancingOptionRequest struct {
+	CompanyID       int        `json:"company_id" example:"1"`
+	ProviderID      int        `json:"provider_id" example:"1"`
+	Name            string     `json:"name" example:"25-year 6.99%"`
+	Type            string     `json:"type" example:"loan"`
+	InterestRate    float64    `json:"interest_rate,omitempty" example:"0.0699"`
+	TermMonths      int        `json:"term_months,omitempty" example:"300"`
+	LoanFee         float64    `json:"loan_fee,omitempty" example:"0.25"`
+	LoanFeeFixed    float64    `json:"loan_fee_fixed,omitempty" example:"0"`
+	ITCPaydownMonth int        `json:"itc_paydown_month,omitempty" example:"18"`
+	MonthlyPayment  float64    `json:"monthly_payment,omitempty" example:"0"`
+	RatePerKWh      float64    `json:"rate_per_kwh,omitempty" example:"0"`
+	Escalator       float64    `json:"escalator,omitempty" example:"0"`
+	States          []string   `json:"states,omitempty" example:"CA,NV"`
+	Active          *bool      `json:"active,omitempty" example:"true"`
+	ActiveFrom      *time.Time `json:"active_from,omitempty" example:"2025-01-01T00:00:00Z"`
+	ActiveUntil     *time.Time `json:"active_until,omitempty" example:"2025-12-31T23:59:59Z"`
+}
+
+// FinancingProvidersResponse represents the response for listing financing providers
+type FinancingProvidersResponse struct {
+	Providers []*models.FinancingProvider `json:"providers"`
+	Total     int                         `json:"total"`
+}
+
+// FinancingOptionsResponse represents the response for listing financing options
+type FinancingOptionsResponse struct {
+	Options []*models.FinancingOption `json:"options"`
+	Total   int                       `json:"total"`
+}
+
+// CreateProvider godoc
+// @Summary Create a financing provider
+// @Description Adds a lender or third-party owner to a company's financing catalog
+// @Tags financing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FinancingProviderRequest true "Provider details"
+// @Success 201 {object} models.FinancingProvider
+// @Failure 400 {object} ErrorResponse
+// @Router /api/financing-providers [post]
+func (h *FinancingHandler) CreateProvider(w http.ResponseWriter, r *http.Request) {
+	var req FinancingProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CompanyID == 0 {
+		respondError(w, http.StatusBadRequest, "Company ID is required")
+		return
+	}
+
+	provider := &models.FinancingProvider{CompanyID: req.CompanyID, Active: true}
+	applyFinancingProviderRequest(provider, req)
+
+	if err := h.financingService.CreateProvider(r.Context(), provider); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, provider)
+}
+
+// ListProviders godoc
+// @Summary List financing providers
+// @Description Lists the financing providers of a company
+// @Tags financing
+// @Produce json
+// @Security BearerAuth
+// @Param company_id query int true "Company ID"
+// @Success 200 {object} FinancingProvidersResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/financing-providers [get]
+func (h *FinancingHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(r.URL.Query().Get("company_id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	providers, err := h.financingService.ListProviders(r.Context(), companyID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch financing providers")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, FinancingProvidersResponse{
+		Providers: providers,
+		Total:     len(providers),
+	})
+}
+
+// GetProvider godoc
+// @Summary Get financing provider by ID
+// @Description Get a financing provider by its ID
+// @Tags financing
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Provider ID"
+// @Success 200 {object} models.FinancingProvider
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/financing-providers/{id} [get]
+func (h *FinancingHandler) GetProvider(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid provider ID")
+		return
+	}
+
+	provider, err := h.financingService.GetProviderByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Financing provider not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, provider)
+}
+
+// UpdateProvider godoc
+// @Summary Update a financing provider
+// @Description Updates a financing provider's name, description or active flag. Deactivating a provider withdraws all of its options.
+// @Tags financing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Provider ID"
+// @Param request body FinancingProviderRequest true "Provider details"
+// @Success 200 {object} models.FinancingProvider
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/financing-providers/{id} [put]
+func (h *FinancingHandler) UpdateProvider(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid provider ID")
+		return
+	}
+
+	provider, err := h.financingService.GetProviderByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Financing provider not found")
+		return
+	}
+
+	var req FinancingProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	applyFinancingProviderRequest(provider, req)
+
+	if err := h.financingService.UpdateProvider(r.Context(), provider); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, provider)
+}
+
+// DeleteProvider godoc
+// @Summary Delete a financing provider
+// @Description Deletes a financing provider and its options. Deals and proposals keep the option IDs they were sold with.
+// @Tags financing
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Provider ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/financing-providers/{id} [delete]
+func (h *FinancingHandler) DeleteProvider(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid provider ID")
+		return
+	}
+
+	if err := h.financingService.DeleteProvider(r.Context(), id); err != nil {
+		if errors.Is(err, models.ErrFinancingProviderNotFound) {
+			respondError(w, http.StatusNotFound, "Financing provider not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete financing provider")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// CreateOption godoc
+// @Summary Create a financing option
+// @Description Adds a loan, lease or PPA offered through one of the company's providers. The fields that apply depend on type.
+// @Tags financing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FinancingOptionRequest true "Option details"
+// @Success 201 {object} models.FinancingOption
+// @Failure 400 {object} ErrorResponse
+// @Router /api/financing-options [post]
+func (h *FinancingHandler) CreateOption(w http.ResponseWriter, r *http.Request) {
+	var req FinancingOptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CompanyID == 0 {
+		respondError(w, http.StatusBadRequest, "Company ID is required")
+		return
+	}
+
+	option := &models.FinancingOption{CompanyID: req.CompanyID, Active: true}
+	applyFinancingOptionRequest(option, req)
+
+	if err := h.financingService.CreateOption(r.Context(), option); err != nil {
+		respondFinancingOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, option)
+}
+
+// ListOptions godoc
+// @Summary List financing options
+// @Description Lists a company's financing options. With available set, only options that can be offered now, in state when given, are returned.
+// @Tags financing
+// @Produce json
+// @Security BearerAuth
+// @Param company_id query int true "Company ID"
+// @Param active query bool false "Only options marked active"
+// @Param available query bool false "Only options available now"
+// @Param state query string false "Only options available in this state"
+// @Success 200 {object} FinancingOptionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/financing-options [get]
+func (h *FinancingHandler) ListOptions(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(r.URL.Query().Get("company_id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+	availableOnly, _ := strconv.ParseBool(r.URL.Query().Get("available"))
+	state := r.URL.Query().Get("state")
+
+	var options []*models.FinancingOption
+	if availableOnly || state != "" {
+		options, err = h.financingService.Available(r.Context(), companyID, state, time.Now())
+	} else {
+		options, err = h.financingService.ListOptions(r.Context(), companyID, activeOnly)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch financing options")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, FinancingOptionsResponse{
+		Options: options,
+		Total:   len(options),
+	})
+}
+
+// GetOption godoc
+// @Summary Get financing option by ID
+// @Description Get a financing option and its provider by ID
+// @Tags financing
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Option ID"
+// @Success 200 {object} models.FinancingOption
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/financing-options/{id} [get]
+func (h *FinancingHandler) GetOption(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid option ID")
+		return
+	}
+
+	option, err := h.financingService.GetOptionByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Financing option not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, option)
+}
+
+// UpdateOption godoc
+// @Summary Update a financing option
+// @Description Updates a financing option. Quotes already saved keep the terms they were calculated with.
+// @Tags financing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Option ID"
+// @Param request body FinancingOptionRequest true "Option details"
+// @Success 200 {object} models.FinancingOption
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/financing-options/{id} [put]
+func (h *FinancingHandler) UpdateOption(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid option ID")
+		return
+	}
+
+	option, err := h.financingService.GetOptionByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Financing option not found")
+		return
+	}
+
+	var req FinancingOptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	applyFinancingOptionRequest(option, req)
+	option.Provider = nil
+
+	if err := h.financingService.UpdateOption(r.Context(), option); err != nil {
+		respondFinancingOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, option)
+}
+
+// DeleteOption godoc
+// @Summary Delete a financing option
+// @Description Removes a financing option from the catalog. Deals and proposals keep the option ID they were sold with.
+// @Tags financing
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Option ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/financing-options/{id} [delete]
+func (h *FinancingHandler) DeleteOption(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid option ID")
+		return
+	}
+
+	if err := h.financingService.DeleteOption(r.Context(), id); err != nil {
+		if errors.Is(err, models.ErrFinancingOptionNotFound) {
+			respondError(w, http.StatusNotFound, "Financing option not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete financing option")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+func respondFinancingOptionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrFinancingOptionNotFound):
+		respondError(w, http.StatusNotFound, "Financing option not found")
+	case errors.Is(err, models.ErrFinancingProviderNotFound),
+		errors.Is(err, models.ErrInvalidFinancingOptionName),
+		errors.Is(err, models.ErrInvalidFinancingDateRange),
+		errors.Is(err, finance.ErrInvalidProduct):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to save financing option")
+	}
+}
+
+func applyFinancingProviderRequest(provider *models.FinancingProvider, req FinancingProviderRequest) {
+	provider.Name = req.Name
+	provider.Description = req.Description
+	if req.Active != nil {
+		provider.Active = *req.Active
+	}
+}
+
+func applyFinancingOptionRequest(option *models.FinancingOption, req FinancingOptionRequest) {
+	option.ProviderID = req.ProviderID
+	option.Name = req.Name
+	option.Type = req.Type
+	option.InterestRate = req.InterestRate
+	option.TermMonths = req.TermMonths
+	option.LoanFee = req.LoanFee
+	option.LoanFeeFixed = req.LoanFeeFixed
+	option.ITCPaydownMonth = req.ITCPaydownMonth
+	option.MonthlyPayment = req.MonthlyPayment
+	option.RatePerKWh = req.RatePerKWh
+	option.Escalator = req.Escalator
+	option.States = req.States
+	option.ActiveFrom = req.ActiveFrom
+	option.ActiveUntil = req.ActiveUntil
+	if req.Active != nil {
+		option.Active = *req.Active
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type GenabilityHandler struct {
+	genabilityService *service.GenabilityService
+}
+
+func NewGenabilityHandler(genabilityService *service.GenabilityService) *GenabilityHandler {
+	return &GenabilityHandler{genabilityService: genabilityService}
+}
+
+// ListLSEs godoc
+// @Summary List utilities by zip code
+// @Description Lists the utilities (load serving entities) serving residential electricity in a zip code, the ones with the most customers first.
+// @Tags genability
+// @Produce json
+// @Security BearerAuth
+// @Param zip query string true "Zip code"
+// @Param country query string false "Country code, US by default"
+// @Success 200 {array} client.LoadServingEntity
+// @Failure 400 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/genability/lses [get]
+func (h *GenabilityHandler) ListLSEs(w http.ResponseWriter, r *http.Request) {
+	lses, err := h.genabilityService.ListLSEs(r.Context(), r.URL.Query().Get("zip"), r.URL.Query().Get("country"))
+	if err != nil {
+		respondGenabilityError(w, err, "Failed to list utilities")
+		return
+	}
+	respondJSON(w, http.StatusOK, lses)
+}
+
+// SearchTariffs godoc
+// @Summary Search tariffs
+// @Description Lists active tariffs by zip code, customer class and utility. A zip code or utility is required; residential tariffs are searched when no customer class is given.
+// @Tags genability
+// @Produce json
+// @Security BearerAuth
+// @Param zip query string false "Zip code"
+// @Param country query string false "Country code, US by default"
+// @Param customer_class query string false "RESIDENTIAL, GENERAL or SPECIAL_USE"
+// @Param lse_id query int false "Utility (LSE) ID"
+// @Success 200 {array} client.Tariff
+// @Failure 400 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/genability/tariffs [get]
+func (h *GenabilityHandler) SearchTariffs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	search := client.TariffSearch{
+		ZipCode:       q.Get("zip"),
+		Country:       q.Get("country"),
+		CustomerClass: q.Get("customer_class"),
+	}
+	if v := q.Get("lse_id"); v != "" {
+		lseID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid lse_id")
+			return
+		}
+		search.LseID = uint(lseID)
+	}
+
+	tariffs, err := h.genabilityService.SearchTariffs(r.Context(), search)
+	if err != nil {
+		respondGenabilityError(w, err, "Failed to search tariffs")
+		return
+	}
+	respondJSON(w, http.StatusOK, tariffs)
+}
+
+// SetAccountProperties godoc
+// @Summary Set Genability account properties
+// @Description Sets the customer class, utility, tariff, territory or building area a Genability account is billed with. Only the fields given are set.
+// @Tags genability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param accountId path string true "Genability account ID"
+// @Param request body service.AccountPropertiesInput true "Properties"
+// @Success 200 {object} client.Account
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/genability/accounts/{accountId}/properties [put]
+func (h *GenabilityHandler) SetAccountProperties(w http.ResponseWriter, r *http.Request) {
+	var input service.AccountPropertiesInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	account, err := h.genabilityService.SetAccountProperties(r.Context(), chi.URLParam(r, "accountId"), input)
+	if err != nil {
+		respondGenabilityError(w, err, "Failed to set account properties")
+		return
+	}
+	respondJSON(w, http.StatusOK, account)
+}
+
+// CalculateBills godoc
+// @Summary Calculate bills on a tariff
+// @Description Prices a year of monthly usage on a tariff with Genability's on-demand calculator, and the same year net of monthly solar production when it is given, returning both bills and the annual savings.
+// @Tags genability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.BillCalculationInput true "Usage and tariff"
+// @Success 200 {object} service.BillCalculation
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/genability/calculate [post]
+func (h *GenabilityHandler) CalculateBills(w http.ResponseWriter, r *http.Request) {
+	var input service.BillCalculationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	bills, err := h.genabilityService.CalculateBills(r.Context(), input)
+	if err != nil {
+		respondGenabilityError(w, err, "Failed to calculate bills")
+		return
+	}
+	respondJSON(w, http.StatusOK, bills)
+}
+
+// respondGenabilityError maps Genability failures: its validation and not
+// found responses pass through, anything else from it is a bad gateway.
+func respondGenabilityError(w http.ResponseWriter, err error, message string) {
+	var apiErr *client.APIError
+	switch {
+	case errors.Is(err, service.ErrInvalidGenabilityRequest):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrGenabilityUnavailable):
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &apiErr):
+		respondError(w, http.StatusBadGateway, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
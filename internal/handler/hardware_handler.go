@@ -0,0 +1,555 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type HardwareHandler struct {
+	hardwareService *service.HardwareService
+}
+
+func NewHardwareHandler(hardwareService *service.HardwareService) *HardwareHandler {
+	return &HardwareHandler{hardwareService: hardwareService}
+}
+
+// PanelsResponse represents the response for listing panels
+type PanelsResponse struct {
+	Panels []*models.Panel `json:"panels"`
+	Total  int             `json:"total"`
+}
+
+// InvertersResponse represents the response for listing inverters
+type InvertersResponse struct {
+	Inverters []*models.Inverter `json:"inverters"`
+	Total     int                `json:"total"`
+}
+
+// BatteriesResponse represents the response for listing batteries
+type BatteriesResponse struct {
+	Batteries []*models.Battery `json:"batteries"`
+	Total     int               `json:"total"`
+}
+
+// CreatePanel godoc
+// @Summary Create a panel
+// @Description Adds a solar panel to the hardware catalog
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Panel true "Panel details"
+// @Success 201 {object} models.Panel
+// @Failure 400 {object} ErrorResponse
+// @Router /api/hardware/panels [post]
+func (h *HardwareHandler) CreatePanel(w http.ResponseWriter, r *http.Request) {
+	panel := models.Panel{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&panel); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	panel.ID = 0
+
+	if err := h.hardwareService.CreatePanel(r.Context(), &panel); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, panel)
+}
+
+// ListPanels godoc
+// @Summary List panels
+// @Description Lists the panel catalog
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param active query bool false "Only active panels"
+// @Success 200 {object} PanelsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/panels [get]
+func (h *HardwareHandler) ListPanels(w http.ResponseWriter, r *http.Request) {
+	panels, err := h.hardwareService.ListPanels(r.Context(), r.URL.Query().Get("active") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch panels")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, PanelsResponse{Panels: panels, Total: len(panels)})
+}
+
+// GetPanel godoc
+// @Summary Get panel by ID
+// @Description Get a catalog panel by its ID
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Panel ID"
+// @Success 200 {object} models.Panel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/hardware/panels/{id} [get]
+func (h *HardwareHandler) GetPanel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid panel ID")
+		return
+	}
+
+	panel, err := h.hardwareService.GetPanel(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Panel not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, panel)
+}
+
+// UpdatePanel godoc
+// @Summary Update a panel
+// @Description Updates a catalog panel
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Panel ID"
+// @Param request body models.Panel true "Panel details"
+// @Success 200 {object} models.Panel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/hardware/panels/{id} [put]
+func (h *HardwareHandler) UpdatePanel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid panel ID")
+		return
+	}
+
+	panel, err := h.hardwareService.GetPanel(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Panel not found")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(panel); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	panel.ID = id
+
+	if err := h.hardwareService.UpdatePanel(r.Context(), panel); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, panel)
+}
+
+// DeletePanel godoc
+// @Summary Delete a panel
+// @Description Removes a panel from the catalog and from every company allowed list
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Panel ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/panels/{id} [delete]
+func (h *HardwareHandler) DeletePanel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid panel ID")
+		return
+	}
+
+	if err := h.hardwareService.DeletePanel(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete panel")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// CreateInverter godoc
+// @Summary Create an inverter
+// @Description Adds an inverter to the hardware catalog
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Inverter true "Inverter details"
+// @Success 201 {object} models.Inverter
+// @Failure 400 {object} ErrorResponse
+// @Router /api/hardware/inverters [post]
+func (h *HardwareHandler) CreateInverter(w http.ResponseWriter, r *http.Request) {
+	inverter := models.Inverter{Active: true, MPPTCount: 1}
+	if err := json.NewDecoder(r.Body).Decode(&inverter); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	inverter.ID = 0
+
+	if err := h.hardwareService.CreateInverter(r.Context(), &inverter); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, inverter)
+}
+
+// ListInverters godoc
+// @Summary List inverters
+// @Description Lists the inverter catalog
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param active query bool false "Only active inverters"
+// @Success 200 {object} InvertersResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/inverters [get]
+func (h *HardwareHandler) ListInverters(w http.ResponseWriter, r *http.Request) {
+	inverters, err := h.hardwareService.ListInverters(r.Context(), r.URL.Query().Get("active") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch inverters")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, InvertersResponse{Inverters: inverters, Total: len(inverters)})
+}
+
+// GetInverter godoc
+// @Summary Get inverter by ID
+// @Description Get a catalog inverter by its ID
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Inverter ID"
+// @Success 200 {object} models.Inverter
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/hardware/inverters/{id} [get]
+func (h *HardwareHandler) GetInverter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid inverter ID")
+		return
+	}
+
+	inverter, err := h.hardwareService.GetInverter(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Inverter not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, inverter)
+}
+
+// UpdateInverter godoc
+// @Summary Update an inverter
+// @Description Updates a catalog inverter
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Inverter ID"
+// @Param request body models.Inverter true "Inverter details"
+// @Success 200 {object} models.Inverter
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/hardware/inverters/{id} [put]
+func (h *HardwareHandler) UpdateInverter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid inverter ID")
+		return
+	}
+
+	inverter, err := h.hardwareService.GetInverter(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Inverter not found")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(inverter); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	inverter.ID = id
+
+	if err := h.hardwareService.UpdateInverter(r.Context(), inverter); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, inverter)
+}
+
+// DeleteInverter godoc
+// @Summary Delete an inverter
+// @Description Removes an inverter from the catalog and from every company allowed list
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Inverter ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/inverters/{id} [delete]
+func (h *HardwareHandler) DeleteInverter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid inverter ID")
+		return
+	}
+
+	if err := h.hardwareService.DeleteInverter(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete inverter")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// CreateBattery godoc
+// @Summary Create a battery
+// @Description Adds a battery to the hardware catalog
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Battery true "Battery details"
+// @Success 201 {object} models.Battery
+// @Failure 400 {object} ErrorResponse
+// @Router /api/hardware/batteries [post]
+func (h *HardwareHandler) CreateBattery(w http.ResponseWriter, r *http.Request) {
+	battery := models.Battery{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&battery); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	battery.ID = 0
+
+	if err := h.hardwareService.CreateBattery(r.Context(), &battery); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, battery)
+}
+
+// ListBatteries godoc
+// @Summary List batteries
+// @Description Lists the battery catalog
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param active query bool false "Only active batteries"
+// @Success 200 {object} BatteriesResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/batteries [get]
+func (h *HardwareHandler) ListBatteries(w http.ResponseWriter, r *http.Request) {
+	batteries, err := h.hardwareService.ListBatteries(r.Context(), r.URL.Query().Get("active") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch batteries")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, BatteriesResponse{Batteries: batteries, Total: len(batteries)})
+}
+
+// GetBattery godoc
+// @Summary Get battery by ID
+// @Description Get a catalog battery by its ID
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Battery ID"
+// @Success 200 {object} models.Battery
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/hardware/batteries/{id} [get]
+func (h *HardwareHandler) GetBattery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battery ID")
+		return
+	}
+
+	battery, err := h.hardwareService.GetBattery(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Battery not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, battery)
+}
+
+// UpdateBattery godoc
+// @Summary Update a battery
+// @Description Updates a catalog battery
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Battery ID"
+// @Param request body models.Battery true "Battery details"
+// @Success 200 {object} models.Battery
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/hardware/batteries/{id} [put]
+func (h *HardwareHandler) UpdateBattery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battery ID")
+		return
+	}
+
+	battery, err := h.hardwareService.GetBattery(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Battery not found")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(battery); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	battery.ID = id
+
+	if err := h.hardwareService.UpdateBattery(r.Context(), battery); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, battery)
+}
+
+// DeleteBattery godoc
+// @Summary Delete a battery
+// @Description Removes a battery from the catalog and from every company allowed list
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Battery ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/batteries/{id} [delete]
+func (h *HardwareHandler) DeleteBattery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battery ID")
+		return
+	}
+
+	if err := h.hardwareService.DeleteBattery(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete battery")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// GetCompanyHardware godoc
+// @Summary Get a company's hardware
+// @Description Lists the panels, inverters and batteries a company may sell. A hardware type without an allowed list returns the whole active catalog.
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Company ID"
+// @Success 200 {object} service.CompanyHardwareList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/companies/{id}/hardware [get]
+func (h *HardwareHandler) GetCompanyHardware(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	list, err := h.hardwareService.CompanyHardware(r.Context(), companyID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch company hardware")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// SetCompanyHardware godoc
+// @Summary Set a company's hardware
+// @Description Replaces a company's allowed panels, inverters and/or batteries. Omitted lists are left unchanged; an empty list allows the whole active catalog.
+// @Tags hardware
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Company ID"
+// @Param request body service.CompanyHardwareRequest true "Allowed hardware"
+// @Success 200 {object} service.CompanyHardwareList
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/companies/{id}/hardware [put]
+func (h *HardwareHandler) SetCompanyHardware(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	var req service.CompanyHardwareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.hardwareService.SetCompanyHardware(r.Context(), companyID, req); err != nil {
+		if isHardwareError(err) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to save company hardware")
+		return
+	}
+
+	list, err := h.hardwareService.CompanyHardware(r.Context(), companyID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch company hardware")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// Sync godoc
+// @Summary Sync hardware from LightFusion
+// @Description Imports the LightFusion panel, inverter and battery catalog, matching existing items by external ID
+// @Tags hardware
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.HardwareSyncResult
+// @Failure 502 {object} ErrorResponse
+// @Router /api/hardware/sync [post]
+func (h *HardwareHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	result, err := h.hardwareService.SyncFromLightFusion(r.Context())
+	if err != nil {
+		respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// isHardwareError reports whether err comes from validating a hardware
+// selection against the catalog.
+func isHardwareError(err error) bool {
+	return errors.Is(err, models.ErrHardwareNotAllowed) ||
+		errors.Is(err, models.ErrPanelNotFound) ||
+		errors.Is(err, models.ErrInverterNotFound) ||
+		errors.Is(err, models.ErrBatteryNotFound)
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type IncentiveHandler struct {
+	incentiveService *service.IncentiveService
+}
+
+func NewIncentiveHandler(incentiveService *service.IncentiveService) *IncentiveHandler {
+	return &IncentiveHandler{incentiveService: incentiveService}
+}
+
+// IncentiveRequest represents the request body for creating or updating an incentive
+type IncentiveRequest struct {
+	Name        string           `json:"name" example:"NY-Sun Megawatt Block"`
+	Type        string           `json:"type" example:"rebate"`
+	Description string           `json:"description,omitempty" example:"Upstate residential block 10"`
+	Rate        float64          `json:"rate,omitempty" example:"0"`
+	Schedule    []incentive.Step `json:"schedule,omitempty"`
+	Amount      float64          `json:"amount,omitempty" example:"0"`
+	PerKW       float64          `json:"per_kw,omitempty" example:"200"`
+	PerKWh      float64          `json:"per_kwh,omitempty" example:"0"`
+	MaxAmount   float64          `json:"max_amount,omitempty" example:"5000"`
+	Years       int              `json:"years,omitempty" example:"0"`
+	States      []string         `json:"states,omitempty" example:"NY"`
+	UtilityIDs  []int            `json:"utility_ids,omitempty"`
+	MinSystemKW float64          `json:"min_system_kw,omitempty" example:"0"`
+	MaxSystemKW float64          `json:"max_system_kw,omitempty" example:"25"`
+	Ownership   []string         `json:"ownership,omitempty" example:"owned"`
+	Active      *bool            `json:"active,omitempty" example:"true"`
+	ActiveFrom  *time.Time       `json:"active_from,omitempty" example:"2025-01-01T00:00:00Z"`
+	ActiveUntil *time.Time       `json:"active_until,omitempty" example:"2025-12-31T23:59:59Z"`
+}
+
+// IncentivesResponse represents the response for listing incentives
+type IncentivesResponse struct {
+	Incentives []*models.Incentive `json:"incentives"`
+	Total      int                 `json:"total"`
+}
+
+// Create godoc
+// @Summary Create an incentive
+// @Description Adds a tax credit, rebate, performance payment or tax exemption to the incentive catalog. The fields that apply depend on type.
+// @Tags incentives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body IncentiveRequest true "Incentive details"
+// @Success 201 {object} models.Incentive
+// @Failure 400 {object} ErrorResponse
+// @Router /api/incentives [post]
+func (h *IncentiveHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req IncentiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	i := &models.Incentive{Active: true}
+	applyIncentiveRequest(i, req)
+
+	if err := h.incentiveService.Create(r.Context(), i); err != nil {
+		respondIncentiveError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, i)
+}
+
+// List godoc
+// @Summary List incentives
+// @Description Lists the incentive catalog. With state, utility_id, system_kw or ownership, only incentives available now that such a system can claim are returned.
+// @Tags incentives
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Only incentives of this type"
+// @Param active query bool false "Only incentives marked active"
+// @Param state query string false "Eligible in this state"
+// @Param utility_id query int false "Eligible for this utility"
+// @Param system_kw query number false "Eligible for this system size"
+// @Param ownership query string false "Eligible for this ownership (owned or third_party)"
+// @Success 200 {object} IncentivesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/incentives [get]
+func (h *IncentiveHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	incentiveType := query.Get("type")
+	activeOnly, _ := strconv.ParseBool(query.Get("active"))
+
+	system := incentive.System{
+		State:     query.Get("state"),
+		Ownership: query.Get("ownership"),
+	}
+	if v := query.Get("utility_id"); v != "" {
+		utilityID, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid utility ID")
+			return
+		}
+		system.UtilityID = &utilityID
+	}
+	if v := query.Get("system_kw"); v != "" {
+		sizeKW, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid system size")
+			return
+		}
+		system.SizeKW = sizeKW
+	}
+	eligibleOnly := system.State != "" || system.Ownership != "" || system.UtilityID != nil || system.SizeKW > 0
+
+	var incentives []*models.Incentive
+	var err error
+	if eligibleOnly {
+		incentives, err = h.incentiveService.Eligible(r.Context(), system, time.Now())
+	} else {
+		incentives, err = h.incentiveService.List(r.Context(), incentiveType, activeOnly)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch incentives")
+		return
+	}
+	if eligibleOnly && incentiveType != "" {
+		filtered := incentives[:0]
+		for _, i := range incentives {
+			if i.Type == incentiveType {
+				filtered = append(filtered, i)
+			}
+		}
+		incentives = filtered
+	}
+
+	respondJSON(w, http.StatusOK, IncentivesResponse{
+		Incentives: incentives,
+		Total:      len(incentives),
+	})
+}
+
+// GetByID godoc
+// @Summary Get incentive by ID
+// @Description Get an incentive from the catalog by its ID
+// @Tags incentives
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Incentive ID"
+// @Success 200 {object} models.Incentive
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/incentives/{id} [get]
+func (h *IncentiveHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incentive ID")
+		return
+	}
+
+	i, err := h.incentiveService.GetByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Incentive not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, i)
+}
+
+// Update godoc
+// @Summary Update an incentive
+// @Description Updates an incentive. Quotes already saved keep the incentives they were calculated with.
+// @Tags incentives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Incentive ID"
+// @Param request body IncentiveRequest true "Incentive details"
+// @Success 200 {object} models.Incentive
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/incentives/{id} [put]
+func (h *IncentiveHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incentive ID")
+		return
+	}
+
+	i, err := h.incentiveService.GetByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Incentive not found")
+		return
+	}
+
+	var req IncentiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	applyIncentiveRequest(i, req)
+
+	if err := h.incentiveService.Update(r.Context(), i); err != nil {
+		respondIncentiveError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, i)
+}
+
+// Delete godoc
+// @Summary Delete an incentive
+// @Description Removes an incentive from the catalog. Quotes already saved keep its value.
+// @Tags incentives
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Incentive ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/incentives/{id} [delete]
+func (h *IncentiveHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incentive ID")
+		return
+	}
+
+	if err := h.incentiveService.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, models.ErrIncentiveNotFound) {
+			respondError(w, http.StatusNotFound, "Incentive not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete incentive")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+func respondIncentiveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrIncentiveNotFound):
+		respondError(w, http.StatusNotFound, "Incentive not found")
+	case errors.Is(err, models.ErrInvalidIncentiveName),
+		errors.Is(err, models.ErrInvalidIncentiveDateRange),
+		errors.Is(err, incentive.ErrInvalidIncentive):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to save incentive")
+	}
+}
+
+func applyIncentiveRequest(i *models.Incentive, req IncentiveRequest) {
+	i.Name = req.Name
+	i.Type = req.Type
+	i.Description = req.Description
+	i.Rate = req.Rate
+	i.Schedule = req.Schedule
+	i.Amount = req.Amount
+	i.PerKW = req.PerKW
+	i.PerKWh = req.PerKWh
+	i.MaxAmount = req.MaxAmount
+	i.Years = req.Years
+	i.States = req.States
+	i.UtilityIDs = req.UtilityIDs
+	i.MinSystemKW = req.MinSystemKW
+	i.MaxSystemKW = req.MaxSystemKW
+	i.Ownership = req.Ownership
+	i.ActiveFrom = req.ActiveFrom
+	i.ActiveUntil = req.ActiveUntil
+	if req.Active != nil {
+		i.Active = *req.Active
+	}
+}
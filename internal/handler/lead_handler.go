@@ -64,6 +64,10 @@ func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request) {
 	}
 	response, err := h.leadService.CreateLead(r.Context(), req, userID, effectiveCompanyID)
 	if err != nil {
+		if isHardwareError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -222,6 +226,35 @@ func (h *LeadHandler) UpdateLead(w http.ResponseWriter, r *http.Request) {
 	if annualProduction, ok := updates["annual_production"].(float64); ok {
 		lead.AnnualProduction = annualProduction
 	}
+	if inverterCount, ok := updates["inverter_count"].(float64); ok {
+		lead.InverterCount = int(inverterCount)
+	}
+	if batteryCount, ok := updates["battery_count"].(float64); ok {
+		lead.BatteryCount = int(batteryCount)
+	}
+
+	var selection service.HardwareSelection
+	if panelID, ok := updates["panel_id"].(float64); ok {
+		id := int(panelID)
+		selection.PanelID = &id
+	}
+	if inverterID, ok := updates["inverter_id"].(float64); ok {
+		id := int(inverterID)
+		selection.InverterID = &id
+	}
+	if batteryID, ok := updates["battery_id"].(float64); ok {
+		id := int(batteryID)
+		selection.BatteryID = &id
+	}
+	if err := h.leadService.UpdateHardware(r.Context(), lead, selection); err != nil {
+		if isHardwareError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Failed to validate lead hardware: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to update lead")
+		return
+	}
 
 	if err := h.leadRepo.Update(r.Context(), lead); err != nil {
 		log.Printf("Failed to update lead: %v", err)
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/production"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+)
+
+type ProductionHandler struct {
+	productionService *service.ProductionService
+}
+
+func NewProductionHandler(productionService *service.ProductionService) *ProductionHandler {
+	return &ProductionHandler{productionService: productionService}
+}
+
+// Estimate godoc
+// @Summary Estimate solar production
+// @Description Models a year of production from the nearest TMY weather station, the sun's position, the array's tilt and azimuth, and the panel's temperature coefficient and inverter losses. With a lead, its location, system and hardware fill in what the request leaves out and the estimate is compared with the lead's designed production.
+// @Tags production
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.EstimateProductionInput true "Array to estimate"
+// @Success 200 {object} service.ProductionEstimate
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/production/estimate [post]
+func (h *ProductionHandler) Estimate(w http.ResponseWriter, r *http.Request) {
+	var input service.EstimateProductionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	estimate, err := h.productionService.Estimate(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, production.ErrInvalidSystem):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrLeadNotFound),
+			errors.Is(err, models.ErrPanelNotFound),
+			errors.Is(err, models.ErrInverterNotFound):
+			respondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, production.ErrNoWeather):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to estimate production")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, estimate)
+}
@@ -11,17 +11,20 @@ import (
 	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
 )
 
 type Project3DHandler struct {
 	lightFusionClient *client.LightFusionClient
 	leadRepo          *repo.LeadRepo
+	hardwareService   *service.HardwareService
 }
 
-func NewProject3DHandler(lightFusionClient *client.LightFusionClient, leadRepo *repo.LeadRepo) *Project3DHandler {
+func NewProject3DHandler(lightFusionClient *client.LightFusionClient, leadRepo *repo.LeadRepo, hardwareService *service.HardwareService) *Project3DHandler {
 	return &Project3DHandler{
 		lightFusionClient: lightFusionClient,
 		leadRepo:          leadRepo,
+		hardwareService:   hardwareService,
 	}
 }
 
@@ -59,11 +62,17 @@ type HomeownerRequest struct {
 	Phone     string `json:"phone" example:"+1234567890"`
 }
 
+// HardwareRequest selects catalog hardware. IDSource says whether the IDs are
+// local catalog IDs or LightFusion IDs; either way they are translated to
+// LightFusion IDs before the project is created. Without IDSource, IDs are
+// local, and an ID with no local item is still accepted as a LightFusion ID
+// with a Deprecation header on the response.
 type HardwareRequest struct {
-	PanelID         int  `json:"panel_id" example:"1"`
-	InverterID      int  `json:"inverter_id" example:"1"`
-	StorageID       *int `json:"storage_id,omitempty" example:"1"`
-	StorageQuantity *int `json:"storage_quantity,omitempty" example:"2"`
+	IDSource        string `json:"id_source,omitempty" enums:"local,lightfusion" example:"local"`
+	PanelID         int    `json:"panel_id" example:"1"`
+	InverterID      int    `json:"inverter_id" example:"1"`
+	StorageID       *int   `json:"storage_id,omitempty" example:"1"`
+	StorageQuantity *int   `json:"storage_quantity,omitempty" example:"2"`
 }
 
 // Create3DProjectResponse represents the API response
@@ -106,6 +115,38 @@ func (h *Project3DHandler) Create3DProject(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	selection := service.HardwareSelection{
+		PanelID:    &req.Hardware.PanelID,
+		InverterID: &req.Hardware.InverterID,
+		BatteryID:  req.Hardware.StorageID,
+	}
+	selection, legacyIDs, err := h.hardwareService.ResolveSelection(r.Context(), selection, req.Hardware.IDSource)
+	if err != nil {
+		if isHardwareError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to resolve hardware")
+		return
+	}
+	if legacyIDs {
+		log.Printf("Warning: 3D project request selected hardware by LightFusion ID without id_source")
+		w.Header().Set("Deprecation", "true")
+	}
+	if err := h.hardwareService.ValidateSelection(r.Context(), req.CompanyID, selection); err != nil {
+		if isHardwareError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to validate hardware")
+		return
+	}
+	external, err := h.hardwareService.ExternalSelection(r.Context(), selection)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to resolve hardware")
+		return
+	}
+
 	lightFusionReq := client.Create3DProjectRequest{
 		Latitude:  req.Latitude,
 		Longitude: req.Longitude,
@@ -123,9 +164,9 @@ func (h *Project3DHandler) Create3DProject(w http.ResponseWriter, r *http.Reques
 			Phone:     req.Homeowner.Phone,
 		},
 		Hardware: client.HardwareDetails{
-			PanelID:         req.Hardware.PanelID,
-			InverterID:      req.Hardware.InverterID,
-			StorageID:       req.Hardware.StorageID,
+			PanelID:         *external.PanelID,
+			InverterID:      *external.InverterID,
+			StorageID:       external.BatteryID,
 			StorageQuantity: req.Hardware.StorageQuantity,
 		},
 		Consumption:       req.Consumption,
@@ -167,6 +208,12 @@ func (h *Project3DHandler) Create3DProject(w http.ResponseWriter, r *http.Reques
 			ExternalLeadID:   &resp.LeadID,
 			SystemSize:       resp.SystemSize,
 			AnnualProduction: resp.AnnualProduction,
+			PanelID:          selection.PanelID,
+			InverterID:       selection.InverterID,
+			BatteryID:        selection.BatteryID,
+		}
+		if req.Hardware.StorageQuantity != nil {
+			lead.BatteryCount = *req.Hardware.StorageQuantity
 		}
 		lead.SetLightFusion3DProject(resp.ID, resp.LeadID)
 
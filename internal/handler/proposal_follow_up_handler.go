@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type ProposalFollowUpHandler struct {
+	followUpService *service.ProposalFollowUpService
+}
+
+func NewProposalFollowUpHandler(followUpService *service.ProposalFollowUpService) *ProposalFollowUpHandler {
+	return &ProposalFollowUpHandler{followUpService: followUpService}
+}
+
+// GetSettings godoc
+// @Summary Get proposal reminder settings
+// @Description Returns a company's proposal reminder schedule. Companies that have not configured one get the defaults.
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Company ID"
+// @Success 200 {object} models.ProposalReminderSettings
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/companies/{id}/proposal-reminders [get]
+func (h *ProposalFollowUpHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	settings, err := h.followUpService.Settings(r.Context(), companyID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch reminder settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings godoc
+// @Summary Update proposal reminder settings
+// @Description Updates when homeowners are reminded before a proposal expires and when the sales rep hears about a viewed but unaccepted proposal. Omitted fields are left unchanged.
+// @Tags proposals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Company ID"
+// @Param request body service.ProposalReminderSettingsRequest true "Reminder settings"
+// @Success 200 {object} models.ProposalReminderSettings
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/companies/{id}/proposal-reminders [put]
+func (h *ProposalFollowUpHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	companyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	var req service.ProposalReminderSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	settings, err := h.followUpService.UpdateSettings(r.Context(), companyID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrCompanyNotFound):
+			respondError(w, http.StatusNotFound, "Company not found")
+		case errors.Is(err, models.ErrInvalidReminderDays), errors.Is(err, models.ErrInvalidFollowUpHours):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to save reminder settings")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// ListNotifications godoc
+// @Summary List proposal notifications
+// @Description Lists the reminders and follow-ups sent for a proposal, newest first
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {array} models.NotificationLog
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/proposals/{id}/notifications [get]
+func (h *ProposalFollowUpHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	entries, err := h.followUpService.Notifications(r.Context(), id)
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entries)
+}
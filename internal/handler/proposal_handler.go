@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/middleware"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type ProposalHandler struct {
+	proposalService *service.ProposalService
+}
+
+func NewProposalHandler(proposalService *service.ProposalService) *ProposalHandler {
+	return &ProposalHandler{proposalService: proposalService}
+}
+
+// RejectProposalRequest represents the request body for rejecting a proposal
+type RejectProposalRequest struct {
+	Reason string `json:"reason,omitempty" example:"Went with another installer"`
+}
+
+// ProposalResponse represents the response for proposal operations
+type ProposalResponse struct {
+	Proposal *models.Proposal `json:"proposal"`
+}
+
+// ProposalsResponse represents the response for listing proposals
+type ProposalsResponse struct {
+	Proposals []*models.Proposal `json:"proposals"`
+	Total     int64              `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
+
+// Create godoc
+// @Summary Create a proposal from a lead
+// @Description Creates a draft proposal prefilled with the lead's system and production details and financials from the quote calculator
+// @Tags proposals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.CreateProposalInput true "Proposal details"
+// @Success 201 {object} ProposalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/proposals [post]
+func (h *ProposalHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input service.CreateProposalInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if input.LeadID == 0 {
+		respondError(w, http.StatusBadRequest, "Lead ID is required")
+		return
+	}
+
+	proposal, err := h.proposalService.CreateFromLead(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, models.ErrLeadNotFound) {
+			respondError(w, http.StatusNotFound, "Lead not found")
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, ProposalResponse{Proposal: proposal})
+}
+
+// GetByID godoc
+// @Summary Get proposal by ID
+// @Description Get a proposal by its ID
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {object} ProposalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/proposals/{id} [get]
+func (h *ProposalHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	proposal, err := h.proposalService.GetByID(r.Context(), id)
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ProposalResponse{Proposal: proposal})
+}
+
+// List godoc
+// @Summary List proposals
+// @Description Lists proposals, newest first, filtered by company, sales rep, homeowner, lead, project or status
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param company_id query int false "Filter by company ID"
+// @Param sales_id query int false "Filter by sales rep ID"
+// @Param homeowner_id query int false "Filter by homeowner ID"
+// @Param lead_id query int false "Filter by lead ID"
+// @Param project_id query int false "Filter by project ID"
+// @Param status query string false "Filter by status" Enums(draft, sent, viewed, accepted, rejected, expired)
+// @Param limit query int false "Number of items per page" default(20)
+// @Param offset query int false "Number of items to skip" default(0)
+// @Success 200 {object} ProposalsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/proposals [get]
+func (h *ProposalHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 20
+	offset := 0
+
+	var filter repo.ProposalFilter
+	for param, dst := range map[string]**int{
+		"company_id":   &filter.CompanyID,
+		"sales_id":     &filter.SalesID,
+		"homeowner_id": &filter.HomeownerID,
+		"lead_id":      &filter.LeadID,
+		"project_id":   &filter.ProjectID,
+	} {
+		if v := query.Get(param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid "+param)
+				return
+			}
+			*dst = &id
+		}
+	}
+	if v := query.Get("status"); v != "" {
+		status := models.ProposalStatus(v)
+		filter.Status = &status
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	proposals, total, err := h.proposalService.List(r.Context(), filter, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch proposals")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ProposalsResponse{
+		Proposals: proposals,
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+// Delete godoc
+// @Summary Delete a draft proposal
+// @Description Deletes a proposal that has not been sent yet
+// @Tags proposals
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/proposals/{id} [delete]
+func (h *ProposalHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	if err := h.proposalService.Delete(r.Context(), id); err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Send godoc
+// @Summary Send a proposal
+// @Description Marks a draft proposal as sent to the homeowner. Proposals without an expiry date expire 30 days after sending.
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {object} ProposalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Router /api/proposals/{id}/send [post]
+func (h *ProposalHandler) Send(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	proposal, err := h.proposalService.Send(r.Context(), id)
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ProposalResponse{Proposal: proposal})
+}
+
+// Accept godoc
+// @Summary Accept a proposal
+// @Description Records the homeowner accepting a sent or viewed proposal and creates a priced deal from it in the same transaction. Retrying returns the deal created the first time. A proposal with options is accepted through one of its options instead.
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {object} service.AcceptedProposal
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/proposals/{id}/accept [post]
+func (h *ProposalHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	accepted, err := h.proposalService.Accept(r.Context(), id)
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, accepted)
+}
+
+// Reject godoc
+// @Summary Reject a proposal
+// @Description Records the homeowner rejecting a sent or viewed proposal
+// @Tags proposals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param request body RejectProposalRequest false "Rejection reason"
+// @Success 200 {object} ProposalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Router /api/proposals/{id}/reject [post]
+func (h *ProposalHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	var req RejectProposalRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	proposal, err := h.proposalService.Reject(r.Context(), id, req.Reason)
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ProposalResponse{Proposal: proposal})
+}
+
+// View godoc
+// @Summary View a proposal by its public code
+// @Description Opens a proposal from the homeowner link without an account. Returns a homeowner-safe view, records the view and marks a sent proposal as viewed. Rate limited per client IP.
+// @Tags proposals
+// @Produce json
+// @Param code path string true "Proposal code"
+// @Success 200 {object} service.PublicProposal
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Router /p/{code} [get]
+func (h *ProposalHandler) View(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	proposal, err := h.proposalService.ViewByCode(r.Context(), code, r.UserAgent(), middleware.ClientIP(r))
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, proposal)
+}
+
+// ViewDocument godoc
+// @Summary Download a proposal PDF by its public code
+// @Description Returns the proposal PDF from the homeowner link, under the same rules as the proposal view: drafts are not public and expired proposals are refused. Rate limited per client IP.
+// @Tags proposals
+// @Produce application/pdf
+// @Param code path string true "Proposal code"
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Router /p/{code}/pdf [get]
+func (h *ProposalHandler) ViewDocument(w http.ResponseWriter, r *http.Request) {
+	proposal, file, err := h.proposalService.DocumentByCode(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		if errors.Is(err, models.ErrProposalNotFound) || errors.Is(err, models.ErrProposalExpired) {
+			respondProposalError(w, err)
+			return
+		}
+		log.Printf("Failed to render proposal PDF: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to render proposal PDF")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+proposal.Code+`.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// ListViews godoc
+// @Summary List proposal views
+// @Description Lists every time the homeowner opened the proposal link, newest first
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {array} models.ProposalView
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/proposals/{id}/views [get]
+func (h *ProposalHandler) ListViews(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	views, err := h.proposalService.ListViews(r.Context(), id)
+	if err != nil {
+		respondProposalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// Document godoc
+// @Summary Download proposal PDF
+// @Description Returns the branded proposal PDF, rendering it again first if the proposal changed since it was last rendered
+// @Tags proposals
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/proposals/{id}/pdf [get]
+func (h *ProposalHandler) Document(w http.ResponseWriter, r *http.Request) {
+	h.serveDocument(w, r, false)
+}
+
+// RegenerateDocument godoc
+// @Summary Regenerate proposal PDF
+// @Description Renders the proposal PDF again even if nothing changed, and returns it
+// @Tags proposals
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/proposals/{id}/pdf [post]
+func (h *ProposalHandler) RegenerateDocument(w http.ResponseWriter, r *http.Request) {
+	h.serveDocument(w, r, true)
+}
+
+func (h *ProposalHandler) serveDocument(w http.ResponseWriter, r *http.Request, force bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	proposal, file, err := h.proposalService.Document(r.Context(), id, force)
+	if err != nil {
+		if errors.Is(err, models.ErrProposalNotFound) {
+			respondError(w, http.StatusNotFound, "Proposal not found")
+			return
+		}
+		log.Printf("Failed to render proposal PDF: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to render proposal PDF")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+proposal.Code+`.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// respondProposalError maps proposal service errors to HTTP statuses.
+func respondProposalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrProposalNotFound):
+		respondError(w, http.StatusNotFound, "Proposal not found")
+	case errors.Is(err, models.ErrProposalOptionNotFound):
+		respondError(w, http.StatusNotFound, "Proposal option not found")
+	case errors.Is(err, models.ErrInvalidProposalTransition),
+		errors.Is(err, models.ErrProposalOptionLocked),
+		errors.Is(err, models.ErrProposalOptionRequired):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrProposalExpired):
+		respondError(w, http.StatusGone, err.Error())
+	case errors.Is(err, service.ErrPriceBelowMinimum),
+		errors.Is(err, service.ErrInvalidPricingSystemSize),
+		errors.Is(err, service.ErrInvalidContractPrice),
+		errors.Is(err, models.ErrInvalidDealProfit),
+		errors.Is(err, models.ErrFinancingOptionNotFound):
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process proposal")
+	}
+}
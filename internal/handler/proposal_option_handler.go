@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type ProposalOptionHandler struct {
+	optionService *service.ProposalOptionService
+}
+
+func NewProposalOptionHandler(optionService *service.ProposalOptionService) *ProposalOptionHandler {
+	return &ProposalOptionHandler{optionService: optionService}
+}
+
+// ProposalOptionResponse represents the response for proposal option operations
+type ProposalOptionResponse struct {
+	Option *models.ProposalOption `json:"option"`
+}
+
+// Create godoc
+// @Summary Add a proposal option
+// @Description Adds a system option, such as "Good", "Better" or "Best", to a proposal that has not been answered yet. The option starts at version 1.
+// @Tags proposals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param request body service.ProposalOptionInput true "Option details"
+// @Success 201 {object} ProposalOptionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/proposals/{id}/options [post]
+func (h *ProposalOptionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	proposalID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	var input service.ProposalOptionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	option, err := h.optionService.Create(r.Context(), proposalID, input)
+	if err != nil {
+		respondProposalOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, ProposalOptionResponse{Option: option})
+}
+
+// List godoc
+// @Summary List proposal options
+// @Description Lists a proposal's options in display order, each with its current version
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {array} models.ProposalOption
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/proposals/{id}/options [get]
+func (h *ProposalOptionHandler) List(w http.ResponseWriter, r *http.Request) {
+	proposalID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	options, err := h.optionService.List(r.Context(), proposalID)
+	if err != nil {
+		respondProposalOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, options)
+}
+
+// Update godoc
+// @Summary Edit a proposal option
+// @Description Saves the option's new contents as its next version. Earlier versions are kept unchanged. Accepted and superseded options cannot be edited.
+// @Tags proposals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param optionId path int true "Option ID"
+// @Param request body service.ProposalOptionInput true "Option details"
+// @Success 200 {object} ProposalOptionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/proposals/{id}/options/{optionId} [put]
+func (h *ProposalOptionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	proposalID, optionID, ok := optionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var input service.ProposalOptionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	option, err := h.optionService.Update(r.Context(), proposalID, optionID, input)
+	if err != nil {
+		respondProposalOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ProposalOptionResponse{Option: option})
+}
+
+// Versions godoc
+// @Summary List proposal option versions
+// @Description Lists every version of an option, newest first
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param optionId path int true "Option ID"
+// @Success 200 {array} models.ProposalOptionVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/proposals/{id}/options/{optionId}/versions [get]
+func (h *ProposalOptionHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	proposalID, optionID, ok := optionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.optionService.Versions(r.Context(), proposalID, optionID)
+	if err != nil {
+		respondProposalOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, versions)
+}
+
+// Compare godoc
+// @Summary Compare proposal options
+// @Description Returns the current version of every option side by side. Each row is one metric with a value per option and, where more or less is better, the index of the best option.
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {object} service.OptionComparison
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/proposals/{id}/options/compare [get]
+func (h *ProposalOptionHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	proposalID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	comparison, err := h.optionService.Compare(r.Context(), proposalID)
+	if err != nil {
+		respondProposalOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, comparison)
+}
+
+// Accept godoc
+// @Summary Accept a proposal option
+// @Description Accepts the proposal with the chosen option and creates a priced deal from it. The option's system and financials are copied onto the proposal, the option is locked and every other option is marked superseded.
+// @Tags proposals
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param optionId path int true "Option ID"
+// @Success 200 {object} service.AcceptedProposal
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/proposals/{id}/options/{optionId}/accept [post]
+func (h *ProposalOptionHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	proposalID, optionID, ok := optionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	accepted, err := h.optionService.Accept(r.Context(), proposalID, optionID)
+	if err != nil {
+		respondProposalOptionError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, accepted)
+}
+
+func optionIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	proposalID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID")
+		return 0, 0, false
+	}
+	optionID, err := strconv.Atoi(chi.URLParam(r, "optionId"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid option ID")
+		return 0, 0, false
+	}
+	return proposalID, optionID, true
+}
+
+func respondProposalOptionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidOptionLabel),
+		errors.Is(err, models.ErrInvalidOptionSystemSize),
+		errors.Is(err, models.ErrInvalidOptionCount),
+		errors.Is(err, models.ErrInvalidProposalCost),
+		isHardwareError(err):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondProposalError(w, err)
+	}
+}
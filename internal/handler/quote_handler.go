@@ -1,9 +1,20 @@
 package handler
 
 import (
+	"github.com/Bilal-Cplusoft/sun_ready/internal/battery"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
 	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/tariff"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
+	"github.com/go-chi/chi/v5"
 	"net/http"
 	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
 )
 
 type QuoteHandler struct {
@@ -17,12 +28,13 @@ func NewQuoteHandler(quoteService *service.QuoteService) *QuoteHandler {
 
 // GetQuote godoc
 // @Summary      Calculate solar quote
-// @Description  Takes input parameters for a solar system and returns a detailed quote with costs, savings, and payback period.
+// @Description  Takes input parameters for a solar system and returns a detailed quote with costs, savings, and payback period, comparing each financing product in FinancingProducts, or those the company offers in the quote's State, or cash and a loan.
 // @Tags         quote
 // @Accept       json
 // @Produce      json
-// @Param        quote  body      service.QuoteInput  true  "Quote input payload"
-// @Success      200    {object}  service.QuoteResult
+// @Param        company_id  query  int  false  "Company whose financing catalog supplies the loan terms and products"
+// @Param        quote  body      models.QuoteInput  true  "Quote input payload"
+// @Success      200    {object}  models.QuoteResult
 // @Failure      400    {object}  map[string]string  "Invalid request payload"
 // @Failure      500    {object}  map[string]string  "Failed to calculate quote"
 // @Router       /api/quote [post]
@@ -32,16 +44,26 @@ func (h *QuoteHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var input service.QuoteInput
+	var input models.QuoteInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "invalid request payload: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
-	result, err := h.quoteService.CalculateQuote(input)
+	var companyID *int
+	if companyIDStr := r.URL.Query().Get("company_id"); companyIDStr != "" {
+		id, err := strconv.Atoi(companyIDStr)
+		if err != nil {
+			http.Error(w, "invalid company ID", http.StatusBadRequest)
+			return
+		}
+		companyID = &id
+	}
+
+	result, err := h.quoteService.CalculateQuote(r.Context(), companyID, input)
 	if err != nil {
-		http.Error(w, "failed to calculate quote: "+err.Error(), http.StatusInternalServerError)
+		respondQuoteError(w, err)
 		return
 	}
 
@@ -49,3 +71,190 @@ func (h *QuoteHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
+
+// QuotesResponse represents the response for listing quotes
+type QuotesResponse struct {
+	Quotes []*models.Quote `json:"quotes"`
+	Total  int64           `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// RecomputeQuoteRequest represents the request body for recomputing a quote
+type RecomputeQuoteRequest struct {
+	RefreshDefaults bool `json:"refresh_defaults" example:"false"`
+}
+
+// Create godoc
+// @Summary Save a quote
+// @Description Calculates a quote and saves it with its inputs, the assumptions used for any rate the inputs left out, and the result. Link it to a lead with lead_id.
+// @Tags quote
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.CreateQuoteInput true "Quote input"
+// @Success 201 {object} models.Quote
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/quotes [post]
+func (h *QuoteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input service.CreateQuoteInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	quote, err := h.quoteService.Create(r.Context(), input)
+	if err != nil {
+		respondQuoteError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, quote)
+}
+
+// List godoc
+// @Summary List saved quotes
+// @Description Lists saved quotes, newest first, optionally for one lead or company
+// @Tags quote
+// @Produce json
+// @Security BearerAuth
+// @Param lead_id query int false "Filter by lead ID"
+// @Param company_id query int false "Filter by company ID"
+// @Param limit query int false "Number of items per page" default(20)
+// @Param offset query int false "Number of items to skip" default(0)
+// @Success 200 {object} QuotesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/quotes [get]
+func (h *QuoteHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 20
+	offset := 0
+
+	var filter repo.QuoteFilter
+	for param, dst := range map[string]**int{
+		"lead_id":    &filter.LeadID,
+		"company_id": &filter.CompanyID,
+	} {
+		if v := query.Get(param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid "+param)
+				return
+			}
+			*dst = &id
+		}
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	quotes, total, err := h.quoteService.List(r.Context(), filter, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch quotes")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, QuotesResponse{
+		Quotes: quotes,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// GetByID godoc
+// @Summary Get a saved quote
+// @Description Returns a saved quote exactly as it was calculated
+// @Tags quote
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Quote ID"
+// @Success 200 {object} models.Quote
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/quotes/{id} [get]
+func (h *QuoteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid quote ID")
+		return
+	}
+
+	quote, err := h.quoteService.GetByID(r.Context(), id)
+	if err != nil {
+		respondQuoteError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, quote)
+}
+
+// Recompute godoc
+// @Summary Recompute a saved quote
+// @Description Calculates a saved quote again and saves the new result. The quote keeps the assumptions it was saved with unless refresh_defaults is set, in which case rates its inputs left out are taken from the current defaults.
+// @Tags quote
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Quote ID"
+// @Param request body RecomputeQuoteRequest false "Recompute options"
+// @Success 200 {object} models.Quote
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/quotes/{id}/recompute [post]
+func (h *QuoteHandler) Recompute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid quote ID")
+		return
+	}
+
+	var req RecomputeQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	quote, err := h.quoteService.Recompute(r.Context(), id, req.RefreshDefaults)
+	if err != nil {
+		respondQuoteError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, quote)
+}
+
+func respondQuoteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrQuoteNotFound):
+		respondError(w, http.StatusNotFound, "Quote not found")
+	case errors.Is(err, models.ErrLeadNotFound):
+		respondError(w, http.StatusNotFound, "Lead not found")
+	case errors.Is(err, models.ErrFinancingOptionNotFound),
+		errors.Is(err, models.ErrFinancingOptionNotAvailable):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrInvalidQuoteSystemSize),
+		errors.Is(err, models.ErrInvalidQuoteProduction),
+		errors.Is(err, models.ErrInvalidQuoteBill),
+		errors.Is(err, models.ErrInvalidQuoteAssumption),
+		errors.Is(err, tariff.ErrInvalidTariff),
+		errors.Is(err, tariff.ErrInvalidProfile),
+		errors.Is(err, finance.ErrInvalidProduct),
+		errors.Is(err, battery.ErrInvalidBattery),
+		errors.Is(err, incentive.ErrInvalidIncentive),
+		errors.Is(err, usage.ErrInvalidUsage):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process quote")
+	}
+}
@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/middleware"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type SignatureHandler struct {
+	signatureService *service.SignatureService
+}
+
+func NewSignatureHandler(signatureService *service.SignatureService) *SignatureHandler {
+	return &SignatureHandler{signatureService: signatureService}
+}
+
+// SignatureRequestResponse represents the response for signature request operations
+type SignatureRequestResponse struct {
+	Request    *models.SignatureRequest `json:"request"`
+	SigningURL string                   `json:"signing_url" example:"https://app.sunready.com/sign/SIGN-7K3QX2M4N5P6R7S8T9V2W3X4Y5"`
+}
+
+// VerifySignerRequest represents the request body for verifying a signer's code
+type VerifySignerRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// Create godoc
+// @Summary Request a contract signature
+// @Description Renders the deal's contract and opens a signature request for it. Without a signer name the deal's homeowner is asked to sign. The signer verifies their identity with a one-time code sent by email or SMS.
+// @Tags signatures
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deal ID"
+// @Param request body service.CreateSignatureRequestInput false "Signer details"
+// @Success 201 {object} SignatureRequestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/deals/{id}/signature-requests [post]
+func (h *SignatureHandler) Create(w http.ResponseWriter, r *http.Request) {
+	dealID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid deal ID")
+		return
+	}
+
+	var input service.CreateSignatureRequestInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	request, err := h.signatureService.Create(r.Context(), dealID, input, middleware.ClientIP(r), r.UserAgent())
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, SignatureRequestResponse{
+		Request:    request,
+		SigningURL: h.signatureService.SigningURL(request),
+	})
+}
+
+// ListByDeal godoc
+// @Summary List a deal's signature requests
+// @Description Lists every signature request of a deal, newest first
+// @Tags signatures
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deal ID"
+// @Success 200 {array} models.SignatureRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/deals/{id}/signature-requests [get]
+func (h *SignatureHandler) ListByDeal(w http.ResponseWriter, r *http.Request) {
+	dealID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid deal ID")
+		return
+	}
+
+	requests, err := h.signatureService.ListByDeal(r.Context(), dealID)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, requests)
+}
+
+// Get godoc
+// @Summary Get a signature request
+// @Description Returns a signature request with its hash-chained audit trail
+// @Tags signatures
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Signature request ID"
+// @Success 200 {object} service.SignatureAudit
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/signature-requests/{id} [get]
+func (h *SignatureHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid signature request ID")
+		return
+	}
+
+	audit, err := h.signatureService.Get(r.Context(), id)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, audit)
+}
+
+// Document godoc
+// @Summary Download the contract
+// @Description Returns the signed contract PDF, or the unsigned contract while the request is still open
+// @Tags signatures
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "Signature request ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/signature-requests/{id}/document [get]
+func (h *SignatureHandler) Document(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid signature request ID")
+		return
+	}
+
+	request, file, err := h.signatureService.Document(r.Context(), id)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+	servePDF(w, file, request.Token+".pdf")
+}
+
+// Certificate godoc
+// @Summary Download the completion certificate
+// @Description Returns the completion certificate of a signed request: signer identity, verification method, IP address and timestamps, document fingerprints and the audit trail
+// @Tags signatures
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "Signature request ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/signature-requests/{id}/certificate [get]
+func (h *SignatureHandler) Certificate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid signature request ID")
+		return
+	}
+
+	request, file, err := h.signatureService.Certificate(r.Context(), id)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+	servePDF(w, file, request.Token+"-certificate.pdf")
+}
+
+// Verify godoc
+// @Summary Verify a signature request
+// @Description Recomputes the SHA-256 of the stored contracts and every audit trail event, and reports whether anything changed since it was recorded
+// @Tags signatures
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Signature request ID"
+// @Success 200 {object} service.SignatureVerification
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/signature-requests/{id}/verify [get]
+func (h *SignatureHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid signature request ID")
+		return
+	}
+
+	result, err := h.signatureService.Verify(r.Context(), id)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// View godoc
+// @Summary Open a signing link
+// @Description Returns the signer's view of a signature request opened by its token. Rate limited per client IP.
+// @Tags signatures
+// @Produce json
+// @Param token path string true "Signature token"
+// @Success 200 {object} service.PublicSignatureRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Router /sign/{token} [get]
+func (h *SignatureHandler) View(w http.ResponseWriter, r *http.Request) {
+	view, err := h.signatureService.PublicView(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, view)
+}
+
+// ViewDocument godoc
+// @Summary Read the contract from a signing link
+// @Description Returns the contract PDF to be signed, or the signed copy once signing is complete. Each open is recorded in the audit trail.
+// @Tags signatures
+// @Produce application/pdf
+// @Param token path string true "Signature token"
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Router /sign/{token}/document [get]
+func (h *SignatureHandler) ViewDocument(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	file, err := h.signatureService.OpenDocument(r.Context(), token, middleware.ClientIP(r), r.UserAgent())
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	servePDF(w, file, token+".pdf")
+}
+
+// SendCode godoc
+// @Summary Send a signer verification code
+// @Description Sends a one-time code to the signer's email or phone. A new code replaces any code sent before.
+// @Tags signatures
+// @Produce json
+// @Param token path string true "Signature token"
+// @Success 200 {object} service.PublicSignatureRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Failure 502 {object} ErrorResponse
+// @Router /sign/{token}/code [post]
+func (h *SignatureHandler) SendCode(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	if err := h.signatureService.SendCode(r.Context(), token, middleware.ClientIP(r), r.UserAgent()); err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+	view, err := h.signatureService.PublicView(r.Context(), token)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, view)
+}
+
+// VerifyCode godoc
+// @Summary Verify a signer
+// @Description Checks the signer's one-time code. A verified signer has one hour to sign.
+// @Tags signatures
+// @Accept json
+// @Produce json
+// @Param token path string true "Signature token"
+// @Param request body VerifySignerRequest true "Verification code"
+// @Success 200 {object} service.PublicSignatureRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Router /sign/{token}/verify [post]
+func (h *SignatureHandler) VerifyCode(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	var req VerifySignerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if _, err := h.signatureService.VerifyCode(r.Context(), token, req.Code, middleware.ClientIP(r), r.UserAgent()); err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+	view, err := h.signatureService.PublicView(r.Context(), token)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, view)
+}
+
+// Sign godoc
+// @Summary Sign a contract
+// @Description Records the verified signer's consent and drawn or typed signature, renders the signed contract and completion certificate, and marks the deal signed. A deal changed since the contract was sent is refused with 409
+// @Tags signatures
+// @Accept json
+// @Produce json
+// @Param token path string true "Signature token"
+// @Param request body service.SignInput true "Consent and signature"
+// @Success 200 {object} service.PublicSignatureRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 429 {string} string "Too many requests"
+// @Router /sign/{token}/sign [post]
+func (h *SignatureHandler) Sign(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	var input service.SignInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if _, err := h.signatureService.Sign(r.Context(), token, input, middleware.ClientIP(r), r.UserAgent()); err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+	view, err := h.signatureService.PublicView(r.Context(), token)
+	if err != nil {
+		respondSignatureError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, view)
+}
+
+func servePDF(w http.ResponseWriter, file io.ReadCloser, filename string) {
+	defer file.Close()
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// respondSignatureError maps signature service errors to HTTP statuses.
+func respondSignatureError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrSignatureRequestNotFound):
+		respondError(w, http.StatusNotFound, "Signature request not found")
+	case errors.Is(err, models.ErrDealNotFound):
+		respondError(w, http.StatusNotFound, "Deal not found")
+	case errors.Is(err, models.ErrSignatureRequestExpired):
+		respondError(w, http.StatusGone, err.Error())
+	case errors.Is(err, models.ErrSignatureRequestCompleted),
+		errors.Is(err, models.ErrSignatureRequestIncomplete),
+		errors.Is(err, models.ErrDealAlreadySigned),
+		errors.Is(err, models.ErrContractChanged):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrSignerNotVerified),
+		errors.Is(err, models.ErrInvalidVerificationCode):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrInvalidSignerName),
+		errors.Is(err, models.ErrInvalidSignerContact),
+		errors.Is(err, models.ErrConsentRequired),
+		errors.Is(err, models.ErrInvalidSignature):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTooManyVerificationCodes):
+		respondError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, service.ErrVerificationCodeNotSent):
+		respondError(w, http.StatusBadGateway, err.Error())
+	default:
+		log.Printf("Signature request failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to process signature request")
+	}
+}
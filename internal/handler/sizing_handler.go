@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/production"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/sizing"
+)
+
+type SizingHandler struct {
+	sizingService *service.SizingService
+}
+
+func NewSizingHandler(sizingService *service.SizingService) *SizingHandler {
+	return &SizingHandler{sizingService: sizingService}
+}
+
+// Size godoc
+// @Summary Size a system to a target offset
+// @Description Picks the panel count, DC and AC size and inverter count that cover target_solar_offset percent of 12 months of consumption, in kWh or dollars, within the roof's panel and size caps. Mode "max" fills the caps instead. The offset is reported before and after rounding to whole panels. The kWh/kW yield comes from the request, the lead's manual yield or a local production estimate.
+// @Tags sizing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.SizeSystemInput true "Consumption, hardware and caps"
+// @Success 200 {object} service.SystemSizing
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sizing [post]
+func (h *SizingHandler) Size(w http.ResponseWriter, r *http.Request) {
+	var input service.SizeSystemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.sizingService.Size(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, sizing.ErrInvalidSizing),
+			errors.Is(err, production.ErrInvalidSystem):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrLeadNotFound),
+			errors.Is(err, models.ErrPanelNotFound),
+			errors.Is(err, models.ErrInverterNotFound):
+			respondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, production.ErrNoWeather):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to size system")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
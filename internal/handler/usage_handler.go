@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/service"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxUsageUploadBytes fits a year of 15-minute Green Button XML.
+const maxUsageUploadBytes = 64 << 20
+
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// Upload godoc
+// @Summary Upload a lead's interval usage
+// @Description Uploads a Green Button (ESPI XML) download or a utility interval CSV export, as a multipart "file" field or as the raw request body. Readings are normalized to a year of hourly usage, with gaps filled from readings at the same time of day, and the lead's annual kWh usage is set from it. Quotes, proposals and sizing for the lead then use this load shape. A new upload replaces the last.
+// @Tags usage
+// @Accept multipart/form-data,text/csv,application/xml
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Lead ID"
+// @Param format query string false "green_button or csv; detected from the file when omitted"
+// @Param file formData file false "Usage file"
+// @Success 201 {object} models.LeadUsage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/usage [post]
+func (h *UsageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	leadID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUsageUploadBytes)
+
+	var body io.Reader = r.Body
+	fileName := ""
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "A usage file is required")
+			return
+		}
+		defer file.Close()
+		body, fileName = file, header.Filename
+	}
+
+	record, err := h.usageService.Import(r.Context(), leadID, r.URL.Query().Get("format"), fileName, body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, "Usage file is too large")
+		case errors.Is(err, usage.ErrInvalidUsage):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, usage.ErrInsufficientUsage):
+			respondError(w, http.StatusUnprocessableEntity, "Usage file needs at least a week of readings")
+		case errors.Is(err, models.ErrLeadNotFound):
+			respondError(w, http.StatusNotFound, "Lead not found")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to import usage")
+		}
+		return
+	}
+
+	record.HourlyKWh = nil
+	respondJSON(w, http.StatusCreated, record)
+}
+
+// Get godoc
+// @Summary Get a lead's usage
+// @Description Returns the lead's normalized usage: monthly and annual kWh and how much of the year was measured. Hourly kWh is included with include_hourly=true.
+// @Tags usage
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Lead ID"
+// @Param include_hourly query bool false "Include the 8760 hourly values"
+// @Success 200 {object} models.LeadUsage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/usage [get]
+func (h *UsageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	leadID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	record, err := h.usageService.Get(r.Context(), leadID)
+	if err != nil {
+		if errors.Is(err, models.ErrLeadUsageNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to get usage")
+		return
+	}
+	if r.URL.Query().Get("include_hourly") != "true" {
+		record.HourlyKWh = nil
+	}
+	respondJSON(w, http.StatusOK, record)
+}
+
+// Delete godoc
+// @Summary Delete a lead's usage
+// @Description Removes the lead's uploaded usage. Quotes go back to a typical load shape; the lead's annual usage is left as it is.
+// @Tags usage
+// @Security BearerAuth
+// @Param id path int true "Lead ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/usage [delete]
+func (h *UsageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	leadID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	if err := h.usageService.Delete(r.Context(), leadID); err != nil {
+		if errors.Is(err, models.ErrLeadUsageNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete usage")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Synthesize godoc
+// @Summary Synthesize an hourly load profile
+// @Description Expands 12 monthly kWh figures (January first), or an annual figure, into 8760 hourly values shaped like a reference home for the climate zone, building area and electrification (EV charging, heat pump heating). Monthly figures are reproduced exactly; an annual figure is split across months by the reference shape, and with neither the annual is estimated from the building. A lead supplies its annual usage and state when they are not given. Hourly kWh is included with include_hourly=true.
+// @Tags usage
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.SynthesizeLoadInput true "Consumption and home"
+// @Param include_hourly query bool false "Include the 8760 hourly values"
+// @Success 200 {object} usage.SyntheticProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/usage/synthesize [post]
+func (h *UsageHandler) Synthesize(w http.ResponseWriter, r *http.Request) {
+	var input service.SynthesizeLoadInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	profile, err := h.usageService.Synthesize(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, usage.ErrInvalidUsage):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrLeadNotFound):
+			respondError(w, http.StatusNotFound, "Lead not found")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to synthesize load profile")
+		}
+		return
+	}
+	if r.URL.Query().Get("include_hourly") != "true" {
+		profile.Hourly = nil
+	}
+	respondJSON(w, http.StatusOK, profile)
+}
@@ -0,0 +1,263 @@
+// Package incentive values solar incentives (tax credits, rebates,
+// performance payments and tax exemptions) for a system, year by year.
+package incentive
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidIncentive = errors.New("invalid incentive")
+
+// Incentive types.
+const (
+	// TypeFederalITC is the federal investment tax credit: Rate, or the
+	// Schedule rate for the install year, of the system cost.
+	TypeFederalITC = "federal_itc"
+	// TypeStateTaxCredit is a state tax credit on the system cost, received
+	// with the federal credit.
+	TypeStateTaxCredit = "state_tax_credit"
+	// TypeRebate is paid once at install: a flat amount, per kW, per
+	// expected first-year kWh and/or a fraction of cost.
+	TypeRebate = "rebate"
+	// TypePerformance pays per kWh produced for Years, e.g. SRECs or a
+	// performance-based incentive.
+	TypePerformance = "performance"
+	// TypePropertyTaxExemption exempts the added home value from property
+	// tax: Rate is the property tax rate saved on the system cost each year.
+	TypePropertyTaxExemption = "property_tax_exemption"
+)
+
+// Ownership types. Third-party owned systems are leased or sold under a
+// PPA; the owner, not the homeowner, claims most incentives.
+const (
+	OwnershipOwned      = "owned"
+	OwnershipThirdParty = "third_party"
+)
+
+// Program is one incentive and who can claim it. Amounts are in dollars and
+// rates are fractions.
+type Program struct {
+	Name string `json:"name" example:"Residential Clean Energy Credit"`
+	Type string `json:"type" example:"federal_itc"`
+	// Rate is a fraction of system cost for tax credits and rebates, or
+	// the yearly property tax rate for exemptions.
+	Rate float64 `json:"rate,omitempty" example:"0.30"`
+	// Schedule, when set, gives Rate by install year.
+	Schedule []Step `json:"schedule,omitempty"`
+	// Amount is a flat amount for rebates.
+	Amount float64 `json:"amount,omitempty" example:"0"`
+	PerKW  float64 `json:"per_kw,omitempty" example:"0"`
+	// PerKWh is paid on expected first-year production for rebates and on
+	// each year's production for performance payments.
+	PerKWh float64 `json:"per_kwh,omitempty" example:"0"`
+	// MaxAmount caps the total; zero means no cap.
+	MaxAmount float64 `json:"max_amount,omitempty" example:"5000"`
+	// Years limits how long performance payments and exemptions last; zero
+	// means the life of the model.
+	Years int `json:"years,omitempty" example:"10"`
+
+	Eligibility
+}
+
+// Step is the rate for installs from FromYear until the next step.
+type Step struct {
+	FromYear int     `json:"from_year" example:"2022"`
+	Rate     float64 `json:"rate" example:"0.30"`
+}
+
+// FederalITC is the residential clean energy credit as enacted, for quotes
+// when the catalog has no federal ITC of its own: 26% for 2020 and 2021
+// installs, 30% from 2022 under the Inflation Reduction Act, and nothing
+// for installs after 2025, when the credit ended.
+var FederalITC = Program{
+	Name: "Residential Clean Energy Credit",
+	Type: TypeFederalITC,
+	Schedule: []Step{
+		{FromYear: 2020, Rate: 0.26},
+		{FromYear: 2022, Rate: 0.30},
+		{FromYear: 2026, Rate: 0},
+	},
+	Eligibility: Eligibility{Ownership: []string{OwnershipOwned}},
+}
+
+// Eligibility limits who can claim a program. Empty lists and zero sizes
+// do not limit it.
+type Eligibility struct {
+	States      []string `json:"states,omitempty" example:"NY"`
+	UtilityIDs  []int    `json:"utility_ids,omitempty"`
+	MinSystemKW float64  `json:"min_system_kw,omitempty" example:"0"`
+	MaxSystemKW float64  `json:"max_system_kw,omitempty" example:"25"`
+	Ownership   []string `json:"ownership,omitempty" example:"owned"`
+}
+
+// System is what incentives are valued on.
+type System struct {
+	State     string
+	UtilityID *int
+	SizeKW    float64
+	Cost      float64
+	Ownership string
+	// InstallYear picks the scheduled rate.
+	InstallYear            int
+	FirstYearProductionKWh float64
+	Degradation            float64
+	// Years is the length of the model and TaxCreditYear the year tax
+	// credits are received, as for finance.CashFlowInput.
+	Years         int
+	TaxCreditYear int
+}
+
+// Value is what a program is worth to a system. Yearly holds the amount
+// received in years 1..n.
+type Value struct {
+	Name   string    `json:"name" example:"NY-Sun Megawatt Block"`
+	Type   string    `json:"type" example:"rebate"`
+	Amount float64   `json:"amount" example:"2400.00"`
+	Yearly []float64 `json:"yearly"`
+}
+
+// Validate checks the program and normalizes its eligibility.
+func (p *Program) Validate() error {
+	switch p.Type {
+	case TypeFederalITC, TypeStateTaxCredit, TypeRebate, TypePerformance, TypePropertyTaxExemption:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidIncentive, p.Type)
+	}
+	if p.Rate < 0 || p.Rate > 1 || p.Amount < 0 || p.PerKW < 0 || p.PerKWh < 0 || p.MaxAmount < 0 || p.Years < 0 {
+		return fmt.Errorf("%w: amounts must not be negative and rates must be at most 1", ErrInvalidIncentive)
+	}
+	for i, step := range p.Schedule {
+		if step.Rate < 0 || step.Rate > 1 {
+			return fmt.Errorf("%w: scheduled rates must be between 0 and 1", ErrInvalidIncentive)
+		}
+		if i > 0 && step.FromYear <= p.Schedule[i-1].FromYear {
+			return fmt.Errorf("%w: schedule years must increase", ErrInvalidIncentive)
+		}
+	}
+	if p.MaxSystemKW > 0 && p.MaxSystemKW < p.MinSystemKW {
+		return fmt.Errorf("%w: maximum system size is below the minimum", ErrInvalidIncentive)
+	}
+	for i, s := range p.States {
+		p.States[i] = strings.ToUpper(strings.TrimSpace(s))
+	}
+	for _, o := range p.Ownership {
+		if o != OwnershipOwned && o != OwnershipThirdParty {
+			return fmt.Errorf("%w: unknown ownership %q", ErrInvalidIncentive, o)
+		}
+	}
+	return nil
+}
+
+// Eligible reports whether the system can claim the program. An empty
+// ownership on the system matches any program, but a program limited to
+// some states needs the system's state to be known.
+func (p *Program) Eligible(s System) bool {
+	if len(p.States) > 0 &&
+		!slices.ContainsFunc(p.States, func(state string) bool { return strings.EqualFold(state, s.State) }) {
+		return false
+	}
+	if len(p.UtilityIDs) > 0 && (s.UtilityID == nil || !slices.Contains(p.UtilityIDs, *s.UtilityID)) {
+		return false
+	}
+	if s.SizeKW < p.MinSystemKW || (p.MaxSystemKW > 0 && s.SizeKW > p.MaxSystemKW) {
+		return false
+	}
+	if len(p.Ownership) > 0 && s.Ownership != "" && !slices.Contains(p.Ownership, s.Ownership) {
+		return false
+	}
+	return true
+}
+
+// RateFor returns the program's rate for an install year: the last
+// scheduled step at or before it, 0 before the first step, or Rate without
+// a schedule.
+func (p *Program) RateFor(year int) float64 {
+	if len(p.Schedule) == 0 {
+		return p.Rate
+	}
+	rate := 0.0
+	for _, step := range p.Schedule {
+		if step.FromYear > year {
+			break
+		}
+		rate = step.Rate
+	}
+	return rate
+}
+
+// Value returns what the program is worth to the system, whether or not
+// it is eligible.
+func (p *Program) Value(s System) Value {
+	v := Value{Name: p.Name, Type: p.Type, Yearly: make([]float64, s.Years)}
+	if s.Years == 0 {
+		return v
+	}
+	years := s.Years
+	if p.Years > 0 {
+		years = min(p.Years, s.Years)
+	}
+
+	switch p.Type {
+	case TypeFederalITC, TypeStateTaxCredit:
+		year := min(max(s.TaxCreditYear, 1), s.Years)
+		v.Yearly[year-1] = s.Cost * p.RateFor(s.InstallYear)
+	case TypeRebate:
+		v.Yearly[0] = p.Amount + p.PerKW*s.SizeKW + p.PerKWh*s.FirstYearProductionKWh + s.Cost*p.RateFor(s.InstallYear)
+	case TypePerformance:
+		for y := 0; y < years; y++ {
+			v.Yearly[y] = p.PerKWh * s.FirstYearProductionKWh * math.Pow(1-s.Degradation, float64(y))
+		}
+	case TypePropertyTaxExemption:
+		for y := 0; y < years; y++ {
+			v.Yearly[y] = s.Cost * p.RateFor(s.InstallYear)
+		}
+	}
+
+	// The cap applies to the total, so later payments stop once it is hit.
+	left := math.Inf(1)
+	if p.MaxAmount > 0 {
+		left = p.MaxAmount
+	}
+	for y, amount := range v.Yearly {
+		amount = math.Min(amount, left)
+		left -= amount
+		v.Yearly[y] = round2(amount)
+		v.Amount += amount
+	}
+	v.Amount = round2(v.Amount)
+	return v
+}
+
+// Apply values every program the system is eligible for, skipping those
+// worth nothing.
+func Apply(programs []Program, s System) []Value {
+	var values []Value
+	for _, p := range programs {
+		if !p.Eligible(s) {
+			continue
+		}
+		if v := p.Value(s); v.Amount > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Yearly sums the values received in each of years 1..n.
+func Yearly(values []Value, years int) []float64 {
+	total := make([]float64, years)
+	for _, v := range values {
+		for y := 0; y < years && y < len(v.Yearly); y++ {
+			total[y] += v.Yearly[y]
+		}
+	}
+	return total
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is an in-memory token bucket per client IP. Each client may
+// burst up to Burst requests and regains Rate requests per second.
+type RateLimiter struct {
+	rate    float64
+	burst   float64
+	mu      sync.Mutex
+	clients map[string]*bucket
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func NewRateLimiter(ratePerSecond float64, burst int) *RateLimiter {
+	rl := &RateLimiter{
+		rate:    ratePerSecond,
+		burst:   float64(burst),
+		clients: make(map[string]*bucket),
+	}
+	go rl.cleanup(10 * time.Minute)
+	return rl
+}
+
+// Allow reports whether a request from key may proceed and consumes a token.
+func (rl *RateLimiter) Allow(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	b, ok := rl.clients[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, lastSeen: now}
+		rl.clients[key] = b
+	}
+
+	b.tokens += now.Sub(b.lastSeen).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Middleware rejects requests over the limit with 429 Too Many Requests.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rl.Allow(ClientIP(r)) {
+			retryAfter := 1
+			if rl.rate > 0 && rl.rate < 1 {
+				retryAfter = int(1/rl.rate + 0.5)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cleanup forgets clients that have been idle long enough to be back at a
+// full bucket.
+func (rl *RateLimiter) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rl.mu.Lock()
+		for key, b := range rl.clients {
+			if time.Since(b.lastSeen) > interval {
+				delete(rl.clients, key)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
+
+// trustedProxies are the proxies whose forwarding headers ClientIP believes.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the proxies, as IPs or CIDR ranges, whose
+// X-Forwarded-For and X-Real-IP headers are trusted. Call it before serving
+// requests. With none set, forwarding headers are ignored.
+func SetTrustedProxies(proxies []string) error {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	trustedProxies = nets
+	return nil
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that made the request. Only when
+// the request comes from a trusted proxy are forwarding headers read: the
+// client is the rightmost X-Forwarded-For hop that is not itself a trusted
+// proxy, or X-Real-IP when there is no X-Forwarded-For. Anything a client
+// sends to the left of that hop could be forged, so it is ignored.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				return host
+			}
+			if !isTrustedProxy(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return host
+}
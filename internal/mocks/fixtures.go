@@ -0,0 +1,133 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+)
+
+// Fixtures are the canned data the mock server answers with. They use the
+// clients' own types, so a fixture file reads like the real APIs' JSON.
+type Fixtures struct {
+	LightFusion LightFusionFixtures `json:"lightfusion"`
+	Genability  GenabilityFixtures  `json:"genability"`
+}
+
+// LightFusionFixtures script the 3D design flow. A project's build reports
+// progress on each status poll and finishes in BuildState after
+// BuildPolls polls, sized to its consumption at KwhPerKw. Meshes are read
+// from MeshDir, or generated placeholders are served.
+type LightFusionFixtures struct {
+	Panels         []client.Panel        `json:"panels"`
+	Inverters      []client.Inverter     `json:"inverters"`
+	Batteries      []client.Battery      `json:"batteries"`
+	Adders         []client.Adder        `json:"adders"`
+	PriceBreakdown client.PriceBreakdown `json:"price_breakdown"`
+	KwhPerKw       float64               `json:"kwh_per_kw"`
+	BuildPolls     int                   `json:"build_polls"`
+	BuildState     int                   `json:"build_state"`
+	MeshDir        string                `json:"mesh_dir"`
+}
+
+// GenabilityFixtures are the utilities and tariffs served. Bills are
+// calculated at each tariff's flat rates.
+type GenabilityFixtures struct {
+	LSEs    []client.LoadServingEntity `json:"lses"`
+	Tariffs []Tariff                   `json:"tariffs"`
+}
+
+// Tariff is a Genability tariff with the flat rates the mock bills it at.
+// Exported kWh are credited at ExportCreditPerKWh.
+type Tariff struct {
+	client.Tariff
+	RatePerKWh          float64 `json:"ratePerKwh"`
+	FixedChargePerMonth float64 `json:"fixedChargePerMonth"`
+	ExportCreditPerKWh  float64 `json:"exportCreditPerKwh"`
+}
+
+// Lead states LightFusion reports for a build.
+const (
+	BuildProcessing = 0
+	BuildDone       = 1
+	BuildErrored    = 2
+)
+
+// DefaultFixtures is one utility with a residential tariff, a panel, an
+// inverter and a battery, and builds that finish on the second poll.
+func DefaultFixtures() *Fixtures {
+	return &Fixtures{
+		LightFusion: LightFusionFixtures{
+			Panels: []client.Panel{{
+				ID: 1, Manufacturer: "Mock Solar", Model: "MS-400", DisplayName: "Mock Solar 400W",
+				Active: true, IsDefault: true, Power: 400, PricePerWatt: 0.45, IsDomestic: true,
+				Voc: 37.1, Isc: 13.9, Vmp: 31.0, Imp: 12.9, TempCoeffVoc: -0.27, TempCoeffPmax: -0.34, NOCT: 45, Efficiency: 20.5,
+			}},
+			Inverters: []client.Inverter{{
+				ID: 1, Name: "Mock Inverter 7.6", Manufacturer: "Mock Power", Model: "MP-7600", Category: "string",
+				CostType: "fixed", IsActive: true, Cost: 1800, Capacity: 7.6, Quantity: 1,
+				MaxDCVoltage: 600, MPPTMinVoltage: 100, MPPTMaxVoltage: 480, MPPTCount: 2, MaxInputCurrent: 15, Efficiency: 97.5,
+			}},
+			Batteries: []client.Battery{{
+				ID: 1, Name: "Mock Battery 13.5", Manufacturer: "Mock Power", Model: "MB-13", Capacity: 13.5,
+				UsableCapacity: 13.5, Power: 5, RoundTripEfficiency: 0.9, Cost: 11000, IsActive: true,
+			}},
+			Adders: []client.Adder{{
+				ID: 1, CompanyID: 1, Name: "Main panel upgrade", Cost: 2500, CostType: "fixed",
+				States: []string{}, Active: true, CategoryID: 1, Quantity: 1,
+			}},
+			PriceBreakdown: client.PriceBreakdown{
+				Items:                       []client.PriceItem{{Name: "Base system", Price: 24000}, {Name: "Main panel upgrade", Price: 2500}},
+				BasePricePerWatt:            3.00,
+				TotalPricePerWatt:           3.31,
+				TotalPricePerWattFinanced:   3.80,
+				DefaultBasePrice:            3.00,
+				MinimumBasePrice:            2.50,
+				TotalAmount:                 30400,
+				TotalAmountWithoutDealerFee: 26500,
+				TotalFee:                    3900,
+			},
+			KwhPerKw:   1400,
+			BuildPolls: 2,
+			BuildState: BuildDone,
+		},
+		Genability: GenabilityFixtures{
+			LSEs: []client.LoadServingEntity{{
+				ID: 734, Name: "Mock Electric Company", Code: "MEC", WebsiteHome: "https://example.com",
+				ServiceTypes: "ELECTRICITY", TotalCustomers: 1200000, ResidentialCustomers: 1050000,
+			}},
+			Tariffs: []Tariff{{
+				Tariff: client.Tariff{
+					ID: 3001, Name: "Residential Service", Code: "E-1", LseID: 734, LseName: "Mock Electric Company",
+					ServiceType: "ELECTRICITY", IsActive: true, MasterID: 522, CustomerClass: client.CustomerClassResidential, TariffType: "DEFAULT",
+				},
+				RatePerKWh:          0.30,
+				FixedChargePerMonth: 10,
+				ExportCreditPerKWh:  0.30,
+			}, {
+				Tariff: client.Tariff{
+					ID: 3002, Name: "General Service", Code: "A-1", LseID: 734, LseName: "Mock Electric Company",
+					ServiceType: "ELECTRICITY", IsActive: true, MasterID: 523, CustomerClass: client.CustomerClassGeneral, TariffType: "DEFAULT",
+				},
+				RatePerKWh:          0.26,
+				FixedChargePerMonth: 25,
+				ExportCreditPerKWh:  0.08,
+			}},
+		},
+	}
+}
+
+// LoadFixtures reads fixtures from a JSON file over the defaults; sections
+// the file leaves out keep their default values.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	f := DefaultFixtures()
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,276 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) genabilityRoutes(r chi.Router) {
+	r.Use(requireBasicAuth)
+	r.Post("/v1/accounts", s.genabilityCreateAccount)
+	r.Get("/v1/accounts/{id}", s.genabilityShowAccount)
+	r.Put("/v1/accounts/{id}/properties", s.genabilitySetProperty)
+	r.Get("/v1/accounts/{id}/tariffs", s.genabilityAccountTariffs)
+	r.Get("/public/tariffs", s.genabilityTariffs)
+	r.Get("/public/tariffs/{masterId}", s.genabilityShowTariff)
+	r.Get("/public/lses", s.genabilityLSEs)
+	r.Post("/v1/ondemand/calculate", s.genabilityCalculate)
+}
+
+func requireBasicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			genabilityError(w, http.StatusUnauthorized, "NotAuthorized", "credentials are required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// genabilityResults writes results in Genability's response envelope.
+func genabilityResults[T any](w http.ResponseWriter, count int, results []T) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  "success",
+		"type":    "results",
+		"count":   count,
+		"results": nonNil(results),
+	})
+}
+
+func genabilityError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"status":  "error",
+		"count":   1,
+		"results": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+func (s *Server) genabilityCreateAccount(w http.ResponseWriter, r *http.Request) {
+	var account client.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		genabilityError(w, http.StatusBadRequest, "InvalidArgument", "invalid account")
+		return
+	}
+	if account.Properties == nil {
+		account.Properties = map[string]client.AccountProperty{}
+	}
+	account.Status = "ACTIVE"
+
+	s.mu.Lock()
+	account.ID = fmt.Sprintf("mock-account-%d", s.id())
+	stored := account
+	s.accounts[account.ID] = &stored
+	s.mu.Unlock()
+	genabilityResults(w, 1, []client.Account{account})
+}
+
+func (s *Server) genabilityShowAccount(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.account(chi.URLParam(r, "id"))
+	if !ok {
+		genabilityError(w, http.StatusNotFound, "ObjectNotFound", "account not found")
+		return
+	}
+	genabilityResults(w, 1, []client.Account{account})
+}
+
+func (s *Server) genabilitySetProperty(w http.ResponseWriter, r *http.Request) {
+	var kv client.KeyValue
+	if err := json.NewDecoder(r.Body).Decode(&kv); err != nil || kv.Key == "" {
+		genabilityError(w, http.StatusBadRequest, "InvalidArgument", "keyName is required")
+		return
+	}
+
+	s.mu.Lock()
+	stored, ok := s.accounts[chi.URLParam(r, "id")]
+	var account client.Account
+	if ok {
+		stored.Properties[kv.Key] = client.AccountProperty{KeyValue: kv}
+		account = *stored
+	}
+	s.mu.Unlock()
+	if !ok {
+		genabilityError(w, http.StatusNotFound, "ObjectNotFound", "account not found")
+		return
+	}
+	genabilityResults(w, 1, []client.Account{account})
+}
+
+// genabilityAccountTariffs lists the tariffs of the account's utility, or
+// all of them when it has none set.
+func (s *Server) genabilityAccountTariffs(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.account(chi.URLParam(r, "id"))
+	if !ok {
+		genabilityError(w, http.StatusNotFound, "ObjectNotFound", "account not found")
+		return
+	}
+	lseID, _ := strconv.ParseUint(account.Properties[client.AccountPropertyLseID].Value, 10, 64)
+	class := client.CustomerClassResidential
+	for name, value := range customerClassNames {
+		if account.Properties[client.AccountPropertyCustomerClass].Value == value {
+			class = name
+		}
+	}
+	tariffs := s.tariffs(uint(lseID), class)
+	genabilityResults(w, len(tariffs), tariffs)
+}
+
+// customerClassNames are the customerClass account property values.
+var customerClassNames = map[string]string{
+	client.CustomerClassResidential: "1",
+	client.CustomerClassGeneral:     "2",
+	client.CustomerClassSpecialUse:  "4",
+}
+
+func (s *Server) genabilityTariffs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lseID, _ := strconv.ParseUint(q.Get("lseId"), 10, 64)
+	tariffs := s.tariffs(uint(lseID), q.Get("customerClasses"))
+
+	start, _ := strconv.Atoi(q.Get("pageStart"))
+	count, err := strconv.Atoi(q.Get("pageCount"))
+	if err != nil || count <= 0 {
+		count = 25
+	}
+	start = min(max(start, 0), len(tariffs))
+	end := min(start+count, len(tariffs))
+	genabilityResults(w, len(tariffs), tariffs[start:end])
+}
+
+func (s *Server) genabilityShowTariff(w http.ResponseWriter, r *http.Request) {
+	masterID, _ := strconv.ParseUint(chi.URLParam(r, "masterId"), 10, 64)
+	t, ok := s.tariff(uint(masterID))
+	if !ok {
+		genabilityError(w, http.StatusNotFound, "ObjectNotFound", "tariff not found")
+		return
+	}
+	genabilityResults(w, 1, []client.Tariff{t.Tariff})
+}
+
+func (s *Server) genabilityLSEs(w http.ResponseWriter, r *http.Request) {
+	lses := s.fixtures.Genability.LSEs
+	genabilityResults(w, len(lses), lses)
+}
+
+// genabilityCalculate bills consumption inputs at the tariff's flat rate,
+// credits negative ones at its export credit, and adds its fixed charge for
+// each month of the period.
+func (s *Server) genabilityCalculate(w http.ResponseWriter, r *http.Request) {
+	var req client.CalculateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		genabilityError(w, http.StatusBadRequest, "InvalidArgument", "invalid calculation request")
+		return
+	}
+	from, errFrom := parseGenabilityDate(req.From)
+	to, errTo := parseGenabilityDate(req.To)
+	if errFrom != nil || errTo != nil || !to.After(from) {
+		genabilityError(w, http.StatusBadRequest, "InvalidArgument", "fromDateTime must be before toDateTime")
+		return
+	}
+	t, ok := s.tariff(req.MasterTariffID)
+	if !ok {
+		genabilityError(w, http.StatusNotFound, "ObjectNotFound", fmt.Sprintf("tariff %d not found", req.MasterTariffID))
+		return
+	}
+
+	cost := client.CalculatedCost{
+		MasterTariffID: t.MasterID,
+		TariffName:     t.Name,
+		From:           req.From,
+		To:             req.To,
+	}
+	for _, in := range req.PropertyInputs {
+		if in.Key != client.PropertyConsumption {
+			continue
+		}
+		kwh, err := strconv.ParseFloat(in.Value, 64)
+		if err != nil {
+			genabilityError(w, http.StatusBadRequest, "InvalidArgument", "consumption must be a number")
+			return
+		}
+		item := client.CalculatedCostItem{
+			RateName:     "Energy charge",
+			ChargeType:   "CONSUMPTION_BASED",
+			From:         in.From,
+			To:           in.To,
+			QuantityKey:  client.PropertyConsumption,
+			RateAmount:   t.RatePerKWh,
+			ItemQuantity: kwh,
+		}
+		if kwh < 0 {
+			item.RateName = "Export credit"
+			item.RateAmount = t.ExportCreditPerKWh
+		}
+		item.Cost = round2(kwh * item.RateAmount)
+		cost.Items = append(cost.Items, item)
+	}
+	for month := from; month.Before(to); month = month.AddDate(0, 1, 0) {
+		cost.Items = append(cost.Items, client.CalculatedCostItem{
+			RateName:     "Customer charge",
+			ChargeType:   "FIXED_PRICE",
+			From:         month.Format(time.DateOnly),
+			To:           month.AddDate(0, 1, 0).Format(time.DateOnly),
+			RateAmount:   t.FixedChargePerMonth,
+			ItemQuantity: 1,
+			Cost:         t.FixedChargePerMonth,
+		})
+	}
+	for _, item := range cost.Items {
+		cost.TotalCost += item.Cost
+	}
+	cost.TotalCost = round2(cost.TotalCost)
+	genabilityResults(w, 1, []client.CalculatedCost{cost})
+}
+
+func (s *Server) account(id string) (client.Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[id]
+	if !ok {
+		return client.Account{}, false
+	}
+	return *account, true
+}
+
+// tariffs lists the fixture tariffs of a utility and customer class; zero
+// and empty match any.
+func (s *Server) tariffs(lseID uint, class string) []client.Tariff {
+	var out []client.Tariff
+	for _, t := range s.fixtures.Genability.Tariffs {
+		if lseID != 0 && t.LseID != lseID {
+			continue
+		}
+		if class != "" && !strings.EqualFold(t.CustomerClass, class) {
+			continue
+		}
+		out = append(out, t.Tariff)
+	}
+	return out
+}
+
+func (s *Server) tariff(masterID uint) (Tariff, bool) {
+	for _, t := range s.fixtures.Genability.Tariffs {
+		if t.MasterID == masterID {
+			return t, true
+		}
+	}
+	return Tariff{}, false
+}
+
+func parseGenabilityDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,320 @@
+package mocks
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/go-chi/chi/v5"
+)
+
+// mockSessionToken is the token every LightFusion login gets.
+const mockSessionToken = "mock-lightfusion-session"
+
+// project is a LightFusion 3D project and the polls its build has had.
+type project struct {
+	ID        int
+	HouseID   int
+	Request   client.Create3DProjectRequest
+	Panel     client.Panel
+	Inverter  client.Inverter
+	KwhUsage  float64
+	Panels    int
+	SizeKW    float64
+	Annual    float64
+	Polls     int
+	CreatedAt time.Time
+}
+
+func (s *Server) lightFusionRoutes(r chi.Router) {
+	r.Post("/v1/users/sessions", s.lightFusionLogin)
+	r.Get("/mesh/leads/{id}/mesh/{file}", s.lightFusionMesh)
+	r.Group(func(r chi.Router) {
+		r.Use(requireBearer)
+		r.Post("/v1/lead/create", s.lightFusionCreateProject)
+		r.Post("/v1/leads/{id}/complete", s.lightFusionCompleteLead)
+		r.Post("/v3/adders.ListProjectAdders", s.lightFusionProjectAdders)
+		r.Post("/v3/adders.GetPriceBreakdown", s.lightFusionPriceBreakdown)
+		r.Post("/v3/hardware.ListPanels", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]any{"panels": nonNil(s.fixtures.LightFusion.Panels)})
+		})
+		r.Post("/v3/hardware.ListInverters", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]any{"inverters": nonNil(s.fixtures.LightFusion.Inverters)})
+		})
+		r.Post("/v3/hardware.ListBatteries", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]any{"batteries": nonNil(s.fixtures.LightFusion.Batteries)})
+		})
+	})
+}
+
+func requireBearer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) lightFusionLogin(w http.ResponseWriter, r *http.Request) {
+	var req client.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Contact == "" || req.Password == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+		return
+	}
+	writeJSON(w, http.StatusOK, client.LoginResponse{
+		Token:   mockSessionToken,
+		User:    map[string]any{"id": 1, "email": req.Contact},
+		Contact: map[string]any{"contact": req.Contact},
+	})
+}
+
+// lightFusionCreateProject starts a build sized to the request's
+// consumption and target offset.
+func (s *Server) lightFusionCreateProject(w http.ResponseWriter, r *http.Request) {
+	var req client.Create3DProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	f := s.fixtures.LightFusion
+
+	p := &project{Request: req, CreatedAt: time.Now()}
+	p.Panel = pick(f.Panels, func(panel client.Panel) bool { return panel.ID == req.Hardware.PanelID })
+	p.Inverter = pick(f.Inverters, func(inv client.Inverter) bool { return inv.ID == req.Hardware.InverterID })
+	p.KwhUsage = annualKWh(req)
+	offset := float64(req.TargetSolarOffset)
+	if offset <= 0 {
+		offset = 100
+	}
+	if p.Panel.Power > 0 && f.KwhPerKw > 0 {
+		p.Panels = int(math.Ceil(p.KwhUsage * offset / 100 / f.KwhPerKw * 1000 / p.Panel.Power))
+	}
+	p.SizeKW = float64(p.Panels) * p.Panel.Power / 1000
+	p.Annual = math.Round(p.SizeKW * f.KwhPerKw)
+
+	s.mu.Lock()
+	p.ID, p.HouseID = s.id(), s.id()
+	s.projects[p.ID] = p
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, client.Create3DProjectResponse{
+		ID:               p.ID,
+		LeadID:           p.HouseID,
+		Status:           "processing",
+		AnnualProduction: p.Annual,
+		SystemSize:       p.SizeKW,
+		EstimatedCost:    math.Round(p.SizeKW * 1000 * f.PriceBreakdown.TotalPricePerWatt),
+		AnnualSavings:    math.Round(p.Annual * s.firstRate()),
+	})
+}
+
+// lightFusionProjectAdders answers the first call of a status check, and
+// counts it as a poll of the project's build.
+func (s *Server) lightFusionProjectAdders(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProjectID int `json:"project_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	p, ok := s.projects[req.ProjectID]
+	if ok {
+		p.Polls++
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+		return
+	}
+
+	inverter := p.Inverter
+	inverter.Quantity = 1
+	panel := p.Panel
+	writeJSON(w, http.StatusOK, client.Status3DProjectResponse{
+		Panel:    &panel,
+		Inverter: []client.Inverter{inverter},
+		Adders:   nonNil(s.fixtures.LightFusion.Adders),
+	})
+}
+
+func (s *Server) lightFusionPriceBreakdown(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.fixtures.LightFusion.PriceBreakdown)
+}
+
+// lightFusionCompleteLead reports a build's lead: processing with growing
+// progress until it has been polled BuildPolls times, then BuildState.
+func (s *Server) lightFusionCompleteLead(w http.ResponseWriter, r *http.Request) {
+	houseID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	s.mu.Lock()
+	var p *project
+	for _, candidate := range s.projects {
+		if candidate.HouseID == houseID {
+			c := *candidate
+			p = &c
+			break
+		}
+	}
+	s.mu.Unlock()
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "lead not found"})
+		return
+	}
+
+	f := s.fixtures.LightFusion
+	state, progress := BuildProcessing, 100
+	if p.Polls >= f.BuildPolls {
+		state = f.BuildState
+	} else {
+		progress = p.Polls * 100 / max(1, f.BuildPolls)
+	}
+
+	lead := client.LeadData{
+		ID:        p.HouseID,
+		CreatedAt: p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		State:     state,
+		Latitude:  p.Request.Latitude,
+		Longitude: p.Request.Longitude,
+		CompanyID: 1,
+		HouseID:   p.HouseID,
+		LeadID:    p.HouseID,
+		CreatorID: 1,
+		Build:     client.BuildInfo{ID: p.ID, State: state, Progress: progress},
+		Address: client.AddressInfo{
+			ID:         p.HouseID,
+			Street:     p.Request.Address.Street,
+			City:       p.Request.Address.City,
+			State:      p.Request.Address.State,
+			PostalCode: p.Request.Address.PostalCode,
+			Country:    p.Request.Address.Country,
+		},
+		House: client.HouseInfo{
+			ID:            p.HouseID,
+			KwhUsage:      int(math.Round(p.KwhUsage)),
+			InverterCount: 1,
+			Panel: client.PanelInfo{
+				ID: p.Panel.ID, Name: p.Panel.DisplayName, Manufacturer: p.Panel.Manufacturer,
+				MaxPowerCurrent: p.Panel.Imp, MaxPowerVoltage: p.Panel.Vmp, Power: int(p.Panel.Power),
+			},
+			Inverter:   client.InverterInfo{ID: p.Inverter.ID, Name: p.Inverter.Name, Manufacturer: p.Inverter.Manufacturer},
+			PanelCount: p.Panels,
+			SystemSize: int(math.Round(p.SizeKW)),
+		},
+		Production: client.ProductionInfo{ID: p.ID, Annual: p.Annual, KwhPerKw: f.KwhPerKw},
+		Company:    client.CompanyInfo{ID: 1, Name: "Mock Solar Co", Slug: "mock-solar"},
+	}
+	if p.Request.Hardware.StorageQuantity != nil {
+		lead.House.BatteryCount = *p.Request.Hardware.StorageQuantity
+	}
+	if len(s.fixtures.Genability.Tariffs) > 0 {
+		t := s.fixtures.Genability.Tariffs[0]
+		lead.CurrentProvider = client.ProviderInfo{
+			ID:        1,
+			UtilityID: int(t.LseID),
+			Utility:   client.UtilityInfo{ID: int(t.LseID), Name: t.LseName},
+			TariffID:  int(t.MasterID),
+			Tariff:    client.TariffInfo{ID: int(t.MasterID), Name: t.Name},
+		}
+		lead.FutureProvider = lead.CurrentProvider
+	}
+	writeJSON(w, http.StatusOK, client.LeadCompletionResponse{Lead: lead})
+}
+
+// meshFiles are the files of a project's mesh.
+var meshFiles = map[string]string{
+	"scene.jpg": "image/jpeg",
+	"scene.obj": "text/plain",
+	"scene.ply": "text/plain",
+	"scene.mtl": "text/plain",
+}
+
+// lightFusionMesh serves a mesh file from MeshDir, or a placeholder: a
+// single textured roof plane.
+func (s *Server) lightFusionMesh(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "file")
+	contentType, ok := meshFiles[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if dir := s.fixtures.LightFusion.MeshDir; dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+		return
+	}
+
+	var data []byte
+	switch name {
+	case "scene.obj":
+		data = []byte("mtllib scene.mtl\nv 0 0 0\nv 10 0 0\nv 10 6 3\nv 0 6 3\nvt 0 0\nvt 1 0\nvt 1 1\nvt 0 1\nusemtl roof\nf 1/1 2/2 3/3 4/4\n")
+	case "scene.mtl":
+		data = []byte("newmtl roof\nKd 1 1 1\nmap_Kd scene.jpg\n")
+	case "scene.ply":
+		data = []byte("ply\nformat ascii 1.0\nelement vertex 4\nproperty float x\nproperty float y\nproperty float z\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n0 0 0\n10 0 0\n10 6 3\n0 6 3\n4 0 1 2 3\n")
+	case "scene.jpg":
+		img := image.NewGray(image.Rect(0, 0, 8, 8))
+		for i := range img.Pix {
+			img.Pix[i] = 96
+		}
+		img.SetGray(0, 0, color.Gray{Y: 160})
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, img, nil)
+		data = buf.Bytes()
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+// annualKWh reads a project's yearly consumption: twelve months, a month
+// to scale up, or a year, in kWh or in dollars at the first tariff's rate.
+func annualKWh(req client.Create3DProjectRequest) float64 {
+	total := 0.0
+	for _, v := range req.Consumption {
+		total += float64(v)
+	}
+	if strings.EqualFold(req.Period, "month") && len(req.Consumption) == 1 {
+		total *= 12
+	}
+	if strings.EqualFold(req.Unit, "dollars") || strings.EqualFold(req.Unit, "usd") {
+		total /= 0.30
+	}
+	return total
+}
+
+func (s *Server) firstRate() float64 {
+	if len(s.fixtures.Genability.Tariffs) == 0 {
+		return 0
+	}
+	return s.fixtures.Genability.Tariffs[0].RatePerKWh
+}
+
+// pick returns the first item matching, else the first item.
+func pick[T any](items []T, match func(T) bool) T {
+	var zero T
+	for _, item := range items {
+		if match(item) {
+			return item
+		}
+	}
+	if len(items) > 0 {
+		return items[0]
+	}
+	return zero
+}
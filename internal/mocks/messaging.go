@@ -0,0 +1,85 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sendSMS records a Twilio message and answers as Twilio does when it
+// queues one.
+func (s *Server) sendSMS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"code": 21602, "message": "Invalid form body", "status": 400})
+		return
+	}
+	to, from, body := r.PostForm.Get("To"), r.PostForm.Get("From"), r.PostForm.Get("Body")
+	if to == "" || body == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"code": 21604, "message": "A 'To' phone number and 'Body' are required", "status": 400})
+		return
+	}
+
+	s.mu.Lock()
+	msg := SMS{SID: fmt.Sprintf("SM%032d", s.id()), To: to, From: from, Body: body, SentAt: time.Now()}
+	s.sms = append(s.sms, msg)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"sid":          msg.SID,
+		"account_sid":  chi.URLParam(r, "sid"),
+		"to":           msg.To,
+		"from":         msg.From,
+		"body":         msg.Body,
+		"status":       "queued",
+		"num_segments": "1",
+		"direction":    "outbound-api",
+		"date_created": msg.SentAt.Format(time.RFC1123Z),
+	})
+}
+
+// sendMail records a SendGrid v3 mail send. SendGrid accepts mail with an
+// empty 202.
+func (s *Server) sendMail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+		From struct {
+			Email string `json:"email"`
+		} `json:"from"`
+		Subject string `json:"subject"`
+		Content []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Personalizations) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"errors": []map[string]string{{"message": "Bad Request", "field": "personalizations"}}})
+		return
+	}
+
+	mail := Email{From: req.From.Email, Subject: req.Subject, SentAt: time.Now()}
+	for _, p := range req.Personalizations {
+		for _, to := range p.To {
+			mail.To = append(mail.To, to.Email)
+		}
+	}
+	for _, c := range req.Content {
+		switch c.Type {
+		case "text/plain":
+			mail.Text = c.Value
+		case "text/html":
+			mail.HTML = c.Value
+		}
+	}
+
+	s.mu.Lock()
+	s.emails = append(s.emails, mail)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,165 @@
+// Package mocks fakes the third-party APIs the app calls — LightFusion,
+// Genability, Twilio and SendGrid — so the lead, 3D design and proposal
+// flow runs without live credentials. Each API is served under its own
+// path prefix; point the app at them with:
+//
+//	LIGHTFUSION_API=http://localhost:8090/lightfusion
+//	LIGHTFUSION_MESH_URL=http://localhost:8090/lightfusion/mesh
+//	GENABILITY_URL=http://localhost:8090/genability/rest
+//	TWILIO_API_URL=http://localhost:8090/twilio
+//	SENDGRID_API_URL=http://localhost:8090/sendgrid
+//
+// Text messages and mail sent are recorded and listed under /_mocks, so
+// tests can read the codes and links sent to users.
+package mocks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/go-chi/chi/v5"
+)
+
+// SMS is a text message sent through Twilio.
+type SMS struct {
+	SID    string    `json:"sid"`
+	To     string    `json:"to"`
+	From   string    `json:"from"`
+	Body   string    `json:"body"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Email is a message sent through SendGrid.
+type Email struct {
+	To      []string  `json:"to"`
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	HTML    string    `json:"html"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Server is the mock of all four APIs. It is safe for concurrent use.
+type Server struct {
+	fixtures *Fixtures
+	router   chi.Router
+
+	mu       sync.Mutex
+	projects map[int]*project
+	accounts map[string]*client.Account
+	sms      []SMS
+	emails   []Email
+	nextID   int
+}
+
+// NewServer returns a server answering with fixtures, or DefaultFixtures
+// when nil.
+func NewServer(fixtures *Fixtures) *Server {
+	if fixtures == nil {
+		fixtures = DefaultFixtures()
+	}
+	s := &Server{fixtures: fixtures}
+	s.Reset()
+
+	r := chi.NewRouter()
+	r.Route("/lightfusion", s.lightFusionRoutes)
+	r.Route("/genability/rest", s.genabilityRoutes)
+	r.Post("/twilio/2010-04-01/Accounts/{sid}/Messages.json", s.sendSMS)
+	r.Post("/sendgrid/v3/mail/send", s.sendMail)
+	r.Get("/_mocks/sms", s.listSMS)
+	r.Get("/_mocks/emails", s.listEmails)
+	r.Delete("/_mocks", s.reset)
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.router = r
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Reset forgets projects, accounts and messages.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects = map[int]*project{}
+	s.accounts = map[string]*client.Account{}
+	s.sms = nil
+	s.emails = nil
+	s.nextID = 1000
+}
+
+// SMS returns the text messages sent to a number, or all of them when to
+// is empty, oldest first.
+func (s *Server) SMS(to string) []SMS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []SMS
+	for _, m := range s.sms {
+		if to == "" || m.To == to {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Emails returns the mail sent to an address, or all of it when to is
+// empty, oldest first.
+func (s *Server) Emails(to string) []Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Email
+	for _, m := range s.emails {
+		if to == "" || containsFold(m.To, to) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// id returns a new identifier. s.mu must be held.
+func (s *Server) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) listSMS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, nonNil(s.SMS(r.URL.Query().Get("to"))))
+}
+
+func (s *Server) listEmails(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, nonNil(s.Emails(r.URL.Query().Get("to"))))
+}
+
+func (s *Server) reset(w http.ResponseWriter, r *http.Request) {
+	s.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
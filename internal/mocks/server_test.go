@@ -0,0 +1,228 @@
+package mocks
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+)
+
+// serve starts the mock server with the default fixtures for one test.
+func serve(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s := NewServer(DefaultFixtures())
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func TestLightFusionClient(t *testing.T) {
+	_, srv := serve(t)
+	ctx := context.Background()
+	// GetProjectFiles downloads into ./media.
+	t.Chdir(t.TempDir())
+
+	lf := client.NewLightFusionClient(srv.URL+"/lightfusion", "")
+	lf.SetMeshURL(srv.URL + "/lightfusion/mesh")
+
+	token, err := lf.Login(ctx, "rep@example.com", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if token != mockSessionToken {
+		t.Errorf("token = %q, want %q", token, mockSessionToken)
+	}
+
+	panels, err := lf.ListPanels(ctx)
+	if err != nil {
+		t.Fatalf("ListPanels: %v", err)
+	}
+	if len(panels) != 1 || panels[0].ID != 1 {
+		t.Fatalf("panels = %+v, want the fixture panel", panels)
+	}
+
+	project, err := lf.Create3DProject(ctx, client.Create3DProjectRequest{
+		Latitude:  37.77,
+		Longitude: -122.42,
+		Address: client.AddressDetails{
+			Street: "1 Main St", City: "San Francisco", State: "CA", PostalCode: "94105", Country: "US",
+		},
+		Hardware:          client.HardwareDetails{PanelID: panels[0].ID, InverterID: 1},
+		Consumption:       []int{12000},
+		Period:            "year",
+		Unit:              "kwh",
+		TargetSolarOffset: 100,
+	})
+	if err != nil {
+		t.Fatalf("Create3DProject: %v", err)
+	}
+	// 12,000 kWh at 1,400 kWh per kW takes 22 of the 400 W panels.
+	if project.SystemSize != 8.8 || project.AnnualProduction != 12320 {
+		t.Errorf("project sized %v kW making %v kWh, want 8.8 kW making 12320 kWh", project.SystemSize, project.AnnualProduction)
+	}
+
+	// The default build finishes on its second poll.
+	for poll, want := range []int{BuildProcessing, BuildDone} {
+		status, err := lf.GetProjectStatus(ctx, project.ID, project.LeadID)
+		if err != nil {
+			t.Fatalf("GetProjectStatus poll %d: %v", poll+1, err)
+		}
+		if status.Panel == nil || status.PriceBreakdown == nil || status.LeadCompletion == nil {
+			t.Fatalf("poll %d: status is missing its panel, price breakdown or lead: %+v", poll+1, status)
+		}
+		if got := status.LeadCompletion.Lead.State; got != want {
+			t.Errorf("poll %d: lead state = %d, want %d", poll+1, got, want)
+		}
+		if got := status.LeadCompletion.Lead.House.PanelCount; got != 22 {
+			t.Errorf("poll %d: panel count = %d, want 22", poll+1, got)
+		}
+	}
+
+	files, err := lf.GetProjectFiles(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("GetProjectFiles: %v", err)
+	}
+	if len(files.Errors) > 0 {
+		t.Errorf("GetProjectFiles errors: %v", files.Errors)
+	}
+	for _, path := range []string{files.JPGPath, files.OBJPath, files.PLYPath, files.MTLPath} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("mesh file %q was not downloaded: %v", path, err)
+		}
+	}
+}
+
+func TestGenabilityClient(t *testing.T) {
+	_, srv := serve(t)
+	ctx := context.Background()
+	t.Setenv("GENABILITY_ID", "mock-id")
+	t.Setenv("GENABILITY_KEY", "mock-key")
+	t.Setenv("GENABILITY_URL", srv.URL+"/genability/rest")
+
+	agent, err := client.NewAgent()
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	accounts := client.NewAccounts(agent)
+	tariffs := client.NewTariffs(agent)
+
+	account, err := accounts.Create(ctx, client.Account{Name: "Test homeowner"})
+	if err != nil {
+		t.Fatalf("Create account: %v", err)
+	}
+	if account.ID == "" || account.Status != "ACTIVE" {
+		t.Fatalf("account = %+v, want an active account with an ID", account)
+	}
+
+	current, err := tariffs.GetCurrent(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("GetCurrent: %v", err)
+	}
+	if current.MasterID != 522 {
+		t.Errorf("current tariff = %d, want the residential 522", current.MasterID)
+	}
+
+	// Changing the customer class changes the account's tariff, so the
+	// cached one must not be served.
+	prop, _ := client.NewCustomerClassProp(client.CustomerClassGeneral)
+	account, err = accounts.SetProperty(ctx, account.ID, prop)
+	if err != nil {
+		t.Fatalf("SetProperty: %v", err)
+	}
+	if got := account.Properties[client.AccountPropertyCustomerClass].Value; got != "2" {
+		t.Errorf("customer class property = %q, want %q", got, "2")
+	}
+	current, err = tariffs.GetCurrent(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("GetCurrent after SetProperty: %v", err)
+	}
+	if current.MasterID != 523 {
+		t.Errorf("current tariff after SetProperty = %d, want the general 523", current.MasterID)
+	}
+
+	found, err := tariffs.Search(ctx, client.TariffSearch{ZipCode: "94105", Country: "US", LseID: 734})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(found) != 1 || found[0].MasterID != 522 {
+		t.Errorf("Search = %+v, want the residential tariff", found)
+	}
+
+	lses, err := client.NewLSEs(agent).Index(ctx, "94105", "US")
+	if err != nil {
+		t.Fatalf("LSEs: %v", err)
+	}
+	if len(lses) != 1 || lses[0].ID != 734 {
+		t.Errorf("LSEs = %+v, want the fixture utility", lses)
+	}
+
+	// 500 kWh at $0.30 plus the $10 customer charge.
+	cost, err := client.NewCalculator(agent).Calculate(ctx, client.CalculateRequest{
+		From:           "2025-01-01",
+		To:             "2025-02-01",
+		MasterTariffID: 522,
+		PropertyInputs: []client.PropertyInput{client.NewConsumptionInput("2025-01-01", "2025-02-01", 500)},
+	})
+	if err != nil {
+		t.Fatalf("Calculate: %v", err)
+	}
+	if cost.TotalCost != 160 {
+		t.Errorf("total cost = %v, want 160", cost.TotalCost)
+	}
+}
+
+func TestTwilioClient(t *testing.T) {
+	s, srv := serve(t)
+	t.Setenv("TWILIO_SID", "ACmock")
+	t.Setenv("TWILIO_AUTH", "mocktoken")
+	t.Setenv("TWILIO_FROM", "+15550000000")
+	t.Setenv("TWILIO_API_URL", srv.URL+"/twilio")
+
+	tw := client.InitializeTwilio()
+	const to = "+15551234567"
+	if err := tw.SendSMS(to, "Your proposal is ready"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if err := tw.SendOTP(to); err != nil {
+		t.Fatalf("SendOTP: %v", err)
+	}
+
+	sent := s.SMS(to)
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages to %s, want 2", len(sent), to)
+	}
+	if sent[0].Body != "Your proposal is ready" || sent[0].From != "+15550000000" {
+		t.Errorf("first message = %+v", sent[0])
+	}
+	code := strings.TrimPrefix(sent[1].Body, "Your verification code is ")
+	if err := tw.VerifyOTP(to, code); err != nil {
+		t.Errorf("VerifyOTP with the code sent: %v", err)
+	}
+}
+
+func TestSendGridClient(t *testing.T) {
+	s, srv := serve(t)
+	t.Setenv("SENDGRID_API_KEY", "mock-key")
+	t.Setenv("SENDGRID_FROM_EMAIL", "team@example.com")
+	t.Setenv("SENDGRID_API_URL", srv.URL+"/sendgrid")
+
+	sg := client.InitializeSendGrid()
+	const to = "homeowner@example.com"
+	if err := sg.SendEmail(to, "Homeowner", "Your proposal", "Hello,\nIt is ready."); err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	sent := s.Emails(to)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d emails to %s, want 1", len(sent), to)
+	}
+	if sent[0].Subject != "Your proposal" || sent[0].From != "team@example.com" || sent[0].Text != "Hello,\nIt is ready." {
+		t.Errorf("email = %+v", sent[0])
+	}
+	if sent[0].HTML != "Hello,<br>It is ready." {
+		t.Errorf("email HTML = %q", sent[0].HTML)
+	}
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Adder cost types. Fixed adders are priced per unit, the others scale with
+// the size of the system.
+const (
+	AdderCostTypeFixed    = "fixed"
+	AdderCostTypePerWatt  = "per_watt"
+	AdderCostTypePerKW    = "per_kw"
+	AdderCostTypePerPanel = "per_panel"
+)
+
+type AdderCategory struct {
+	ID          int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at"`
+	CompanyID   int       `json:"company_id" gorm:"column:company_id;not null;index" example:"1"`
+	Name        string    `json:"name" gorm:"column:name;not null" example:"Roofing"`
+	Description string    `json:"description" gorm:"column:description" example:"Roof work required before install"`
+	Position    int       `json:"position" gorm:"column:position;default:0" example:"0"`
+}
+
+func (AdderCategory) TableName() string {
+	return "adder_categories"
+}
+
+func (c *AdderCategory) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if len(c.Name) == 0 || len(c.Name) > 250 {
+		return ErrInvalidAdderCategoryName
+	}
+	return nil
+}
+
+// Adder is a company-owned price adjustment such as a main panel upgrade or
+// a steep roof surcharge. Every change bumps Version and is recorded in
+// adder_versions so deals keep the price they were sold with.
+type Adder struct {
+	ID            int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+	CompanyID     int       `json:"company_id" gorm:"column:company_id;not null;index" example:"1"`
+	CategoryID    *int      `json:"category_id" gorm:"column:category_id" example:"1"`
+	Name          string    `json:"name" gorm:"column:name;not null" example:"Main panel upgrade"`
+	Description   string    `json:"description" gorm:"column:description" example:"Upgrade to 200A service"`
+	Cost          float64   `json:"cost" gorm:"column:cost;not null" example:"2500.00"`
+	CostType      string    `json:"cost_type" gorm:"column:cost_type;not null;default:'fixed'" example:"fixed"`
+	States        []string  `json:"states" gorm:"column:states;type:text;serializer:json" example:"CA,NV"`
+	Active        bool      `json:"active" gorm:"column:active" example:"true"`
+	IsAutomatic   bool      `json:"is_automatic" gorm:"column:is_automatic;default:false" example:"false"`
+	MinSystemSize float64   `json:"min_system_size" gorm:"column:min_system_size;default:0" example:"0"`
+	MaxSystemSize float64   `json:"max_system_size" gorm:"column:max_system_size;default:0" example:"0"`
+	Version       int       `json:"version" gorm:"column:version;not null;default:1" example:"1"`
+}
+
+func (Adder) TableName() string {
+	return "adders"
+}
+
+func (a *Adder) Validate() error {
+	a.Name = strings.TrimSpace(a.Name)
+	if len(a.Name) == 0 || len(a.Name) > 250 {
+		return ErrInvalidAdderName
+	}
+	if a.Cost < 0 {
+		return ErrInvalidAdderCost
+	}
+	a.CostType = strings.ToLower(strings.TrimSpace(a.CostType))
+	if a.CostType == "" {
+		a.CostType = AdderCostTypeFixed
+	}
+	switch a.CostType {
+	case AdderCostTypeFixed, AdderCostTypePerWatt, AdderCostTypePerKW, AdderCostTypePerPanel:
+	default:
+		return ErrInvalidAdderCostType
+	}
+	if a.MinSystemSize < 0 || a.MaxSystemSize < 0 ||
+		(a.MaxSystemSize > 0 && a.MinSystemSize > a.MaxSystemSize) {
+		return ErrInvalidAdderSizeRange
+	}
+	for i, s := range a.States {
+		a.States[i] = strings.ToUpper(strings.TrimSpace(s))
+	}
+	return nil
+}
+
+// AvailableFor reports whether the adder can be offered for a system of the
+// given size in the given state. No states means every state and a zero size
+// bound means the bound is not set.
+func (a *Adder) AvailableFor(state string, systemSizeKW float64) bool {
+	if !a.Active {
+		return false
+	}
+	if len(a.States) > 0 {
+		found := false
+		for _, s := range a.States {
+			if strings.EqualFold(s, state) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if a.MinSystemSize > 0 && systemSizeKW < a.MinSystemSize {
+		return false
+	}
+	if a.MaxSystemSize > 0 && systemSizeKW > a.MaxSystemSize {
+		return false
+	}
+	return true
+}
+
+// Snapshot returns the immutable version record for the adder's current state.
+func (a *Adder) Snapshot() *AdderVersion {
+	return &AdderVersion{
+		AdderID:       a.ID,
+		Version:       a.Version,
+		Name:          a.Name,
+		Cost:          a.Cost,
+		CostType:      a.CostType,
+		States:        append([]string(nil), a.States...),
+		Active:        a.Active,
+		IsAutomatic:   a.IsAutomatic,
+		MinSystemSize: a.MinSystemSize,
+		MaxSystemSize: a.MaxSystemSize,
+	}
+}
+
+// AdderVersion is an immutable copy of an adder as it was at a given version.
+type AdderVersion struct {
+	ID            int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	AdderID       int       `json:"adder_id" gorm:"column:adder_id;not null;uniqueIndex:idx_adder_versions_adder_version" example:"1"`
+	Version       int       `json:"version" gorm:"column:version;not null;uniqueIndex:idx_adder_versions_adder_version" example:"1"`
+	Name          string    `json:"name" gorm:"column:name;not null" example:"Main panel upgrade"`
+	Cost          float64   `json:"cost" gorm:"column:cost;not null" example:"2500.00"`
+	CostType      string    `json:"cost_type" gorm:"column:cost_type;not null" example:"fixed"`
+	States        []string  `json:"states" gorm:"column:states;type:text;serializer:json" example:"CA,NV"`
+	Active        bool      `json:"active" gorm:"column:active" example:"true"`
+	IsAutomatic   bool      `json:"is_automatic" gorm:"column:is_automatic" example:"false"`
+	MinSystemSize float64   `json:"min_system_size" gorm:"column:min_system_size" example:"0"`
+	MaxSystemSize float64   `json:"max_system_size" gorm:"column:max_system_size" example:"0"`
+}
+
+func (AdderVersion) TableName() string {
+	return "adder_versions"
+}
+
+// DealAdder records an adder a deal was priced with, at the catalog version
+// and price in force at the time.
+type DealAdder struct {
+	ID           int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+	DealID       int       `json:"deal_id" gorm:"column:deal_id;not null;index" example:"1"`
+	AdderID      int       `json:"adder_id" gorm:"column:adder_id;not null" example:"1"`
+	AdderVersion int       `json:"adder_version" gorm:"column:adder_version;not null" example:"1"`
+	Name         string    `json:"name" gorm:"column:name;not null" example:"Main panel upgrade"`
+	Cost         float64   `json:"cost" gorm:"column:cost;not null" example:"2500.00"`
+	CostType     string    `json:"cost_type" gorm:"column:cost_type;not null" example:"fixed"`
+	Quantity     int       `json:"quantity" gorm:"column:quantity;not null;default:1" example:"1"`
+	CustomPrice  *float64  `json:"custom_price" gorm:"column:custom_price" example:"2000.00"`
+	IsAutomatic  bool      `json:"is_automatic" gorm:"column:is_automatic" example:"false"`
+	Amount       float64   `json:"amount" gorm:"column:amount;not null" example:"2500.00"`
+}
+
+func (DealAdder) TableName() string {
+	return "deal_adders"
+}
+
+// PriceFor returns the price of the adder line for a system of the given size.
+// A custom price set by the rep takes precedence over the catalog cost.
+func (a *DealAdder) PriceFor(systemSizeKW float64, panelCount int) float64 {
+	cost := a.Cost
+	if a.CustomPrice != nil && *a.CustomPrice >= 0 {
+		cost = *a.CustomPrice
+	}
+	quantity := float64(a.Quantity)
+	if quantity <= 0 {
+		quantity = 1
+	}
+
+	var amount float64
+	switch a.CostType {
+	case AdderCostTypePerWatt:
+		amount = cost * systemSizeKW * 1000
+	case AdderCostTypePerKW:
+		amount = cost * systemSizeKW
+	case AdderCostTypePerPanel:
+		amount = cost * float64(panelCount)
+	default:
+		amount = cost * quantity
+	}
+	return math.Round(amount*100) / 100
+}
@@ -4,6 +4,14 @@ import (
 "time"
 )
 
+// Deal statuses set by the API. A deal a homeowner accepted below the
+// company's minimum price or at a loss waits in DealStatusPricingReview
+// until the company reprices or approves it.
+const (
+	DealStatusPending       = "pending"
+	DealStatusPricingReview = "pricing_review"
+)
+
 type Deal struct {
 ID                   int        `json:"id" gorm:"primaryKey;column:id"`
 CreatedAt            time.Time  `json:"created_at" gorm:"column:created_at"`
@@ -21,6 +29,7 @@ HomeownerID          int        `json:"homeowner_id" gorm:"column:homeowner_id;n
 DocumentID           *int       `json:"document_id" gorm:"column:document_id" example:"1"`
 FinancingOptionID    *int       `json:"financing_option_id" gorm:"column:financing_option_id" example:"1"`
 CompanyID            int        `json:"company_id" gorm:"column:company_id;not null" example:"1"`
+ProposalID           *int       `json:"proposal_id" gorm:"column:proposal_id;uniqueIndex" example:"1"`
 
 // System Details
 SystemSize           float64    `json:"system_size" gorm:"column:system_size" example:"10.5"`
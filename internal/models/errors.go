@@ -16,15 +16,81 @@ ErrInvalidDealSalesCommission  = errors.New("sales commission cost must be betwe
 ErrInvalidDealProfit           = errors.New("profit must be between 0 and 10000000")
 ErrDealNotFound                = errors.New("deal not found")
 
+// Adder errors
+ErrInvalidAdderName          = errors.New("adder name must be between 1 and 250 characters")
+ErrInvalidAdderCost          = errors.New("adder cost must be greater than or equal to 0")
+ErrInvalidAdderCostType      = errors.New("adder cost type must be one of: fixed, per_watt, per_kw, per_panel")
+ErrInvalidAdderSizeRange     = errors.New("adder minimum system size must not exceed the maximum")
+ErrInvalidAdderCategoryName  = errors.New("adder category name must be between 1 and 250 characters")
+ErrAdderNotFound             = errors.New("adder not found")
+ErrAdderCategoryNotFound     = errors.New("adder category not found")
+ErrAdderNotAvailable         = errors.New("adder is not available for this system")
+
+// Hardware errors
+ErrInvalidHardwareName      = errors.New("hardware manufacturer and model are required")
+ErrInvalidHardwareCost      = errors.New("hardware cost must be greater than or equal to 0")
+ErrInvalidPanelPower        = errors.New("panel power must be greater than 0")
+ErrInvalidInverterCapacity  = errors.New("inverter capacity must be greater than 0")
+ErrInvalidBatteryCapacity   = errors.New("battery capacity must be greater than 0")
+ErrPanelNotFound            = errors.New("panel not found")
+ErrInverterNotFound         = errors.New("inverter not found")
+ErrBatteryNotFound          = errors.New("battery not found")
+ErrHardwareNotAllowed       = errors.New("hardware is not active or not allowed for this company")
+
 // Lead errors
 ErrInvalidLeadLatitude  = errors.New("latitude must be between -90 and 90")
 ErrInvalidLeadLongitude = errors.New("longitude must be between -180 and 180")
 ErrLeadNotFound         = errors.New("lead not found")
+ErrLeadUsageNotFound    = errors.New("lead has no uploaded usage")
 
 // Proposal errors
-ErrInvalidProposalCode = errors.New("proposal code is required")
-ErrInvalidProposalCost = errors.New("system cost must be greater than or equal to 0")
-ErrProposalNotFound    = errors.New("proposal not found")
+ErrInvalidProposalCode       = errors.New("proposal code is required")
+ErrInvalidProposalCost       = errors.New("system cost must be greater than or equal to 0")
+ErrProposalNotFound          = errors.New("proposal not found")
+ErrInvalidProposalTransition = errors.New("proposal cannot move to that status")
+ErrProposalExpired           = errors.New("proposal has expired")
+ErrInvalidReminderDays       = errors.New("reminder days must be between 1 and 90")
+ErrInvalidFollowUpHours      = errors.New("viewed follow-up hours must be greater than or equal to 0")
+ErrProposalOptionNotFound    = errors.New("proposal option not found")
+ErrProposalOptionLocked      = errors.New("proposal option can no longer be changed")
+ErrProposalOptionRequired    = errors.New("proposal has options; accept one of them")
+ErrInvalidOptionLabel        = errors.New("option label must be between 1 and 100 characters")
+ErrInvalidOptionSystemSize   = errors.New("option system size must be greater than 0")
+ErrInvalidOptionCount        = errors.New("option panel, inverter and battery counts must be greater than or equal to 0")
+
+// Signature errors
+ErrSignatureRequestNotFound  = errors.New("signature request not found")
+ErrSignatureRequestExpired   = errors.New("signature request has expired")
+ErrSignatureRequestCompleted = errors.New("signature request is already complete")
+ErrSignatureRequestIncomplete = errors.New("signature request has not been signed yet")
+ErrDealAlreadySigned         = errors.New("deal is already signed")
+ErrContractChanged           = errors.New("deal has changed since the contract was sent; request a new signature")
+ErrInvalidSignerName         = errors.New("signer name is required")
+ErrInvalidSignerContact      = errors.New("signer needs an email or phone number for the verification channel")
+ErrInvalidVerificationCode   = errors.New("verification code is invalid")
+ErrSignerNotVerified         = errors.New("signer identity has not been verified")
+ErrConsentRequired           = errors.New("consent to sign electronically is required")
+ErrInvalidSignature          = errors.New("a drawn signature PNG or a typed name is required")
+
+// Quote errors
+ErrQuoteNotFound          = errors.New("quote not found")
+ErrInvalidQuoteSystemSize = errors.New("system size must be greater than 0")
+ErrInvalidQuoteProduction = errors.New("annual production must be greater than 0")
+ErrInvalidQuoteBill       = errors.New("monthly electric bill must be greater than 0")
+ErrInvalidQuoteAssumption = errors.New("invalid quote assumption")
+
+// Financing errors
+ErrInvalidFinancingProviderName = errors.New("financing provider name must be between 1 and 250 characters")
+ErrInvalidFinancingOptionName   = errors.New("financing option name must be between 1 and 250 characters")
+ErrInvalidFinancingDateRange    = errors.New("financing option must start before it ends")
+ErrFinancingProviderNotFound    = errors.New("financing provider not found")
+ErrFinancingOptionNotFound      = errors.New("financing option not found")
+ErrFinancingOptionNotAvailable  = errors.New("financing option is not available for this company, state or date")
+
+// Incentive errors
+ErrInvalidIncentiveName      = errors.New("incentive name must be between 1 and 250 characters")
+ErrInvalidIncentiveDateRange = errors.New("incentive must start before it ends")
+ErrIncentiveNotFound         = errors.New("incentive not found")
 
 // Model3D errors
 ErrInvalidModel3DLeadID      = errors.New("3D model must be associated with a valid lead")
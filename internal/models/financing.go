@@ -0,0 +1,129 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
+	"gorm.io/gorm"
+)
+
+// FinancingProvider is a lender or third-party owner a company sells
+// through, e.g. a loan provider or a lease and PPA fund. Deleting one only
+// marks it deleted, as deals keep the options they were sold with.
+type FinancingProvider struct {
+	ID          int            `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+	CompanyID   int            `json:"company_id" gorm:"column:company_id;not null;index" example:"1"`
+	Name        string         `json:"name" gorm:"column:name;not null" example:"SunPower Financial"`
+	Description string         `json:"description" gorm:"column:description" example:"Residential solar loans"`
+	Active      bool           `json:"active" gorm:"column:active" example:"true"`
+}
+
+func (FinancingProvider) TableName() string {
+	return "financing_providers"
+}
+
+func (p *FinancingProvider) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if len(p.Name) == 0 || len(p.Name) > 250 {
+		return ErrInvalidFinancingProviderName
+	}
+	return nil
+}
+
+// FinancingOption is one product a provider offers through a company: a
+// loan at a rate, term and dealer fee, a lease or a PPA. The fields that
+// apply depend on Type, as for finance.ProductSpec. An option is offered
+// while it and its provider are active, between ActiveFrom and ActiveUntil
+// when set, and only in States when any are listed. Like providers, a
+// deleted option is only marked deleted.
+type FinancingOption struct {
+	ID              int            `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+	CompanyID       int            `json:"company_id" gorm:"column:company_id;not null;index" example:"1"`
+	ProviderID      int            `json:"provider_id" gorm:"column:provider_id;not null;index" example:"1"`
+	Name            string         `json:"name" gorm:"column:name;not null" example:"25-year 6.99%"`
+	Type            string         `json:"type" gorm:"column:type;not null" example:"loan"`
+	InterestRate    float64        `json:"interest_rate" gorm:"column:interest_rate;default:0" example:"0.0699"`
+	TermMonths      int            `json:"term_months" gorm:"column:term_months;default:0" example:"300"`
+	LoanFee         float64        `json:"loan_fee" gorm:"column:loan_fee;default:0" example:"0.25"`
+	LoanFeeFixed    float64        `json:"loan_fee_fixed" gorm:"column:loan_fee_fixed;default:0" example:"0"`
+	ITCPaydownMonth int            `json:"itc_paydown_month" gorm:"column:itc_paydown_month;default:0" example:"18"`
+	MonthlyPayment  float64        `json:"monthly_payment" gorm:"column:monthly_payment;default:0" example:"0"`
+	RatePerKWh      float64        `json:"rate_per_kwh" gorm:"column:rate_per_kwh;default:0" example:"0"`
+	Escalator       float64        `json:"escalator" gorm:"column:escalator;default:0" example:"0"`
+	States          []string       `json:"states" gorm:"column:states;type:text;serializer:json" example:"CA,NV"`
+	Active          bool           `json:"active" gorm:"column:active" example:"true"`
+	ActiveFrom      *time.Time     `json:"active_from" gorm:"column:active_from" example:"2025-01-01T00:00:00Z"`
+	ActiveUntil     *time.Time     `json:"active_until" gorm:"column:active_until" example:"2025-12-31T23:59:59Z"`
+
+	Provider *FinancingProvider `json:"provider,omitempty" gorm:"foreignKey:ProviderID"`
+}
+
+func (FinancingOption) TableName() string {
+	return "financing_options"
+}
+
+func (o *FinancingOption) Validate() error {
+	o.Name = strings.TrimSpace(o.Name)
+	if len(o.Name) == 0 || len(o.Name) > 250 {
+		return ErrInvalidFinancingOptionName
+	}
+	o.Type = strings.ToLower(strings.TrimSpace(o.Type))
+	if _, err := o.ProductSpec().Product(); err != nil {
+		return err
+	}
+	if o.ActiveFrom != nil && o.ActiveUntil != nil && !o.ActiveFrom.Before(*o.ActiveUntil) {
+		return ErrInvalidFinancingDateRange
+	}
+	for i, s := range o.States {
+		o.States[i] = strings.ToUpper(strings.TrimSpace(s))
+	}
+	return nil
+}
+
+// AvailableFor reports whether the option can be offered in the given state
+// at the given time. No states means every state; an option limited to some
+// states is not offered where the state is unknown.
+func (o *FinancingOption) AvailableFor(state string, at time.Time) bool {
+	if !o.Active || (o.Provider != nil && !o.Provider.Active) {
+		return false
+	}
+	if o.ActiveFrom != nil && at.Before(*o.ActiveFrom) {
+		return false
+	}
+	if o.ActiveUntil != nil && !at.Before(*o.ActiveUntil) {
+		return false
+	}
+	if len(o.States) > 0 {
+		for _, s := range o.States {
+			if strings.EqualFold(s, state) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ProductSpec returns the option as a financing product for quotes.
+func (o *FinancingOption) ProductSpec() finance.ProductSpec {
+	return finance.ProductSpec{
+		Type:            o.Type,
+		Name:            o.Name,
+		APR:             o.InterestRate,
+		TermMonths:      o.TermMonths,
+		LoanFee:         o.LoanFee,
+		LoanFeeFixed:    o.LoanFeeFixed,
+		ITCPaydownMonth: o.ITCPaydownMonth,
+		MonthlyPayment:  o.MonthlyPayment,
+		RatePerKWh:      o.RatePerKWh,
+		Escalator:       o.Escalator,
+		TermYears:       o.TermMonths / 12,
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// GenabilityCacheEntry is a cached Genability response, shared by every
+// replica and kept across restarts until it expires.
+type GenabilityCacheEntry struct {
+	Key       string    `json:"key" gorm:"primaryKey;column:key"`
+	Value     []byte    `json:"value" gorm:"column:value;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;not null;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (GenabilityCacheEntry) TableName() string {
+	return "genability_cache"
+}
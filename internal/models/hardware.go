@@ -0,0 +1,176 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Hardware types used by the company allowed lists.
+const (
+	HardwareTypePanel    = "panel"
+	HardwareTypeInverter = "inverter"
+	HardwareTypeBattery  = "battery"
+)
+
+// Inverter categories. Micro inverters are installed one per panel.
+const (
+	InverterCategoryString = "string"
+	InverterCategoryMicro  = "micro"
+	InverterCategoryHybrid = "hybrid"
+)
+
+// Panel is a solar module in the hardware catalog. ExternalID links it to the
+// LightFusion catalog it was imported from.
+type Panel struct {
+	ID           int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at"`
+	ExternalID   *int      `json:"external_id" gorm:"column:external_id;uniqueIndex" example:"42"`
+	Manufacturer string    `json:"manufacturer" gorm:"column:manufacturer;not null" example:"REC"`
+	Model        string    `json:"model" gorm:"column:model;not null" example:"REC400AA"`
+	DisplayName  string    `json:"display_name" gorm:"column:display_name" example:"REC Alpha 400W"`
+	Power        float64   `json:"power" gorm:"column:power;not null" example:"400"`
+	PricePerWatt float64   `json:"price_per_watt" gorm:"column:price_per_watt;not null;default:0" example:"0.45"`
+	IsDomestic   bool      `json:"is_domestic" gorm:"column:is_domestic;default:false" example:"false"`
+	Active       bool      `json:"active" gorm:"column:active" example:"true"`
+	IsDefault    bool      `json:"is_default" gorm:"column:is_default;default:false" example:"false"`
+
+	// Datasheet electrical specs at STC
+	OpenCircuitVoltage  float64 `json:"open_circuit_voltage" gorm:"column:open_circuit_voltage" example:"48.5"`
+	ShortCircuitCurrent float64 `json:"short_circuit_current" gorm:"column:short_circuit_current" example:"10.25"`
+	MaxPowerVoltage     float64 `json:"max_power_voltage" gorm:"column:max_power_voltage" example:"41.2"`
+	MaxPowerCurrent     float64 `json:"max_power_current" gorm:"column:max_power_current" example:"9.71"`
+	TempCoeffVoc        float64 `json:"temp_coeff_voc" gorm:"column:temp_coeff_voc" example:"-0.24"`
+	TempCoeffPmax       float64 `json:"temp_coeff_pmax" gorm:"column:temp_coeff_pmax" example:"-0.26"`
+	NOCT                float64 `json:"noct" gorm:"column:noct" example:"44"`
+	Efficiency          float64 `json:"efficiency" gorm:"column:efficiency" example:"21.6"`
+}
+
+func (Panel) TableName() string {
+	return "panels"
+}
+
+func (p *Panel) Validate() error {
+	p.Manufacturer = strings.TrimSpace(p.Manufacturer)
+	p.Model = strings.TrimSpace(p.Model)
+	if p.Manufacturer == "" || p.Model == "" {
+		return ErrInvalidHardwareName
+	}
+	if p.Power <= 0 {
+		return ErrInvalidPanelPower
+	}
+	if p.PricePerWatt < 0 {
+		return ErrInvalidHardwareCost
+	}
+	return nil
+}
+
+// Inverter is a string, micro or hybrid inverter in the hardware catalog.
+type Inverter struct {
+	ID           int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at"`
+	ExternalID   *int      `json:"external_id" gorm:"column:external_id;uniqueIndex" example:"7"`
+	Manufacturer string    `json:"manufacturer" gorm:"column:manufacturer;not null" example:"SolarEdge"`
+	Model        string    `json:"model" gorm:"column:model;not null" example:"SE7600H-US"`
+	Name         string    `json:"name" gorm:"column:name" example:"SolarEdge 7.6kW"`
+	Category     string    `json:"category" gorm:"column:category;not null;default:'string'" example:"string"`
+	CostType     string    `json:"cost_type" gorm:"column:cost_type;not null;default:'fixed'" example:"fixed"`
+	Cost         float64   `json:"cost" gorm:"column:cost;not null;default:0" example:"1800.00"`
+	Capacity     float64   `json:"capacity" gorm:"column:capacity;not null" example:"7600"`
+	IsDomestic   bool      `json:"is_domestic" gorm:"column:is_domestic;default:false" example:"false"`
+	Active       bool      `json:"active" gorm:"column:active" example:"true"`
+	IsDefault    bool      `json:"is_default" gorm:"column:is_default;default:false" example:"false"`
+
+	// Datasheet electrical specs
+	MaxDCVoltage           float64 `json:"max_dc_voltage" gorm:"column:max_dc_voltage" example:"480"`
+	MPPTMinVoltage         float64 `json:"mppt_min_voltage" gorm:"column:mppt_min_voltage" example:"380"`
+	MPPTMaxVoltage         float64 `json:"mppt_max_voltage" gorm:"column:mppt_max_voltage" example:"480"`
+	MPPTCount              int     `json:"mppt_count" gorm:"column:mppt_count;default:1" example:"1"`
+	MaxInputCurrentPerMPPT float64 `json:"max_input_current_per_mppt" gorm:"column:max_input_current_per_mppt" example:"20"`
+	MaxDCPower             float64 `json:"max_dc_power" gorm:"column:max_dc_power" example:"11800"`
+	Efficiency             float64 `json:"efficiency" gorm:"column:efficiency" example:"99"`
+}
+
+func (Inverter) TableName() string {
+	return "inverters"
+}
+
+func (i *Inverter) Validate() error {
+	i.Manufacturer = strings.TrimSpace(i.Manufacturer)
+	i.Model = strings.TrimSpace(i.Model)
+	if i.Manufacturer == "" || i.Model == "" {
+		return ErrInvalidHardwareName
+	}
+	if i.Capacity <= 0 {
+		return ErrInvalidInverterCapacity
+	}
+	if i.Cost < 0 {
+		return ErrInvalidHardwareCost
+	}
+	i.Category = strings.ToLower(strings.TrimSpace(i.Category))
+	if i.Category == "" {
+		i.Category = InverterCategoryString
+	}
+	return nil
+}
+
+// IsMicro reports whether the inverter is installed one per panel.
+func (i *Inverter) IsMicro() bool {
+	return i.Category == InverterCategoryMicro
+}
+
+// Battery is a home storage battery in the hardware catalog.
+type Battery struct {
+	ID                  int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt           time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt           time.Time `json:"updated_at" gorm:"column:updated_at"`
+	ExternalID          *int      `json:"external_id" gorm:"column:external_id;uniqueIndex" example:"3"`
+	Manufacturer        string    `json:"manufacturer" gorm:"column:manufacturer;not null" example:"Tesla"`
+	Model               string    `json:"model" gorm:"column:model;not null" example:"Powerwall 3"`
+	Name                string    `json:"name" gorm:"column:name" example:"Tesla Powerwall 3"`
+	CapacityKWh         float64   `json:"capacity_kwh" gorm:"column:capacity_kwh;not null" example:"13.5"`
+	UsableCapacityKWh   float64   `json:"usable_capacity_kwh" gorm:"column:usable_capacity_kwh" example:"13.5"`
+	PowerKW             float64   `json:"power_kw" gorm:"column:power_kw" example:"11.5"`
+	RoundTripEfficiency float64   `json:"round_trip_efficiency" gorm:"column:round_trip_efficiency" example:"89"`
+	Cost                float64   `json:"cost" gorm:"column:cost;not null;default:0" example:"11000.00"`
+	IsDomestic          bool      `json:"is_domestic" gorm:"column:is_domestic;default:false" example:"false"`
+	Active              bool      `json:"active" gorm:"column:active" example:"true"`
+	IsDefault           bool      `json:"is_default" gorm:"column:is_default;default:false" example:"false"`
+}
+
+func (Battery) TableName() string {
+	return "batteries"
+}
+
+func (b *Battery) Validate() error {
+	b.Manufacturer = strings.TrimSpace(b.Manufacturer)
+	b.Model = strings.TrimSpace(b.Model)
+	if b.Manufacturer == "" || b.Model == "" {
+		return ErrInvalidHardwareName
+	}
+	if b.CapacityKWh <= 0 {
+		return ErrInvalidBatteryCapacity
+	}
+	if b.Cost < 0 {
+		return ErrInvalidHardwareCost
+	}
+	if b.UsableCapacityKWh <= 0 || b.UsableCapacityKWh > b.CapacityKWh {
+		b.UsableCapacityKWh = b.CapacityKWh
+	}
+	return nil
+}
+
+// CompanyHardware puts a catalog item on a company's allowed list.
+type CompanyHardware struct {
+	ID           int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+	CompanyID    int       `json:"company_id" gorm:"column:company_id;not null;uniqueIndex:idx_company_hardware_item" example:"1"`
+	HardwareType string    `json:"hardware_type" gorm:"column:hardware_type;not null;uniqueIndex:idx_company_hardware_item" example:"panel"`
+	HardwareID   int       `json:"hardware_id" gorm:"column:hardware_id;not null;uniqueIndex:idx_company_hardware_item" example:"1"`
+	IsDefault    bool      `json:"is_default" gorm:"column:is_default;default:false" example:"false"`
+}
+
+func (CompanyHardware) TableName() string {
+	return "company_hardware"
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+)
+
+// Incentive is a federal, state or utility incentive in the shared catalog
+// quotes draw on. The fields that apply depend on Type, as for
+// incentive.Program. An incentive is offered while it is active and
+// between ActiveFrom and ActiveUntil when set; who can claim it is limited
+// by state, utility, system size and ownership.
+type Incentive struct {
+	ID          int              `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" gorm:"column:updated_at"`
+	Name        string           `json:"name" gorm:"column:name;not null" example:"NY-Sun Megawatt Block"`
+	Type        string           `json:"type" gorm:"column:type;not null;index" example:"rebate"`
+	Description string           `json:"description" gorm:"column:description" example:"Upstate residential block 10"`
+	Rate        float64          `json:"rate" gorm:"column:rate;default:0" example:"0"`
+	Schedule    []incentive.Step `json:"schedule" gorm:"column:schedule;type:text;serializer:json"`
+	Amount      float64          `json:"amount" gorm:"column:amount;default:0" example:"0"`
+	PerKW       float64          `json:"per_kw" gorm:"column:per_kw;default:0" example:"200"`
+	PerKWh      float64          `json:"per_kwh" gorm:"column:per_kwh;default:0" example:"0"`
+	MaxAmount   float64          `json:"max_amount" gorm:"column:max_amount;default:0" example:"5000"`
+	Years       int              `json:"years" gorm:"column:years;default:0" example:"0"`
+	States      []string         `json:"states" gorm:"column:states;type:text;serializer:json" example:"NY"`
+	UtilityIDs  []int            `json:"utility_ids" gorm:"column:utility_ids;type:text;serializer:json"`
+	MinSystemKW float64          `json:"min_system_kw" gorm:"column:min_system_kw;default:0" example:"0"`
+	MaxSystemKW float64          `json:"max_system_kw" gorm:"column:max_system_kw;default:0" example:"25"`
+	Ownership   []string         `json:"ownership" gorm:"column:ownership;type:text;serializer:json" example:"owned"`
+	Active      bool             `json:"active" gorm:"column:active" example:"true"`
+	ActiveFrom  *time.Time       `json:"active_from" gorm:"column:active_from" example:"2025-01-01T00:00:00Z"`
+	ActiveUntil *time.Time       `json:"active_until" gorm:"column:active_until" example:"2025-12-31T23:59:59Z"`
+}
+
+func (Incentive) TableName() string {
+	return "incentives"
+}
+
+func (i *Incentive) Validate() error {
+	i.Name = strings.TrimSpace(i.Name)
+	if len(i.Name) == 0 || len(i.Name) > 250 {
+		return ErrInvalidIncentiveName
+	}
+	i.Type = strings.ToLower(strings.TrimSpace(i.Type))
+	program := i.Program()
+	if err := program.Validate(); err != nil {
+		return err
+	}
+	i.States = program.States
+	if i.ActiveFrom != nil && i.ActiveUntil != nil && !i.ActiveFrom.Before(*i.ActiveUntil) {
+		return ErrInvalidIncentiveDateRange
+	}
+	return nil
+}
+
+// AvailableAt reports whether the incentive is offered at the given time.
+func (i *Incentive) AvailableAt(at time.Time) bool {
+	if !i.Active {
+		return false
+	}
+	if i.ActiveFrom != nil && at.Before(*i.ActiveFrom) {
+		return false
+	}
+	return i.ActiveUntil == nil || at.Before(*i.ActiveUntil)
+}
+
+// Program returns the incentive as quotes value it.
+func (i *Incentive) Program() incentive.Program {
+	return incentive.Program{
+		Name:      i.Name,
+		Type:      i.Type,
+		Rate:      i.Rate,
+		Schedule:  i.Schedule,
+		Amount:    i.Amount,
+		PerKW:     i.PerKW,
+		PerKWh:    i.PerKWh,
+		MaxAmount: i.MaxAmount,
+		Years:     i.Years,
+		Eligibility: incentive.Eligibility{
+			States:      i.States,
+			UtilityIDs:  i.UtilityIDs,
+			MinSystemKW: i.MinSystemKW,
+			MaxSystemKW: i.MaxSystemKW,
+			Ownership:   i.Ownership,
+		},
+	}
+}
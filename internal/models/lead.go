@@ -52,6 +52,7 @@ PanelCount          int        `json:"panel_count" gorm:"column:panel_count" exa
 PanelID             *int       `json:"panel_id" gorm:"column:panel_id" example:"1"`
 InverterID          *int       `json:"inverter_id" gorm:"column:inverter_id" example:"1"`
 InverterCount       int        `json:"inverter_count" gorm:"column:inverter_count;default:1" example:"1"`
+BatteryID           *int       `json:"battery_id" gorm:"column:battery_id" example:"1"`
 BatteryCount        int        `json:"battery_count" gorm:"column:battery_count;default:0" example:"0"`
 UtilityID           *int       `json:"utility_id" gorm:"column:utility_id" example:"1"`
 TariffID            *int       `json:"tariff_id" gorm:"column:tariff_id" example:"1"`
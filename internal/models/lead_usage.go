@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// LeadUsage is a lead's measured usage, normalized from an uploaded Green
+// Button or utility CSV file into a year of hourly kWh. A lead has at most
+// one; a new upload replaces it.
+type LeadUsage struct {
+	ID              int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"column:updated_at"`
+	LeadID          int       `json:"lead_id" gorm:"column:lead_id;not null;uniqueIndex" example:"1"`
+	Format          string    `json:"format" gorm:"column:format;not null" example:"green_button"`
+	FileName        string    `json:"file_name" gorm:"column:file_name" example:"pge_electric_usage.xml"`
+	Start           time.Time `json:"start" gorm:"column:period_start" example:"2024-06-01T00:00:00Z"`
+	End             time.Time `json:"end" gorm:"column:period_end" example:"2025-06-01T00:00:00Z"`
+	IntervalMinutes int       `json:"interval_minutes" gorm:"column:interval_minutes" example:"15"`
+	ReadingHours    int       `json:"reading_hours" gorm:"column:reading_hours" example:"8712"`
+	FilledHours     int       `json:"filled_hours" gorm:"column:filled_hours" example:"48"`
+	AnnualKWh       float64   `json:"annual_kwh" gorm:"column:annual_kwh" example:"10840.25"`
+	MonthlyKWh      []float64 `json:"monthly_kwh" gorm:"column:monthly_kwh;type:text;serializer:json"`
+	HourlyKWh       []float64 `json:"hourly_kwh,omitempty" gorm:"column:hourly_kwh;type:text;serializer:json"`
+}
+
+func (LeadUsage) TableName() string {
+	return "lead_usage"
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
 )
 
 // ProposalStatus represents the status of a proposal
@@ -40,6 +42,8 @@ type Proposal struct {
 	// Production & Consumption
 	AnnualProduction  float64 `json:"annual_production" gorm:"column:annual_production" example:"13000"`
 	AnnualConsumption float64 `json:"annual_consumption" gorm:"column:annual_consumption" example:"12000"`
+	MonthlyProduction  []float64 `json:"monthly_production,omitempty" gorm:"column:monthly_production;type:text;serializer:json"`
+	MonthlyConsumption []float64 `json:"monthly_consumption,omitempty" gorm:"column:monthly_consumption;type:text;serializer:json"`
 	
 	// Financial Details
 	SystemCost           float64 `json:"system_cost" gorm:"column:system_cost" example:"25000.00"`
@@ -48,6 +52,11 @@ type Proposal struct {
 	MonthlyPayment       float64 `json:"monthly_payment" gorm:"column:monthly_payment" example:"150.00"`
 	FinancingOptionID    *int    `json:"financing_option_id" gorm:"column:financing_option_id" example:"1"`
 	FinancingProvider    string  `json:"financing_provider" gorm:"column:financing_provider" example:"SunPower Financial"`
+	AcceptedOptionID     *int    `json:"accepted_option_id" gorm:"column:accepted_option_id" example:"2"`
+	// LoanTermMonths is the term MonthlyPayment is paid over, and CashFlow
+	// the year-by-year model of the quote the financials came from.
+	LoanTermMonths       int                    `json:"loan_term_months" gorm:"column:loan_term_months" example:"300"`
+	CashFlow             []finance.YearCashFlow `json:"cash_flow,omitempty" gorm:"column:cash_flow;type:text;serializer:json"`
 	
 	// Utility Details
 	UtilityID            *int    `json:"utility_id" gorm:"column:utility_id" example:"1"`
@@ -58,15 +67,18 @@ type Proposal struct {
 	DocumentID      *int       `json:"document_id" gorm:"column:document_id" example:"1"`
 	DocumentURL     string     `json:"document_url" gorm:"column:document_url" example:"https://docs.example.com/proposal.pdf"`
 	ContractURL     string     `json:"contract_url" gorm:"column:contract_url" example:"https://docs.example.com/contract.pdf"`
+	DocumentHash    string     `json:"-" gorm:"column:document_hash"`
 	
 	// Timestamps
 	SentAt     *time.Time `json:"sent_at" gorm:"column:sent_at" example:"2025-10-01T10:00:00Z"`
 	ViewedAt   *time.Time `json:"viewed_at" gorm:"column:viewed_at" example:"2025-10-01T11:00:00Z"`
 	AcceptedAt *time.Time `json:"accepted_at" gorm:"column:accepted_at" example:"2025-10-01T12:00:00Z"`
+	RejectedAt *time.Time `json:"rejected_at" gorm:"column:rejected_at" example:"2025-10-01T12:00:00Z"`
 	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at" example:"2025-10-30T23:59:59Z"`
 	
 	// Additional Info
 	Notes      string `json:"notes" gorm:"column:notes;type:text" example:"Custom proposal notes"`
+	RejectionReason string `json:"rejection_reason" gorm:"column:rejection_reason;type:text" example:"Went with another installer"`
 	Address    string `json:"address" gorm:"column:address" example:"123 Solar St, San Francisco, CA 94102"`
 }
 
@@ -85,6 +97,29 @@ func (p *Proposal) Validate() error {
 	return nil
 }
 
+// proposalTransitions lists the statuses each status may move to.
+var proposalTransitions = map[ProposalStatus][]ProposalStatus{
+	ProposalStatusDraft:  {ProposalStatusSent},
+	ProposalStatusSent:   {ProposalStatusViewed, ProposalStatusAccepted, ProposalStatusRejected, ProposalStatusExpired},
+	ProposalStatusViewed: {ProposalStatusViewed, ProposalStatusAccepted, ProposalStatusRejected, ProposalStatusExpired},
+}
+
+// CanTransitionTo reports whether the proposal may move to the given status.
+// Accepted, rejected and expired proposals are final.
+func (p *Proposal) CanTransitionTo(status ProposalStatus) bool {
+	for _, next := range proposalTransitions[p.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether the proposal is waiting on the homeowner.
+func (p *Proposal) IsOpen() bool {
+	return p.Status == ProposalStatusSent || p.Status == ProposalStatusViewed
+}
+
 // IsExpired checks if the proposal has expired
 func (p *Proposal) IsExpired() bool {
 	if p.ExpiresAt == nil {
@@ -128,4 +163,11 @@ func (p *Proposal) MarkAccepted() {
 // MarkRejected marks the proposal as rejected
 func (p *Proposal) MarkRejected() {
 	p.Status = ProposalStatusRejected
+	now := time.Now()
+	p.RejectedAt = &now
+}
+
+// MarkExpired marks the proposal as expired
+func (p *Proposal) MarkExpired() {
+	p.Status = ProposalStatusExpired
 }
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ProposalOptionStatus represents the status of a proposal option
+type ProposalOptionStatus string
+
+const (
+	ProposalOptionStatusActive     ProposalOptionStatus = "active"
+	ProposalOptionStatusAccepted   ProposalOptionStatus = "accepted"
+	ProposalOptionStatusSuperseded ProposalOptionStatus = "superseded"
+)
+
+// ProposalOption is one of the systems offered in a proposal, e.g. the
+// "Good", "Better" and "Best" packages. Its contents live in immutable
+// versions; CurrentVersion points at the latest one.
+type ProposalOption struct {
+	ID             int                  `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt      time.Time            `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time            `json:"updated_at" gorm:"column:updated_at"`
+	ProposalID     int                  `json:"proposal_id" gorm:"column:proposal_id;not null;index" example:"1"`
+	Label          string               `json:"label" gorm:"column:label;not null" example:"Better"`
+	Position       int                  `json:"position" gorm:"column:position" example:"1"`
+	Status         ProposalOptionStatus `json:"status" gorm:"column:status;not null;default:'active'" example:"active"`
+	CurrentVersion int                  `json:"current_version" gorm:"column:current_version;not null" example:"2"`
+	AcceptedAt     *time.Time           `json:"accepted_at,omitempty" gorm:"column:accepted_at" example:"2025-10-01T12:00:00Z"`
+
+	Version *ProposalOptionVersion `json:"version,omitempty" gorm:"-"`
+}
+
+func (ProposalOption) TableName() string {
+	return "proposal_options"
+}
+
+// IsLocked reports whether the option can no longer be edited.
+func (o *ProposalOption) IsLocked() bool {
+	return o.Status != ProposalOptionStatusActive
+}
+
+// ProposalOptionSnapshot is the full system and financial picture of an
+// option at one version.
+type ProposalOptionSnapshot struct {
+	SystemSize    float64 `json:"system_size" gorm:"column:system_size" example:"10.5"`
+	PanelCount    int     `json:"panel_count" gorm:"column:panel_count" example:"30"`
+	PanelID       *int    `json:"panel_id" gorm:"column:panel_id" example:"1"`
+	InverterID    *int    `json:"inverter_id" gorm:"column:inverter_id" example:"1"`
+	InverterCount int     `json:"inverter_count" gorm:"column:inverter_count" example:"1"`
+	BatteryID     *int    `json:"battery_id" gorm:"column:battery_id" example:"1"`
+	BatteryCount  int     `json:"battery_count" gorm:"column:battery_count" example:"1"`
+
+	AnnualProduction  float64 `json:"annual_production" gorm:"column:annual_production" example:"13000"`
+	AnnualConsumption float64 `json:"annual_consumption" gorm:"column:annual_consumption" example:"12000"`
+
+	SystemCost          float64 `json:"system_cost" gorm:"column:system_cost" example:"25000.00"`
+	Incentives          float64 `json:"incentives" gorm:"column:incentives" example:"7500.00"`
+	NetCost             float64 `json:"net_cost" gorm:"column:net_cost" example:"17500.00"`
+	MonthlyPayment      float64 `json:"monthly_payment" gorm:"column:monthly_payment" example:"150.00"`
+	FinancingOptionID   *int    `json:"financing_option_id" gorm:"column:financing_option_id" example:"1"`
+	FinancingProvider   string  `json:"financing_provider" gorm:"column:financing_provider" example:"SunPower Financial"`
+	FinancingTermMonths int     `json:"financing_term_months" gorm:"column:financing_term_months" example:"300"`
+	FinancingAPR        float64 `json:"financing_apr" gorm:"column:financing_apr" example:"0.0699"`
+
+	CurrentUtilityBill   float64 `json:"current_utility_bill" gorm:"column:current_utility_bill" example:"200.00"`
+	EstimatedUtilityBill float64 `json:"estimated_utility_bill" gorm:"column:estimated_utility_bill" example:"20.00"`
+
+	Notes string `json:"notes" gorm:"column:notes;type:text" example:"Includes critter guard"`
+}
+
+// Validate validates the snapshot. A missing net cost is filled in as the
+// system cost less incentives.
+func (s *ProposalOptionSnapshot) Validate() error {
+	if s.SystemSize <= 0 {
+		return ErrInvalidOptionSystemSize
+	}
+	if s.PanelCount < 0 || s.InverterCount < 0 || s.BatteryCount < 0 {
+		return ErrInvalidOptionCount
+	}
+	if s.SystemCost < 0 || s.Incentives < 0 || s.NetCost < 0 || s.MonthlyPayment < 0 {
+		return ErrInvalidProposalCost
+	}
+	if s.NetCost == 0 && s.SystemCost > s.Incentives {
+		s.NetCost = s.SystemCost - s.Incentives
+	}
+	return nil
+}
+
+// ApplyTo copies the snapshot onto the proposal's own system and financial
+// fields, dropping the cash flow of the quote they replace.
+func (s *ProposalOptionSnapshot) ApplyTo(p *Proposal) {
+	p.SystemSize = s.SystemSize
+	p.PanelCount = s.PanelCount
+	p.PanelID = s.PanelID
+	p.InverterID = s.InverterID
+	p.BatteryCount = s.BatteryCount
+	p.AnnualProduction = s.AnnualProduction
+	p.AnnualConsumption = s.AnnualConsumption
+	p.SystemCost = s.SystemCost
+	p.Incentives = s.Incentives
+	p.NetCost = s.NetCost
+	p.MonthlyPayment = s.MonthlyPayment
+	p.LoanTermMonths = s.FinancingTermMonths
+	p.FinancingOptionID = s.FinancingOptionID
+	p.FinancingProvider = s.FinancingProvider
+	p.CurrentUtilityBill = s.CurrentUtilityBill
+	p.EstimatedUtilityBill = s.EstimatedUtilityBill
+	p.CashFlow = nil
+}
+
+// ProposalOptionVersion is an immutable snapshot of an option. Editing an
+// option adds a version; existing versions are never changed.
+type ProposalOptionVersion struct {
+	ID         int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+	OptionID   int       `json:"option_id" gorm:"column:option_id;not null;uniqueIndex:idx_proposal_option_version" example:"1"`
+	Version    int       `json:"version" gorm:"column:version;not null;uniqueIndex:idx_proposal_option_version" example:"2"`
+	ProposalID int       `json:"proposal_id" gorm:"column:proposal_id;not null;index" example:"1"`
+	CreatedBy  *int      `json:"created_by,omitempty" gorm:"column:created_by" example:"1"`
+
+	ProposalOptionSnapshot `gorm:"embedded"`
+}
+
+func (ProposalOptionVersion) TableName() string {
+	return "proposal_option_versions"
+}
+
+// NormalizeOptionLabel trims an option label and checks it is not empty.
+func NormalizeOptionLabel(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" || len(label) > 100 {
+		return "", ErrInvalidOptionLabel
+	}
+	return label, nil
+}
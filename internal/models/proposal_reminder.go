@@ -0,0 +1,108 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Notification channels and kinds recorded in the notification log.
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelSMS   = "sms"
+
+	NotificationKindProposalReminder       = "proposal_reminder"
+	NotificationKindProposalViewedFollowUp = "proposal_viewed_follow_up"
+)
+
+// Notification log statuses.
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+)
+
+// MaxNotificationAttempts is how many times a failed notification is retried.
+const MaxNotificationAttempts = 3
+
+// ProposalReminderSettings is a company's schedule for proposal follow-ups.
+type ProposalReminderSettings struct {
+	ID        int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+	CompanyID int       `json:"company_id" gorm:"column:company_id;uniqueIndex;not null" example:"1"`
+	Enabled   bool      `json:"enabled" gorm:"column:enabled" example:"true"`
+
+	// ReminderDays are the days before expiry on which the homeowner is
+	// reminded, e.g. [7, 3, 1].
+	ReminderDays []int `json:"reminder_days" gorm:"column:reminder_days;type:text;serializer:json" example:"7,3,1"`
+
+	// ViewedFollowUpHours is how long after the homeowner first opens a
+	// proposal the sales rep is told it has not been accepted. 0 disables it.
+	ViewedFollowUpHours int `json:"viewed_follow_up_hours" gorm:"column:viewed_follow_up_hours" example:"48"`
+
+	EmailEnabled bool `json:"email_enabled" gorm:"column:email_enabled" example:"true"`
+
+	// SMSEnabled texts the reminders as well. It is off by default: a
+	// company turns it on once it has the homeowners' consent to text.
+	SMSEnabled bool `json:"sms_enabled" gorm:"column:sms_enabled" example:"false"`
+}
+
+func (ProposalReminderSettings) TableName() string {
+	return "proposal_reminder_settings"
+}
+
+// DefaultProposalReminderSettings returns the schedule used by companies
+// that have not configured their own.
+func DefaultProposalReminderSettings(companyID int) *ProposalReminderSettings {
+	return &ProposalReminderSettings{
+		CompanyID:           companyID,
+		Enabled:             true,
+		ReminderDays:        []int{7, 3, 1},
+		ViewedFollowUpHours: 48,
+		EmailEnabled:        true,
+	}
+}
+
+// Validate validates the reminder settings and sorts the reminder days
+// from furthest to closest to expiry, dropping duplicates.
+func (s *ProposalReminderSettings) Validate() error {
+	if s.ViewedFollowUpHours < 0 {
+		return ErrInvalidFollowUpHours
+	}
+
+	seen := make(map[int]bool, len(s.ReminderDays))
+	days := make([]int, 0, len(s.ReminderDays))
+	for _, d := range s.ReminderDays {
+		if d < 1 || d > 90 {
+			return ErrInvalidReminderDays
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(days)))
+	s.ReminderDays = days
+	return nil
+}
+
+// NotificationLog records one notification so that it is sent at most once.
+// Key identifies the notification, e.g. "proposal:12:reminder:3:email".
+type NotificationLog struct {
+	ID         int        `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	Key        string     `json:"key" gorm:"column:key;uniqueIndex;not null" example:"proposal:12:reminder:3:email"`
+	ProposalID *int       `json:"proposal_id" gorm:"column:proposal_id;index" example:"12"`
+	Kind       string     `json:"kind" gorm:"column:kind;not null" example:"proposal_reminder"`
+	Channel    string     `json:"channel" gorm:"column:channel;not null" example:"email"`
+	Recipient  string     `json:"recipient" gorm:"column:recipient" example:"homeowner@example.com"`
+	Status     string     `json:"status" gorm:"column:status;not null" example:"sent"`
+	Attempts   int        `json:"attempts" gorm:"column:attempts" example:"1"`
+	Error      string     `json:"error,omitempty" gorm:"column:error;type:text"`
+	SentAt     *time.Time `json:"sent_at" gorm:"column:sent_at" example:"2025-10-01T10:00:00Z"`
+}
+
+func (NotificationLog) TableName() string {
+	return "notification_logs"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ProposalView records one homeowner opening a proposal link.
+type ProposalView struct {
+	ID         int       `json:"id" gorm:"primaryKey;column:id"`
+	ProposalID int       `json:"proposal_id" gorm:"column:proposal_id;not null;index" example:"1"`
+	ViewedAt   time.Time `json:"viewed_at" gorm:"column:viewed_at;not null" example:"2025-10-01T11:00:00Z"`
+	UserAgent  string    `json:"user_agent" gorm:"column:user_agent;type:text" example:"Mozilla/5.0"`
+	IPAddress  string    `json:"ip_address" gorm:"column:ip_address" example:"203.0.113.7"`
+}
+
+func (ProposalView) TableName() string {
+	return "proposal_views"
+}
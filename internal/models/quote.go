@@ -0,0 +1,315 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/battery"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/tariff"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
+)
+
+// Quote is a saved solar quote. It keeps the inputs it was calculated from,
+// the assumptions filled in for anything the inputs left out, and the
+// result, so a quote shown to a homeowner does not change when the default
+// assumptions do.
+type Quote struct {
+	ID           int              `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt    time.Time        `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time        `json:"updated_at" gorm:"column:updated_at"`
+	LeadID       *int             `json:"lead_id" gorm:"column:lead_id;index" example:"1"`
+	CompanyID    *int             `json:"company_id" gorm:"column:company_id;index" example:"1"`
+	CreatedBy    *int             `json:"created_by" gorm:"column:created_by" example:"1"`
+	Input        QuoteInput       `json:"input" gorm:"column:input;type:text;serializer:json"`
+	Assumptions  QuoteAssumptions `json:"assumptions" gorm:"column:assumptions;type:text;serializer:json"`
+	Result       QuoteResult      `json:"result" gorm:"column:result;type:text;serializer:json"`
+	CalculatedAt time.Time        `json:"calculated_at" gorm:"column:calculated_at;not null" example:"2025-10-01T10:00:00Z"`
+}
+
+func (Quote) TableName() string {
+	return "quotes"
+}
+
+// QuoteInput describes the system and the homeowner's bill. Optional rates
+// override the default assumptions.
+//
+// FinancingProducts lists the products to compare; when it is empty, the
+// company catalog's are compared, or cash and a loan at the assumed rate.
+// FinancingOptionID picks the catalog loan the monthly payment is quoted on,
+// and Storage adds batteries.
+//
+// With a Tariff, bills are modeled hour by hour. Hourly load and production
+// are synthesized from the annual figures when not given, with load shaped
+// like a reference home in the State's climate when MonthlyConsumptionKWh,
+// BuildingAreaSqFt, HasEV or HasHeatPump describe it, keeping any monthly
+// figures.
+//
+// Incentives the system is eligible for by State, UtilityID and size come
+// from the incentive catalog; Incentives and AdditionalIncentive add to them.
+type QuoteInput struct {
+	SystemSizeKW                   float64
+	AnnualProductionKWh            float64
+	MonthlyElectricBill            float64
+	ElectricalOffsetPct            float64
+	PanelCount                     int
+	State                          string
+	CostPerWatt                    *float64
+	UtilityRatePerKWh              *float64
+	AnnualUtilityIncrease          *float64
+	FederalTaxCredit               *float64
+	LoanInterestRate               *float64
+	LoanTermYears                  *int
+	AnnualDegradation              *float64
+	InflationRate                  *float64
+	DiscountRate                   *float64
+	OMCostPerKWYear                *float64
+	InverterReplacementYear        *int
+	InverterReplacementCostPerWatt *float64
+	TaxCreditYear                  *int
+	Tariff                         *tariff.Tariff
+	HourlyLoadKWh                  []float64
+	HourlyProductionKWh            []float64
+	MonthlyConsumptionKWh          []float64
+	BuildingAreaSqFt               float64
+	HasEV                          bool
+	HasHeatPump                    bool
+	FinancingProducts              []finance.ProductSpec
+	FinancingOptionID              *int
+	Storage                        *QuoteStorage
+	UtilityID                      *int
+	InstallYear                    *int
+	Incentives                     []incentive.Program
+	AdditionalIncentive            float64
+}
+
+// Validate validates quote input
+func (i *QuoteInput) Validate() error {
+	if i.SystemSizeKW <= 0 {
+		return ErrInvalidQuoteSystemSize
+	}
+	if i.AnnualProductionKWh <= 0 {
+		return ErrInvalidQuoteProduction
+	}
+	if i.MonthlyElectricBill <= 0 {
+		return ErrInvalidQuoteBill
+	}
+	if err := i.validateAssumptions(); err != nil {
+		return err
+	}
+	if i.Tariff != nil {
+		if err := i.Tariff.Validate(); err != nil {
+			return err
+		}
+	}
+	if (i.HourlyLoadKWh != nil && len(i.HourlyLoadKWh) != tariff.HoursPerYear) ||
+		(i.HourlyProductionKWh != nil && len(i.HourlyProductionKWh) != tariff.HoursPerYear) {
+		return tariff.ErrInvalidProfile
+	}
+	if i.SynthesizesLoad() {
+		shape := i.LoadShape(0)
+		if err := shape.Validate(); err != nil {
+			return err
+		}
+	}
+	if i.Storage != nil {
+		if err := i.Storage.Validate(); err != nil {
+			return err
+		}
+	}
+	for n := range i.Incentives {
+		if err := i.Incentives[n].Validate(); err != nil {
+			return err
+		}
+	}
+	if i.AdditionalIncentive < 0 {
+		return fmt.Errorf("%w: additional incentive must not be negative", incentive.ErrInvalidIncentive)
+	}
+	for _, spec := range i.FinancingProducts {
+		if _, err := spec.Product(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAssumptions checks the rates that override the default
+// assumptions. Growth rates may be negative, but not -100% or below.
+func (i *QuoteInput) validateAssumptions() error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidQuoteAssumption, reason)
+	}
+	switch {
+	case i.ElectricalOffsetPct < 0:
+		return invalid("electrical offset must not be negative")
+	case i.PanelCount < 0:
+		return invalid("panel count must not be negative")
+	case rateBelow(i.CostPerWatt, 0):
+		return invalid("cost per watt must not be negative")
+	case rateBelow(i.UtilityRatePerKWh, 0):
+		return invalid("utility rate must not be negative")
+	case rateAtOrBelow(i.AnnualUtilityIncrease, -1):
+		return invalid("annual utility increase must be greater than -1")
+	case rateBelow(i.FederalTaxCredit, 0) || rateAbove(i.FederalTaxCredit, 1):
+		return invalid("federal tax credit must be between 0 and 1")
+	case rateBelow(i.LoanInterestRate, 0):
+		return invalid("loan interest rate must not be negative")
+	case i.LoanTermYears != nil && (*i.LoanTermYears <= 0 || *i.LoanTermYears > maxQuoteLoanTermYears):
+		return invalid(fmt.Sprintf("loan term must be between 1 and %d years", maxQuoteLoanTermYears))
+	case rateBelow(i.AnnualDegradation, 0) || rateAtOrAbove(i.AnnualDegradation, 1):
+		return invalid("annual degradation must be at least 0 and less than 1")
+	case rateAtOrBelow(i.InflationRate, -1):
+		return invalid("inflation rate must be greater than -1")
+	case rateAtOrBelow(i.DiscountRate, -1):
+		return invalid("discount rate must be greater than -1")
+	case rateBelow(i.OMCostPerKWYear, 0):
+		return invalid("O&M cost must not be negative")
+	case i.InverterReplacementYear != nil && *i.InverterReplacementYear < 0:
+		return invalid("inverter replacement year must not be negative")
+	case rateBelow(i.InverterReplacementCostPerWatt, 0):
+		return invalid("inverter replacement cost must not be negative")
+	case i.TaxCreditYear != nil && *i.TaxCreditYear < 0:
+		return invalid("tax credit year must not be negative")
+	}
+	return nil
+}
+
+// maxQuoteLoanTermYears bounds the loan term a quote accepts.
+const maxQuoteLoanTermYears = 50
+
+func rateBelow(v *float64, limit float64) bool     { return v != nil && *v < limit }
+func rateAtOrBelow(v *float64, limit float64) bool { return v != nil && *v <= limit }
+func rateAbove(v *float64, limit float64) bool     { return v != nil && *v > limit }
+func rateAtOrAbove(v *float64, limit float64) bool { return v != nil && *v >= limit }
+
+// SynthesizesLoad reports whether hourly load is built from a reference
+// home rather than the typical load shape.
+func (i *QuoteInput) SynthesizesLoad() bool {
+	return i.HourlyLoadKWh == nil &&
+		(len(i.MonthlyConsumptionKWh) > 0 || i.BuildingAreaSqFt > 0 || i.HasEV || i.HasHeatPump)
+}
+
+// LoadShape describes the home for a synthesized load, falling back to
+// annualKWh without monthly figures.
+func (i *QuoteInput) LoadShape(annualKWh float64) usage.SyntheticInput {
+	return usage.SyntheticInput{
+		Monthly:          i.MonthlyConsumptionKWh,
+		AnnualKWh:        annualKWh,
+		Climate:          usage.ClimateForState(i.State),
+		BuildingAreaSqFt: i.BuildingAreaSqFt,
+		EV:               i.HasEV,
+		HeatPump:         i.HasHeatPump,
+	}
+}
+
+// QuoteAssumptions are the rates a quote was calculated with: the input's
+// own values where it had them and the defaults of the day otherwise.
+type QuoteAssumptions struct {
+	CostPerWatt           float64 `json:"cost_per_watt" example:"3.00"`
+	UtilityRatePerKWh     float64 `json:"utility_rate_per_kwh" example:"0.13"`
+	AnnualUtilityIncrease float64 `json:"annual_utility_increase" example:"0.03"`
+	FederalTaxCredit      float64 `json:"federal_tax_credit" example:"0.30"`
+	LoanInterestRate      float64 `json:"loan_interest_rate" example:"0.0699"`
+	LoanTermYears         int     `json:"loan_term_years" example:"25"`
+	// LoanTermMonths is the loan's exact term; LoanTermYears rounds a
+	// catalog term that is not a whole number of years down.
+	LoanTermMonths int `json:"loan_term_months" example:"300"`
+
+	AnnualDegradation              float64 `json:"annual_degradation" example:"0.005"`
+	InflationRate                  float64 `json:"inflation_rate" example:"0.025"`
+	DiscountRate                   float64 `json:"discount_rate" example:"0.05"`
+	OMCostPerKWYear                float64 `json:"om_cost_per_kw_year" example:"15"`
+	InverterReplacementYear        int     `json:"inverter_replacement_year" example:"12"`
+	InverterReplacementCostPerWatt float64 `json:"inverter_replacement_cost_per_watt" example:"0.20"`
+	TaxCreditYear                  int     `json:"tax_credit_year" example:"1"`
+
+	// LoanFee, LoanFeeFixed and LoanITCPaydownMonth are the dealer fee and
+	// tax credit paydown of the loan the monthly payment is quoted on, and
+	// FinancingProducts the company's products on offer, all from its
+	// financing catalog.
+	LoanFee             float64               `json:"loan_fee" example:"0.25"`
+	LoanFeeFixed        float64               `json:"loan_fee_fixed" example:"0"`
+	LoanITCPaydownMonth int                   `json:"loan_itc_paydown_month,omitempty" example:"18"`
+	FinancingProducts   []finance.ProductSpec `json:"financing_products,omitempty"`
+
+	// InstallYear picks scheduled incentive rates, and Incentives are the
+	// catalog incentives the system was eligible for. A federal ITC among
+	// them sets FederalTaxCredit, else incentive.FederalITC does, unless
+	// the input overrides it.
+	InstallYear int                 `json:"install_year" example:"2025"`
+	Incentives  []incentive.Program `json:"incentives,omitempty"`
+}
+
+type QuoteResult struct {
+	SystemCostBeforeIncentives float64 `json:"system_cost_before_incentives"`
+	FederalTaxCredit           float64 `json:"federal_tax_credit"`
+	SystemCostAfterIncentives  float64 `json:"system_cost_after_incentives"`
+	EstimatedMonthlyPayment    float64 `json:"estimated_monthly_payment"`
+	LoanTermMonths             int     `json:"loan_term_months"`
+	CurrentMonthlyBill         float64 `json:"current_monthly_bill"`
+	EstimatedNewMonthlyBill    float64 `json:"estimated_new_monthly_bill"`
+	MonthlySavings             float64 `json:"monthly_savings"`
+	FirstYearSavings           float64 `json:"first_year_savings"`
+	TwentyFiveYearSavings      float64 `json:"twenty_five_year_savings"`
+	SystemSizeKW               float64 `json:"system_size_kw"`
+	AnnualProductionKWh        float64 `json:"annual_production_kwh"`
+	PanelCount                 int     `json:"panel_count"`
+	ElectricalOffset           float64 `json:"electrical_offset_pct"`
+	CostPerWatt                float64 `json:"cost_per_watt"`
+	SimplePaybackYears         float64 `json:"simple_payback_years"`
+	BreakEvenYear              int     `json:"break_even_year"`
+	Summary                    string  `json:"summary"`
+	// Bills is the month-by-month bill before and after solar, set when
+	// the quote was modeled on a tariff.
+	Bills *tariff.Comparison `json:"bills,omitempty"`
+	// CashFlow is the year-by-year model behind the long-term figures.
+	CashFlow *finance.CashFlow `json:"cash_flow,omitempty"`
+	// Financing compares the financing products on offer side by side.
+	Financing []finance.Offer `json:"financing,omitempty"`
+	// Incentives lists every incentive the system gets, the federal tax
+	// credit first, with what each is worth year by year.
+	// TotalIncentives is their sum.
+	Incentives      []incentive.Value `json:"incentives,omitempty"`
+	TotalIncentives float64           `json:"total_incentives"`
+	// Storage is the effect of the quote's batteries, when it has any.
+	Storage *QuoteStorageResult `json:"storage,omitempty"`
+}
+
+// QuoteStorage adds batteries to a quote. With a critical load and a
+// target number of backup hours, a zero Count is filled in with the
+// recommended count.
+type QuoteStorage struct {
+	battery.Config
+	CostPerBattery float64 `json:"cost_per_battery" example:"11000.00"`
+	CriticalLoadKW float64 `json:"critical_load_kw,omitempty" example:"1.5"`
+	BackupHours    float64 `json:"backup_hours,omitempty" example:"24"`
+}
+
+func (s *QuoteStorage) Validate() error {
+	if err := s.Config.Validate(); err != nil {
+		return err
+	}
+	if s.CostPerBattery < 0 || s.CriticalLoadKW < 0 || s.BackupHours < 0 {
+		return fmt.Errorf("%w: cost, critical load and backup hours must not be negative", battery.ErrInvalidBattery)
+	}
+	if s.Count == 0 && (s.CriticalLoadKW == 0 || s.BackupHours == 0) {
+		return fmt.Errorf("%w: a battery count or a critical load and backup hours are required", battery.ErrInvalidBattery)
+	}
+	return nil
+}
+
+// QuoteStorageResult is what the batteries add to a quote. Bills compares
+// the solar-only bill (Before) with the bill with storage (After) on the
+// quote's tariff, or on a flat rate with full net metering when it has none.
+type QuoteStorageResult struct {
+	BatteryCount       int                `json:"battery_count" example:"2"`
+	RecommendedCount   int                `json:"recommended_count,omitempty" example:"2"`
+	BackupHours        float64            `json:"backup_hours" example:"18.0"`
+	Dispatch           *battery.Result    `json:"dispatch"`
+	Bills              *tariff.Comparison `json:"bills"`
+	AnnualBillSavings  float64            `json:"annual_bill_savings" example:"640.25"`
+	Cost               float64            `json:"cost" example:"22000.00"`
+	NetCost            float64            `json:"net_cost" example:"15400.00"`
+	SimplePaybackYears float64            `json:"simple_payback_years" example:"24.05"`
+}
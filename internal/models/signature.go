@@ -0,0 +1,158 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// SignatureStatus represents the status of a signature request
+type SignatureStatus string
+
+const (
+	SignatureStatusPending   SignatureStatus = "pending"
+	SignatureStatusVerified  SignatureStatus = "verified"
+	SignatureStatusCompleted SignatureStatus = "completed"
+)
+
+const (
+	SignatureTypeDrawn = "drawn"
+	SignatureTypeTyped = "typed"
+)
+
+// Signature audit trail event types.
+const (
+	SignatureEventCreated          = "created"
+	SignatureEventDocumentViewed   = "document_viewed"
+	SignatureEventCodeSent         = "code_sent"
+	SignatureEventCodeFailed       = "code_failed"
+	SignatureEventIdentityVerified = "identity_verified"
+	SignatureEventConsentGiven     = "consent_given"
+	SignatureEventSigned           = "signed"
+	SignatureEventCompleted        = "completed"
+)
+
+// SignatureRequest asks one signer to sign a deal's contract. The signer
+// opens it with Token, proves who they are with a one-time code sent over
+// VerificationChannel, consents to electronic records and signs.
+type SignatureRequest struct {
+	ID                  int             `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt           time.Time       `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt           time.Time       `json:"updated_at" gorm:"column:updated_at"`
+	Token               string          `json:"token" gorm:"column:token;uniqueIndex;not null" example:"SIGN-7K3QX2M4N5P6R7S8T9V2W3X4Y5"`
+	DealID              int             `json:"deal_id" gorm:"column:deal_id;not null;index" example:"1"`
+	CompanyID           int             `json:"company_id" gorm:"column:company_id;not null" example:"1"`
+	Status              SignatureStatus `json:"status" gorm:"column:status;not null;default:'pending'" example:"pending"`
+	SignerName          string          `json:"signer_name" gorm:"column:signer_name;not null" example:"Jane Homeowner"`
+	SignerEmail         string          `json:"signer_email" gorm:"column:signer_email" example:"jane@example.com"`
+	SignerPhone         string          `json:"signer_phone" gorm:"column:signer_phone" example:"+14155550100"`
+	VerificationChannel string          `json:"verification_channel" gorm:"column:verification_channel;not null" example:"email"`
+	ConsentText         string          `json:"consent_text" gorm:"column:consent_text;type:text"`
+	ExpiresAt           time.Time       `json:"expires_at" gorm:"column:expires_at;not null" example:"2025-10-15T10:00:00Z"`
+
+	// The contract as presented to the signer and the terms it was
+	// rendered from
+	DocumentURL  string         `json:"document_url" gorm:"column:document_url" example:"/api/signature-requests/1/document"`
+	DocumentHash string         `json:"document_hash" gorm:"column:document_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Contract     *ContractTerms `json:"contract,omitempty" gorm:"column:contract;type:text;serializer:json"`
+
+	// Signing
+	VerifiedAt        *time.Time `json:"verified_at" gorm:"column:verified_at" example:"2025-10-01T10:05:00Z"`
+	ConsentedAt       *time.Time `json:"consented_at" gorm:"column:consented_at" example:"2025-10-01T10:06:00Z"`
+	SignatureType     string     `json:"signature_type,omitempty" gorm:"column:signature_type" example:"drawn"`
+	SignatureImageURL string     `json:"signature_image_url,omitempty" gorm:"column:signature_image_url" example:"/signatures/SIGN-7K3QX2M4N5P6R7S8T9V2W3X4Y5.png"`
+	TypedSignature    string     `json:"typed_signature,omitempty" gorm:"column:typed_signature" example:"Jane Homeowner"`
+	SignedAt          *time.Time `json:"signed_at" gorm:"column:signed_at" example:"2025-10-01T10:06:00Z"`
+	SignerIP          string     `json:"signer_ip,omitempty" gorm:"column:signer_ip" example:"203.0.113.7"`
+	SignerUserAgent   string     `json:"signer_user_agent,omitempty" gorm:"column:signer_user_agent;type:text" example:"Mozilla/5.0"`
+
+	// The signed contract and its completion certificate
+	SignedDocumentURL  string `json:"signed_document_url,omitempty" gorm:"column:signed_document_url" example:"/api/signature-requests/1/document"`
+	SignedDocumentHash string `json:"signed_document_hash,omitempty" gorm:"column:signed_document_hash" example:"60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"`
+	CertificateURL     string `json:"certificate_url,omitempty" gorm:"column:certificate_url" example:"/api/signature-requests/1/certificate"`
+}
+
+// ContractTerms are the deal terms a contract shows, frozen when its
+// signature request is created so the signed copy shows the same ones.
+type ContractTerms struct {
+	CompanyName       string  `json:"company_name" example:"Acme Solar"`
+	LogoPath          string  `json:"logo_path,omitempty" example:"https://cdn.example.com/logo.png"`
+	Address           string  `json:"address" example:"123 Solar Street, CA 90210"`
+	SystemSize        float64 `json:"system_size" example:"10.5"`
+	PanelCount        int     `json:"panel_count" example:"30"`
+	PanelName         string  `json:"panel_name,omitempty" example:"REC Alpha Pure 400"`
+	InverterName      string  `json:"inverter_name,omitempty" example:"Enphase IQ8+"`
+	ProductionKWH     int     `json:"production_kwh" example:"13000"`
+	FinancingProvider string  `json:"financing_provider,omitempty" example:"SunPower Financial"`
+	TotalCost         float64 `json:"total_cost" example:"25000.00"`
+}
+
+// SameDeal reports whether two sets of terms describe the same system at
+// the same price; the installer's name and logo may differ.
+func (t ContractTerms) SameDeal(other ContractTerms) bool {
+	t.CompanyName, t.LogoPath = other.CompanyName, other.LogoPath
+	return t == other
+}
+
+func (SignatureRequest) TableName() string {
+	return "signature_requests"
+}
+
+// IsExpired reports whether the request can no longer be signed.
+func (r *SignatureRequest) IsExpired() bool {
+	return r.Status != SignatureStatusCompleted && time.Now().After(r.ExpiresAt)
+}
+
+// SignatureCode is the one-time code last sent for a signature request. Only
+// its hash is kept. Sends counts the codes sent since SendWindowStart, which
+// caps how often a signer can ask for another one.
+type SignatureCode struct {
+	ID              int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"column:updated_at;index"`
+	RequestID       int       `json:"request_id" gorm:"column:request_id;uniqueIndex;not null" example:"1"`
+	CodeHash        string    `json:"-" gorm:"column:code_hash"`
+	ExpiresAt       time.Time `json:"expires_at" gorm:"column:expires_at" example:"2025-10-01T10:10:00Z"`
+	Attempts        int       `json:"attempts" gorm:"column:attempts" example:"0"`
+	Sends           int       `json:"sends" gorm:"column:sends" example:"1"`
+	SendWindowStart time.Time `json:"send_window_start" gorm:"column:send_window_start" example:"2025-10-01T10:00:00Z"`
+}
+
+func (SignatureCode) TableName() string {
+	return "signature_codes"
+}
+
+// SignatureEvent is one entry in a signature request's audit trail. Each
+// event's Hash covers its own fields and the previous event's hash, so
+// editing or removing any event breaks every hash after it. The first event
+// chains from the hash of the unsigned document.
+type SignatureEvent struct {
+	ID         int       `json:"id" gorm:"primaryKey;column:id"`
+	RequestID  int       `json:"request_id" gorm:"column:request_id;not null;index" example:"1"`
+	Type       string    `json:"type" gorm:"column:type;not null" example:"identity_verified"`
+	OccurredAt time.Time `json:"occurred_at" gorm:"column:occurred_at;not null" example:"2025-10-01T10:05:00Z"`
+	IPAddress  string    `json:"ip_address" gorm:"column:ip_address" example:"203.0.113.7"`
+	UserAgent  string    `json:"user_agent" gorm:"column:user_agent;type:text" example:"Mozilla/5.0"`
+	Detail     string    `json:"detail" gorm:"column:detail;type:text" example:"Code sent by email to j***@example.com"`
+	PrevHash   string    `json:"prev_hash" gorm:"column:prev_hash;not null"`
+	Hash       string    `json:"hash" gorm:"column:hash;not null"`
+}
+
+func (SignatureEvent) TableName() string {
+	return "signature_events"
+}
+
+// ComputeHash returns the SHA-256 of the event's fields and PrevHash.
+func (e *SignatureEvent) ComputeHash() string {
+	fields := []string{
+		e.PrevHash,
+		e.Type,
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.IPAddress,
+		e.UserAgent,
+		e.Detail,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
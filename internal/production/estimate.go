@@ -0,0 +1,193 @@
+package production
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidSystem = errors.New("invalid system")
+
+// Defaults for what a system leaves out, close to PVWatts' defaults.
+const (
+	DefaultTiltDeg            = 20
+	DefaultAzimuthDeg         = 180
+	DefaultLosses             = 0.14
+	DefaultAlbedo             = 0.2
+	DefaultInverterEfficiency = 0.96
+	DefaultDCACRatio          = 1.2
+)
+
+// Module holds the panel specs production depends on. Zero values take
+// the defaults of a typical monocrystalline panel.
+type Module struct {
+	// TempCoeffPmax is the power temperature coefficient in %/°C.
+	TempCoeffPmax float64 `json:"temp_coeff_pmax,omitempty" example:"-0.26"`
+	// NOCT is the nominal operating cell temperature in °C.
+	NOCT float64 `json:"noct,omitempty" example:"44"`
+	// Efficiency is the module efficiency in percent.
+	Efficiency float64 `json:"efficiency,omitempty" example:"21.6"`
+}
+
+var defaultModule = Module{TempCoeffPmax: -0.37, NOCT: 45, Efficiency: 20}
+
+// System is an array to estimate. Angles are in degrees; azimuth is
+// clockwise from north, so 180 faces south.
+type System struct {
+	SizeKW     float64 `json:"system_size_kw" example:"8.4"`
+	TiltDeg    float64 `json:"tilt_deg" example:"20"`
+	AzimuthDeg float64 `json:"azimuth_deg" example:"180"`
+	Module     Module  `json:"module"`
+	// InverterEfficiency is a fraction; zero means 96%.
+	InverterEfficiency float64 `json:"inverter_efficiency,omitempty" example:"0.96"`
+	// InverterKW is the inverter AC capacity output is clipped to; zero
+	// means SizeKW over a 1.2 DC/AC ratio.
+	InverterKW float64 `json:"inverter_kw,omitempty" example:"7.6"`
+	// Losses are DC losses other than temperature as a fraction: soiling,
+	// shading, wiring, mismatch and the like. Nil means 14%.
+	Losses *float64 `json:"losses,omitempty" example:"0.14"`
+	// Albedo is the ground reflectance; zero means 0.2.
+	Albedo float64 `json:"albedo,omitempty" example:"0.2"`
+}
+
+// Estimate is a year of modeled production.
+type Estimate struct {
+	Station    string  `json:"station" example:"SAN FRANCISCO INTL AP"`
+	DistanceKm float64 `json:"distance_km" example:"12.4"`
+	// Hourly is AC production in kWh for each hour of the year.
+	Hourly    []float64   `json:"hourly_kwh,omitempty"`
+	Monthly   [12]float64 `json:"monthly_kwh"`
+	AnnualKWh float64     `json:"annual_kwh" example:"12150.40"`
+	KWhPerKW  float64     `json:"kwh_per_kw" example:"1446.48"`
+	// POAInsolation is the year's irradiance on the array in kWh/m².
+	POAInsolation float64 `json:"poa_insolation_kwh_m2" example:"1870.25"`
+	// Losses break the gap between nameplate output at plane-of-array
+	// irradiance and AC output down into kWh.
+	TemperatureLossKWh float64 `json:"temperature_loss_kwh" example:"640.10"`
+	SystemLossKWh      float64 `json:"system_loss_kwh" example:"1920.70"`
+	InverterLossKWh    float64 `json:"inverter_loss_kwh" example:"480.30"`
+	ClippingLossKWh    float64 `json:"clipping_loss_kwh" example:"12.00"`
+}
+
+func (s *System) Validate() error {
+	if s.SizeKW <= 0 {
+		return fmt.Errorf("%w: system size must be greater than 0", ErrInvalidSystem)
+	}
+	if s.TiltDeg < 0 || s.TiltDeg > 90 {
+		return fmt.Errorf("%w: tilt must be between 0 and 90 degrees", ErrInvalidSystem)
+	}
+	if s.AzimuthDeg < 0 || s.AzimuthDeg >= 360 {
+		return fmt.Errorf("%w: azimuth must be between 0 and 360 degrees", ErrInvalidSystem)
+	}
+	if s.InverterEfficiency < 0 || s.InverterEfficiency > 1 || s.InverterKW < 0 || s.Albedo < 0 || s.Albedo > 1 {
+		return fmt.Errorf("%w: inverter and albedo values are out of range", ErrInvalidSystem)
+	}
+	if s.Losses != nil && (*s.Losses < 0 || *s.Losses >= 1) {
+		return fmt.Errorf("%w: losses must be between 0 and 1", ErrInvalidSystem)
+	}
+	return nil
+}
+
+// Run models a year of production for the system in the weather.
+func Run(w *Weather, s System) (*Estimate, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if len(w.Hours) != HoursPerYear {
+		return nil, fmt.Errorf("%w: weather must have %d hours", ErrInvalidWeather, HoursPerYear)
+	}
+
+	module := s.Module
+	if module.TempCoeffPmax == 0 {
+		module.TempCoeffPmax = defaultModule.TempCoeffPmax
+	}
+	if module.NOCT == 0 {
+		module.NOCT = defaultModule.NOCT
+	}
+	if module.Efficiency == 0 {
+		module.Efficiency = defaultModule.Efficiency
+	}
+	inverterEfficiency := s.InverterEfficiency
+	if inverterEfficiency == 0 {
+		inverterEfficiency = DefaultInverterEfficiency
+	}
+	inverterKW := s.InverterKW
+	if inverterKW == 0 {
+		inverterKW = s.SizeKW / DefaultDCACRatio
+	}
+	losses := DefaultLosses
+	if s.Losses != nil {
+		losses = *s.Losses
+	}
+	albedo := s.Albedo
+	if albedo == 0 {
+		albedo = DefaultAlbedo
+	}
+
+	e := &Estimate{Station: w.Station, Hourly: make([]float64, HoursPerYear)}
+	var poaTotal, tempLoss, systemLoss, inverterLoss, clipping float64
+	for h, weather := range w.Hours {
+		sun := Sun(h, w.Latitude, w.Longitude, w.TimeZone)
+		beam, diffuse, ground, cosIncidence := planeOfArray(weather, sun, s.TiltDeg, s.AzimuthDeg, albedo, h)
+		poa := beam + diffuse + ground
+		if poa <= 0 {
+			continue
+		}
+		poaTotal += poa
+
+		// Light reflected off the glass at steep angles (ASHRAE model).
+		if cosIncidence > 0 {
+			beam *= math.Max(0, 1-0.05*(1/cosIncidence-1))
+		} else {
+			beam = 0
+		}
+		effective := beam + diffuse + ground
+
+		// Cell temperature from NOCT, adjusted for wind and for the share
+		// of light turned into power rather than heat.
+		wind := weather.WindSpeed
+		if wind <= 0 {
+			wind = 1
+		}
+		cellTemp := weather.Temperature + (module.NOCT-20)*effective/800*
+			(9.5/(5.7+3.8*wind))*(1-module.Efficiency/100/0.9)
+
+		nameplate := s.SizeKW * effective / 1000
+		dc := nameplate * (1 + module.TempCoeffPmax/100*(cellTemp-25))
+		tempLoss += nameplate - dc
+		systemLoss += dc * losses
+		dc *= 1 - losses
+
+		ac := dc * inverterEfficiency
+		inverterLoss += dc - ac
+		if ac > inverterKW {
+			clipping += ac - inverterKW
+			ac = inverterKW
+		}
+		ac = math.Max(0, ac)
+		e.Hourly[h] = ac
+		e.AnnualKWh += ac
+		e.Monthly[monthOf(h)] += ac
+	}
+
+	for m := range e.Monthly {
+		e.Monthly[m] = round2(e.Monthly[m])
+	}
+	e.KWhPerKW = round2(e.AnnualKWh / s.SizeKW)
+	e.AnnualKWh = round2(e.AnnualKWh)
+	e.POAInsolation = round2(poaTotal / 1000)
+	e.TemperatureLossKWh = round2(tempLoss)
+	e.SystemLossKWh = round2(systemLoss)
+	e.InverterLossKWh = round2(inverterLoss)
+	e.ClippingLossKWh = round2(clipping)
+	return e, nil
+}
+
+// monthOf returns the month (0-11) of an hour of the year.
+func monthOf(hourOfYear int) int {
+	return int(referenceYear.AddDate(0, 0, hourOfYear/24).Month()) - 1
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
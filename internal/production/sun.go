@@ -0,0 +1,76 @@
+package production
+
+import "math"
+
+const deg = math.Pi / 180
+
+// solarConstant is the mean extraterrestrial irradiance in W/m².
+const solarConstant = 1367
+
+// SunPosition is where the sun is in the sky. Azimuth is in degrees
+// clockwise from north.
+type SunPosition struct {
+	ZenithDeg  float64
+	AzimuthDeg float64
+}
+
+// Sun returns the sun's position at the middle of an hour of the year at a
+// location, with the hour in local standard time at timeZone hours from
+// UTC. It uses Spencer's series for declination and the equation of time,
+// which is within a fraction of a degree of more exact algorithms.
+func Sun(hourOfYear int, latitude, longitude, timeZone float64) SunPosition {
+	day := hourOfYear / 24
+	b := 2 * math.Pi * float64(day) / 365
+
+	declination := 0.006918 - 0.399912*math.Cos(b) + 0.070257*math.Sin(b) -
+		0.006758*math.Cos(2*b) + 0.000907*math.Sin(2*b) -
+		0.002697*math.Cos(3*b) + 0.00148*math.Sin(3*b)
+	eotMinutes := 229.18 * (0.000075 + 0.001868*math.Cos(b) - 0.032077*math.Sin(b) -
+		0.014615*math.Cos(2*b) - 0.040849*math.Sin(2*b))
+
+	clockMinutes := (float64(hourOfYear%24) + 0.5) * 60
+	solarMinutes := clockMinutes + eotMinutes + 4*longitude - 60*timeZone
+	hourAngle := (solarMinutes/4 - 180) * deg
+
+	phi := latitude * deg
+	cosZenith := math.Sin(phi)*math.Sin(declination) + math.Cos(phi)*math.Cos(declination)*math.Cos(hourAngle)
+	cosZenith = math.Max(-1, math.Min(1, cosZenith))
+
+	// Azimuth from south, positive to the west, turned to from north.
+	fromSouth := math.Atan2(math.Sin(hourAngle), math.Cos(hourAngle)*math.Sin(phi)-math.Tan(declination)*math.Cos(phi))
+	return SunPosition{
+		ZenithDeg:  math.Acos(cosZenith) / deg,
+		AzimuthDeg: math.Mod(fromSouth/deg+180+360, 360),
+	}
+}
+
+// extraterrestrial returns the irradiance normal to the sun above the
+// atmosphere on a day of the year.
+func extraterrestrial(hourOfYear int) float64 {
+	return solarConstant * (1 + 0.033*math.Cos(2*math.Pi*float64(hourOfYear/24+1)/365))
+}
+
+// planeOfArray returns the irradiance on a surface tilted tiltDeg from
+// horizontal and facing azimuthDeg from north, and the cosine of the
+// angle between the surface and the sun. Diffuse light uses the Hay-Davies
+// model: circumsolar diffuse in proportion to how clear the sky is, the
+// rest isotropic.
+func planeOfArray(h Hour, sun SunPosition, tiltDeg, azimuthDeg, albedo float64, hourOfYear int) (beam, diffuse, ground, cosIncidence float64) {
+	cosZenith := math.Cos(sun.ZenithDeg * deg)
+	if cosZenith <= 0 || h.GHI <= 0 {
+		return 0, 0, 0, 0
+	}
+	tilt := tiltDeg * deg
+	sinZenith := math.Sin(sun.ZenithDeg * deg)
+	cosIncidence = cosZenith*math.Cos(tilt) + sinZenith*math.Sin(tilt)*math.Cos((sun.AzimuthDeg-azimuthDeg)*deg)
+
+	beam = h.DNI * math.Max(0, cosIncidence)
+
+	anisotropy := math.Min(1, h.DNI/extraterrestrial(hourOfYear))
+	// Limit the beam ratio near the horizon, where it blows up.
+	rb := math.Max(0, cosIncidence) / math.Max(cosZenith, math.Cos(85*deg))
+	diffuse = h.DHI * (anisotropy*rb + (1-anisotropy)*(1+math.Cos(tilt))/2)
+
+	ground = h.GHI * albedo * (1 - math.Cos(tilt)) / 2
+	return beam, diffuse, ground, cosIncidence
+}
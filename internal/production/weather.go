@@ -0,0 +1,406 @@
+// Package production estimates solar production hour by hour from typical
+// meteorological year (TMY) weather files, the sun's position and the
+// array's orientation, without calling a design or rate API.
+package production
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HoursPerYear is the length of a weather year and of the hourly
+// production it gives, the same non-leap year tariff profiles use.
+const HoursPerYear = 8760
+
+var (
+	ErrInvalidWeather = errors.New("invalid weather file")
+	ErrNoWeather      = errors.New("no weather data near this location")
+)
+
+// Weather is a typical year of hourly weather at one station. Hours are
+// indexed from midnight on January 1 in local standard time; each value
+// covers the hour that starts there.
+type Weather struct {
+	Station   string
+	State     string
+	Latitude  float64
+	Longitude float64
+	// TimeZone is the UTC offset of local standard time in hours.
+	TimeZone float64
+	Hours    []Hour
+}
+
+// Hour is one hour of weather. Irradiance is in W/m², temperature in °C
+// and wind speed in m/s.
+type Hour struct {
+	GHI         float64
+	DNI         float64
+	DHI         float64
+	Temperature float64
+	WindSpeed   float64
+}
+
+// TemperatureRange returns the lowest and highest hourly temperatures of
+// the year. A typical year leaves out the extremes of any one year.
+func (w *Weather) TemperatureRange() (low, high float64) {
+	if len(w.Hours) == 0 {
+		return 0, 0
+	}
+	low, high = math.Inf(1), math.Inf(-1)
+	for _, h := range w.Hours {
+		low = math.Min(low, h.Temperature)
+		high = math.Max(high, h.Temperature)
+	}
+	return low, high
+}
+
+// ParseWeather reads a TMY3 file, or an NSRDB PSM file with one row per
+// hour. February 29 is dropped from leap years.
+func ParseWeather(r io.Reader) (*Weather, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	first, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWeather, err)
+	}
+	var w *Weather
+	var timeColumns func(header []string) (func(row []string) (month, day, hour int, err error), error)
+	if strings.EqualFold(first[0], "Source") {
+		meta, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWeather, err)
+		}
+		w, err = psmMetadata(first, meta)
+		if err != nil {
+			return nil, err
+		}
+		timeColumns = psmTime
+	} else {
+		w, err = tmy3Metadata(first)
+		if err != nil {
+			return nil, err
+		}
+		timeColumns = tmy3Time
+	}
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWeather, err)
+	}
+	rowTime, err := timeColumns(header)
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for name, prefixes := range map[string][]string{
+		"ghi":  {"GHI"},
+		"dni":  {"DNI"},
+		"dhi":  {"DHI"},
+		"temp": {"Dry-bulb", "Temperature"},
+		"wind": {"Wspd", "Wind Speed"},
+	} {
+		i := column(header, prefixes...)
+		if i < 0 && name != "wind" {
+			return nil, fmt.Errorf("%w: no %s column", ErrInvalidWeather, prefixes[0])
+		}
+		cols[name] = i
+	}
+
+	w.Hours = make([]Hour, HoursPerYear)
+	seen := make([]bool, HoursPerYear)
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWeather, err)
+		}
+		month, day, hour, err := rowTime(row)
+		if err != nil {
+			return nil, err
+		}
+		if month == 2 && day == 29 {
+			continue
+		}
+		h, ok := hourOfYear(month, day, hour)
+		if !ok {
+			return nil, fmt.Errorf("%w: bad date %d/%d hour %d", ErrInvalidWeather, month, day, hour)
+		}
+
+		var values [5]float64
+		for i, name := range []string{"ghi", "dni", "dhi", "temp", "wind"} {
+			if cols[name] < 0 {
+				continue
+			}
+			if values[i], err = field(row, cols[name]); err != nil {
+				return nil, err
+			}
+		}
+		w.Hours[h] = Hour{
+			GHI:         math.Max(0, values[0]),
+			DNI:         math.Max(0, values[1]),
+			DHI:         math.Max(0, values[2]),
+			Temperature: values[3],
+			WindSpeed:   values[4],
+		}
+		seen[h] = true
+	}
+	for h, ok := range seen {
+		if !ok {
+			return nil, fmt.Errorf("%w: missing hour %d of the year", ErrInvalidWeather, h)
+		}
+	}
+	return w, nil
+}
+
+// LoadWeatherFile reads a weather file from disk.
+func LoadWeatherFile(path string) (*Weather, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWeather(f)
+}
+
+// tmy3Metadata reads the first line of a TMY3 file: station ID, name,
+// state, time zone, latitude, longitude and elevation.
+func tmy3Metadata(row []string) (*Weather, error) {
+	if len(row) < 6 {
+		return nil, fmt.Errorf("%w: unrecognized header", ErrInvalidWeather)
+	}
+	var nums [3]float64
+	for i, col := range []int{3, 4, 5} {
+		v, err := strconv.ParseFloat(strings.TrimSpace(row[col]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unrecognized header", ErrInvalidWeather)
+		}
+		nums[i] = v
+	}
+	return &Weather{
+		Station:   strings.TrimSpace(row[1]),
+		State:     strings.TrimSpace(row[2]),
+		TimeZone:  nums[0],
+		Latitude:  nums[1],
+		Longitude: nums[2],
+	}, nil
+}
+
+// psmMetadata reads the name and value lines at the top of a PSM file.
+func psmMetadata(names, values []string) (*Weather, error) {
+	get := func(name string) string {
+		if i := column(names, name); i >= 0 && i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+	w := &Weather{Station: get("Location ID"), State: get("State")}
+	if city := get("City"); city != "" && city != "-" {
+		w.Station = city
+	}
+	for name, dst := range map[string]*float64{"Latitude": &w.Latitude, "Longitude": &w.Longitude, "Time Zone": &w.TimeZone} {
+		v, err := strconv.ParseFloat(get(name), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: no %s in header", ErrInvalidWeather, name)
+		}
+		*dst = v
+	}
+	return w, nil
+}
+
+// tmy3Time reads TMY3 dates and hour-ending times (01:00 to 24:00).
+func tmy3Time(header []string) (func([]string) (int, int, int, error), error) {
+	date, clock := column(header, "Date"), column(header, "Time")
+	if date < 0 || clock < 0 {
+		return nil, fmt.Errorf("%w: no date and time columns", ErrInvalidWeather)
+	}
+	return func(row []string) (int, int, int, error) {
+		if date >= len(row) || clock >= len(row) {
+			return 0, 0, 0, fmt.Errorf("%w: short row", ErrInvalidWeather)
+		}
+		d, err := time.Parse("01/02/2006", strings.TrimSpace(row[date]))
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("%w: bad date %q", ErrInvalidWeather, row[date])
+		}
+		hh, _, _ := strings.Cut(strings.TrimSpace(row[clock]), ":")
+		hour, err := strconv.Atoi(hh)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("%w: bad time %q", ErrInvalidWeather, row[clock])
+		}
+		return int(d.Month()), d.Day(), hour - 1, nil
+	}, nil
+}
+
+// psmTime reads PSM month, day and hour-beginning columns.
+func psmTime(header []string) (func([]string) (int, int, int, error), error) {
+	cols := []int{column(header, "Month"), column(header, "Day"), column(header, "Hour")}
+	for _, c := range cols {
+		if c < 0 {
+			return nil, fmt.Errorf("%w: no month, day and hour columns", ErrInvalidWeather)
+		}
+	}
+	return func(row []string) (int, int, int, error) {
+		var v [3]int
+		for i, c := range cols {
+			f, err := field(row, c)
+			if err != nil {
+				return 0, 0, 0, err
+			}
+			v[i] = int(f)
+		}
+		return v[0], v[1], v[2], nil
+	}, nil
+}
+
+// column returns the index of the first header starting with any of the
+// prefixes, ignoring case, or -1.
+func column(header []string, prefixes ...string) int {
+	for _, prefix := range prefixes {
+		for i, h := range header {
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(h)), strings.ToLower(prefix)) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func field(row []string, i int) (float64, error) {
+	if i >= len(row) {
+		return 0, fmt.Errorf("%w: short row", ErrInvalidWeather)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad value %q", ErrInvalidWeather, row[i])
+	}
+	return v, nil
+}
+
+var referenceYear = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// hourOfYear returns the index of an hour in a non-leap year.
+func hourOfYear(month, day, hour int) (int, bool) {
+	if month < 1 || month > 12 || hour < 0 || hour > 23 {
+		return 0, false
+	}
+	t := time.Date(referenceYear.Year(), time.Month(month), day, hour, 0, 0, 0, time.UTC)
+	if int(t.Month()) != month {
+		return 0, false
+	}
+	return int(t.Sub(referenceYear).Hours()), true
+}
+
+// Library is a directory of weather files, one per station. Only the
+// header of each file is read up front; stations are parsed on first use
+// and kept in memory.
+type Library struct {
+	stations []station
+	// MaxDistanceKm is how far a location may be from the nearest station.
+	MaxDistanceKm float64
+
+	mu    sync.Mutex
+	cache map[string]*Weather
+}
+
+type station struct {
+	path      string
+	latitude  float64
+	longitude float64
+}
+
+// OpenLibrary indexes the .csv weather files in dir. A missing directory
+// gives an empty library.
+func OpenLibrary(dir string) (*Library, error) {
+	l := &Library{MaxDistanceKm: 150, cache: map[string]*Weather{}}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		w, err := readMetadata(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		l.stations = append(l.stations, station{path: path, latitude: w.Latitude, longitude: w.Longitude})
+	}
+	return l, nil
+}
+
+// Len returns the number of stations in the library.
+func (l *Library) Len() int {
+	return len(l.stations)
+}
+
+// Nearest returns the weather of the station nearest a location and its
+// distance in km.
+func (l *Library) Nearest(latitude, longitude float64) (*Weather, float64, error) {
+	best, bestKm := -1, math.Inf(1)
+	for i, s := range l.stations {
+		if km := distanceKm(latitude, longitude, s.latitude, s.longitude); km < bestKm {
+			best, bestKm = i, km
+		}
+	}
+	if best < 0 || bestKm > l.MaxDistanceKm {
+		return nil, 0, ErrNoWeather
+	}
+
+	path := l.stations[best].path
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w, ok := l.cache[path]; ok {
+		return w, bestKm, nil
+	}
+	w, err := LoadWeatherFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	l.cache[path] = w
+	return w, bestKm, nil
+}
+
+// readMetadata reads only the station lines at the top of a weather file.
+func readMetadata(path string) (*Weather, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	first, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWeather, err)
+	}
+	if strings.EqualFold(first[0], "Source") {
+		meta, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWeather, err)
+		}
+		return psmMetadata(first, meta)
+	}
+	return tmy3Metadata(first)
+}
+
+// distanceKm is the great-circle distance between two points.
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	lat1R, lat2R := lat1*deg, lat2*deg
+	dLat, dLon := (lat2-lat1)*deg, (lon2-lon1)*deg
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1R)*math.Cos(lat2R)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
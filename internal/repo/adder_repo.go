@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+)
+
+type AdderRepo struct {
+	db *gorm.DB
+}
+
+func NewAdderRepo(db *gorm.DB) *AdderRepo {
+	return &AdderRepo{db: db}
+}
+
+// WithTx returns a copy of the repo that runs its queries in tx.
+func (r *AdderRepo) WithTx(tx *gorm.DB) *AdderRepo {
+	return &AdderRepo{db: tx}
+}
+
+func (r *AdderRepo) CreateCategory(ctx context.Context, category *models.AdderCategory) error {
+	return r.db.WithContext(ctx).Create(category).Error
+}
+
+func (r *AdderRepo) GetCategoryByID(ctx context.Context, id int) (*models.AdderCategory, error) {
+	var category models.AdderCategory
+	err := r.db.WithContext(ctx).First(&category, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrAdderCategoryNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *AdderRepo) UpdateCategory(ctx context.Context, category *models.AdderCategory) error {
+	return r.db.WithContext(ctx).Save(category).Error
+}
+
+func (r *AdderRepo) DeleteCategory(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Adder{}).
+			Where("category_id = ?", id).
+			Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.AdderCategory{}, id).Error
+	})
+}
+
+func (r *AdderRepo) ListCategories(ctx context.Context, companyID int) ([]*models.AdderCategory, error) {
+	var categories []*models.AdderCategory
+	err := r.db.WithContext(ctx).
+		Where("company_id = ?", companyID).
+		Order("position ASC, name ASC").
+		Find(&categories).Error
+	return categories, err
+}
+
+// Create inserts the adder and its first version record.
+func (r *AdderRepo) Create(ctx context.Context, adder *models.Adder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		adder.Version = 1
+		if err := tx.Create(adder).Error; err != nil {
+			return err
+		}
+		return tx.Create(adder.Snapshot()).Error
+	})
+}
+
+func (r *AdderRepo) GetByID(ctx context.Context, id int) (*models.Adder, error) {
+	var adder models.Adder
+	err := r.db.WithContext(ctx).First(&adder, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrAdderNotFound
+		}
+		return nil, err
+	}
+	return &adder, nil
+}
+
+// Update saves the adder as a new version and records that version.
+func (r *AdderRepo) Update(ctx context.Context, adder *models.Adder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Adder
+		if err := tx.Select("version").First(&current, adder.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrAdderNotFound
+			}
+			return err
+		}
+		adder.Version = current.Version + 1
+		if err := tx.Save(adder).Error; err != nil {
+			return err
+		}
+		return tx.Create(adder.Snapshot()).Error
+	})
+}
+
+// Delete removes the adder from the catalog. Its version history and any
+// deal snapshots are kept.
+func (r *AdderRepo) Delete(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Delete(&models.Adder{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrAdderNotFound
+	}
+	return nil
+}
+
+func (r *AdderRepo) ListByCompany(ctx context.Context, companyID int, activeOnly bool) ([]*models.Adder, error) {
+	var adders []*models.Adder
+	query := r.db.WithContext(ctx).Where("company_id = ?", companyID)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Order("name ASC").Find(&adders).Error
+	return adders, err
+}
+
+func (r *AdderRepo) FindByIDs(ctx context.Context, companyID int, ids []int) ([]*models.Adder, error) {
+	var adders []*models.Adder
+	err := r.db.WithContext(ctx).
+		Where("company_id = ? AND id IN ?", companyID, ids).
+		Find(&adders).Error
+	return adders, err
+}
+
+func (r *AdderRepo) ListVersions(ctx context.Context, adderID int) ([]*models.AdderVersion, error) {
+	var versions []*models.AdderVersion
+	err := r.db.WithContext(ctx).
+		Where("adder_id = ?", adderID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+func (r *AdderRepo) ListDealAdders(ctx context.Context, dealID int) ([]*models.DealAdder, error) {
+	var adders []*models.DealAdder
+	err := r.db.WithContext(ctx).
+		Where("deal_id = ?", dealID).
+		Order("id ASC").
+		Find(&adders).Error
+	return adders, err
+}
+
+// ReplaceDealAdders swaps the adder snapshot of a deal for a new one.
+func (r *AdderRepo) ReplaceDealAdders(ctx context.Context, dealID int, adders []*models.DealAdder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deal_id = ?", dealID).Delete(&models.DealAdder{}).Error; err != nil {
+			return fmt.Errorf("failed to clear deal adders: %w", err)
+		}
+		for _, a := range adders {
+			a.DealID = dealID
+		}
+		if len(adders) == 0 {
+			return nil
+		}
+		return tx.Create(&adders).Error
+	})
+}
@@ -2,6 +2,8 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
 	"gorm.io/gorm"
@@ -15,6 +17,11 @@ func NewDealRepo(db *gorm.DB) *DealRepo {
 	return &DealRepo{db: db}
 }
 
+// WithTx returns a copy of the repo that runs its queries in tx.
+func (r *DealRepo) WithTx(tx *gorm.DB) *DealRepo {
+	return &DealRepo{db: tx}
+}
+
 func (r *DealRepo) Create(ctx context.Context, deal *models.Deal) error {
 	return r.db.WithContext(ctx).Create(deal).Error
 }
@@ -37,10 +44,41 @@ func (r *DealRepo) GetByUUID(ctx context.Context, uuid string) (*models.Deal, er
 	return &deal, nil
 }
 
+// GetByProposalID returns the deal created from a proposal.
+func (r *DealRepo) GetByProposalID(ctx context.Context, proposalID int) (*models.Deal, error) {
+	var deal models.Deal
+	err := r.db.WithContext(ctx).Where("proposal_id = ?", proposalID).First(&deal).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrDealNotFound
+		}
+		return nil, err
+	}
+	return &deal, nil
+}
+
 func (r *DealRepo) Update(ctx context.Context, deal *models.Deal) error {
 	return r.db.WithContext(ctx).Save(deal).Error
 }
 
+// MarkSigned sets the deal's signed time unless it is already signed.
+func (r *DealRepo) MarkSigned(ctx context.Context, id int, signedAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Deal{}).
+		Where("id = ? AND signed_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"signed_at":  signedAt,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrDealAlreadySigned
+	}
+	return nil
+}
+
 func (r *DealRepo) Delete(ctx context.Context, id int) error {
 	return r.db.WithContext(ctx).Delete(&models.Deal{}, id).Error
 }
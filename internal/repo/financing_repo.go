@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+)
+
+type FinancingRepo struct {
+	db *gorm.DB
+}
+
+func NewFinancingRepo(db *gorm.DB) *FinancingRepo {
+	return &FinancingRepo{db: db}
+}
+
+func (r *FinancingRepo) CreateProvider(ctx context.Context, provider *models.FinancingProvider) error {
+	return r.db.WithContext(ctx).Create(provider).Error
+}
+
+func (r *FinancingRepo) GetProviderByID(ctx context.Context, id int) (*models.FinancingProvider, error) {
+	var provider models.FinancingProvider
+	err := r.db.WithContext(ctx).First(&provider, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrFinancingProviderNotFound
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+func (r *FinancingRepo) UpdateProvider(ctx context.Context, provider *models.FinancingProvider) error {
+	return r.db.WithContext(ctx).Save(provider).Error
+}
+
+// DeleteProvider marks the provider and its options deleted. Deals and
+// proposals keep the option IDs they were sold with, and GetSoldOptionByID
+// still finds them.
+func (r *FinancingRepo) DeleteProvider(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", id).Delete(&models.FinancingOption{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.FinancingProvider{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrFinancingProviderNotFound
+		}
+		return nil
+	})
+}
+
+func (r *FinancingRepo) ListProviders(ctx context.Context, companyID int) ([]*models.FinancingProvider, error) {
+	var providers []*models.FinancingProvider
+	err := r.db.WithContext(ctx).
+		Where("company_id = ?", companyID).
+		Order("name ASC").
+		Find(&providers).Error
+	return providers, err
+}
+
+func (r *FinancingRepo) CreateOption(ctx context.Context, option *models.FinancingOption) error {
+	return r.db.WithContext(ctx).Omit("Provider").Create(option).Error
+}
+
+func (r *FinancingRepo) GetOptionByID(ctx context.Context, id int) (*models.FinancingOption, error) {
+	var option models.FinancingOption
+	err := r.db.WithContext(ctx).Preload("Provider").First(&option, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrFinancingOptionNotFound
+		}
+		return nil, err
+	}
+	return &option, nil
+}
+
+// GetSoldOptionByID returns an option even when it has been deleted, for
+// pricing the deals sold with it.
+func (r *FinancingRepo) GetSoldOptionByID(ctx context.Context, id int) (*models.FinancingOption, error) {
+	var option models.FinancingOption
+	err := r.db.WithContext(ctx).Unscoped().
+		Preload("Provider", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&option, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrFinancingOptionNotFound
+		}
+		return nil, err
+	}
+	return &option, nil
+}
+
+func (r *FinancingRepo) UpdateOption(ctx context.Context, option *models.FinancingOption) error {
+	return r.db.WithContext(ctx).Omit("Provider").Save(option).Error
+}
+
+func (r *FinancingRepo) DeleteOption(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Delete(&models.FinancingOption{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrFinancingOptionNotFound
+	}
+	return nil
+}
+
+// ListOptions returns a company's options with their providers, optionally
+// only those marked active. Date ranges and states are left to the caller.
+func (r *FinancingRepo) ListOptions(ctx context.Context, companyID int, activeOnly bool) ([]*models.FinancingOption, error) {
+	var options []*models.FinancingOption
+	query := r.db.WithContext(ctx).Preload("Provider").Where("company_id = ?", companyID)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Order("name ASC").Find(&options).Error
+	return options, err
+}
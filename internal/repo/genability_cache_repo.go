@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GenabilityCacheRepo is a Postgres-backed client.Cache for Genability
+// responses.
+type GenabilityCacheRepo struct {
+	db *gorm.DB
+}
+
+func NewGenabilityCacheRepo(db *gorm.DB) *GenabilityCacheRepo {
+	return &GenabilityCacheRepo{db: db}
+}
+
+func (r *GenabilityCacheRepo) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var entry models.GenabilityCacheEntry
+	err := r.db.WithContext(ctx).Where("key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return entry.Value, true, nil
+}
+
+// Set stores value under key, replacing any value already there.
+func (r *GenabilityCacheRepo) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := models.GenabilityCacheEntry{Key: key, Value: value, ExpiresAt: time.Now().Add(ttl)}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, UpdateAll: true}).
+		Create(&entry).Error
+}
+
+func (r *GenabilityCacheRepo) Delete(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Where("key = ?", key).Delete(&models.GenabilityCacheEntry{}).Error
+}
+
+// DeleteExpired removes expired entries and returns how many it removed.
+func (r *GenabilityCacheRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.GenabilityCacheEntry{})
+	return result.RowsAffected, result.Error
+}
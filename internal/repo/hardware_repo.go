@@ -0,0 +1,188 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HardwareRepo struct {
+	db *gorm.DB
+}
+
+func NewHardwareRepo(db *gorm.DB) *HardwareRepo {
+	return &HardwareRepo{db: db}
+}
+
+func (r *HardwareRepo) CreatePanel(ctx context.Context, panel *models.Panel) error {
+	return r.db.WithContext(ctx).Create(panel).Error
+}
+
+func (r *HardwareRepo) GetPanelByID(ctx context.Context, id int) (*models.Panel, error) {
+	var panel models.Panel
+	err := r.db.WithContext(ctx).First(&panel, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPanelNotFound
+		}
+		return nil, err
+	}
+	return &panel, nil
+}
+
+func (r *HardwareRepo) GetPanelByExternalID(ctx context.Context, externalID int) (*models.Panel, error) {
+	var panel models.Panel
+	err := r.db.WithContext(ctx).Where("external_id = ?", externalID).First(&panel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPanelNotFound
+		}
+		return nil, err
+	}
+	return &panel, nil
+}
+
+func (r *HardwareRepo) UpdatePanel(ctx context.Context, panel *models.Panel) error {
+	return r.db.WithContext(ctx).Save(panel).Error
+}
+
+func (r *HardwareRepo) DeletePanel(ctx context.Context, id int) error {
+	return r.deleteItem(ctx, &models.Panel{}, models.HardwareTypePanel, id)
+}
+
+func (r *HardwareRepo) ListPanels(ctx context.Context, activeOnly bool) ([]*models.Panel, error) {
+	var panels []*models.Panel
+	err := r.listQuery(ctx, activeOnly).Find(&panels).Error
+	return panels, err
+}
+
+func (r *HardwareRepo) CreateInverter(ctx context.Context, inverter *models.Inverter) error {
+	return r.db.WithContext(ctx).Create(inverter).Error
+}
+
+func (r *HardwareRepo) GetInverterByID(ctx context.Context, id int) (*models.Inverter, error) {
+	var inverter models.Inverter
+	err := r.db.WithContext(ctx).First(&inverter, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInverterNotFound
+		}
+		return nil, err
+	}
+	return &inverter, nil
+}
+
+func (r *HardwareRepo) GetInverterByExternalID(ctx context.Context, externalID int) (*models.Inverter, error) {
+	var inverter models.Inverter
+	err := r.db.WithContext(ctx).Where("external_id = ?", externalID).First(&inverter).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInverterNotFound
+		}
+		return nil, err
+	}
+	return &inverter, nil
+}
+
+func (r *HardwareRepo) UpdateInverter(ctx context.Context, inverter *models.Inverter) error {
+	return r.db.WithContext(ctx).Save(inverter).Error
+}
+
+func (r *HardwareRepo) DeleteInverter(ctx context.Context, id int) error {
+	return r.deleteItem(ctx, &models.Inverter{}, models.HardwareTypeInverter, id)
+}
+
+func (r *HardwareRepo) ListInverters(ctx context.Context, activeOnly bool) ([]*models.Inverter, error) {
+	var inverters []*models.Inverter
+	err := r.listQuery(ctx, activeOnly).Find(&inverters).Error
+	return inverters, err
+}
+
+func (r *HardwareRepo) CreateBattery(ctx context.Context, battery *models.Battery) error {
+	return r.db.WithContext(ctx).Create(battery).Error
+}
+
+func (r *HardwareRepo) GetBatteryByID(ctx context.Context, id int) (*models.Battery, error) {
+	var battery models.Battery
+	err := r.db.WithContext(ctx).First(&battery, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBatteryNotFound
+		}
+		return nil, err
+	}
+	return &battery, nil
+}
+
+func (r *HardwareRepo) GetBatteryByExternalID(ctx context.Context, externalID int) (*models.Battery, error) {
+	var battery models.Battery
+	err := r.db.WithContext(ctx).Where("external_id = ?", externalID).First(&battery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBatteryNotFound
+		}
+		return nil, err
+	}
+	return &battery, nil
+}
+
+func (r *HardwareRepo) UpdateBattery(ctx context.Context, battery *models.Battery) error {
+	return r.db.WithContext(ctx).Save(battery).Error
+}
+
+func (r *HardwareRepo) DeleteBattery(ctx context.Context, id int) error {
+	return r.deleteItem(ctx, &models.Battery{}, models.HardwareTypeBattery, id)
+}
+
+func (r *HardwareRepo) ListBatteries(ctx context.Context, activeOnly bool) ([]*models.Battery, error) {
+	var batteries []*models.Battery
+	err := r.listQuery(ctx, activeOnly).Find(&batteries).Error
+	return batteries, err
+}
+
+// ListCompanyHardware returns a company's allowed list, optionally for a
+// single hardware type.
+func (r *HardwareRepo) ListCompanyHardware(ctx context.Context, companyID int, hardwareType string) ([]*models.CompanyHardware, error) {
+	var items []*models.CompanyHardware
+	query := r.db.WithContext(ctx).Where("company_id = ?", companyID)
+	if hardwareType != "" {
+		query = query.Where("hardware_type = ?", hardwareType)
+	}
+	err := query.Order("hardware_type ASC, hardware_id ASC").Find(&items).Error
+	return items, err
+}
+
+// ReplaceCompanyHardware swaps a company's allowed list for one hardware type.
+func (r *HardwareRepo) ReplaceCompanyHardware(ctx context.Context, companyID int, hardwareType string, items []*models.CompanyHardware) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("company_id = ? AND hardware_type = ?", companyID, hardwareType).
+			Delete(&models.CompanyHardware{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&items).Error
+	})
+}
+
+func (r *HardwareRepo) listQuery(ctx context.Context, activeOnly bool) *gorm.DB {
+	query := r.db.WithContext(ctx)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	return query.Order("is_default DESC, manufacturer ASC, model ASC")
+}
+
+func (r *HardwareRepo) deleteItem(ctx context.Context, model interface{}, hardwareType string, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("hardware_type = ? AND hardware_id = ?", hardwareType, id).
+			Delete(&models.CompanyHardware{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(model, id).Error
+	})
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+)
+
+type IncentiveRepo struct {
+	db *gorm.DB
+}
+
+func NewIncentiveRepo(db *gorm.DB) *IncentiveRepo {
+	return &IncentiveRepo{db: db}
+}
+
+func (r *IncentiveRepo) Create(ctx context.Context, incentive *models.Incentive) error {
+	return r.db.WithContext(ctx).Create(incentive).Error
+}
+
+func (r *IncentiveRepo) GetByID(ctx context.Context, id int) (*models.Incentive, error) {
+	var incentive models.Incentive
+	err := r.db.WithContext(ctx).First(&incentive, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrIncentiveNotFound
+		}
+		return nil, err
+	}
+	return &incentive, nil
+}
+
+func (r *IncentiveRepo) Update(ctx context.Context, incentive *models.Incentive) error {
+	return r.db.WithContext(ctx).Save(incentive).Error
+}
+
+func (r *IncentiveRepo) Delete(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Delete(&models.Incentive{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrIncentiveNotFound
+	}
+	return nil
+}
+
+// List returns the catalog, optionally only incentives of one type or
+// those marked active. Eligibility and date ranges are left to the caller.
+func (r *IncentiveRepo) List(ctx context.Context, incentiveType string, activeOnly bool) ([]*models.Incentive, error) {
+	var incentives []*models.Incentive
+	query := r.db.WithContext(ctx)
+	if incentiveType != "" {
+		query = query.Where("type = ?", incentiveType)
+	}
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Order("type ASC, name ASC").Find(&incentives).Error
+	return incentives, err
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LeadUsageRepo struct {
+	db *gorm.DB
+}
+
+func NewLeadUsageRepo(db *gorm.DB) *LeadUsageRepo {
+	return &LeadUsageRepo{db: db}
+}
+
+// Save stores a lead's usage, replacing any it already has.
+func (r *LeadUsageRepo) Save(ctx context.Context, usage *models.LeadUsage) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "lead_id"}}, UpdateAll: true}).
+		Create(usage).Error
+}
+
+func (r *LeadUsageRepo) GetByLeadID(ctx context.Context, leadID int) (*models.LeadUsage, error) {
+	var usage models.LeadUsage
+	err := r.db.WithContext(ctx).Where("lead_id = ?", leadID).First(&usage).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrLeadUsageNotFound
+		}
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (r *LeadUsageRepo) DeleteByLeadID(ctx context.Context, leadID int) error {
+	result := r.db.WithContext(ctx).Where("lead_id = ?", leadID).Delete(&models.LeadUsage{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrLeadUsageNotFound
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepo(db *gorm.DB) *NotificationRepo {
+	return &NotificationRepo{db: db}
+}
+
+// Claim reserves a notification before it is sent. It returns false when
+// the notification was already sent, is being sent, or has failed too many
+// times. A failed notification is claimed again for another attempt.
+func (r *NotificationRepo) Claim(ctx context.Context, entry *models.NotificationLog) (bool, error) {
+	entry.Status = models.NotificationStatusPending
+	entry.Attempts = 1
+
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).
+		Create(entry)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	result = r.db.WithContext(ctx).
+		Model(&models.NotificationLog{}).
+		Where("key = ? AND status = ? AND attempts < ?", entry.Key, models.NotificationStatusFailed, models.MaxNotificationAttempts).
+		Updates(map[string]interface{}{
+			"status":     models.NotificationStatusPending,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"recipient":  entry.Recipient,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("key = ?", entry.Key).First(entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *NotificationRepo) MarkSent(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.NotificationLog{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.NotificationStatusSent,
+			"sent_at":    time.Now(),
+			"error":      "",
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *NotificationRepo) MarkFailed(ctx context.Context, id int, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.NotificationLog{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.NotificationStatusFailed,
+			"error":      reason,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *NotificationRepo) ListByProposal(ctx context.Context, proposalID int) ([]*models.NotificationLog, error) {
+	var entries []*models.NotificationLog
+	err := r.db.WithContext(ctx).
+		Where("proposal_id = ?", proposalID).
+		Order("created_at DESC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetReminderSettings returns a company's proposal reminder settings, or
+// nil when the company has not configured any.
+func (r *NotificationRepo) GetReminderSettings(ctx context.Context, companyID int) (*models.ProposalReminderSettings, error) {
+	var settings models.ProposalReminderSettings
+	err := r.db.WithContext(ctx).Where("company_id = ?", companyID).First(&settings).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *NotificationRepo) SaveReminderSettings(ctx context.Context, settings *models.ProposalReminderSettings) error {
+	return r.db.WithContext(ctx).Save(settings).Error
+}
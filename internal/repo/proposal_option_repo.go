@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProposalOptionRepo struct {
+	db *gorm.DB
+}
+
+func NewProposalOptionRepo(db *gorm.DB) *ProposalOptionRepo {
+	return &ProposalOptionRepo{db: db}
+}
+
+// WithTx returns a copy of the repo that runs its queries in tx.
+func (r *ProposalOptionRepo) WithTx(tx *gorm.DB) *ProposalOptionRepo {
+	return &ProposalOptionRepo{db: tx}
+}
+
+// Create stores a new option together with its first version.
+func (r *ProposalOptionRepo) Create(ctx context.Context, option *models.ProposalOption, version *models.ProposalOptionVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		option.CurrentVersion = 1
+		if option.Status == "" {
+			option.Status = models.ProposalOptionStatusActive
+		}
+		if err := tx.Create(option).Error; err != nil {
+			return fmt.Errorf("failed to create option: %w", err)
+		}
+
+		version.OptionID = option.ID
+		version.ProposalID = option.ProposalID
+		version.Version = 1
+		if err := tx.Create(version).Error; err != nil {
+			return fmt.Errorf("failed to create option version: %w", err)
+		}
+		option.Version = version
+		return nil
+	})
+}
+
+// AddVersion stores version as the option's next version. The option row is
+// locked so concurrent edits get consecutive version numbers.
+func (r *ProposalOptionRepo) AddVersion(ctx context.Context, option *models.ProposalOption, label string, version *models.ProposalOptionVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.ProposalOption
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, option.ID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrProposalOptionNotFound
+			}
+			return err
+		}
+		if current.IsLocked() {
+			return models.ErrProposalOptionLocked
+		}
+
+		version.OptionID = current.ID
+		version.ProposalID = current.ProposalID
+		version.Version = current.CurrentVersion + 1
+		if err := tx.Create(version).Error; err != nil {
+			return fmt.Errorf("failed to create option version: %w", err)
+		}
+
+		current.CurrentVersion = version.Version
+		current.Label = label
+		if err := tx.Save(&current).Error; err != nil {
+			return fmt.Errorf("failed to update option: %w", err)
+		}
+
+		*option = current
+		option.Version = version
+		return nil
+	})
+}
+
+// Get returns an option of a proposal with its current version.
+func (r *ProposalOptionRepo) Get(ctx context.Context, proposalID, optionID int) (*models.ProposalOption, error) {
+	var option models.ProposalOption
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND proposal_id = ?", optionID, proposalID).
+		First(&option).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrProposalOptionNotFound
+		}
+		return nil, err
+	}
+
+	var version models.ProposalOptionVersion
+	err = r.db.WithContext(ctx).
+		Where("option_id = ? AND version = ?", option.ID, option.CurrentVersion).
+		First(&version).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load option version: %w", err)
+	}
+	option.Version = &version
+	return &option, nil
+}
+
+// List returns a proposal's options in display order with their current
+// versions.
+func (r *ProposalOptionRepo) List(ctx context.Context, proposalID int) ([]*models.ProposalOption, error) {
+	var options []*models.ProposalOption
+	err := r.db.WithContext(ctx).
+		Where("proposal_id = ?", proposalID).
+		Order("position, id").
+		Find(&options).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		return options, nil
+	}
+
+	var versions []*models.ProposalOptionVersion
+	err = r.db.WithContext(ctx).
+		Table("proposal_option_versions AS v").
+		Select("v.*").
+		Joins("JOIN proposal_options AS o ON o.id = v.option_id AND o.current_version = v.version").
+		Where("o.proposal_id = ?", proposalID).
+		Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load option versions: %w", err)
+	}
+
+	byOption := make(map[int]*models.ProposalOptionVersion, len(versions))
+	for _, v := range versions {
+		byOption[v.OptionID] = v
+	}
+	for _, option := range options {
+		option.Version = byOption[option.ID]
+	}
+	return options, nil
+}
+
+// ListVersions returns every version of an option, newest first.
+func (r *ProposalOptionRepo) ListVersions(ctx context.Context, optionID int) ([]*models.ProposalOptionVersion, error) {
+	var versions []*models.ProposalOptionVersion
+	err := r.db.WithContext(ctx).
+		Where("option_id = ?", optionID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+func (r *ProposalOptionRepo) Count(ctx context.Context, proposalID int) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.ProposalOption{}).
+		Where("proposal_id = ?", proposalID).
+		Count(&count).Error
+	return count, err
+}
+
+// Accept marks the option accepted and every other option of the proposal
+// superseded. Run it through WithTx to tie it to the proposal's acceptance.
+func (r *ProposalOptionRepo) Accept(ctx context.Context, option *models.ProposalOption) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.ProposalOption{}).
+			Where("id = ? AND status = ?", option.ID, models.ProposalOptionStatusActive).
+			Updates(map[string]interface{}{
+				"status":      models.ProposalOptionStatusAccepted,
+				"accepted_at": now,
+				"updated_at":  now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to accept option: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrProposalOptionLocked
+		}
+
+		err := tx.Model(&models.ProposalOption{}).
+			Where("proposal_id = ? AND id <> ?", option.ProposalID, option.ID).
+			Updates(map[string]interface{}{
+				"status":     models.ProposalOptionStatusSuperseded,
+				"updated_at": now,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("failed to supersede options: %w", err)
+		}
+
+		option.Status = models.ProposalOptionStatusAccepted
+		option.AcceptedAt = &now
+		return nil
+	})
+}
@@ -2,11 +2,25 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ProposalFilter narrows a proposal search. Nil fields are not filtered on.
+type ProposalFilter struct {
+	CompanyID   *int
+	SalesID     *int
+	HomeownerID *int
+	LeadID      *int
+	ProjectID   *int
+	Status      *models.ProposalStatus
+}
+
 type ProposalRepo struct {
 	db *gorm.DB
 }
@@ -15,6 +29,11 @@ func NewProposalRepo(db *gorm.DB) *ProposalRepo {
 	return &ProposalRepo{db: db}
 }
 
+// WithTx returns a copy of the repo that runs its queries in tx.
+func (r *ProposalRepo) WithTx(tx *gorm.DB) *ProposalRepo {
+	return &ProposalRepo{db: tx}
+}
+
 func (r *ProposalRepo) Create(ctx context.Context, proposal *models.Proposal) error {
 	return r.db.WithContext(ctx).Create(proposal).Error
 }
@@ -23,6 +42,23 @@ func (r *ProposalRepo) GetByID(ctx context.Context, id int) (*models.Proposal, e
 	var proposal models.Proposal
 	err := r.db.WithContext(ctx).First(&proposal, id).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrProposalNotFound
+		}
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// GetByIDForUpdate loads a proposal and locks its row until the surrounding
+// transaction ends.
+func (r *ProposalRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.Proposal, error) {
+	var proposal models.Proposal
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&proposal, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrProposalNotFound
+		}
 		return nil, err
 	}
 	return &proposal, nil
@@ -32,6 +68,9 @@ func (r *ProposalRepo) GetByCode(ctx context.Context, code string) (*models.Prop
 	var proposal models.Proposal
 	err := r.db.WithContext(ctx).Where("code = ?", code).First(&proposal).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrProposalNotFound
+		}
 		return nil, err
 	}
 	return &proposal, nil
@@ -41,8 +80,44 @@ func (r *ProposalRepo) Update(ctx context.Context, proposal *models.Proposal) er
 	return r.db.WithContext(ctx).Save(proposal).Error
 }
 
+// acceptedColumns are the columns an acceptance writes: the status and the
+// system and financials an accepted option copies onto the proposal.
+var acceptedColumns = []string{
+	"status", "accepted_at", "accepted_option_id", "updated_at",
+	"system_size", "panel_count", "panel_id", "inverter_id", "battery_count",
+	"annual_production", "annual_consumption",
+	"system_cost", "incentives", "net_cost", "monthly_payment", "loan_term_months", "cash_flow",
+	"financing_option_id", "financing_provider",
+	"current_utility_bill", "estimated_utility_bill",
+}
+
+// SaveAcceptance writes a proposal's acceptance and leaves its other
+// columns, such as its document, as they are in the database.
+func (r *ProposalRepo) SaveAcceptance(ctx context.Context, proposal *models.Proposal) error {
+	return r.db.WithContext(ctx).Model(proposal).Select(acceptedColumns).Updates(proposal).Error
+}
+
+// SaveDocument records a proposal's rendered document and leaves its other
+// columns as they are in the database.
+func (r *ProposalRepo) SaveDocument(ctx context.Context, id int, url, hash string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Proposal{ID: id}).
+		Updates(map[string]interface{}{
+			"document_url":  url,
+			"document_hash": hash,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
 func (r *ProposalRepo) Delete(ctx context.Context, id int) error {
-	return r.db.WithContext(ctx).Delete(&models.Proposal{}, id).Error
+	result := r.db.WithContext(ctx).Delete(&models.Proposal{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrProposalNotFound
+	}
+	return nil
 }
 
 func (r *ProposalRepo) List(ctx context.Context, limit, offset int) ([]*models.Proposal, error) {
@@ -105,3 +180,108 @@ func (r *ProposalRepo) ListByStatus(ctx context.Context, status models.ProposalS
 		Find(&proposals).Error
 	return proposals, err
 }
+
+// Search lists proposals matching the filter, newest first, with the total
+// number of matches.
+func (r *ProposalRepo) Search(ctx context.Context, filter ProposalFilter, limit, offset int) ([]*models.Proposal, int64, error) {
+	var proposals []*models.Proposal
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.Proposal{})
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
+	if filter.SalesID != nil {
+		query = query.Where("sales_id = ?", *filter.SalesID)
+	}
+	if filter.HomeownerID != nil {
+		query = query.Where("homeowner_id = ?", *filter.HomeownerID)
+	}
+	if filter.LeadID != nil {
+		query = query.Where("lead_id = ?", *filter.LeadID)
+	}
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count proposals: %w", err)
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&proposals).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list proposals: %w", err)
+	}
+
+	return proposals, total, nil
+}
+
+func (r *ProposalRepo) CreateView(ctx context.Context, view *models.ProposalView) error {
+	return r.db.WithContext(ctx).Create(view).Error
+}
+
+func (r *ProposalRepo) ListViews(ctx context.Context, proposalID int) ([]*models.ProposalView, error) {
+	var views []*models.ProposalView
+	err := r.db.WithContext(ctx).
+		Where("proposal_id = ?", proposalID).
+		Order("viewed_at DESC").
+		Find(&views).Error
+	return views, err
+}
+
+// ListOpen lists sent and viewed proposals.
+func (r *ProposalRepo) ListOpen(ctx context.Context) ([]*models.Proposal, error) {
+	var proposals []*models.Proposal
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", []models.ProposalStatus{models.ProposalStatusSent, models.ProposalStatusViewed}).
+		Order("id").
+		Find(&proposals).Error
+	return proposals, err
+}
+
+// ExpireOpen marks every open proposal whose expiry date has passed as
+// expired and returns how many were changed.
+func (r *ProposalRepo) ExpireOpen(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Proposal{}).
+		Where("status IN ? AND expires_at IS NOT NULL AND expires_at <= ?",
+			[]models.ProposalStatus{models.ProposalStatusSent, models.ProposalStatusViewed}, now).
+		Updates(map[string]interface{}{
+			"status":     models.ProposalStatusExpired,
+			"updated_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Expire marks a proposal expired if it is still open, leaving a proposal
+// answered in the meantime as it is.
+func (r *ProposalRepo) Expire(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Proposal{}).
+		Where("id = ? AND status IN ?", id,
+			[]models.ProposalStatus{models.ProposalStatusSent, models.ProposalStatusViewed}).
+		Updates(map[string]interface{}{
+			"status":     models.ProposalStatusExpired,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// MarkViewed moves a sent proposal to viewed. A proposal that has moved on
+// from sent in the meantime is left as it is.
+func (r *ProposalRepo) MarkViewed(ctx context.Context, id int, viewedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Proposal{}).
+		Where("id = ? AND status = ?", id, models.ProposalStatusSent).
+		Updates(map[string]interface{}{
+			"status":     models.ProposalStatusViewed,
+			"viewed_at":  viewedAt,
+			"updated_at": viewedAt,
+		}).Error
+}
@@ -2,11 +2,19 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
 	"gorm.io/gorm"
 )
 
+// QuoteFilter narrows a quote search. Nil fields are not filtered on.
+type QuoteFilter struct {
+	LeadID    *int
+	CompanyID *int
+}
+
 type QuoteRepo struct {
 	db *gorm.DB
 }
@@ -15,6 +23,50 @@ func NewQuoteRepo(db *gorm.DB) *QuoteRepo {
 	return &QuoteRepo{db: db}
 }
 
-func (r *QuoteRepo) Create(ctx context.Context, deal *models.Deal) error {
-	return r.db.WithContext(ctx).Create(deal).Error
+func (r *QuoteRepo) Create(ctx context.Context, quote *models.Quote) error {
+	return r.db.WithContext(ctx).Create(quote).Error
+}
+
+func (r *QuoteRepo) GetByID(ctx context.Context, id int) (*models.Quote, error) {
+	var quote models.Quote
+	err := r.db.WithContext(ctx).First(&quote, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrQuoteNotFound
+		}
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (r *QuoteRepo) Update(ctx context.Context, quote *models.Quote) error {
+	return r.db.WithContext(ctx).Save(quote).Error
+}
+
+// Search returns one page of quotes matching filter, newest first, and the
+// total number of matches.
+func (r *QuoteRepo) Search(ctx context.Context, filter QuoteFilter, limit, offset int) ([]*models.Quote, int64, error) {
+	var quotes []*models.Quote
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.Quote{})
+	if filter.LeadID != nil {
+		query = query.Where("lead_id = ?", *filter.LeadID)
+	}
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count quotes: %w", err)
+	}
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&quotes).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list quotes: %w", err)
+	}
+	return quotes, total, nil
 }
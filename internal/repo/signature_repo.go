@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SignatureRepo struct {
+	db *gorm.DB
+}
+
+func NewSignatureRepo(db *gorm.DB) *SignatureRepo {
+	return &SignatureRepo{db: db}
+}
+
+// WithTx returns a copy of the repo that runs its queries in tx.
+func (r *SignatureRepo) WithTx(tx *gorm.DB) *SignatureRepo {
+	return &SignatureRepo{db: tx}
+}
+
+func (r *SignatureRepo) Create(ctx context.Context, request *models.SignatureRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *SignatureRepo) GetByID(ctx context.Context, id int) (*models.SignatureRequest, error) {
+	var request models.SignatureRequest
+	err := r.db.WithContext(ctx).First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrSignatureRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetByIDForUpdate loads a request and locks its row until the surrounding
+// transaction ends. Use it through WithTx.
+func (r *SignatureRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.SignatureRequest, error) {
+	var request models.SignatureRequest
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrSignatureRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *SignatureRepo) GetByToken(ctx context.Context, token string) (*models.SignatureRequest, error) {
+	var request models.SignatureRequest
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrSignatureRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *SignatureRepo) Update(ctx context.Context, request *models.SignatureRequest) error {
+	return r.db.WithContext(ctx).Save(request).Error
+}
+
+// ListByDeal returns a deal's signature requests, newest first.
+func (r *SignatureRepo) ListByDeal(ctx context.Context, dealID int) ([]*models.SignatureRequest, error) {
+	var requests []*models.SignatureRequest
+	err := r.db.WithContext(ctx).
+		Where("deal_id = ?", dealID).
+		Order("created_at DESC").
+		Find(&requests).Error
+	return requests, err
+}
+
+// AppendEvent adds event to the end of a request's audit trail and fills in
+// its hashes. The request row is locked so concurrent events are chained one
+// after the other instead of both chaining from the same predecessor.
+func (r *SignatureRepo) AppendEvent(ctx context.Context, event *models.SignatureEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var request models.SignatureRequest
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, event.RequestID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrSignatureRequestNotFound
+			}
+			return err
+		}
+
+		prevHash := request.DocumentHash
+		var last models.SignatureEvent
+		err = tx.Where("request_id = ?", event.RequestID).Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return fmt.Errorf("failed to load last signature event: %w", err)
+		}
+		if last.ID != 0 {
+			prevHash = last.Hash
+		}
+
+		event.PrevHash = prevHash
+		event.Hash = event.ComputeHash()
+		if err := tx.Create(event).Error; err != nil {
+			return fmt.Errorf("failed to record signature event: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetCodeForUpdate loads the code last sent for a request and locks its row
+// until the surrounding transaction ends. It returns nil when no code was
+// ever sent. Use it through WithTx.
+func (r *SignatureRepo) GetCodeForUpdate(ctx context.Context, requestID int) (*models.SignatureCode, error) {
+	var code models.SignatureCode
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("request_id = ?", requestID).
+		First(&code).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *SignatureRepo) SaveCode(ctx context.Context, code *models.SignatureCode) error {
+	return r.db.WithContext(ctx).Save(code).Error
+}
+
+// DeleteCodesBefore deletes codes last changed before cutoff and returns how
+// many were deleted.
+func (r *SignatureRepo) DeleteCodesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("updated_at < ?", cutoff).Delete(&models.SignatureCode{})
+	return result.RowsAffected, result.Error
+}
+
+// ListEvents returns a request's audit trail in the order it was recorded.
+func (r *SignatureRepo) ListEvents(ctx context.Context, requestID int) ([]*models.SignatureEvent, error) {
+	var events []*models.SignatureEvent
+	err := r.db.WithContext(ctx).
+		Where("request_id = ?", requestID).
+		Order("id").
+		Find(&events).Error
+	return events, err
+}
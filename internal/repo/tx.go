@@ -0,0 +1,23 @@
+package repo
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// TxManager runs work that spans several repos in one database transaction.
+// Repos take part in it through their WithTx method.
+type TxManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// Do runs fn in a transaction, committing when it returns nil and rolling
+// back otherwise.
+func (m *TxManager) Do(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(fn)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"gorm.io/gorm"
+)
+
+type AdderService struct {
+	adderRepo *repo.AdderRepo
+	leadRepo  *repo.LeadRepo
+	dealRepo  *repo.DealRepo
+}
+
+// AdderSelection is a manual adder picked by the rep for a deal.
+type AdderSelection struct {
+	AdderID     int      `json:"adder_id" example:"1"`
+	Quantity    int      `json:"quantity" example:"1"`
+	CustomPrice *float64 `json:"custom_price,omitempty" example:"2000.00"`
+}
+
+// ApplicableAdders splits the adders available for a system into those that
+// are applied automatically and those the rep may add by hand.
+type ApplicableAdders struct {
+	State        string          `json:"state" example:"CA"`
+	SystemSizeKW float64         `json:"system_size_kw" example:"10.5"`
+	Automatic    []*models.Adder `json:"automatic"`
+	Manual       []*models.Adder `json:"manual"`
+}
+
+func NewAdderService(adderRepo *repo.AdderRepo, leadRepo *repo.LeadRepo, dealRepo *repo.DealRepo) *AdderService {
+	return &AdderService{
+		adderRepo: adderRepo,
+		leadRepo:  leadRepo,
+		dealRepo:  dealRepo,
+	}
+}
+
+func (s *AdderService) CreateCategory(ctx context.Context, category *models.AdderCategory) error {
+	if err := category.Validate(); err != nil {
+		return err
+	}
+	return s.adderRepo.CreateCategory(ctx, category)
+}
+
+func (s *AdderService) GetCategoryByID(ctx context.Context, id int) (*models.AdderCategory, error) {
+	return s.adderRepo.GetCategoryByID(ctx, id)
+}
+
+func (s *AdderService) UpdateCategory(ctx context.Context, category *models.AdderCategory) error {
+	if err := category.Validate(); err != nil {
+		return err
+	}
+	return s.adderRepo.UpdateCategory(ctx, category)
+}
+
+func (s *AdderService) DeleteCategory(ctx context.Context, id int) error {
+	return s.adderRepo.DeleteCategory(ctx, id)
+}
+
+func (s *AdderService) ListCategories(ctx context.Context, companyID int) ([]*models.AdderCategory, error) {
+	return s.adderRepo.ListCategories(ctx, companyID)
+}
+
+func (s *AdderService) Create(ctx context.Context, adder *models.Adder) error {
+	if err := s.validate(ctx, adder); err != nil {
+		return err
+	}
+	return s.adderRepo.Create(ctx, adder)
+}
+
+func (s *AdderService) GetByID(ctx context.Context, id int) (*models.Adder, error) {
+	return s.adderRepo.GetByID(ctx, id)
+}
+
+func (s *AdderService) Update(ctx context.Context, adder *models.Adder) error {
+	if err := s.validate(ctx, adder); err != nil {
+		return err
+	}
+	return s.adderRepo.Update(ctx, adder)
+}
+
+func (s *AdderService) Delete(ctx context.Context, id int) error {
+	return s.adderRepo.Delete(ctx, id)
+}
+
+func (s *AdderService) ListByCompany(ctx context.Context, companyID int, activeOnly bool) ([]*models.Adder, error) {
+	return s.adderRepo.ListByCompany(ctx, companyID, activeOnly)
+}
+
+func (s *AdderService) ListVersions(ctx context.Context, adderID int) ([]*models.AdderVersion, error) {
+	return s.adderRepo.ListVersions(ctx, adderID)
+}
+
+func (s *AdderService) ListDealAdders(ctx context.Context, dealID int) ([]*models.DealAdder, error) {
+	return s.adderRepo.ListDealAdders(ctx, dealID)
+}
+
+func (s *AdderService) validate(ctx context.Context, adder *models.Adder) error {
+	if err := adder.Validate(); err != nil {
+		return err
+	}
+	if adder.CategoryID != nil {
+		category, err := s.adderRepo.GetCategoryByID(ctx, *adder.CategoryID)
+		if err != nil {
+			return err
+		}
+		if category.CompanyID != adder.CompanyID {
+			return models.ErrAdderCategoryNotFound
+		}
+	}
+	return nil
+}
+
+// Applicable evaluates the company catalog for a system of the given size in
+// the given state.
+func (s *AdderService) Applicable(ctx context.Context, companyID int, state string, systemSizeKW float64) (*ApplicableAdders, error) {
+	adders, err := s.adderRepo.ListByCompany(ctx, companyID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list adders: %w", err)
+	}
+
+	result := &ApplicableAdders{
+		State:        state,
+		SystemSizeKW: systemSizeKW,
+		Automatic:    []*models.Adder{},
+		Manual:       []*models.Adder{},
+	}
+	for _, adder := range adders {
+		if !adder.AvailableFor(state, systemSizeKW) {
+			continue
+		}
+		if adder.IsAutomatic {
+			result.Automatic = append(result.Automatic, adder)
+		} else {
+			result.Manual = append(result.Manual, adder)
+		}
+	}
+	return result, nil
+}
+
+func (s *AdderService) ApplicableForLead(ctx context.Context, leadID int) (*ApplicableAdders, error) {
+	lead, err := s.leadRepo.GetByID(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Applicable(ctx, lead.CompanyID, stateFromAddress(lead.Address), lead.SystemSize)
+}
+
+func (s *AdderService) ApplicableForDeal(ctx context.Context, dealID int) (*ApplicableAdders, error) {
+	deal, err := s.dealRepo.GetByID(ctx, dealID)
+	if err != nil {
+		return nil, models.ErrDealNotFound
+	}
+	return s.Applicable(ctx, deal.CompanyID, stateFromAddress(deal.Address), deal.SystemSize)
+}
+
+// ResolveDealAdders returns the adder lines a deal should be priced with:
+// every automatic adder that applies plus the manual selections. Adders the
+// deal was already sold with keep their recorded version and price unless
+// refresh is set, so catalog edits never reprice existing deals silently.
+func (s *AdderService) ResolveDealAdders(ctx context.Context, deal *models.Deal, state string, selections []AdderSelection, refresh bool) ([]*models.DealAdder, error) {
+	existing, err := s.adderRepo.ListDealAdders(ctx, deal.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deal adders: %w", err)
+	}
+	if len(existing) > 0 && len(selections) == 0 && !refresh {
+		return existing, nil
+	}
+
+	locked := make(map[int]*models.DealAdder, len(existing))
+	if !refresh {
+		for _, line := range existing {
+			locked[line.AdderID] = line
+		}
+	}
+
+	catalog, err := s.adderRepo.ListByCompany(ctx, deal.CompanyID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list adders: %w", err)
+	}
+	byID := make(map[int]*models.Adder, len(catalog))
+	for _, adder := range catalog {
+		byID[adder.ID] = adder
+	}
+
+	var lines []*models.DealAdder
+	seen := make(map[int]int)
+	add := func(line *models.DealAdder) {
+		if i, ok := seen[line.AdderID]; ok {
+			lines[i] = line
+			return
+		}
+		seen[line.AdderID] = len(lines)
+		lines = append(lines, line)
+	}
+
+	for _, adder := range catalog {
+		if !adder.IsAutomatic {
+			continue
+		}
+		if line, ok := locked[adder.ID]; ok && line.IsAutomatic {
+			add(line)
+			continue
+		}
+		if adder.AvailableFor(state, deal.SystemSize) {
+			add(dealAdderFromCatalog(adder, 1, nil))
+		}
+	}
+
+	// Manual adders already on the deal stay unless the rep re-selects.
+	if len(selections) == 0 {
+		for _, line := range existing {
+			if !line.IsAutomatic && !refresh {
+				add(line)
+			}
+		}
+	}
+
+	for _, sel := range selections {
+		if sel.Quantity <= 0 {
+			continue
+		}
+		adder, ok := byID[sel.AdderID]
+		if !ok || !adder.AvailableFor(state, deal.SystemSize) {
+			return nil, fmt.Errorf("%w: %d", models.ErrAdderNotAvailable, sel.AdderID)
+		}
+		line := dealAdderFromCatalog(adder, sel.Quantity, sel.CustomPrice)
+		if prev, ok := locked[adder.ID]; ok {
+			line.AdderVersion = prev.AdderVersion
+			line.Name = prev.Name
+			line.Cost = prev.Cost
+			line.CostType = prev.CostType
+		}
+		add(line)
+	}
+
+	return lines, nil
+}
+
+// SaveDealAdders records the adder lines a deal was priced with, in tx.
+func (s *AdderService) SaveDealAdders(ctx context.Context, tx *gorm.DB, dealID int, lines []*models.DealAdder) error {
+	fresh := make([]*models.DealAdder, 0, len(lines))
+	for _, line := range lines {
+		copied := *line
+		copied.ID = 0
+		fresh = append(fresh, &copied)
+	}
+	return s.adderRepo.WithTx(tx).ReplaceDealAdders(ctx, dealID, fresh)
+}
+
+func dealAdderFromCatalog(adder *models.Adder, quantity int, customPrice *float64) *models.DealAdder {
+	return &models.DealAdder{
+		AdderID:      adder.ID,
+		AdderVersion: adder.Version,
+		Name:         adder.Name,
+		Cost:         adder.Cost,
+		CostType:     adder.CostType,
+		Quantity:     quantity,
+		CustomPrice:  customPrice,
+		IsAutomatic:  adder.IsAutomatic,
+	}
+}
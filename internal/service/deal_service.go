@@ -8,17 +8,24 @@ import (
 )
 
 type DealService struct {
-	dealRepo *repo.DealRepo
+	dealRepo        *repo.DealRepo
+	hardwareService *HardwareService
 }
 
-func NewDealService(dealRepo *repo.DealRepo) *DealService {
-	return &DealService{dealRepo: dealRepo}
+func NewDealService(dealRepo *repo.DealRepo, hardwareService *HardwareService) *DealService {
+	return &DealService{
+		dealRepo:        dealRepo,
+		hardwareService: hardwareService,
+	}
 }
 
 func (s *DealService) Create(ctx context.Context, deal *models.Deal) error {
 	if err := deal.Validate(); err != nil {
 		return err
 	}
+	if err := s.validateHardware(ctx, deal); err != nil {
+		return err
+	}
 	return s.dealRepo.Create(ctx, deal)
 }
 
@@ -34,6 +41,17 @@ func (s *DealService) Update(ctx context.Context, deal *models.Deal) error {
 	if err := deal.Validate(); err != nil {
 		return err
 	}
+	existing, err := s.dealRepo.GetByID(ctx, deal.ID)
+	if err != nil {
+		return err
+	}
+	// Hardware that was allowed when the deal was sold stays valid even if
+	// the company later drops it, so only a changed selection is checked.
+	if !sameID(existing.PanelID, deal.PanelID) || !sameID(existing.InverterID, deal.InverterID) {
+		if err := s.validateHardware(ctx, deal); err != nil {
+			return err
+		}
+	}
 	return s.dealRepo.Update(ctx, deal)
 }
 
@@ -72,3 +90,17 @@ func (s *DealService) Archive(ctx context.Context, id int) error {
 func (s *DealService) Unarchive(ctx context.Context, id int) error {
 	return s.dealRepo.Unarchive(ctx, id)
 }
+
+func (s *DealService) validateHardware(ctx context.Context, deal *models.Deal) error {
+	return s.hardwareService.ValidateSelection(ctx, deal.CompanyID, HardwareSelection{
+		PanelID:    deal.PanelID,
+		InverterID: deal.InverterID,
+	})
+}
+
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/design"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/production"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+// Site temperatures used when the request gives none and there is no
+// weather near the site.
+const (
+	defaultSiteMinTempC = -10
+	defaultSiteMaxTempC = 35
+)
+
+// DesignService checks the electrical design of a lead or deal before it
+// goes to permitting.
+type DesignService struct {
+	leadRepo        *repo.LeadRepo
+	dealRepo        *repo.DealRepo
+	hardwareService *HardwareService
+	weather         *production.Library
+}
+
+// DesignCheckInput overrides what a check would otherwise work out: the
+// site's temperature extremes, from the nearest weather station, and the
+// string layout, laid out evenly over the inverters.
+type DesignCheckInput struct {
+	MinTempC *float64        `json:"min_temp_c,omitempty" example:"-12"`
+	MaxTempC *float64        `json:"max_temp_c,omitempty" example:"38"`
+	Strings  []design.String `json:"strings,omitempty"`
+}
+
+// DesignCheck is a design report with the system it was run for.
+type DesignCheck struct {
+	*design.Report
+	LeadID        *int `json:"lead_id,omitempty" example:"1"`
+	DealID        *int `json:"deal_id,omitempty" example:"1"`
+	PanelID       int  `json:"panel_id" example:"1"`
+	InverterID    int  `json:"inverter_id" example:"1"`
+	PanelCount    int  `json:"panel_count" example:"24"`
+	InverterCount int  `json:"inverter_count" example:"1"`
+}
+
+// designedSystem is what a lead or deal says about its system.
+type designedSystem struct {
+	panelID, inverterID *int
+	panelCount          int
+	inverterCount       int
+	systemSizeKW        float64
+	location            *[2]float64
+}
+
+func NewDesignService(leadRepo *repo.LeadRepo, dealRepo *repo.DealRepo, hardwareService *HardwareService, weather *production.Library) *DesignService {
+	return &DesignService{leadRepo: leadRepo, dealRepo: dealRepo, hardwareService: hardwareService, weather: weather}
+}
+
+// CheckLead checks a lead's design.
+func (s *DesignService) CheckLead(ctx context.Context, leadID int, input DesignCheckInput) (*DesignCheck, error) {
+	lead, err := s.leadRepo.GetByID(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.check(ctx, leadSystem(lead), input)
+	if err != nil {
+		return nil, err
+	}
+	result.LeadID = &lead.ID
+	return result, nil
+}
+
+// CheckDeal checks a deal's design. The deal's lead, if any, gives the
+// site and inverter count.
+func (s *DesignService) CheckDeal(ctx context.Context, dealID int, input DesignCheckInput) (*DesignCheck, error) {
+	deal, err := s.dealRepo.GetByID(ctx, dealID)
+	if err != nil {
+		return nil, err
+	}
+	system := designedSystem{
+		panelID:      deal.PanelID,
+		inverterID:   deal.InverterID,
+		panelCount:   deal.PanelCount,
+		systemSizeKW: deal.SystemSize,
+	}
+	if deal.LeadID != nil {
+		lead, err := s.leadRepo.GetByID(ctx, *deal.LeadID)
+		if err != nil {
+			return nil, err
+		}
+		fromLead := leadSystem(lead)
+		system.location = fromLead.location
+		if system.inverterID != nil && lead.InverterID != nil && *lead.InverterID == *system.inverterID {
+			system.inverterCount = lead.InverterCount
+		}
+	}
+	result, err := s.check(ctx, system, input)
+	if err != nil {
+		return nil, err
+	}
+	result.DealID = &deal.ID
+	result.LeadID = deal.LeadID
+	return result, nil
+}
+
+func leadSystem(lead *models.Lead) designedSystem {
+	system := designedSystem{
+		panelID:       lead.PanelID,
+		inverterID:    lead.InverterID,
+		panelCount:    lead.PanelCount,
+		inverterCount: lead.InverterCount,
+		systemSizeKW:  lead.SystemSize,
+	}
+	if lead.Latitude != 0 || lead.Longitude != 0 {
+		system.location = &[2]float64{lead.Latitude, lead.Longitude}
+	}
+	return system
+}
+
+func (s *DesignService) check(ctx context.Context, system designedSystem, input DesignCheckInput) (*DesignCheck, error) {
+	if system.panelID == nil || system.inverterID == nil {
+		return nil, fmt.Errorf("%w: a panel and inverter must be selected", design.ErrInvalidDesign)
+	}
+	panel, err := s.hardwareService.GetPanel(ctx, *system.panelID)
+	if err != nil {
+		return nil, err
+	}
+	inverter, err := s.hardwareService.GetInverter(ctx, *system.inverterID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := design.Design{
+		Panel: design.Panel{
+			PowerW:        panel.Power,
+			Voc:           panel.OpenCircuitVoltage,
+			Isc:           panel.ShortCircuitCurrent,
+			Vmp:           panel.MaxPowerVoltage,
+			Imp:           panel.MaxPowerCurrent,
+			TempCoeffVoc:  panel.TempCoeffVoc,
+			TempCoeffPmax: panel.TempCoeffPmax,
+			NOCT:          panel.NOCT,
+		},
+		Inverter: design.Inverter{
+			ACWatts:                inverter.Capacity,
+			MaxDCVoltage:           inverter.MaxDCVoltage,
+			MPPTMinVoltage:         inverter.MPPTMinVoltage,
+			MPPTMaxVoltage:         inverter.MPPTMaxVoltage,
+			MPPTCount:              inverter.MPPTCount,
+			MaxInputCurrentPerMPPT: inverter.MaxInputCurrentPerMPPT,
+			MaxDCPower:             inverter.MaxDCPower,
+			Micro:                  inverter.IsMicro(),
+		},
+		PanelCount:    system.panelCount,
+		InverterCount: system.inverterCount,
+		Strings:       input.Strings,
+		Site:          design.Site{MinTempC: defaultSiteMinTempC, MaxTempC: defaultSiteMaxTempC},
+	}
+	if d.PanelCount == 0 && system.systemSizeKW > 0 {
+		d.PanelCount = int(math.Round(system.systemSizeKW * 1000 / panel.Power))
+	}
+
+	var weather *production.Weather
+	if system.location != nil {
+		weather, _, err = s.weather.Nearest(system.location[0], system.location[1])
+		if err != nil && !errors.Is(err, production.ErrNoWeather) {
+			return nil, err
+		}
+	}
+	siteSource := "defaults"
+	if weather != nil {
+		d.Site.MinTempC, d.Site.MaxTempC = weather.TemperatureRange()
+		siteSource = "typical-year weather at " + weather.Station
+	}
+	if input.MinTempC != nil {
+		d.Site.MinTempC = *input.MinTempC
+	}
+	if input.MaxTempC != nil {
+		d.Site.MaxTempC = *input.MaxTempC
+	}
+
+	if weather != nil && d.PanelCount > 0 && panel.Power > 0 && inverter.Capacity > 0 {
+		estimate, err := production.Run(weather, production.System{
+			SizeKW:     float64(d.PanelCount) * panel.Power / 1000,
+			TiltDeg:    production.DefaultTiltDeg,
+			AzimuthDeg: production.DefaultAzimuthDeg,
+			Module: production.Module{
+				TempCoeffPmax: panel.TempCoeffPmax,
+				NOCT:          panel.NOCT,
+				Efficiency:    panel.Efficiency,
+			},
+			InverterEfficiency: inverter.Efficiency / 100,
+			InverterKW:         float64(d.Inverters()) * inverter.Capacity / 1000,
+		})
+		if err != nil {
+			return nil, err
+		}
+		d.ClippingLossKWh, d.AnnualKWh = estimate.ClippingLossKWh, estimate.AnnualKWh
+	}
+
+	report, err := design.Check(d)
+	if err != nil {
+		return nil, err
+	}
+	if input.MinTempC == nil || input.MaxTempC == nil {
+		severity := design.SeverityInfo
+		if weather == nil {
+			severity = design.SeverityWarning
+		}
+		report.Add(design.CodeSiteTemperatures, severity, "NEC 690.7(A)",
+			"Site temperatures of %.0f°C to %.0f°C come from %s; confirm them against ASHRAE design data for permitting",
+			d.Site.MinTempC, d.Site.MaxTempC, siteSource)
+	}
+	return &DesignCheck{
+		Report:        report,
+		PanelID:       panel.ID,
+		InverterID:    inverter.ID,
+		PanelCount:    d.PanelCount,
+		InverterCount: d.Inverters(),
+	}, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB is a Postgres stand-in that records the statements gorm sends it.
+// Selects find nothing and inserts return ID 1, which is enough to drive a
+// service through its create paths without a database.
+type fakeDB struct {
+	mu    sync.Mutex
+	execs []fakeStatement
+}
+
+type fakeStatement struct {
+	SQL  string
+	Args []any
+}
+
+// openFakeDB returns a gorm handle on a new fakeDB.
+func openFakeDB(t *testing.T) (*gorm.DB, *fakeDB) {
+	t.Helper()
+	f := &fakeDB{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(f)}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open fake database: %v", err)
+	}
+	return db, f
+}
+
+// inserted returns the column values of the statements inserting into table.
+func (f *fakeDB) inserted(table string) []map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []map[string]any
+	prefix := `INSERT INTO "` + table + `" (`
+	for _, s := range f.execs {
+		if !strings.HasPrefix(s.SQL, prefix) {
+			continue
+		}
+		columns := strings.Split(s.SQL[len(prefix):strings.Index(s.SQL, ") VALUES")], ",")
+		row := make(map[string]any, len(columns))
+		for i, c := range columns {
+			if i < len(s.Args) {
+				row[strings.Trim(c, `"`)] = s.Args[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) {
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	f.mu.Lock()
+	f.execs = append(f.execs, fakeStatement{SQL: query, Args: values})
+	f.mu.Unlock()
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+var returningColumns = regexp.MustCompile(`RETURNING (.+)$`)
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query, args)
+	m := returningColumns.FindStringSubmatch(query)
+	if m == nil {
+		return &fakeRows{}, nil
+	}
+	var columns []string
+	for _, col := range strings.Split(m[1], ",") {
+		columns = append(columns, strings.Trim(strings.TrimSpace(col), `"`))
+	}
+	return &fakeRows{columns: columns, left: 1}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows is no rows, or one row of 1s for an insert's returned columns.
+type fakeRows struct {
+	columns []string
+	left    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	r.left--
+	for i := range dest {
+		dest[i] = int64(1)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+type FinancingService struct {
+	financingRepo *repo.FinancingRepo
+}
+
+func NewFinancingService(financingRepo *repo.FinancingRepo) *FinancingService {
+	return &FinancingService{financingRepo: financingRepo}
+}
+
+func (s *FinancingService) CreateProvider(ctx context.Context, provider *models.FinancingProvider) error {
+	if err := provider.Validate(); err != nil {
+		return err
+	}
+	return s.financingRepo.CreateProvider(ctx, provider)
+}
+
+func (s *FinancingService) GetProviderByID(ctx context.Context, id int) (*models.FinancingProvider, error) {
+	return s.financingRepo.GetProviderByID(ctx, id)
+}
+
+func (s *FinancingService) UpdateProvider(ctx context.Context, provider *models.FinancingProvider) error {
+	if err := provider.Validate(); err != nil {
+		return err
+	}
+	return s.financingRepo.UpdateProvider(ctx, provider)
+}
+
+func (s *FinancingService) DeleteProvider(ctx context.Context, id int) error {
+	return s.financingRepo.DeleteProvider(ctx, id)
+}
+
+func (s *FinancingService) ListProviders(ctx context.Context, companyID int) ([]*models.FinancingProvider, error) {
+	return s.financingRepo.ListProviders(ctx, companyID)
+}
+
+func (s *FinancingService) CreateOption(ctx context.Context, option *models.FinancingOption) error {
+	if err := s.validateOption(ctx, option); err != nil {
+		return err
+	}
+	return s.financingRepo.CreateOption(ctx, option)
+}
+
+func (s *FinancingService) GetOptionByID(ctx context.Context, id int) (*models.FinancingOption, error) {
+	return s.financingRepo.GetOptionByID(ctx, id)
+}
+
+// GetSoldOptionByID returns an option a deal was sold with, even if it has
+// since been deleted from the catalog.
+func (s *FinancingService) GetSoldOptionByID(ctx context.Context, id int) (*models.FinancingOption, error) {
+	return s.financingRepo.GetSoldOptionByID(ctx, id)
+}
+
+func (s *FinancingService) UpdateOption(ctx context.Context, option *models.FinancingOption) error {
+	if err := s.validateOption(ctx, option); err != nil {
+		return err
+	}
+	return s.financingRepo.UpdateOption(ctx, option)
+}
+
+func (s *FinancingService) DeleteOption(ctx context.Context, id int) error {
+	return s.financingRepo.DeleteOption(ctx, id)
+}
+
+func (s *FinancingService) ListOptions(ctx context.Context, companyID int, activeOnly bool) ([]*models.FinancingOption, error) {
+	return s.financingRepo.ListOptions(ctx, companyID, activeOnly)
+}
+
+// Available returns the options a company can offer in a state at a time.
+func (s *FinancingService) Available(ctx context.Context, companyID int, state string, at time.Time) ([]*models.FinancingOption, error) {
+	options, err := s.financingRepo.ListOptions(ctx, companyID, true)
+	if err != nil {
+		return nil, err
+	}
+	available := make([]*models.FinancingOption, 0, len(options))
+	for _, option := range options {
+		if option.AvailableFor(state, at) {
+			available = append(available, option)
+		}
+	}
+	return available, nil
+}
+
+// Resolve returns a company's option by ID if it can be offered in a state
+// at a time.
+func (s *FinancingService) Resolve(ctx context.Context, companyID, optionID int, state string, at time.Time) (*models.FinancingOption, error) {
+	option, err := s.financingRepo.GetOptionByID(ctx, optionID)
+	if err != nil {
+		return nil, err
+	}
+	if option.CompanyID != companyID || !option.AvailableFor(state, at) {
+		return nil, models.ErrFinancingOptionNotAvailable
+	}
+	return option, nil
+}
+
+func (s *FinancingService) validateOption(ctx context.Context, option *models.FinancingOption) error {
+	if err := option.Validate(); err != nil {
+		return err
+	}
+	provider, err := s.financingRepo.GetProviderByID(ctx, option.ProviderID)
+	if err != nil {
+		return err
+	}
+	if provider.CompanyID != option.CompanyID {
+		return models.ErrFinancingProviderNotFound
+	}
+	return nil
+}
+
+// pricingFinancingOption converts a catalog option to the form the pricing
+// engine grosses dealer fees up from.
+func pricingFinancingOption(option *models.FinancingOption) *client.FinancingOption {
+	return &client.FinancingOption{
+		ID:           option.ID,
+		Name:         option.Name,
+		InterestRate: option.InterestRate,
+		Duration:     option.TermMonths,
+		LoanFee:      option.LoanFee,
+		LoanFeeFixed: option.LoanFeeFixed,
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+)
+
+var (
+	ErrGenabilityUnavailable    = errors.New("genability is not configured")
+	ErrInvalidGenabilityRequest = errors.New("invalid genability request")
+)
+
+// GenabilityService looks up utilities and tariffs and prices bills on
+// them through the Genability API.
+type GenabilityService struct {
+	accounts   *client.Accounts
+	lses       *client.LSEs
+	tariffs    *client.Tariffs
+	calculator *client.Calculator
+}
+
+// NewGenabilityService returns a service on agent. With a nil agent every
+// call fails with ErrGenabilityUnavailable.
+func NewGenabilityService(agent *client.Agent) *GenabilityService {
+	if agent == nil {
+		return &GenabilityService{}
+	}
+	return &GenabilityService{
+		accounts:   client.NewAccounts(agent),
+		lses:       client.NewLSEs(agent),
+		tariffs:    client.NewTariffs(agent),
+		calculator: client.NewCalculator(agent),
+	}
+}
+
+func (s *GenabilityService) available() error {
+	if s.calculator == nil {
+		return ErrGenabilityUnavailable
+	}
+	return nil
+}
+
+// ListLSEs lists the utilities serving residential electricity in a zip
+// code. Country defaults to US.
+func (s *GenabilityService) ListLSEs(ctx context.Context, zip, country string) ([]client.LoadServingEntity, error) {
+	if err := s.available(); err != nil {
+		return nil, err
+	}
+	if zip == "" {
+		return nil, fmt.Errorf("%w: zip is required", ErrInvalidGenabilityRequest)
+	}
+	if country == "" {
+		country = "US"
+	}
+	return s.lses.Index(ctx, zip, country)
+}
+
+// SearchTariffs lists active tariffs by zip code, customer class and
+// utility. A zip code or a utility is required.
+func (s *GenabilityService) SearchTariffs(ctx context.Context, search client.TariffSearch) ([]client.Tariff, error) {
+	if err := s.available(); err != nil {
+		return nil, err
+	}
+	if search.ZipCode == "" && search.LseID == 0 {
+		return nil, fmt.Errorf("%w: zip or lse_id is required", ErrInvalidGenabilityRequest)
+	}
+	if search.CustomerClass != "" {
+		if _, ok := client.NewCustomerClassProp(search.CustomerClass); !ok {
+			return nil, fmt.Errorf("%w: unknown customer class %q", ErrInvalidGenabilityRequest, search.CustomerClass)
+		}
+	}
+	if search.ZipCode != "" && search.Country == "" {
+		search.Country = "US"
+	}
+	return s.tariffs.Search(ctx, search)
+}
+
+// AccountPropertiesInput sets the properties a Genability account is
+// billed with. Only the fields given are set.
+type AccountPropertiesInput struct {
+	CustomerClass    *string  `json:"customer_class,omitempty" example:"RESIDENTIAL"`
+	LseID            *uint    `json:"lse_id,omitempty" example:"734"`
+	MasterTariffID   *uint    `json:"master_tariff_id,omitempty" example:"522"`
+	TerritoryID      *uint    `json:"territory_id,omitempty" example:"3538"`
+	BuildingAreaSqFt *float64 `json:"building_area_sqft,omitempty" example:"2000"`
+}
+
+func (in AccountPropertiesInput) properties() ([]client.AccountProperty, error) {
+	var props []client.AccountProperty
+	if in.CustomerClass != nil {
+		prop, ok := client.NewCustomerClassProp(*in.CustomerClass)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown customer class %q", ErrInvalidGenabilityRequest, *in.CustomerClass)
+		}
+		props = append(props, prop)
+	}
+	ids := []struct {
+		key   string
+		value *uint
+	}{
+		{client.AccountPropertyLseID, in.LseID},
+		{client.AccountPropertyMasterTariffID, in.MasterTariffID},
+		{client.AccountPropertyTerritoryID, in.TerritoryID},
+	}
+	for _, id := range ids {
+		if id.value != nil {
+			props = append(props, client.NewAccountProp(id.key, strconv.FormatUint(uint64(*id.value), 10)))
+		}
+	}
+	if in.BuildingAreaSqFt != nil {
+		if *in.BuildingAreaSqFt <= 0 {
+			return nil, fmt.Errorf("%w: building area must be greater than 0", ErrInvalidGenabilityRequest)
+		}
+		props = append(props, client.NewAccountProp(client.AccountPropertyBuildingArea, strconv.FormatFloat(*in.BuildingAreaSqFt, 'f', -1, 64)))
+	}
+	return props, nil
+}
+
+// SetAccountProperties sets the given properties on an account and
+// returns it.
+func (s *GenabilityService) SetAccountProperties(ctx context.Context, accountID string, input AccountPropertiesInput) (*client.Account, error) {
+	if err := s.available(); err != nil {
+		return nil, err
+	}
+	props, err := input.properties()
+	if err != nil {
+		return nil, err
+	}
+	return s.accounts.SetProperties(ctx, accountID, props)
+}
+
+// BillCalculationInput is a year of monthly usage, and optionally solar
+// production, to price on a tariff. Year is the calendar year billed,
+// last year by default.
+type BillCalculationInput struct {
+	MasterTariffID        uint      `json:"master_tariff_id" example:"522"`
+	TerritoryID           *uint     `json:"territory_id,omitempty" example:"3538"`
+	Year                  int       `json:"year,omitempty" example:"2025"`
+	MonthlyConsumptionKWh []float64 `json:"monthly_consumption_kwh"`
+	MonthlyProductionKWh  []float64 `json:"monthly_production_kwh,omitempty"`
+	DetailLevel           string    `json:"detail_level,omitempty" example:"CHARGE_TYPE"`
+}
+
+func (in *BillCalculationInput) Validate() error {
+	if in.MasterTariffID == 0 {
+		return fmt.Errorf("%w: master_tariff_id is required", ErrInvalidGenabilityRequest)
+	}
+	if len(in.MonthlyConsumptionKWh) != 12 {
+		return fmt.Errorf("%w: monthly consumption needs 12 values", ErrInvalidGenabilityRequest)
+	}
+	if in.MonthlyProductionKWh != nil && len(in.MonthlyProductionKWh) != 12 {
+		return fmt.Errorf("%w: monthly production needs 12 values", ErrInvalidGenabilityRequest)
+	}
+	for m := range 12 {
+		if in.MonthlyConsumptionKWh[m] < 0 || (in.MonthlyProductionKWh != nil && in.MonthlyProductionKWh[m] < 0) {
+			return fmt.Errorf("%w: monthly kWh must not be negative", ErrInvalidGenabilityRequest)
+		}
+	}
+	switch in.DetailLevel {
+	case "", client.DetailLevelTotal, client.DetailLevelChargeType, client.DetailLevelRate:
+	default:
+		return fmt.Errorf("%w: unknown detail level %q", ErrInvalidGenabilityRequest, in.DetailLevel)
+	}
+	return nil
+}
+
+// BillCalculation is a year's bill on a tariff before and after solar.
+type BillCalculation struct {
+	PreSolar      *client.CalculatedCost `json:"pre_solar"`
+	PostSolar     *client.CalculatedCost `json:"post_solar,omitempty"`
+	AnnualSavings float64                `json:"annual_savings" example:"1450.20"`
+}
+
+// CalculateBills prices a year of usage on a tariff, and the same year net
+// of solar production when it is given. Months the system produces more
+// than the home uses bill negative consumption, which the tariff's own net
+// metering rules credit.
+func (s *GenabilityService) CalculateBills(ctx context.Context, input BillCalculationInput) (*BillCalculation, error) {
+	if err := s.available(); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if input.Year == 0 {
+		input.Year = time.Now().Year() - 1
+	}
+	if input.DetailLevel == "" {
+		input.DetailLevel = client.DetailLevelChargeType
+	}
+
+	pre, err := s.calculateYear(ctx, input, input.MonthlyConsumptionKWh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate pre-solar bill: %w", err)
+	}
+	result := &BillCalculation{PreSolar: pre}
+	if input.MonthlyProductionKWh == nil {
+		return result, nil
+	}
+
+	net := make([]float64, 12)
+	for m := range net {
+		net[m] = input.MonthlyConsumptionKWh[m] - input.MonthlyProductionKWh[m]
+	}
+	post, err := s.calculateYear(ctx, input, net)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate post-solar bill: %w", err)
+	}
+	result.PostSolar = post
+	result.AnnualSavings = roundCents(pre.TotalCost - post.TotalCost)
+	return result, nil
+}
+
+func (s *GenabilityService) calculateYear(ctx context.Context, input BillCalculationInput, monthly []float64) (*client.CalculatedCost, error) {
+	const day = "2006-01-02"
+	start := time.Date(input.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	req := client.CalculateRequest{
+		From:           start.Format(day),
+		To:             start.AddDate(1, 0, 0).Format(day),
+		MasterTariffID: input.MasterTariffID,
+		GroupBy:        client.GroupByMonth,
+		DetailLevel:    input.DetailLevel,
+	}
+	for m, kwh := range monthly {
+		from := start.AddDate(0, m, 0)
+		req.PropertyInputs = append(req.PropertyInputs, client.NewConsumptionInput(from.Format(day), from.AddDate(0, 1, 0).Format(day), kwh))
+	}
+	if input.TerritoryID != nil {
+		req.PropertyInputs = append(req.PropertyInputs, client.PropertyInput{
+			Key:   client.PropertyTerritoryID,
+			Value: strconv.FormatUint(uint64(*input.TerritoryID), 10),
+		})
+	}
+	return s.calculator.Calculate(ctx, req)
+}
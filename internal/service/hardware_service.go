@@ -0,0 +1,622 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+type HardwareService struct {
+	hardwareRepo      *repo.HardwareRepo
+	lightFusionClient *client.LightFusionClient
+}
+
+// Kinds of hardware ID a 3D project request may select hardware with.
+const (
+	HardwareIDSourceLocal       = "local"
+	HardwareIDSourceLightFusion = "lightfusion"
+)
+
+// HardwareSelection is the hardware picked for a lead, deal or 3D project.
+// Nil IDs are not checked.
+type HardwareSelection struct {
+	PanelID    *int `json:"panel_id,omitempty" example:"1"`
+	InverterID *int `json:"inverter_id,omitempty" example:"1"`
+	BatteryID  *int `json:"battery_id,omitempty" example:"1"`
+}
+
+// CompanyHardwareList is a company's allowed hardware, resolved to catalog items.
+type CompanyHardwareList struct {
+	Panels    []*models.Panel    `json:"panels"`
+	Inverters []*models.Inverter `json:"inverters"`
+	Batteries []*models.Battery  `json:"batteries"`
+}
+
+// CompanyHardwareRequest replaces a company's allowed lists. A nil list
+// leaves that hardware type unchanged and an empty one allows the whole
+// active catalog.
+type CompanyHardwareRequest struct {
+	PanelIDs          []int `json:"panel_ids,omitempty" example:"1,2"`
+	InverterIDs       []int `json:"inverter_ids,omitempty" example:"1"`
+	BatteryIDs        []int `json:"battery_ids,omitempty" example:"1"`
+	DefaultPanelID    *int  `json:"default_panel_id,omitempty" example:"1"`
+	DefaultInverterID *int  `json:"default_inverter_id,omitempty" example:"1"`
+	DefaultBatteryID  *int  `json:"default_battery_id,omitempty" example:"1"`
+}
+
+// HardwareSyncResult counts what a LightFusion catalog sync did.
+type HardwareSyncResult struct {
+	PanelsCreated    int      `json:"panels_created"`
+	PanelsUpdated    int      `json:"panels_updated"`
+	InvertersCreated int      `json:"inverters_created"`
+	InvertersUpdated int      `json:"inverters_updated"`
+	BatteriesCreated int      `json:"batteries_created"`
+	BatteriesUpdated int      `json:"batteries_updated"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+func NewHardwareService(hardwareRepo *repo.HardwareRepo, lightFusionClient *client.LightFusionClient) *HardwareService {
+	return &HardwareService{
+		hardwareRepo:      hardwareRepo,
+		lightFusionClient: lightFusionClient,
+	}
+}
+
+func (s *HardwareService) CreatePanel(ctx context.Context, panel *models.Panel) error {
+	if err := panel.Validate(); err != nil {
+		return err
+	}
+	return s.hardwareRepo.CreatePanel(ctx, panel)
+}
+
+func (s *HardwareService) GetPanel(ctx context.Context, id int) (*models.Panel, error) {
+	return s.hardwareRepo.GetPanelByID(ctx, id)
+}
+
+func (s *HardwareService) UpdatePanel(ctx context.Context, panel *models.Panel) error {
+	if err := panel.Validate(); err != nil {
+		return err
+	}
+	return s.hardwareRepo.UpdatePanel(ctx, panel)
+}
+
+func (s *HardwareService) DeletePanel(ctx context.Context, id int) error {
+	return s.hardwareRepo.DeletePanel(ctx, id)
+}
+
+func (s *HardwareService) ListPanels(ctx context.Context, activeOnly bool) ([]*models.Panel, error) {
+	return s.hardwareRepo.ListPanels(ctx, activeOnly)
+}
+
+func (s *HardwareService) CreateInverter(ctx context.Context, inverter *models.Inverter) error {
+	if err := inverter.Validate(); err != nil {
+		return err
+	}
+	return s.hardwareRepo.CreateInverter(ctx, inverter)
+}
+
+func (s *HardwareService) GetInverter(ctx context.Context, id int) (*models.Inverter, error) {
+	return s.hardwareRepo.GetInverterByID(ctx, id)
+}
+
+func (s *HardwareService) UpdateInverter(ctx context.Context, inverter *models.Inverter) error {
+	if err := inverter.Validate(); err != nil {
+		return err
+	}
+	return s.hardwareRepo.UpdateInverter(ctx, inverter)
+}
+
+func (s *HardwareService) DeleteInverter(ctx context.Context, id int) error {
+	return s.hardwareRepo.DeleteInverter(ctx, id)
+}
+
+func (s *HardwareService) ListInverters(ctx context.Context, activeOnly bool) ([]*models.Inverter, error) {
+	return s.hardwareRepo.ListInverters(ctx, activeOnly)
+}
+
+func (s *HardwareService) CreateBattery(ctx context.Context, battery *models.Battery) error {
+	if err := battery.Validate(); err != nil {
+		return err
+	}
+	return s.hardwareRepo.CreateBattery(ctx, battery)
+}
+
+func (s *HardwareService) GetBattery(ctx context.Context, id int) (*models.Battery, error) {
+	return s.hardwareRepo.GetBatteryByID(ctx, id)
+}
+
+func (s *HardwareService) UpdateBattery(ctx context.Context, battery *models.Battery) error {
+	if err := battery.Validate(); err != nil {
+		return err
+	}
+	return s.hardwareRepo.UpdateBattery(ctx, battery)
+}
+
+func (s *HardwareService) DeleteBattery(ctx context.Context, id int) error {
+	return s.hardwareRepo.DeleteBattery(ctx, id)
+}
+
+func (s *HardwareService) ListBatteries(ctx context.Context, activeOnly bool) ([]*models.Battery, error) {
+	return s.hardwareRepo.ListBatteries(ctx, activeOnly)
+}
+
+// CompanyHardware resolves a company's allowed lists. A hardware type with
+// no allowed list falls back to the whole active catalog, so new companies
+// can sell before an admin narrows their options down.
+func (s *HardwareService) CompanyHardware(ctx context.Context, companyID int) (*CompanyHardwareList, error) {
+	allowed, err := s.allowedIDs(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	panels, err := s.hardwareRepo.ListPanels(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	inverters, err := s.hardwareRepo.ListInverters(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	batteries, err := s.hardwareRepo.ListBatteries(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &CompanyHardwareList{
+		Panels:    []*models.Panel{},
+		Inverters: []*models.Inverter{},
+		Batteries: []*models.Battery{},
+	}
+	for _, p := range panels {
+		if def, ok := allowed.lookup(models.HardwareTypePanel, p.ID); ok {
+			p.IsDefault = def
+			list.Panels = append(list.Panels, p)
+		}
+	}
+	for _, i := range inverters {
+		if def, ok := allowed.lookup(models.HardwareTypeInverter, i.ID); ok {
+			i.IsDefault = def
+			list.Inverters = append(list.Inverters, i)
+		}
+	}
+	for _, b := range batteries {
+		if def, ok := allowed.lookup(models.HardwareTypeBattery, b.ID); ok {
+			b.IsDefault = def
+			list.Batteries = append(list.Batteries, b)
+		}
+	}
+	return list, nil
+}
+
+// SetCompanyHardware replaces the allowed lists given in the request.
+func (s *HardwareService) SetCompanyHardware(ctx context.Context, companyID int, req CompanyHardwareRequest) error {
+	lists := []struct {
+		hardwareType string
+		ids          []int
+		defaultID    *int
+		exists       func(context.Context, int) error
+	}{
+		{models.HardwareTypePanel, req.PanelIDs, req.DefaultPanelID, func(ctx context.Context, id int) error {
+			_, err := s.hardwareRepo.GetPanelByID(ctx, id)
+			return err
+		}},
+		{models.HardwareTypeInverter, req.InverterIDs, req.DefaultInverterID, func(ctx context.Context, id int) error {
+			_, err := s.hardwareRepo.GetInverterByID(ctx, id)
+			return err
+		}},
+		{models.HardwareTypeBattery, req.BatteryIDs, req.DefaultBatteryID, func(ctx context.Context, id int) error {
+			_, err := s.hardwareRepo.GetBatteryByID(ctx, id)
+			return err
+		}},
+	}
+
+	for _, l := range lists {
+		if l.ids == nil {
+			continue
+		}
+		items := make([]*models.CompanyHardware, 0, len(l.ids))
+		for _, id := range l.ids {
+			if err := l.exists(ctx, id); err != nil {
+				return err
+			}
+			items = append(items, &models.CompanyHardware{
+				CompanyID:    companyID,
+				HardwareType: l.hardwareType,
+				HardwareID:   id,
+				IsDefault:    l.defaultID != nil && *l.defaultID == id,
+			})
+		}
+		if err := s.hardwareRepo.ReplaceCompanyHardware(ctx, companyID, l.hardwareType, items); err != nil {
+			return fmt.Errorf("failed to save %s allowed list: %w", l.hardwareType, err)
+		}
+	}
+	return nil
+}
+
+// ValidateSelection checks that every selected item exists, is active and is
+// on the company's allowed list.
+func (s *HardwareService) ValidateSelection(ctx context.Context, companyID int, sel HardwareSelection) error {
+	if sel.PanelID == nil && sel.InverterID == nil && sel.BatteryID == nil {
+		return nil
+	}
+	allowed, err := s.allowedIDs(ctx, companyID)
+	if err != nil {
+		return err
+	}
+
+	if sel.PanelID != nil {
+		panel, err := s.hardwareRepo.GetPanelByID(ctx, *sel.PanelID)
+		if err != nil {
+			return err
+		}
+		if _, ok := allowed.lookup(models.HardwareTypePanel, panel.ID); !ok || !panel.Active {
+			return fmt.Errorf("%w: panel %d", models.ErrHardwareNotAllowed, panel.ID)
+		}
+	}
+	if sel.InverterID != nil {
+		inverter, err := s.hardwareRepo.GetInverterByID(ctx, *sel.InverterID)
+		if err != nil {
+			return err
+		}
+		if _, ok := allowed.lookup(models.HardwareTypeInverter, inverter.ID); !ok || !inverter.Active {
+			return fmt.Errorf("%w: inverter %d", models.ErrHardwareNotAllowed, inverter.ID)
+		}
+	}
+	if sel.BatteryID != nil {
+		battery, err := s.hardwareRepo.GetBatteryByID(ctx, *sel.BatteryID)
+		if err != nil {
+			return err
+		}
+		if _, ok := allowed.lookup(models.HardwareTypeBattery, battery.ID); !ok || !battery.Active {
+			return fmt.Errorf("%w: battery %d", models.ErrHardwareNotAllowed, battery.ID)
+		}
+	}
+	return nil
+}
+
+// ExternalSelection maps local catalog IDs to the LightFusion IDs they were
+// imported from. Items created locally keep their own ID.
+func (s *HardwareService) ExternalSelection(ctx context.Context, sel HardwareSelection) (HardwareSelection, error) {
+	var out HardwareSelection
+	if sel.PanelID != nil {
+		panel, err := s.hardwareRepo.GetPanelByID(ctx, *sel.PanelID)
+		if err != nil {
+			return out, err
+		}
+		out.PanelID = externalOrLocal(panel.ExternalID, panel.ID)
+	}
+	if sel.InverterID != nil {
+		inverter, err := s.hardwareRepo.GetInverterByID(ctx, *sel.InverterID)
+		if err != nil {
+			return out, err
+		}
+		out.InverterID = externalOrLocal(inverter.ExternalID, inverter.ID)
+	}
+	if sel.BatteryID != nil {
+		battery, err := s.hardwareRepo.GetBatteryByID(ctx, *sel.BatteryID)
+		if err != nil {
+			return out, err
+		}
+		out.BatteryID = externalOrLocal(battery.ExternalID, battery.ID)
+	}
+	return out, nil
+}
+
+// ResolveSelection maps a selection to local catalog IDs. Source says which
+// kind of ID it holds. An empty source means local IDs, but an ID with no
+// local item is looked up as a LightFusion ID, as 3D project requests used
+// to send; legacy reports when that happened.
+func (s *HardwareService) ResolveSelection(ctx context.Context, sel HardwareSelection, source string) (resolved HardwareSelection, legacy bool, err error) {
+	switch source {
+	case HardwareIDSourceLightFusion:
+		resolved, err = s.localSelection(ctx, sel)
+		return resolved, false, err
+	case HardwareIDSourceLocal:
+		_, err = s.ExternalSelection(ctx, sel)
+		return sel, false, err
+	case "":
+		_, err = s.ExternalSelection(ctx, sel)
+		if !isHardwareNotFound(err) {
+			return sel, false, err
+		}
+		if resolved, lfErr := s.localSelection(ctx, sel); lfErr == nil {
+			return resolved, true, nil
+		}
+		return sel, false, err
+	default:
+		return sel, false, fmt.Errorf("%w: unknown hardware ID source %q", models.ErrHardwareNotAllowed, source)
+	}
+}
+
+// localSelection maps LightFusion IDs to the catalog items imported from them.
+func (s *HardwareService) localSelection(ctx context.Context, sel HardwareSelection) (HardwareSelection, error) {
+	var out HardwareSelection
+	if sel.PanelID != nil {
+		panel, err := s.hardwareRepo.GetPanelByExternalID(ctx, *sel.PanelID)
+		if err != nil {
+			return out, err
+		}
+		out.PanelID = &panel.ID
+	}
+	if sel.InverterID != nil {
+		inverter, err := s.hardwareRepo.GetInverterByExternalID(ctx, *sel.InverterID)
+		if err != nil {
+			return out, err
+		}
+		out.InverterID = &inverter.ID
+	}
+	if sel.BatteryID != nil {
+		battery, err := s.hardwareRepo.GetBatteryByExternalID(ctx, *sel.BatteryID)
+		if err != nil {
+			return out, err
+		}
+		out.BatteryID = &battery.ID
+	}
+	return out, nil
+}
+
+func isHardwareNotFound(err error) bool {
+	return errors.Is(err, models.ErrPanelNotFound) ||
+		errors.Is(err, models.ErrInverterNotFound) ||
+		errors.Is(err, models.ErrBatteryNotFound)
+}
+
+// SyncFromLightFusion imports the LightFusion hardware catalog, matching on
+// external ID. Pricing is overwritten and specs that LightFusion does not
+// report are kept. Active and default flags are taken from LightFusion only
+// when an item is first imported; after that they are managed locally.
+func (s *HardwareService) SyncFromLightFusion(ctx context.Context) (*HardwareSyncResult, error) {
+	if s.lightFusionClient == nil {
+		return nil, fmt.Errorf("LightFusion client is not configured")
+	}
+	result := &HardwareSyncResult{}
+
+	panels, err := s.lightFusionClient.ListPanels(ctx)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("panels: %v", err))
+	}
+	for _, p := range panels {
+		created, err := s.upsertPanel(ctx, p)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("panel %d: %v", p.ID, err))
+			continue
+		}
+		if created {
+			result.PanelsCreated++
+		} else {
+			result.PanelsUpdated++
+		}
+	}
+
+	inverters, err := s.lightFusionClient.ListInverters(ctx)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("inverters: %v", err))
+	}
+	for _, i := range inverters {
+		created, err := s.upsertInverter(ctx, i)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("inverter %d: %v", i.ID, err))
+			continue
+		}
+		if created {
+			result.InvertersCreated++
+		} else {
+			result.InvertersUpdated++
+		}
+	}
+
+	batteries, err := s.lightFusionClient.ListBatteries(ctx)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("batteries: %v", err))
+	}
+	for _, b := range batteries {
+		created, err := s.upsertBattery(ctx, b)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("battery %d: %v", b.ID, err))
+			continue
+		}
+		if created {
+			result.BatteriesCreated++
+		} else {
+			result.BatteriesUpdated++
+		}
+	}
+
+	return result, nil
+}
+
+// RunSync imports the LightFusion catalog every interval until ctx is done.
+func (s *HardwareService) RunSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.SyncFromLightFusion(ctx)
+		if err != nil {
+			log.Printf("Warning: hardware catalog sync failed: %v", err)
+		} else {
+			log.Printf("Hardware catalog synced: %d/%d panels, %d/%d inverters, %d/%d batteries created/updated, %d errors",
+				result.PanelsCreated, result.PanelsUpdated,
+				result.InvertersCreated, result.InvertersUpdated,
+				result.BatteriesCreated, result.BatteriesUpdated,
+				len(result.Errors))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *HardwareService) upsertPanel(ctx context.Context, p client.Panel) (bool, error) {
+	panel, err := s.hardwareRepo.GetPanelByExternalID(ctx, p.ID)
+	created := false
+	if err == models.ErrPanelNotFound {
+		externalID := p.ID
+		panel = &models.Panel{ExternalID: &externalID}
+		created = true
+	} else if err != nil {
+		return false, err
+	}
+
+	panel.Manufacturer = p.Manufacturer
+	panel.Model = p.Model
+	panel.DisplayName = p.DisplayName
+	panel.Power = p.Power
+	panel.PricePerWatt = p.PricePerWatt
+	panel.IsDomestic = p.IsDomestic
+	if created {
+		panel.Active = p.Active
+		panel.IsDefault = p.IsDefault
+	}
+	setIfNonZero(&panel.OpenCircuitVoltage, p.Voc)
+	setIfNonZero(&panel.ShortCircuitCurrent, p.Isc)
+	setIfNonZero(&panel.MaxPowerVoltage, p.Vmp)
+	setIfNonZero(&panel.MaxPowerCurrent, p.Imp)
+	setIfNonZero(&panel.TempCoeffVoc, p.TempCoeffVoc)
+	setIfNonZero(&panel.TempCoeffPmax, p.TempCoeffPmax)
+	setIfNonZero(&panel.NOCT, p.NOCT)
+	setIfNonZero(&panel.Efficiency, p.Efficiency)
+
+	if err := panel.Validate(); err != nil {
+		return false, err
+	}
+	if created {
+		return true, s.hardwareRepo.CreatePanel(ctx, panel)
+	}
+	return false, s.hardwareRepo.UpdatePanel(ctx, panel)
+}
+
+func (s *HardwareService) upsertInverter(ctx context.Context, i client.Inverter) (bool, error) {
+	inverter, err := s.hardwareRepo.GetInverterByExternalID(ctx, i.ID)
+	created := false
+	if err == models.ErrInverterNotFound {
+		externalID := i.ID
+		inverter = &models.Inverter{ExternalID: &externalID}
+		created = true
+	} else if err != nil {
+		return false, err
+	}
+
+	inverter.Manufacturer = i.Manufacturer
+	inverter.Model = i.Model
+	if inverter.Model == "" {
+		inverter.Model = i.Name
+	}
+	inverter.Name = i.Name
+	inverter.Category = i.Category
+	inverter.CostType = i.CostType
+	inverter.Cost = i.Cost
+	inverter.Capacity = i.Capacity
+	if created {
+		inverter.Active = i.IsActive
+	}
+	setIfNonZero(&inverter.MaxDCVoltage, i.MaxDCVoltage)
+	setIfNonZero(&inverter.MPPTMinVoltage, i.MPPTMinVoltage)
+	setIfNonZero(&inverter.MPPTMaxVoltage, i.MPPTMaxVoltage)
+	setIfNonZero(&inverter.MaxInputCurrentPerMPPT, i.MaxInputCurrent)
+	setIfNonZero(&inverter.Efficiency, i.Efficiency)
+	if i.MPPTCount > 0 {
+		inverter.MPPTCount = i.MPPTCount
+	}
+
+	if err := inverter.Validate(); err != nil {
+		return false, err
+	}
+	if created {
+		return true, s.hardwareRepo.CreateInverter(ctx, inverter)
+	}
+	return false, s.hardwareRepo.UpdateInverter(ctx, inverter)
+}
+
+func (s *HardwareService) upsertBattery(ctx context.Context, b client.Battery) (bool, error) {
+	battery, err := s.hardwareRepo.GetBatteryByExternalID(ctx, b.ID)
+	created := false
+	if err == models.ErrBatteryNotFound {
+		externalID := b.ID
+		battery = &models.Battery{ExternalID: &externalID}
+		created = true
+	} else if err != nil {
+		return false, err
+	}
+
+	battery.Manufacturer = b.Manufacturer
+	battery.Model = b.Model
+	if battery.Model == "" {
+		battery.Model = b.Name
+	}
+	battery.Name = b.Name
+	battery.CapacityKWh = b.Capacity
+	battery.Cost = b.Cost
+	battery.IsDomestic = b.IsDomestic
+	if created {
+		battery.Active = b.IsActive
+	}
+	setIfNonZero(&battery.UsableCapacityKWh, b.UsableCapacity)
+	setIfNonZero(&battery.PowerKW, b.Power)
+	setIfNonZero(&battery.RoundTripEfficiency, b.RoundTripEfficiency)
+
+	if err := battery.Validate(); err != nil {
+		return false, err
+	}
+	if created {
+		return true, s.hardwareRepo.CreateBattery(ctx, battery)
+	}
+	return false, s.hardwareRepo.UpdateBattery(ctx, battery)
+}
+
+type allowedHardware struct {
+	restricted map[string]bool
+	items      map[string]map[int]bool
+}
+
+func (s *HardwareService) allowedIDs(ctx context.Context, companyID int) (*allowedHardware, error) {
+	rows, err := s.hardwareRepo.ListCompanyHardware(ctx, companyID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load company hardware: %w", err)
+	}
+	allowed := &allowedHardware{
+		restricted: map[string]bool{},
+		items:      map[string]map[int]bool{},
+	}
+	for _, row := range rows {
+		allowed.restricted[row.HardwareType] = true
+		if allowed.items[row.HardwareType] == nil {
+			allowed.items[row.HardwareType] = map[int]bool{}
+		}
+		allowed.items[row.HardwareType][row.HardwareID] = row.IsDefault
+	}
+	return allowed, nil
+}
+
+// lookup reports whether an item is allowed and whether it is the company
+// default. Unrestricted types allow everything and keep the catalog default.
+func (a *allowedHardware) lookup(hardwareType string, id int) (isDefault bool, ok bool) {
+	if !a.restricted[hardwareType] {
+		return false, true
+	}
+	isDefault, ok = a.items[hardwareType][id]
+	return isDefault, ok
+}
+
+func externalOrLocal(externalID *int, id int) *int {
+	if externalID != nil {
+		v := *externalID
+		return &v
+	}
+	return &id
+}
+
+func setIfNonZero(dst *float64, v float64) {
+	if v != 0 {
+		*dst = v
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/mocks"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+func TestSyncFromLightFusionKeepsInactiveItemsInactive(t *testing.T) {
+	fixtures := mocks.DefaultFixtures()
+	fixtures.LightFusion.Panels[0].Active = false
+	fixtures.LightFusion.Inverters[0].IsActive = false
+	fixtures.LightFusion.Batteries[0].IsActive = false
+	srv := httptest.NewServer(mocks.NewServer(fixtures))
+	t.Cleanup(srv.Close)
+
+	db, fake := openFakeDB(t)
+	s := NewHardwareService(repo.NewHardwareRepo(db), client.NewLightFusionClient(srv.URL+"/lightfusion", "token"))
+	result, err := s.SyncFromLightFusion(context.Background())
+	if err != nil {
+		t.Fatalf("SyncFromLightFusion: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("sync errors: %v", result.Errors)
+	}
+	if result.PanelsCreated != 1 || result.InvertersCreated != 1 || result.BatteriesCreated != 1 {
+		t.Fatalf("sync result = %+v, want one of each created", result)
+	}
+
+	for _, table := range []string{"panels", "inverters", "batteries"} {
+		rows := fake.inserted(table)
+		if len(rows) != 1 {
+			t.Fatalf("%s: %d inserts, want 1", table, len(rows))
+		}
+		active, ok := rows[0]["active"]
+		if !ok {
+			t.Errorf("%s: insert leaves active to the column default", table)
+		} else if active != false {
+			t.Errorf("%s: inserted active = %v, want false", table, active)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+type IncentiveService struct {
+	incentiveRepo *repo.IncentiveRepo
+}
+
+func NewIncentiveService(incentiveRepo *repo.IncentiveRepo) *IncentiveService {
+	return &IncentiveService{incentiveRepo: incentiveRepo}
+}
+
+func (s *IncentiveService) Create(ctx context.Context, incentive *models.Incentive) error {
+	if err := incentive.Validate(); err != nil {
+		return err
+	}
+	return s.incentiveRepo.Create(ctx, incentive)
+}
+
+func (s *IncentiveService) GetByID(ctx context.Context, id int) (*models.Incentive, error) {
+	return s.incentiveRepo.GetByID(ctx, id)
+}
+
+func (s *IncentiveService) Update(ctx context.Context, incentive *models.Incentive) error {
+	if err := incentive.Validate(); err != nil {
+		return err
+	}
+	return s.incentiveRepo.Update(ctx, incentive)
+}
+
+func (s *IncentiveService) Delete(ctx context.Context, id int) error {
+	return s.incentiveRepo.Delete(ctx, id)
+}
+
+func (s *IncentiveService) List(ctx context.Context, incentiveType string, activeOnly bool) ([]*models.Incentive, error) {
+	return s.incentiveRepo.List(ctx, incentiveType, activeOnly)
+}
+
+// Eligible returns the incentives offered at a time that a system can
+// claim.
+func (s *IncentiveService) Eligible(ctx context.Context, system incentive.System, at time.Time) ([]*models.Incentive, error) {
+	incentives, err := s.incentiveRepo.List(ctx, "", true)
+	if err != nil {
+		return nil, err
+	}
+	eligible := make([]*models.Incentive, 0, len(incentives))
+	for _, i := range incentives {
+		program := i.Program()
+		if i.AvailableAt(at) && program.Eligible(system) {
+			eligible = append(eligible, i)
+		}
+	}
+	return eligible, nil
+}
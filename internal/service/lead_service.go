@@ -20,6 +20,7 @@ type LeadService struct {
 	leadRepo *repo.LeadRepo
 	houseRepo *repo.HouseRepo
 	genabilityClient *client.Agent
+	hardwareService *HardwareService
 }
 
 type CreateLead struct {
@@ -38,6 +39,11 @@ type CreateLead struct {
 	SystemSize       float64 `json:"system_size" example:"10.5"`
 	PanelCount       int     `json:"panel_count" example:"30"`
 	HardwareType     *string `json:"hardware_type,omitempty"`
+	PanelID          *int    `json:"panel_id,omitempty" example:"1"`
+	InverterID       *int    `json:"inverter_id,omitempty" example:"1"`
+	InverterCount    int     `json:"inverter_count,omitempty" example:"1"`
+	BatteryID        *int    `json:"battery_id,omitempty" example:"1"`
+	BatteryCount     int     `json:"battery_count,omitempty" example:"0"`
 	KwhUsage         float64 `json:"kwh_usage" example:"12000"`
 	Consumption      []int   `json:"consumption,omitempty"`
 	SalesRepEmail     *string `json:"sales_rep_email,omitempty"`
@@ -49,25 +55,26 @@ type CreateLead struct {
 }
 
 
-func NewLeadService(leadRepo *repo.LeadRepo, houseRepo *repo.HouseRepo) *LeadService {
-	var genClient *client.Agent
-
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("Failed to initialize Genability client: %v", r)
-		}
-	}()
-
-	genClient = client.NewAgent()
-
+// NewLeadService returns a lead service. With a nil genabilityClient, leads
+// are created without looking up their utility and tariff.
+func NewLeadService(leadRepo *repo.LeadRepo, houseRepo *repo.HouseRepo, hardwareService *HardwareService, genabilityClient *client.Agent) *LeadService {
 	return &LeadService{
 		leadRepo: leadRepo,
 		houseRepo: houseRepo,
-		genabilityClient: genClient,
+		genabilityClient: genabilityClient,
+		hardwareService: hardwareService,
 	}
 }
 
 func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int, effectiveCompanyID int) (*CreateLeadResponse, error) {
+	selection := HardwareSelection{PanelID: req.PanelID, InverterID: req.InverterID, BatteryID: req.BatteryID}
+	if err := s.hardwareService.ValidateSelection(ctx, effectiveCompanyID, selection); err != nil {
+		return nil, err
+	}
+	if req.InverterCount <= 0 {
+		req.InverterCount = 1
+	}
+
 	house := models.House{
 			Lat:         req.Latitude,
 			Lng:         req.Longitude,
@@ -89,6 +96,11 @@ func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int
 			KwhUsage:   req.KwhUsage,
 			SystemSize: req.SystemSize,
 			PanelCount: req.PanelCount,
+			PanelID:    req.PanelID,
+			InverterID: req.InverterID,
+			InverterCount: req.InverterCount,
+			BatteryID:  req.BatteryID,
+			BatteryCount: req.BatteryCount,
 			Source:     0,
 			State:      0,
 		}
@@ -132,3 +144,20 @@ func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int
 		HouseID: int(houseID),
 	}, nil
 }
+
+// UpdateHardware validates and applies a hardware change on an existing lead.
+func (s *LeadService) UpdateHardware(ctx context.Context, lead *models.Lead, selection HardwareSelection) error {
+	if err := s.hardwareService.ValidateSelection(ctx, lead.CompanyID, selection); err != nil {
+		return err
+	}
+	if selection.PanelID != nil {
+		lead.PanelID = selection.PanelID
+	}
+	if selection.InverterID != nil {
+		lead.InverterID = selection.InverterID
+	}
+	if selection.BatteryID != nil {
+		lead.BatteryID = selection.BatteryID
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultBasePricePerWatt        = 3.00
+	defaultInstallationCostPerWatt = 0.75
+	defaultSalesCommissionRate     = 0.10
+)
+
+var (
+	ErrInvalidPricingSystemSize = errors.New("deal system size must be greater than 0 to price it")
+	ErrPriceBelowMinimum        = errors.New("target EPC is below the company minimum base price")
+	ErrInvalidContractPrice     = errors.New("contract price must be greater than 0")
+)
+
+type PricingService struct {
+	tx               *repo.TxManager
+	dealRepo         *repo.DealRepo
+	companyRepo      *repo.CompanyRepo
+	adderService     *AdderService
+	hardwareService  *HardwareService
+	financingService *FinancingService
+}
+
+// PricingInput carries everything the engine needs besides the deal and its
+// company: the manual adders picked by the rep and the financing option whose
+// dealer fee is grossed up into the contract price. Automatic adders come from
+// the company catalog and hardware from the deal's catalog panel and inverter.
+// RefreshAdders reprices the adders a deal was already sold with at current
+// catalog prices.
+type PricingInput struct {
+	State                   string                  `json:"state,omitempty" example:"CA"`
+	AdderSelections         []AdderSelection        `json:"adder_selections,omitempty"`
+	RefreshAdders           bool                    `json:"refresh_adders,omitempty" example:"false"`
+	InverterCount           int                     `json:"inverter_count,omitempty" example:"1"`
+	FinancingOption         *client.FinancingOption `json:"financing_option,omitempty"`
+	InstallationCostPerWatt *float64                `json:"installation_cost_per_watt,omitempty" example:"0.75"`
+
+	// AllowBelowMinimum honours a contract price below the company minimum
+	// or at a loss, e.g. one a homeowner has already accepted, and flags
+	// the priced deal for review instead of refusing it.
+	AllowBelowMinimum bool `json:"-"`
+}
+
+// PricedDeal is the result of pricing a deal: the updated deal and the
+// breakdown that produced its cost and profit fields.
+type PricedDeal struct {
+	Deal           *models.Deal           `json:"deal"`
+	PriceBreakdown *client.PriceBreakdown `json:"price_breakdown"`
+	Adders         []*models.DealAdder    `json:"adders"`
+	// NeedsReview is set when the deal was priced below the company
+	// minimum or at a loss under AllowBelowMinimum.
+	NeedsReview bool `json:"needs_review,omitempty" example:"false"`
+}
+
+// PricingHardware is the catalog hardware a deal is priced with.
+type PricingHardware struct {
+	Panel         *models.Panel
+	Inverter      *models.Inverter
+	InverterCount int
+}
+
+// DealCosts are the internal cost components of a priced deal.
+type DealCosts struct {
+	HardwareCost        float64 `json:"hardware_cost"`
+	InstallationCost    float64 `json:"installation_cost"`
+	AdderCost           float64 `json:"adder_cost"`
+	SalesCommissionCost float64 `json:"sales_commission_cost"`
+	Profit              float64 `json:"profit"`
+}
+
+func NewPricingService(tx *repo.TxManager, dealRepo *repo.DealRepo, companyRepo *repo.CompanyRepo, adderService *AdderService, hardwareService *HardwareService, financingService *FinancingService) *PricingService {
+	return &PricingService{
+		tx:               tx,
+		dealRepo:         dealRepo,
+		companyRepo:      companyRepo,
+		adderService:     adderService,
+		hardwareService:  hardwareService,
+		financingService: financingService,
+	}
+}
+
+// PriceDeal builds a price breakdown for the deal, writes the resulting cost
+// and profit fields back onto it and saves it together with its adder
+// snapshot in one transaction. A deal held for pricing review goes back to
+// pending.
+func (s *PricingService) PriceDeal(ctx context.Context, dealID int, input PricingInput) (*PricedDeal, error) {
+	deal, err := s.dealRepo.GetByID(ctx, dealID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrDealNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	priced, err := s.price(ctx, deal, input, 0)
+	if err != nil {
+		return nil, err
+	}
+	if deal.Status == models.DealStatusPricingReview {
+		deal.Status = models.DealStatusPending
+	}
+
+	err = s.tx.Do(ctx, func(tx *gorm.DB) error {
+		if err := s.dealRepo.WithTx(tx).Update(ctx, deal); err != nil {
+			return fmt.Errorf("failed to save priced deal: %w", err)
+		}
+		if err := s.adderService.SaveDealAdders(ctx, tx, deal.ID, priced.Adders); err != nil {
+			return fmt.Errorf("failed to save deal adders: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return priced, nil
+}
+
+// PriceAtContractPrice prices a deal that has not been saved yet so that its
+// total comes to contractPrice: the base price per watt is whatever is left
+// of the contract price once the adders are paid for. Nothing is persisted;
+// the caller saves the deal and its adders.
+func (s *PricingService) PriceAtContractPrice(ctx context.Context, deal *models.Deal, contractPrice float64, input PricingInput) (*PricedDeal, error) {
+	if contractPrice <= 0 {
+		return nil, ErrInvalidContractPrice
+	}
+	return s.price(ctx, deal, input, contractPrice)
+}
+
+// price runs the pricing engine over the deal and applies the result to it.
+// A positive contractPrice sets the base price per watt from it; otherwise
+// the deal's target EPC or the company default is used.
+func (s *PricingService) price(ctx context.Context, deal *models.Deal, input PricingInput, contractPrice float64) (*PricedDeal, error) {
+	company, err := s.companyRepo.GetByID(ctx, deal.CompanyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrCompanyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := input.State
+	if state == "" {
+		state = stateFromAddress(deal.Address)
+	}
+	adders, err := s.adderService.ResolveDealAdders(ctx, deal, state, input.AdderSelections, input.RefreshAdders)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an explicit financing option, the dealer fee comes from the
+	// deal's option in the company catalog, deleted or not. A contract
+	// price already includes whatever fee was agreed, so it is not grossed
+	// up again.
+	if input.FinancingOption == nil && deal.FinancingOptionID != nil && contractPrice <= 0 {
+		option, err := s.financingService.GetSoldOptionByID(ctx, *deal.FinancingOptionID)
+		if err != nil {
+			return nil, err
+		}
+		if option.CompanyID == deal.CompanyID {
+			input.FinancingOption = pricingFinancingOption(option)
+		}
+	}
+
+	if contractPrice > 0 {
+		if deal.SystemSize <= 0 {
+			return nil, ErrInvalidPricingSystemSize
+		}
+		var adderTotal float64
+		for _, adder := range adders {
+			adderTotal += adder.PriceFor(deal.SystemSize, deal.PanelCount)
+		}
+		deal.TargetEPC = math.Max(contractPrice-adderTotal, 0) / (deal.SystemSize * 1000)
+	}
+
+	hardware, err := s.dealHardware(ctx, deal, input.InverterCount)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown, costs, err := s.BuildPriceBreakdown(company, deal, adders, hardware, input)
+	if err != nil {
+		return nil, err
+	}
+	ApplyDealCosts(deal, breakdown, costs)
+
+	needsReview := breakdown.BasePricePerWatt < breakdown.MinimumBasePrice || deal.Profit < 0
+	if err := deal.Validate(); err != nil {
+		lossAllowed := input.AllowBelowMinimum && deal.Profit < 0 && errors.Is(err, models.ErrInvalidDealProfit)
+		if !lossAllowed {
+			return nil, err
+		}
+	}
+
+	return &PricedDeal{Deal: deal, PriceBreakdown: breakdown, Adders: adders, NeedsReview: needsReview}, nil
+}
+
+// BuildPriceBreakdown prices a deal with the given adder lines without
+// persisting anything. The Amount of each line is filled in.
+//
+// The contract price is the base EPC times the system size plus every adder;
+// the financing dealer fee is then grossed up on top of it.
+// Hardware and installation are internal costs, the rep is paid the company
+// commission rate on the pre-fee amount and whatever remains is profit.
+func (s *PricingService) BuildPriceBreakdown(company *models.Company, deal *models.Deal, adders []*models.DealAdder, hardware PricingHardware, input PricingInput) (*client.PriceBreakdown, DealCosts, error) {
+	var costs DealCosts
+	if deal.SystemSize <= 0 {
+		return nil, costs, ErrInvalidPricingSystemSize
+	}
+	watts := deal.SystemSize * 1000
+
+	minimumBase, defaultBase := companyBasePrices(company)
+	basePPW := defaultBase
+	if deal.TargetEPC > 0 {
+		basePPW = deal.TargetEPC
+	}
+	if minimumBase > 0 && basePPW < minimumBase && !input.AllowBelowMinimum {
+		return nil, costs, ErrPriceBelowMinimum
+	}
+
+	breakdown := &client.PriceBreakdown{
+		BasePricePerWatt: basePPW,
+		DefaultBasePrice: defaultBase,
+		MinimumBasePrice: minimumBase,
+	}
+
+	baseAmount := roundCents(basePPW * watts)
+	breakdown.Items = append(breakdown.Items, client.PriceItem{Name: "Base system", Price: baseAmount})
+
+	for _, adder := range adders {
+		adder.Amount = adder.PriceFor(deal.SystemSize, deal.PanelCount)
+		if adder.Amount == 0 {
+			continue
+		}
+		costs.AdderCost += adder.Amount
+		breakdown.Items = append(breakdown.Items, client.PriceItem{Name: adder.Name, Price: adder.Amount})
+	}
+
+	subtotal := baseAmount + costs.AdderCost
+	total := subtotal
+	if fo := input.FinancingOption; fo != nil {
+		if fo.LoanFee > 0 && fo.LoanFee < 1 {
+			total = subtotal / (1 - fo.LoanFee)
+		}
+		total += fo.LoanFeeFixed
+		total = roundCents(total)
+		breakdown.TotalFee = roundCents(total - subtotal)
+		if breakdown.TotalFee > 0 {
+			breakdown.Items = append(breakdown.Items, client.PriceItem{Name: "Dealer fee", Price: breakdown.TotalFee})
+		}
+	}
+
+	breakdown.TotalAmountWithoutDealerFee = roundCents(subtotal)
+	breakdown.TotalAmount = total
+	breakdown.TotalPricePerWatt = roundCents(subtotal / watts)
+	breakdown.TotalPricePerWattFinanced = roundCents(total / watts)
+
+	if hardware.Panel != nil {
+		costs.HardwareCost += hardware.Panel.PricePerWatt * watts
+	}
+	if hardware.Inverter != nil {
+		costs.HardwareCost += inverterCost(hardware.Inverter, hardware.InverterCount, watts)
+	}
+	costs.HardwareCost = roundCents(costs.HardwareCost)
+
+	installPPW := defaultInstallationCostPerWatt
+	if input.InstallationCostPerWatt != nil {
+		installPPW = *input.InstallationCostPerWatt
+	}
+	costs.InstallationCost = roundCents(installPPW * watts)
+
+	costs.SalesCommissionCost = roundCents(subtotal * companyCommissionRate(company))
+	costs.AdderCost = roundCents(costs.AdderCost)
+	costs.Profit = roundCents(subtotal - costs.HardwareCost - costs.InstallationCost - costs.AdderCost - costs.SalesCommissionCost)
+
+	return breakdown, costs, nil
+}
+
+// ApplyDealCosts copies a price breakdown and its costs onto the deal so that
+// the stored financial fields always agree with each other.
+func ApplyDealCosts(deal *models.Deal, breakdown *client.PriceBreakdown, costs DealCosts) {
+	deal.TargetEPC = breakdown.BasePricePerWatt
+	deal.TotalCost = breakdown.TotalAmount
+	deal.HardwareCost = costs.HardwareCost
+	deal.InstallationCost = costs.InstallationCost
+	deal.SalesCommissionCost = costs.SalesCommissionCost
+	deal.Profit = costs.Profit
+}
+
+// dealHardware loads the deal's catalog panel and inverter. Micro inverters
+// are counted one per panel unless a count is given.
+func (s *PricingService) dealHardware(ctx context.Context, deal *models.Deal, inverterCount int) (PricingHardware, error) {
+	var hardware PricingHardware
+	if deal.PanelID != nil {
+		panel, err := s.hardwareService.GetPanel(ctx, *deal.PanelID)
+		if err != nil {
+			return hardware, err
+		}
+		hardware.Panel = panel
+	}
+	if deal.InverterID != nil {
+		inverter, err := s.hardwareService.GetInverter(ctx, *deal.InverterID)
+		if err != nil {
+			return hardware, err
+		}
+		hardware.Inverter = inverter
+		hardware.InverterCount = inverterCount
+		if hardware.InverterCount <= 0 && inverter.IsMicro() {
+			hardware.InverterCount = deal.PanelCount
+		}
+	}
+	return hardware, nil
+}
+
+func inverterCost(inv *models.Inverter, count int, watts float64) float64 {
+	quantity := float64(count)
+	if quantity <= 0 {
+		quantity = 1
+	}
+	switch strings.ToLower(inv.CostType) {
+	case models.AdderCostTypePerWatt:
+		return inv.Cost * watts
+	case models.AdderCostTypePerKW:
+		return inv.Cost * watts / 1000
+	default:
+		return inv.Cost * quantity
+	}
+}
+
+// companyBasePrices returns the minimum and default base price per watt. The
+// company Baseline is the floor and BaselineAdder is added on top of it for
+// the default price offered to reps.
+func companyBasePrices(company *models.Company) (minimum, def float64) {
+	if company == nil || company.Baseline == nil || *company.Baseline <= 0 {
+		return 0, defaultBasePricePerWatt
+	}
+	minimum = *company.Baseline
+	def = minimum
+	if company.BaselineAdder != nil {
+		def += *company.BaselineAdder
+	}
+	return minimum, def
+}
+
+func companyCommissionRate(company *models.Company) float64 {
+	rate := defaultSalesCommissionRate
+	if company == nil {
+		return rate
+	}
+	if company.SalesCommissionDefault != nil {
+		rate = *company.SalesCommissionDefault
+	}
+	if company.SalesCommissionMin != nil && rate < *company.SalesCommissionMin {
+		rate = *company.SalesCommissionMin
+	}
+	if company.SalesCommissionMax != nil && *company.SalesCommissionMax > 0 && rate > *company.SalesCommissionMax {
+		rate = *company.SalesCommissionMax
+	}
+	return rate
+}
+
+var stateZipPattern = regexp.MustCompile(`\b([A-Z]{2})\s+\d{5}(?:-\d{4})?\b`)
+
+// stateFromAddress extracts the two letter state code from a US address such
+// as "123 Solar Street, CA 90210".
+func stateFromAddress(address string) string {
+	m := stateZipPattern.FindStringSubmatch(address)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/production"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+// ProductionService estimates production locally from the weather files on
+// disk, so a lead can be quoted before its 3D design is ready and designs
+// can be checked against an independent model.
+type ProductionService struct {
+	weather         *production.Library
+	leadRepo        *repo.LeadRepo
+	hardwareService *HardwareService
+}
+
+// EstimateProductionInput describes the array to estimate. With a lead,
+// anything left out is taken from the lead: its location, system size and
+// panel and inverter. Tilt and azimuth default to a south-facing 20° roof.
+type EstimateProductionInput struct {
+	LeadID        *int     `json:"lead_id,omitempty" example:"1"`
+	Latitude      *float64 `json:"latitude,omitempty" example:"37.7749"`
+	Longitude     *float64 `json:"longitude,omitempty" example:"-122.4194"`
+	SystemSizeKW  float64  `json:"system_size_kw,omitempty" example:"8.4"`
+	TiltDeg       *float64 `json:"tilt_deg,omitempty" example:"20"`
+	AzimuthDeg    *float64 `json:"azimuth_deg,omitempty" example:"180"`
+	PanelID       *int     `json:"panel_id,omitempty" example:"1"`
+	InverterID    *int     `json:"inverter_id,omitempty" example:"1"`
+	InverterCount int      `json:"inverter_count,omitempty" example:"1"`
+	Losses        *float64 `json:"losses,omitempty" example:"0.14"`
+	IncludeHourly bool     `json:"include_hourly,omitempty" example:"false"`
+}
+
+// ProductionEstimate is a local estimate with the system it was run for.
+// For a lead designed in LightFusion, DesignAnnualKWh is the design's
+// production and DifferencePct how far the estimate is above (positive) or
+// below it.
+type ProductionEstimate struct {
+	*production.Estimate
+	System          production.System `json:"system"`
+	DesignAnnualKWh *float64          `json:"design_annual_kwh,omitempty" example:"12400"`
+	DifferencePct   *float64          `json:"difference_pct,omitempty" example:"-2.01"`
+}
+
+func NewProductionService(weather *production.Library, leadRepo *repo.LeadRepo, hardwareService *HardwareService) *ProductionService {
+	return &ProductionService{weather: weather, leadRepo: leadRepo, hardwareService: hardwareService}
+}
+
+// Estimate models a year of production at the nearest weather station.
+func (s *ProductionService) Estimate(ctx context.Context, input EstimateProductionInput) (*ProductionEstimate, error) {
+	var designAnnualKWh float64
+	if input.LeadID != nil {
+		lead, err := s.leadRepo.GetByID(ctx, *input.LeadID)
+		if err != nil {
+			return nil, err
+		}
+		if input.Latitude == nil || input.Longitude == nil {
+			input.Latitude, input.Longitude = &lead.Latitude, &lead.Longitude
+		}
+		if input.SystemSizeKW == 0 {
+			input.SystemSizeKW = lead.SystemSize
+		}
+		if input.PanelID == nil {
+			input.PanelID = lead.PanelID
+		}
+		if input.InverterID == nil {
+			input.InverterID = lead.InverterID
+			input.InverterCount = lead.InverterCount
+		}
+		designAnnualKWh = lead.AnnualProduction
+	}
+	if input.Latitude == nil || input.Longitude == nil {
+		return nil, fmt.Errorf("%w: a location or lead is required", production.ErrInvalidSystem)
+	}
+
+	system := production.System{
+		SizeKW:     input.SystemSizeKW,
+		TiltDeg:    production.DefaultTiltDeg,
+		AzimuthDeg: production.DefaultAzimuthDeg,
+		Losses:     input.Losses,
+	}
+	if input.TiltDeg != nil {
+		system.TiltDeg = *input.TiltDeg
+	}
+	if input.AzimuthDeg != nil {
+		system.AzimuthDeg = *input.AzimuthDeg
+	}
+	if input.PanelID != nil {
+		panel, err := s.hardwareService.GetPanel(ctx, *input.PanelID)
+		if err != nil {
+			return nil, err
+		}
+		system.Module = production.Module{
+			TempCoeffPmax: panel.TempCoeffPmax,
+			NOCT:          panel.NOCT,
+			Efficiency:    panel.Efficiency,
+		}
+	}
+	if input.InverterID != nil {
+		inverter, err := s.hardwareService.GetInverter(ctx, *input.InverterID)
+		if err != nil {
+			return nil, err
+		}
+		count := max(input.InverterCount, 1)
+		system.InverterEfficiency = inverter.Efficiency / 100
+		system.InverterKW = inverter.Capacity * float64(count) / 1000
+	}
+	if err := system.Validate(); err != nil {
+		return nil, err
+	}
+
+	weather, distanceKm, err := s.weather.Nearest(*input.Latitude, *input.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	estimate, err := production.Run(weather, system)
+	if err != nil {
+		return nil, err
+	}
+	estimate.DistanceKm = math.Round(distanceKm*10) / 10
+	if !input.IncludeHourly {
+		estimate.Hourly = nil
+	}
+
+	result := &ProductionEstimate{Estimate: estimate, System: system}
+	if designAnnualKWh > 0 {
+		diff := math.Round((estimate.AnnualKWh-designAnnualKWh)/designAnnualKWh*10000) / 100
+		result.DesignAnnualKWh = &designAnnualKWh
+		result.DifferencePct = &diff
+	}
+	return result, nil
+}
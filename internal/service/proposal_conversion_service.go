@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errProposalAlreadyAccepted aborts a conversion that lost the race to
+// another acceptance of the same proposal.
+var errProposalAlreadyAccepted = errors.New("proposal already accepted")
+
+// ProposalConversionService accepts proposals and turns them into deals.
+type ProposalConversionService struct {
+	tx             *repo.TxManager
+	proposalRepo   *repo.ProposalRepo
+	optionRepo     *repo.ProposalOptionRepo
+	dealRepo       *repo.DealRepo
+	adderRepo      *repo.AdderRepo
+	pricingService *PricingService
+}
+
+// AcceptedProposal is an accepted proposal and the deal created from it.
+type AcceptedProposal struct {
+	Proposal *models.Proposal `json:"proposal"`
+	Deal     *models.Deal     `json:"deal"`
+}
+
+func NewProposalConversionService(tx *repo.TxManager, proposalRepo *repo.ProposalRepo, optionRepo *repo.ProposalOptionRepo, dealRepo *repo.DealRepo, adderRepo *repo.AdderRepo, pricingService *PricingService) *ProposalConversionService {
+	return &ProposalConversionService{
+		tx:             tx,
+		proposalRepo:   proposalRepo,
+		optionRepo:     optionRepo,
+		dealRepo:       dealRepo,
+		adderRepo:      adderRepo,
+		pricingService: pricingService,
+	}
+}
+
+// Accept accepts a proposal, with one of its options when it has any, and
+// creates its deal in the same transaction. The proposal is read again under
+// a row lock and only its status and the columns an option sets are written,
+// so concurrent changes such as a new document are kept. The deal is priced
+// so that its total is the accepted system cost; a price below the company
+// minimum or at a loss is honoured and the deal held for pricing review.
+//
+// Retrying an acceptance that already went through returns the proposal and
+// the deal created the first time; the unique proposal ID on deals keeps a
+// second deal from ever being created.
+func (s *ProposalConversionService) Accept(ctx context.Context, proposalID int, optionID *int) (*AcceptedProposal, error) {
+	proposal, err := s.proposalRepo.GetByID(ctx, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status == models.ProposalStatusAccepted {
+		return s.accepted(ctx, proposal, optionID)
+	}
+	if !proposal.CanTransitionTo(models.ProposalStatusAccepted) {
+		return nil, models.ErrInvalidProposalTransition
+	}
+	if proposal.IsExpired() {
+		if err := s.proposalRepo.Expire(ctx, proposal.ID); err != nil {
+			return nil, fmt.Errorf("failed to expire proposal: %w", err)
+		}
+		return nil, models.ErrProposalExpired
+	}
+
+	var deal *models.Deal
+	err = s.tx.Do(ctx, func(tx *gorm.DB) error {
+		current, err := s.proposalRepo.WithTx(tx).GetByIDForUpdate(ctx, proposal.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status == models.ProposalStatusAccepted {
+			return errProposalAlreadyAccepted
+		}
+		if !current.CanTransitionTo(models.ProposalStatusAccepted) {
+			return models.ErrInvalidProposalTransition
+		}
+
+		option, err := s.acceptedOption(ctx, current, optionID)
+		if err != nil {
+			return err
+		}
+		if option != nil {
+			option.Version.ApplyTo(current)
+			current.AcceptedOptionID = &option.ID
+		}
+		current.MarkAccepted()
+
+		var adders []*models.DealAdder
+		deal, adders, err = s.priceDeal(ctx, current, option)
+		if err != nil {
+			return err
+		}
+
+		if option != nil {
+			if err := s.optionRepo.WithTx(tx).Accept(ctx, option); err != nil {
+				return err
+			}
+		}
+		if err := s.proposalRepo.WithTx(tx).SaveAcceptance(ctx, current); err != nil {
+			return fmt.Errorf("failed to accept proposal: %w", err)
+		}
+		proposal = current
+		return s.createDeal(ctx, tx, deal, adders)
+	})
+	if errors.Is(err, errProposalAlreadyAccepted) {
+		current, err := s.proposalRepo.GetByID(ctx, proposal.ID)
+		if err != nil {
+			return nil, err
+		}
+		return s.accepted(ctx, current, optionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &AcceptedProposal{Proposal: proposal, Deal: deal}, nil
+}
+
+// accepted answers a repeated acceptance. The same option, or none, returns
+// the existing deal, creating it if the proposal was accepted before deals
+// were created automatically. A different option is refused.
+func (s *ProposalConversionService) accepted(ctx context.Context, proposal *models.Proposal, optionID *int) (*AcceptedProposal, error) {
+	if optionID != nil && !sameID(optionID, proposal.AcceptedOptionID) {
+		return nil, models.ErrInvalidProposalTransition
+	}
+
+	deal, err := s.dealRepo.GetByProposalID(ctx, proposal.ID)
+	if err == nil {
+		return &AcceptedProposal{Proposal: proposal, Deal: deal}, nil
+	}
+	if !errors.Is(err, models.ErrDealNotFound) {
+		return nil, err
+	}
+
+	var option *models.ProposalOption
+	if proposal.AcceptedOptionID != nil {
+		option, err = s.optionRepo.Get(ctx, proposal.ID, *proposal.AcceptedOptionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	deal, adders, err := s.priceDeal(ctx, proposal, option)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tx.Do(ctx, func(tx *gorm.DB) error {
+		if _, err := s.proposalRepo.WithTx(tx).GetByIDForUpdate(ctx, proposal.ID); err != nil {
+			return err
+		}
+		if _, err := s.dealRepo.WithTx(tx).GetByProposalID(ctx, proposal.ID); err == nil {
+			return errProposalAlreadyAccepted
+		}
+		return s.createDeal(ctx, tx, deal, adders)
+	})
+	if errors.Is(err, errProposalAlreadyAccepted) {
+		deal, err = s.dealRepo.GetByProposalID(ctx, proposal.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &AcceptedProposal{Proposal: proposal, Deal: deal}, nil
+}
+
+// acceptedOption loads the option being accepted. A proposal with options
+// must be accepted through one of them.
+func (s *ProposalConversionService) acceptedOption(ctx context.Context, proposal *models.Proposal, optionID *int) (*models.ProposalOption, error) {
+	if optionID == nil {
+		count, err := s.optionRepo.Count(ctx, proposal.ID)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, models.ErrProposalOptionRequired
+		}
+		return nil, nil
+	}
+
+	option, err := s.optionRepo.Get(ctx, proposal.ID, *optionID)
+	if err != nil {
+		return nil, err
+	}
+	if option.IsLocked() {
+		return nil, models.ErrProposalOptionLocked
+	}
+	return option, nil
+}
+
+// priceDeal builds the deal for an accepted proposal and prices it at the
+// proposal's system cost. The homeowner has agreed to that price, so one
+// below the company minimum or at a loss does not undo the acceptance; the
+// deal is held for pricing review instead.
+func (s *ProposalConversionService) priceDeal(ctx context.Context, proposal *models.Proposal, option *models.ProposalOption) (*models.Deal, []*models.DealAdder, error) {
+	proposalID := proposal.ID
+	deal := &models.Deal{
+		UUID:              uuid.New().String(),
+		ProposalID:        &proposalID,
+		LeadID:            proposal.LeadID,
+		ProjectID:         proposal.ProjectID,
+		SalesID:           proposal.SalesID,
+		HomeownerID:       proposal.HomeownerID,
+		CompanyID:         proposal.CompanyID,
+		FinancingOptionID: proposal.FinancingOptionID,
+		FinancingProvider: proposal.FinancingProvider,
+		SystemSize:        proposal.SystemSize,
+		PanelCount:        proposal.PanelCount,
+		PanelID:           proposal.PanelID,
+		InverterID:        proposal.InverterID,
+		Address:           proposal.Address,
+		ConsumptionKWH:    int(math.Round(proposal.AnnualConsumption)),
+		ProductionKWH:     int(math.Round(proposal.AnnualProduction)),
+		UtilityID:         proposal.UtilityID,
+	}
+
+	input := PricingInput{AllowBelowMinimum: true}
+	if option != nil && option.Version != nil {
+		input.InverterCount = option.Version.InverterCount
+	}
+	priced, err := s.pricingService.PriceAtContractPrice(ctx, deal, proposal.SystemCost, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to price deal: %w", err)
+	}
+	if priced.NeedsReview {
+		deal.Status = models.DealStatusPricingReview
+	}
+	return deal, priced.Adders, nil
+}
+
+func (s *ProposalConversionService) createDeal(ctx context.Context, tx *gorm.DB, deal *models.Deal, adders []*models.DealAdder) error {
+	if err := s.dealRepo.WithTx(tx).Create(ctx, deal); err != nil {
+		return fmt.Errorf("failed to create deal: %w", err)
+	}
+
+	lines := make([]*models.DealAdder, 0, len(adders))
+	for _, line := range adders {
+		copied := *line
+		copied.ID = 0
+		lines = append(lines, &copied)
+	}
+	if err := s.adderRepo.WithTx(tx).ReplaceDealAdders(ctx, deal.ID, lines); err != nil {
+		return fmt.Errorf("failed to save deal adders: %w", err)
+	}
+	return nil
+}
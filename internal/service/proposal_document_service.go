@@ -0,0 +1,655 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/storage"
+	"github.com/go-pdf/fpdf"
+)
+
+// proposalTemplateVersion is part of the document hash. Bump it when the
+// layout changes so existing PDFs are rendered again.
+const proposalTemplateVersion = 2
+
+const (
+	proposalUtilityEscalator = 0.03
+	proposalDegradation      = 0.005
+	proposalYears            = 25
+	maxLogoBytes             = 5 << 20
+	maxLogoPixels            = 16 << 20
+)
+
+// Typical monthly shares of annual production and consumption, used when a
+// proposal has no monthly figures of its own.
+var (
+	defaultProductionProfile  = [12]float64{0.055, 0.063, 0.083, 0.092, 0.102, 0.106, 0.107, 0.101, 0.088, 0.076, 0.060, 0.052}
+	defaultConsumptionProfile = [12]float64{0.090, 0.080, 0.080, 0.075, 0.080, 0.090, 0.100, 0.100, 0.085, 0.075, 0.075, 0.085}
+	monthLabels               = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+)
+
+// ProposalDocumentService renders proposal PDFs and keeps them in a document
+// store. A PDF is only rendered again when the inputs it was built from
+// change.
+type ProposalDocumentService struct {
+	proposalRepo    *repo.ProposalRepo
+	companyRepo     *repo.CompanyRepo
+	hardwareService *HardwareService
+	store           storage.DocumentStore
+	httpClient      *http.Client
+}
+
+// proposalDocument is everything a proposal PDF is rendered from.
+type proposalDocument struct {
+	Proposal           *models.Proposal
+	CompanyName        string
+	LogoPath           string
+	PanelName          string
+	InverterName       string
+	MonthlyProduction  [12]float64
+	MonthlyConsumption [12]float64
+}
+
+type savingsRow struct {
+	Year              int
+	BillWithoutSolar  float64
+	BillWithSolar     float64
+	Savings           float64
+	CumulativeSavings float64
+}
+
+type financingRow struct {
+	Name           string
+	Upfront        float64
+	Monthly        float64
+	NetCost        float64
+	LifetimeSaving float64
+}
+
+func NewProposalDocumentService(proposalRepo *repo.ProposalRepo, companyRepo *repo.CompanyRepo, hardwareService *HardwareService, store storage.DocumentStore) *ProposalDocumentService {
+	return &ProposalDocumentService{
+		proposalRepo:    proposalRepo,
+		companyRepo:     companyRepo,
+		hardwareService: hardwareService,
+		store:           store,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// EnsureDocument renders the proposal PDF if it is missing or its inputs
+// changed since it was last rendered, and saves the new URL and hash on the
+// proposal. It reports whether a new PDF was rendered.
+func (s *ProposalDocumentService) EnsureDocument(ctx context.Context, proposal *models.Proposal) (bool, error) {
+	doc, err := s.loadDocument(ctx, proposal)
+	if err != nil {
+		return false, err
+	}
+	hash, err := doc.hash()
+	if err != nil {
+		return false, err
+	}
+	if proposal.DocumentURL == proposalDocumentURL(proposal) && proposal.DocumentHash == hash {
+		return false, nil
+	}
+	if err := s.render(ctx, proposal, doc, hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Regenerate renders the proposal PDF even if its inputs are unchanged.
+func (s *ProposalDocumentService) Regenerate(ctx context.Context, proposal *models.Proposal) error {
+	doc, err := s.loadDocument(ctx, proposal)
+	if err != nil {
+		return err
+	}
+	hash, err := doc.hash()
+	if err != nil {
+		return err
+	}
+	return s.render(ctx, proposal, doc, hash)
+}
+
+// Open returns the stored PDF of a proposal.
+func (s *ProposalDocumentService) Open(ctx context.Context, proposal *models.Proposal) (io.ReadCloser, error) {
+	return s.store.Open(ctx, proposalDocumentName(proposal))
+}
+
+func (s *ProposalDocumentService) render(ctx context.Context, proposal *models.Proposal, doc *proposalDocument, hash string) error {
+	var buf bytes.Buffer
+	if err := s.renderPDF(&buf, doc); err != nil {
+		return fmt.Errorf("failed to render proposal PDF: %w", err)
+	}
+
+	if _, err := s.store.Save(ctx, proposalDocumentName(proposal), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to store proposal PDF: %w", err)
+	}
+
+	// Stored documents are private; the PDF is only served through the
+	// proposal routes, which check who may see it.
+	proposal.DocumentURL = proposalDocumentURL(proposal)
+	proposal.DocumentHash = hash
+	if err := s.proposalRepo.SaveDocument(ctx, proposal.ID, proposal.DocumentURL, proposal.DocumentHash); err != nil {
+		return fmt.Errorf("failed to save proposal document: %w", err)
+	}
+	return nil
+}
+
+func (s *ProposalDocumentService) loadDocument(ctx context.Context, proposal *models.Proposal) (*proposalDocument, error) {
+	doc := &proposalDocument{Proposal: proposal}
+
+	company, err := s.companyRepo.GetByID(ctx, proposal.CompanyID)
+	if err != nil {
+		return nil, models.ErrCompanyNotFound
+	}
+	doc.CompanyName = company.Name
+	if company.DisplayName != "" {
+		doc.CompanyName = company.DisplayName
+	}
+	if company.LogoPath != nil {
+		doc.LogoPath = *company.LogoPath
+	}
+
+	if proposal.PanelID != nil {
+		if panel, err := s.hardwareService.GetPanel(ctx, *proposal.PanelID); err == nil {
+			doc.PanelName = panel.DisplayName
+			if doc.PanelName == "" {
+				doc.PanelName = panel.Manufacturer + " " + panel.Model
+			}
+		}
+	}
+	if proposal.InverterID != nil {
+		if inverter, err := s.hardwareService.GetInverter(ctx, *proposal.InverterID); err == nil {
+			doc.InverterName = inverter.Name
+			if doc.InverterName == "" {
+				doc.InverterName = inverter.Manufacturer + " " + inverter.Model
+			}
+		}
+	}
+
+	doc.MonthlyProduction = monthlySeries(proposal.MonthlyProduction, proposal.AnnualProduction, defaultProductionProfile)
+	doc.MonthlyConsumption = monthlySeries(proposal.MonthlyConsumption, proposal.AnnualConsumption, defaultConsumptionProfile)
+	return doc, nil
+}
+
+// hash fingerprints every input that shows up in the PDF.
+func (d *proposalDocument) hash() (string, error) {
+	p := d.Proposal
+	inputs := map[string]interface{}{
+		"template":               proposalTemplateVersion,
+		"code":                   p.Code,
+		"company":                d.CompanyName,
+		"logo":                   d.LogoPath,
+		"panel":                  d.PanelName,
+		"inverter":               d.InverterName,
+		"address":                p.Address,
+		"system_size":            p.SystemSize,
+		"panel_count":            p.PanelCount,
+		"battery_count":          p.BatteryCount,
+		"monthly_production":     d.MonthlyProduction,
+		"monthly_consumption":    d.MonthlyConsumption,
+		"system_cost":            p.SystemCost,
+		"incentives":             p.Incentives,
+		"net_cost":               p.NetCost,
+		"monthly_payment":        p.MonthlyPayment,
+		"loan_term_months":       p.LoanTermMonths,
+		"cash_flow":              p.CashFlow,
+		"financing_provider":     p.FinancingProvider,
+		"current_utility_bill":   p.CurrentUtilityBill,
+		"estimated_utility_bill": p.EstimatedUtilityBill,
+		"expires_at":             p.ExpiresAt,
+		"notes":                  p.Notes,
+	}
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *ProposalDocumentService) renderPDF(w io.Writer, doc *proposalDocument) error {
+	p := doc.Proposal
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetTitle("Solar Proposal "+p.Code, true)
+	pdf.SetAuthor(doc.CompanyName, true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 30
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(130, 130, 130)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s  |  Page %d", tr(doc.CompanyName), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	// Header: logo (or company name) and title
+	logoDrawn := false
+	if doc.LogoPath != "" {
+		if err := drawLogo(pdf, s.httpClient, doc.LogoPath); err != nil {
+			log.Printf("Warning: failed to add company logo to proposal %s: %v", p.Code, err)
+		} else {
+			logoDrawn = true
+		}
+	}
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(33, 37, 41)
+	if !logoDrawn {
+		pdf.CellFormat(contentWidth, 10, tr(doc.CompanyName), "", 1, "L", false, 0, "")
+	} else {
+		pdf.SetY(37)
+	}
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.SetTextColor(230, 126, 34)
+	pdf.CellFormat(contentWidth, 12, "Your Solar Proposal", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.CellFormat(contentWidth, 6, tr(p.Address), "", 1, "L", false, 0, "")
+	meta := "Proposal " + p.Code
+	if p.ExpiresAt != nil {
+		meta += "  |  Valid until " + p.ExpiresAt.Format("January 2, 2006")
+	}
+	pdf.CellFormat(contentWidth, 6, meta, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	// System specs
+	sectionTitle(pdf, contentWidth, "Your System")
+	specs := [][2]string{
+		{"System size", fmt.Sprintf("%.2f kW", p.SystemSize)},
+		{"Panels", fmt.Sprintf("%d", p.PanelCount)},
+	}
+	if doc.PanelName != "" {
+		specs = append(specs, [2]string{"Panel model", doc.PanelName})
+	}
+	if doc.InverterName != "" {
+		specs = append(specs, [2]string{"Inverter", doc.InverterName})
+	}
+	if p.BatteryCount > 0 {
+		specs = append(specs, [2]string{"Batteries", fmt.Sprintf("%d", p.BatteryCount)})
+	}
+	specs = append(specs,
+		[2]string{"Estimated annual production", fmt.Sprintf("%s kWh", formatNumber(p.AnnualProduction, 0))},
+		[2]string{"Annual consumption", fmt.Sprintf("%s kWh", formatNumber(p.AnnualConsumption, 0))},
+	)
+	if p.AnnualConsumption > 0 {
+		specs = append(specs, [2]string{"Energy offset", fmt.Sprintf("%.0f%%", p.AnnualProduction/p.AnnualConsumption*100)})
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(33, 37, 41)
+	for i, spec := range specs {
+		fill := i%2 == 0
+		pdf.SetFillColor(245, 245, 245)
+		pdf.CellFormat(contentWidth/2, 7, spec[0], "", 0, "L", fill, 0, "")
+		pdf.CellFormat(contentWidth/2, 7, tr(spec[1]), "", 1, "R", fill, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Production vs consumption chart
+	sectionTitle(pdf, contentWidth, "Monthly Production vs Consumption (kWh)")
+	drawMonthlyChart(pdf, 15, pdf.GetY(), contentWidth, 60, doc.MonthlyProduction, doc.MonthlyConsumption)
+	pdf.Ln(4)
+
+	// Financing comparison
+	pdf.AddPage()
+	sectionTitle(pdf, contentWidth, "Financing Options")
+	financing := financingRows(p)
+	widths := []float64{contentWidth * 0.28, contentWidth * 0.18, contentWidth * 0.18, contentWidth * 0.18, contentWidth * 0.18}
+	tableHeader(pdf, widths, []string{"Option", "Due today", "Monthly", "Net cost", "25-yr savings"})
+	pdf.SetFont("Helvetica", "", 10)
+	for i, row := range financing {
+		fill := i%2 == 1
+		pdf.SetFillColor(245, 245, 245)
+		pdf.CellFormat(widths[0], 7, tr(row.Name), "", 0, "L", fill, 0, "")
+		pdf.CellFormat(widths[1], 7, formatMoney(row.Upfront), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[2], 7, formatMoney(row.Monthly), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[3], 7, formatMoney(row.NetCost), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[4], 7, formatMoney(row.LifetimeSaving), "", 1, "R", fill, 0, "")
+	}
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.MultiCell(contentWidth, 4, fmt.Sprintf("System price %s less an estimated %s in incentives. Savings assume utility rates rise %.0f%% a year and panel output falls %.1f%% a year.",
+		formatMoney(p.SystemCost), formatMoney(p.Incentives), proposalUtilityEscalator*100, proposalDegradation*100), "", "L", false)
+	pdf.Ln(4)
+
+	// Savings table
+	sectionTitle(pdf, contentWidth, "Estimated Savings")
+	widths = []float64{contentWidth * 0.16, contentWidth * 0.21, contentWidth * 0.21, contentWidth * 0.21, contentWidth * 0.21}
+	tableHeader(pdf, widths, []string{"Year", "Bill without solar", "Bill with solar", "Savings", "Cumulative"})
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(33, 37, 41)
+	for i, row := range savingsRows(p) {
+		fill := i%2 == 1
+		pdf.SetFillColor(245, 245, 245)
+		pdf.CellFormat(widths[0], 7, fmt.Sprintf("%d", row.Year), "", 0, "L", fill, 0, "")
+		pdf.CellFormat(widths[1], 7, formatMoney(row.BillWithoutSolar), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[2], 7, formatMoney(row.BillWithSolar), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[3], 7, formatMoney(row.Savings), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[4], 7, formatMoney(row.CumulativeSavings), "", 1, "R", fill, 0, "")
+	}
+
+	if strings.TrimSpace(p.Notes) != "" {
+		pdf.Ln(6)
+		sectionTitle(pdf, contentWidth, "Notes")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.SetTextColor(33, 37, 41)
+		pdf.MultiCell(contentWidth, 5, tr(p.Notes), "", "L", false)
+	}
+
+	return pdf.Output(w)
+}
+
+// drawLogo places the company logo in the top-left corner. Logos may be a
+// URL or a path on disk; media paths are resolved against the working
+// directory the API serves /media/ from.
+func drawLogo(pdf *fpdf.Fpdf, httpClient *http.Client, logoPath string) error {
+	var data []byte
+
+	if strings.HasPrefix(logoPath, "http://") || strings.HasPrefix(logoPath, "https://") {
+		resp, err := httpClient.Get(logoPath)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("logo request returned %d", resp.StatusCode)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxLogoBytes))
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(logoPath)
+		if err != nil && strings.HasPrefix(logoPath, "/") {
+			data, err = os.ReadFile("." + logoPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// fpdf keeps any error from a bad image and fails the whole document
+	// with it, so the logo is decoded here first and handed over as a plain
+	// 8-bit PNG that fpdf always reads.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unreadable logo: %w", err)
+	}
+	if config.Width*config.Height > maxLogoPixels {
+		return fmt.Errorf("logo is too large: %dx%d", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unreadable logo: %w", err)
+	}
+	rgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rgba); err != nil {
+		return err
+	}
+
+	opts := fpdf.ImageOptions{ImageType: "png", ReadDpi: true}
+	pdf.RegisterImageOptionsReader("logo", opts, &buf)
+	if err := pdf.Error(); err != nil {
+		pdf.ClearError()
+		return err
+	}
+	pdf.ImageOptions("logo", 15, 12, 0, 20, false, opts, 0, "")
+	if err := pdf.Error(); err != nil {
+		pdf.ClearError()
+		return err
+	}
+	return nil
+}
+
+func sectionTitle(pdf *fpdf.Fpdf, width float64, title string) {
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.CellFormat(width, 9, title, "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+}
+
+func tableHeader(pdf *fpdf.Fpdf, widths []float64, headers []string) {
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 126, 34)
+	pdf.SetTextColor(255, 255, 255)
+	for i, header := range headers {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		ln := 0
+		if i == len(headers)-1 {
+			ln = 1
+		}
+		pdf.CellFormat(widths[i], 8, header, "", ln, align, true, 0, "")
+	}
+	pdf.SetTextColor(33, 37, 41)
+}
+
+// drawMonthlyChart draws paired monthly bars for production and consumption.
+func drawMonthlyChart(pdf *fpdf.Fpdf, x, y, width, height float64, production, consumption [12]float64) {
+	maxValue := 0.0
+	for i := 0; i < 12; i++ {
+		maxValue = math.Max(maxValue, math.Max(production[i], consumption[i]))
+	}
+	if maxValue <= 0 {
+		maxValue = 1
+	}
+	axisMax := niceCeiling(maxValue)
+
+	labelWidth := 14.0
+	plotX := x + labelWidth
+	plotWidth := width - labelWidth
+	plotHeight := height - 14
+
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.SetDrawColor(220, 220, 220)
+	for i := 0; i <= 4; i++ {
+		value := axisMax * float64(i) / 4
+		lineY := y + plotHeight - plotHeight*float64(i)/4
+		pdf.Line(plotX, lineY, plotX+plotWidth, lineY)
+		pdf.SetXY(x, lineY-2)
+		pdf.CellFormat(labelWidth-1, 4, formatNumber(value, 0), "", 0, "R", false, 0, "")
+	}
+
+	slot := plotWidth / 12
+	barWidth := slot * 0.35
+	for i := 0; i < 12; i++ {
+		slotX := plotX + slot*float64(i) + slot*0.15
+
+		h := plotHeight * production[i] / axisMax
+		pdf.SetFillColor(230, 126, 34)
+		pdf.Rect(slotX, y+plotHeight-h, barWidth, h, "F")
+
+		h = plotHeight * consumption[i] / axisMax
+		pdf.SetFillColor(52, 152, 219)
+		pdf.Rect(slotX+barWidth, y+plotHeight-h, barWidth, h, "F")
+
+		pdf.SetXY(plotX+slot*float64(i), y+plotHeight+1)
+		pdf.CellFormat(slot, 4, monthLabels[i], "", 0, "C", false, 0, "")
+	}
+
+	legendY := y + plotHeight + 7
+	pdf.SetFillColor(230, 126, 34)
+	pdf.Rect(plotX, legendY+1, 3, 3, "F")
+	pdf.SetXY(plotX+4, legendY)
+	pdf.CellFormat(30, 5, "Solar production", "", 0, "L", false, 0, "")
+	pdf.SetFillColor(52, 152, 219)
+	pdf.Rect(plotX+40, legendY+1, 3, 3, "F")
+	pdf.SetXY(plotX+44, legendY)
+	pdf.CellFormat(30, 5, "Home consumption", "", 0, "L", false, 0, "")
+
+	pdf.SetXY(x, y+height)
+	pdf.SetTextColor(33, 37, 41)
+}
+
+func financingRows(p *models.Proposal) []financingRow {
+	billSavings := 0.0
+	for _, row := range savingsRows(p) {
+		billSavings = row.CumulativeSavings
+	}
+
+	rows := []financingRow{{
+		Name:           "Cash",
+		Upfront:        p.SystemCost,
+		NetCost:        p.NetCost,
+		LifetimeSaving: billSavings - p.NetCost,
+	}}
+	if p.MonthlyPayment > 0 {
+		name := "Loan"
+		if p.FinancingProvider != "" {
+			name = "Loan - " + p.FinancingProvider
+		}
+		if months := loanTermMonths(p); months%12 == 0 {
+			name += fmt.Sprintf(" (%d yr)", months/12)
+		} else {
+			name += fmt.Sprintf(" (%d mo)", months)
+		}
+		loanCost := loanPayments(p)
+		rows = append(rows, financingRow{
+			Name:           name,
+			Monthly:        p.MonthlyPayment,
+			NetCost:        loanCost - p.Incentives,
+			LifetimeSaving: billSavings - loanCost + p.Incentives,
+		})
+	}
+	return rows
+}
+
+// loanTermMonths is the term the proposal's monthly payment is paid over,
+// the proposal's own or else the quote's default of 25 years.
+func loanTermMonths(p *models.Proposal) int {
+	if p.LoanTermMonths > 0 {
+		return p.LoanTermMonths
+	}
+	return proposalYears * 12
+}
+
+// loanPayments totals the loan payments in the quote's cash flow, or the
+// monthly payment over the loan's term when the proposal has no cash flow.
+func loanPayments(p *models.Proposal) float64 {
+	if len(p.CashFlow) == 0 {
+		return p.MonthlyPayment * float64(loanTermMonths(p))
+	}
+	total := 0.0
+	for _, year := range p.CashFlow {
+		total -= year.FinancingPayment
+	}
+	return roundCents(total)
+}
+
+// savingsRows projects the utility bill with and without solar for selected
+// years of the system's life.
+func savingsRows(p *models.Proposal) []savingsRow {
+	var rows []savingsRow
+	cumulative := 0.0
+	for year := 1; year <= proposalYears; year++ {
+		escalation := math.Pow(1+proposalUtilityEscalator, float64(year-1))
+		without := p.CurrentUtilityBill * 12 * escalation
+		offset := (p.CurrentUtilityBill - p.EstimatedUtilityBill) * 12 * escalation * math.Pow(1-proposalDegradation, float64(year-1))
+		with := without - offset
+		cumulative += offset
+		if year == 1 || year%5 == 0 {
+			rows = append(rows, savingsRow{
+				Year:              year,
+				BillWithoutSolar:  roundCents(without),
+				BillWithSolar:     roundCents(with),
+				Savings:           roundCents(offset),
+				CumulativeSavings: roundCents(cumulative),
+			})
+		}
+	}
+	return rows
+}
+
+// monthlySeries uses the given monthly values when there are twelve of them
+// and otherwise spreads the annual total over a typical profile.
+func monthlySeries(values []float64, annual float64, profile [12]float64) [12]float64 {
+	var series [12]float64
+	if len(values) == 12 {
+		copy(series[:], values)
+		return series
+	}
+	total := 0.0
+	for _, share := range profile {
+		total += share
+	}
+	for i, share := range profile {
+		series[i] = math.Round(annual * share / total)
+	}
+	return series
+}
+
+func proposalDocumentURL(proposal *models.Proposal) string {
+	return fmt.Sprintf("/api/proposals/%d/pdf", proposal.ID)
+}
+
+func proposalDocumentName(proposal *models.Proposal) string {
+	return "proposals/" + proposal.Code + ".pdf"
+}
+
+func niceCeiling(v float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, step := range []float64{1, 2, 2.5, 5, 10} {
+		if step*magnitude >= v {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatMoney(v float64) string {
+	if v < 0 {
+		return "-$" + formatNumber(-v, 0)
+	}
+	return "$" + formatNumber(v, 0)
+}
+
+func formatNumber(v float64, decimals int) string {
+	s := fmt.Sprintf("%.*f", decimals, v)
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i:]
+	}
+	neg := strings.HasPrefix(intPart, "-")
+	intPart = strings.TrimPrefix(intPart, "-")
+
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if neg {
+		return "-" + b.String() + frac
+	}
+	return b.String() + frac
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"gorm.io/gorm"
+)
+
+// ProposalFollowUpService expires stale proposals and sends the reminders
+// configured in each company's proposal reminder settings.
+type ProposalFollowUpService struct {
+	proposalRepo     *repo.ProposalRepo
+	notificationRepo *repo.NotificationRepo
+	userRepo         *repo.UserRepo
+	companyRepo      *repo.CompanyRepo
+	email            *client.SendGridClient
+	sms              *client.TwilioClient
+	publicURL        string
+}
+
+// ProposalReminderSettingsRequest updates a company's reminder settings.
+// Omitted fields are left unchanged.
+type ProposalReminderSettingsRequest struct {
+	Enabled             *bool `json:"enabled,omitempty" example:"true"`
+	ReminderDays        []int `json:"reminder_days,omitempty" example:"7,3,1"`
+	ViewedFollowUpHours *int  `json:"viewed_follow_up_hours,omitempty" example:"48"`
+	EmailEnabled        *bool `json:"email_enabled,omitempty" example:"true"`
+	SMSEnabled          *bool `json:"sms_enabled,omitempty" example:"false"`
+}
+
+// FollowUpResult summarizes one follow-up run.
+type FollowUpResult struct {
+	Expired       int64 `json:"expired"`
+	RemindersSent int   `json:"reminders_sent"`
+	FollowUpsSent int   `json:"follow_ups_sent"`
+	Failed        int   `json:"failed"`
+}
+
+// NewProposalFollowUpService creates the follow-up service. publicURL is the
+// base of the homeowner proposal links, e.g. https://app.sunready.com.
+// A nil email or SMS client disables that channel.
+func NewProposalFollowUpService(proposalRepo *repo.ProposalRepo, notificationRepo *repo.NotificationRepo, userRepo *repo.UserRepo, companyRepo *repo.CompanyRepo, email *client.SendGridClient, sms *client.TwilioClient, publicURL string) *ProposalFollowUpService {
+	return &ProposalFollowUpService{
+		proposalRepo:     proposalRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		companyRepo:      companyRepo,
+		email:            email,
+		sms:              sms,
+		publicURL:        strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// Settings returns a company's reminder settings, or the defaults when the
+// company has not configured any.
+func (s *ProposalFollowUpService) Settings(ctx context.Context, companyID int) (*models.ProposalReminderSettings, error) {
+	settings, err := s.notificationRepo.GetReminderSettings(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return models.DefaultProposalReminderSettings(companyID), nil
+	}
+	return settings, nil
+}
+
+// UpdateSettings applies req to a company's reminder settings.
+func (s *ProposalFollowUpService) UpdateSettings(ctx context.Context, companyID int, req ProposalReminderSettingsRequest) (*models.ProposalReminderSettings, error) {
+	if _, err := s.companyRepo.GetByID(ctx, companyID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrCompanyNotFound
+		}
+		return nil, err
+	}
+
+	settings, err := s.Settings(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.ReminderDays != nil {
+		settings.ReminderDays = req.ReminderDays
+	}
+	if req.ViewedFollowUpHours != nil {
+		settings.ViewedFollowUpHours = *req.ViewedFollowUpHours
+	}
+	if req.EmailEnabled != nil {
+		settings.EmailEnabled = *req.EmailEnabled
+	}
+	if req.SMSEnabled != nil {
+		settings.SMSEnabled = *req.SMSEnabled
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepo.SaveReminderSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to save reminder settings: %w", err)
+	}
+	return settings, nil
+}
+
+// Notifications lists the notifications sent for a proposal.
+func (s *ProposalFollowUpService) Notifications(ctx context.Context, proposalID int) ([]*models.NotificationLog, error) {
+	if _, err := s.proposalRepo.GetByID(ctx, proposalID); err != nil {
+		return nil, err
+	}
+	return s.notificationRepo.ListByProposal(ctx, proposalID)
+}
+
+// RunOnce expires open proposals past their expiry date, reminds homeowners
+// of proposals about to expire and tells sales reps about proposals that
+// were viewed but not accepted. Every notification is claimed in the
+// notification log before it is sent, so overlapping or restarted runs
+// never send the same one twice.
+func (s *ProposalFollowUpService) RunOnce(ctx context.Context, now time.Time) (*FollowUpResult, error) {
+	result := &FollowUpResult{}
+
+	expired, err := s.proposalRepo.ExpireOpen(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire proposals: %w", err)
+	}
+	result.Expired = expired
+
+	proposals, err := s.proposalRepo.ListOpen(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open proposals: %w", err)
+	}
+
+	settingsByCompany := make(map[int]*models.ProposalReminderSettings)
+	companyNames := make(map[int]string)
+	for _, proposal := range proposals {
+		settings, ok := settingsByCompany[proposal.CompanyID]
+		if !ok {
+			settings, err = s.Settings(ctx, proposal.CompanyID)
+			if err != nil {
+				log.Printf("Warning: failed to load reminder settings for company %d: %v", proposal.CompanyID, err)
+				continue
+			}
+			settingsByCompany[proposal.CompanyID] = settings
+		}
+		if !settings.Enabled {
+			continue
+		}
+
+		companyName, ok := companyNames[proposal.CompanyID]
+		if !ok {
+			if company, err := s.companyRepo.GetByID(ctx, proposal.CompanyID); err == nil {
+				companyName = company.Name
+			}
+			companyNames[proposal.CompanyID] = companyName
+		}
+
+		if days, ok := dueReminder(proposal, settings.ReminderDays, now); ok {
+			sent, failed := s.remindHomeowner(ctx, proposal, settings, companyName, days)
+			result.RemindersSent += sent
+			result.Failed += failed
+		}
+
+		if followUpDue(proposal, settings.ViewedFollowUpHours, now) {
+			sent, failed := s.notifySales(ctx, proposal, settings)
+			result.FollowUpsSent += sent
+			result.Failed += failed
+		}
+	}
+
+	return result, nil
+}
+
+// Run calls RunOnce every interval until ctx is done.
+func (s *ProposalFollowUpService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.RunOnce(ctx, time.Now())
+		if err != nil {
+			log.Printf("Warning: proposal follow-up run failed: %v", err)
+		} else if result.Expired > 0 || result.RemindersSent > 0 || result.FollowUpsSent > 0 || result.Failed > 0 {
+			log.Printf("Proposal follow-ups: %d expired, %d reminders sent, %d follow-ups sent, %d failed",
+				result.Expired, result.RemindersSent, result.FollowUpsSent, result.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ProposalFollowUpService) remindHomeowner(ctx context.Context, proposal *models.Proposal, settings *models.ProposalReminderSettings, companyName string, days int) (sent, failed int) {
+	homeowner, err := s.userRepo.GetByID(ctx, proposal.HomeownerID)
+	if err != nil {
+		log.Printf("Warning: failed to load homeowner %d for proposal %d: %v", proposal.HomeownerID, proposal.ID, err)
+		return 0, 1
+	}
+
+	sender := companyName
+	if sender == "" {
+		sender = "SunReady"
+	}
+	expires := proposal.ExpiresAt.Format("January 2, 2006")
+	link := s.proposalLink(proposal)
+	key := fmt.Sprintf("proposal:%d:reminder:%d", proposal.ID, days)
+
+	if settings.EmailEnabled && s.email != nil && homeowner.Email != "" {
+		subject := fmt.Sprintf("Your solar proposal from %s expires on %s", sender, expires)
+		body := fmt.Sprintf("Hi %s,\n\nYour solar proposal %s expires on %s. You can review and accept it here:\n%s\n\n%s",
+			firstName(homeowner), proposal.Code, expires, link, sender)
+		ok, err := s.deliver(ctx, proposal, models.NotificationKindProposalReminder, key, models.NotificationChannelEmail, homeowner.Email, func() error {
+			return s.email.SendEmail(homeowner.Email, fullName(homeowner), subject, body)
+		})
+		sent, failed = tally(sent, failed, ok, err)
+	}
+
+	if settings.SMSEnabled && s.sms != nil && homeowner.PhoneNumber != nil && *homeowner.PhoneNumber != "" {
+		phone := *homeowner.PhoneNumber
+		body := fmt.Sprintf("%s: your solar proposal expires on %s. Review it here: %s", sender, expires, link)
+		ok, err := s.deliver(ctx, proposal, models.NotificationKindProposalReminder, key, models.NotificationChannelSMS, phone, func() error {
+			return s.sms.SendSMS(phone, body)
+		})
+		sent, failed = tally(sent, failed, ok, err)
+	}
+
+	return sent, failed
+}
+
+func (s *ProposalFollowUpService) notifySales(ctx context.Context, proposal *models.Proposal, settings *models.ProposalReminderSettings) (sent, failed int) {
+	rep, err := s.userRepo.GetByID(ctx, proposal.SalesID)
+	if err != nil {
+		log.Printf("Warning: failed to load sales rep %d for proposal %d: %v", proposal.SalesID, proposal.ID, err)
+		return 0, 1
+	}
+
+	homeownerName := "The homeowner"
+	if homeowner, err := s.userRepo.GetByID(ctx, proposal.HomeownerID); err == nil {
+		if name := fullName(homeowner); name != "" {
+			homeownerName = name
+		}
+	}
+	viewed := proposal.ViewedAt.Format("January 2, 2006")
+	key := fmt.Sprintf("proposal:%d:viewed_follow_up", proposal.ID)
+
+	if settings.EmailEnabled && s.email != nil && rep.Email != "" {
+		subject := fmt.Sprintf("Proposal %s was viewed but not accepted", proposal.Code)
+		body := fmt.Sprintf("Hi %s,\n\n%s opened proposal %s on %s and has not accepted it yet.", firstName(rep), homeownerName, proposal.Code, viewed)
+		if proposal.ExpiresAt != nil {
+			body += fmt.Sprintf(" It expires on %s.", proposal.ExpiresAt.Format("January 2, 2006"))
+		}
+		if proposal.Address != "" {
+			body += fmt.Sprintf("\n\nAddress: %s", proposal.Address)
+		}
+		ok, err := s.deliver(ctx, proposal, models.NotificationKindProposalViewedFollowUp, key, models.NotificationChannelEmail, rep.Email, func() error {
+			return s.email.SendEmail(rep.Email, fullName(rep), subject, body)
+		})
+		sent, failed = tally(sent, failed, ok, err)
+	}
+
+	if settings.SMSEnabled && s.sms != nil && rep.PhoneNumber != nil && *rep.PhoneNumber != "" {
+		phone := *rep.PhoneNumber
+		body := fmt.Sprintf("%s viewed proposal %s on %s but has not accepted it yet.", homeownerName, proposal.Code, viewed)
+		ok, err := s.deliver(ctx, proposal, models.NotificationKindProposalViewedFollowUp, key, models.NotificationChannelSMS, phone, func() error {
+			return s.sms.SendSMS(phone, body)
+		})
+		sent, failed = tally(sent, failed, ok, err)
+	}
+
+	return sent, failed
+}
+
+// deliver claims the notification keyed key+channel and sends it. It
+// reports false without an error when the notification was already handled.
+func (s *ProposalFollowUpService) deliver(ctx context.Context, proposal *models.Proposal, kind, key, channel, recipient string, send func() error) (bool, error) {
+	proposalID := proposal.ID
+	entry := &models.NotificationLog{
+		Key:        key + ":" + channel,
+		ProposalID: &proposalID,
+		Kind:       kind,
+		Channel:    channel,
+		Recipient:  recipient,
+	}
+
+	claimed, err := s.notificationRepo.Claim(ctx, entry)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim notification %s: %w", entry.Key, err)
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	if err := send(); err != nil {
+		if markErr := s.notificationRepo.MarkFailed(ctx, entry.ID, err.Error()); markErr != nil {
+			log.Printf("Warning: failed to record failed notification %s: %v", entry.Key, markErr)
+		}
+		return false, fmt.Errorf("notification %s: %w", entry.Key, err)
+	}
+
+	if err := s.notificationRepo.MarkSent(ctx, entry.ID); err != nil {
+		log.Printf("Warning: failed to record sent notification %s: %v", entry.Key, err)
+	}
+	return true, nil
+}
+
+func (s *ProposalFollowUpService) proposalLink(proposal *models.Proposal) string {
+	return s.publicURL + "/p/" + proposal.Code
+}
+
+// dueReminder picks the reminder to send now: the one closest to expiry
+// whose time has come. Reminders that fell due before the proposal was sent
+// are skipped rather than sent late.
+func dueReminder(proposal *models.Proposal, days []int, now time.Time) (int, bool) {
+	if proposal.ExpiresAt == nil || !now.Before(*proposal.ExpiresAt) {
+		return 0, false
+	}
+
+	due := 0
+	for _, d := range days {
+		at := proposal.ExpiresAt.AddDate(0, 0, -d)
+		if now.Before(at) {
+			continue
+		}
+		if proposal.SentAt != nil && at.Before(*proposal.SentAt) {
+			continue
+		}
+		if due == 0 || d < due {
+			due = d
+		}
+	}
+	return due, due > 0
+}
+
+// followUpDue reports whether the sales rep should hear that a viewed
+// proposal has not been accepted.
+func followUpDue(proposal *models.Proposal, hours int, now time.Time) bool {
+	if hours <= 0 || proposal.Status != models.ProposalStatusViewed || proposal.ViewedAt == nil {
+		return false
+	}
+	return !now.Before(proposal.ViewedAt.Add(time.Duration(hours) * time.Hour))
+}
+
+func tally(sent, failed int, ok bool, err error) (int, int) {
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return sent, failed + 1
+	}
+	if ok {
+		sent++
+	}
+	return sent, failed
+}
+
+func firstName(user *models.User) string {
+	if user.FirstName != nil && *user.FirstName != "" {
+		return *user.FirstName
+	}
+	return "there"
+}
+
+func fullName(user *models.User) string {
+	var parts []string
+	if user.FirstName != nil && *user.FirstName != "" {
+		parts = append(parts, *user.FirstName)
+	}
+	if user.LastName != nil && *user.LastName != "" {
+		parts = append(parts, *user.LastName)
+	}
+	return strings.Join(parts, " ")
+}
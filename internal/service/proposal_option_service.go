@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+)
+
+// ProposalOptionService manages the good/better/best options of a proposal.
+type ProposalOptionService struct {
+	optionRepo      *repo.ProposalOptionRepo
+	proposalRepo    *repo.ProposalRepo
+	proposals       *ProposalService
+	hardwareService *HardwareService
+}
+
+// ProposalOptionInput creates an option or its next version. Position is
+// only used when the option is created.
+type ProposalOptionInput struct {
+	Label     string `json:"label" example:"Better"`
+	Position  *int   `json:"position,omitempty" example:"1"`
+	CreatedBy *int   `json:"created_by,omitempty" example:"1"`
+	models.ProposalOptionSnapshot
+}
+
+// OptionComparison lines a proposal's options up side by side. Every row
+// holds one value per option, in the order of Options.
+type OptionComparison struct {
+	ProposalID int              `json:"proposal_id" example:"1"`
+	Options    []ComparedOption `json:"options"`
+	Rows       []ComparisonRow  `json:"rows"`
+}
+
+// ComparedOption identifies one column of a comparison.
+type ComparedOption struct {
+	ID      int                         `json:"id" example:"2"`
+	Label   string                      `json:"label" example:"Better"`
+	Status  models.ProposalOptionStatus `json:"status" example:"active"`
+	Version int                         `json:"version" example:"3"`
+}
+
+// ComparisonRow is one metric across all options. Values are nil where the
+// metric does not apply, e.g. payback for an option with no savings. Best is
+// the index of the best value for metrics where more or less is better.
+type ComparisonRow struct {
+	Key    string     `json:"key" example:"net_cost"`
+	Label  string     `json:"label" example:"Net cost"`
+	Unit   string     `json:"unit" example:"USD"`
+	Values []*float64 `json:"values"`
+	Best   *int       `json:"best,omitempty" example:"0"`
+}
+
+// comparisonMetric describes how one comparison row is computed. better is
+// 1 when higher values win, -1 when lower values win and 0 when neither.
+type comparisonMetric struct {
+	key    string
+	label  string
+	unit   string
+	better int
+	value  func(s *models.ProposalOptionSnapshot) *float64
+}
+
+var comparisonMetrics = []comparisonMetric{
+	{"system_size", "System size", "kW", 0, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.SystemSize) }},
+	{"panel_count", "Panels", "count", 0, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(float64(s.PanelCount)) }},
+	{"battery_count", "Batteries", "count", 0, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(float64(s.BatteryCount)) }},
+	{"annual_production", "Annual production", "kWh", 1, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.AnnualProduction) }},
+	{"offset", "Usage offset", "%", 0, func(s *models.ProposalOptionSnapshot) *float64 {
+		if s.AnnualConsumption <= 0 {
+			return nil
+		}
+		return floatPtr(s.AnnualProduction / s.AnnualConsumption * 100)
+	}},
+	{"system_cost", "System cost", "USD", -1, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.SystemCost) }},
+	{"incentives", "Incentives", "USD", 1, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.Incentives) }},
+	{"net_cost", "Net cost", "USD", -1, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.NetCost) }},
+	{"net_cost_per_watt", "Net cost per watt", "USD/W", -1, func(s *models.ProposalOptionSnapshot) *float64 {
+		if s.SystemSize <= 0 {
+			return nil
+		}
+		return floatPtr(s.NetCost / (s.SystemSize * 1000))
+	}},
+	{"monthly_payment", "Monthly payment", "USD", -1, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.MonthlyPayment) }},
+	{"financing_term_months", "Financing term", "months", 0, func(s *models.ProposalOptionSnapshot) *float64 {
+		if s.FinancingTermMonths == 0 {
+			return nil
+		}
+		return floatPtr(float64(s.FinancingTermMonths))
+	}},
+	{"financing_apr", "Financing APR", "%", -1, func(s *models.ProposalOptionSnapshot) *float64 {
+		if s.FinancingTermMonths == 0 {
+			return nil
+		}
+		return floatPtr(s.FinancingAPR * 100)
+	}},
+	{"estimated_utility_bill", "Utility bill after solar", "USD", -1, func(s *models.ProposalOptionSnapshot) *float64 { return floatPtr(s.EstimatedUtilityBill) }},
+	{"monthly_savings", "Monthly savings", "USD", 1, func(s *models.ProposalOptionSnapshot) *float64 {
+		return floatPtr(s.CurrentUtilityBill - s.EstimatedUtilityBill - s.MonthlyPayment)
+	}},
+	{"payback_years", "Simple payback", "years", -1, func(s *models.ProposalOptionSnapshot) *float64 {
+		annualSavings := (s.CurrentUtilityBill - s.EstimatedUtilityBill) * 12
+		if annualSavings <= 0 {
+			return nil
+		}
+		return floatPtr(s.NetCost / annualSavings)
+	}},
+}
+
+func NewProposalOptionService(optionRepo *repo.ProposalOptionRepo, proposalRepo *repo.ProposalRepo, proposals *ProposalService, hardwareService *HardwareService) *ProposalOptionService {
+	return &ProposalOptionService{
+		optionRepo:      optionRepo,
+		proposalRepo:    proposalRepo,
+		proposals:       proposals,
+		hardwareService: hardwareService,
+	}
+}
+
+// Create adds an option to a proposal that is still open for changes.
+func (s *ProposalOptionService) Create(ctx context.Context, proposalID int, input ProposalOptionInput) (*models.ProposalOption, error) {
+	proposal, err := s.editableProposal(ctx, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	label, err := s.validateInput(ctx, proposal, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	position := 0
+	if input.Position != nil {
+		position = *input.Position
+	} else {
+		count, err := s.optionRepo.Count(ctx, proposalID)
+		if err != nil {
+			return nil, err
+		}
+		position = int(count)
+	}
+
+	option := &models.ProposalOption{
+		ProposalID: proposalID,
+		Label:      label,
+		Position:   position,
+		Status:     models.ProposalOptionStatusActive,
+	}
+	version := &models.ProposalOptionVersion{
+		CreatedBy:              input.CreatedBy,
+		ProposalOptionSnapshot: input.ProposalOptionSnapshot,
+	}
+	if err := s.optionRepo.Create(ctx, option, version); err != nil {
+		return nil, err
+	}
+	return option, nil
+}
+
+// Update records an edit as the option's next version. Earlier versions are
+// kept unchanged.
+func (s *ProposalOptionService) Update(ctx context.Context, proposalID, optionID int, input ProposalOptionInput) (*models.ProposalOption, error) {
+	proposal, err := s.editableProposal(ctx, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	option, err := s.optionRepo.Get(ctx, proposalID, optionID)
+	if err != nil {
+		return nil, err
+	}
+	if option.IsLocked() {
+		return nil, models.ErrProposalOptionLocked
+	}
+	if input.Label == "" {
+		input.Label = option.Label
+	}
+	label, err := s.validateInput(ctx, proposal, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &models.ProposalOptionVersion{
+		CreatedBy:              input.CreatedBy,
+		ProposalOptionSnapshot: input.ProposalOptionSnapshot,
+	}
+	if err := s.optionRepo.AddVersion(ctx, option, label, version); err != nil {
+		return nil, err
+	}
+	return option, nil
+}
+
+// List returns a proposal's options with their current versions.
+func (s *ProposalOptionService) List(ctx context.Context, proposalID int) ([]*models.ProposalOption, error) {
+	if _, err := s.proposalRepo.GetByID(ctx, proposalID); err != nil {
+		return nil, err
+	}
+	return s.optionRepo.List(ctx, proposalID)
+}
+
+// Versions returns the version history of an option, newest first.
+func (s *ProposalOptionService) Versions(ctx context.Context, proposalID, optionID int) ([]*models.ProposalOptionVersion, error) {
+	if _, err := s.optionRepo.Get(ctx, proposalID, optionID); err != nil {
+		return nil, err
+	}
+	return s.optionRepo.ListVersions(ctx, optionID)
+}
+
+// Compare lines up the current versions of a proposal's options.
+func (s *ProposalOptionService) Compare(ctx context.Context, proposalID int) (*OptionComparison, error) {
+	options, err := s.List(ctx, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	return compareOptions(proposalID, options), nil
+}
+
+// Accept accepts the proposal with the given option and creates its deal.
+// The option's snapshot becomes the proposal's system and financials, the
+// option is locked and every other option is superseded.
+func (s *ProposalOptionService) Accept(ctx context.Context, proposalID, optionID int) (*AcceptedProposal, error) {
+	return s.proposals.AcceptOption(ctx, proposalID, optionID)
+}
+
+// editableProposal loads a proposal whose options may still change: one
+// that has not been accepted, rejected or expired.
+func (s *ProposalOptionService) editableProposal(ctx context.Context, proposalID int) (*models.Proposal, error) {
+	proposal, err := s.proposalRepo.GetByID(ctx, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != models.ProposalStatusDraft && !proposal.IsOpen() {
+		return nil, models.ErrProposalOptionLocked
+	}
+	if proposal.IsOpen() && proposal.IsExpired() {
+		return nil, models.ErrProposalExpired
+	}
+	return proposal, nil
+}
+
+func (s *ProposalOptionService) validateInput(ctx context.Context, proposal *models.Proposal, input *ProposalOptionInput) (string, error) {
+	label, err := models.NormalizeOptionLabel(input.Label)
+	if err != nil {
+		return "", err
+	}
+	if err := input.ProposalOptionSnapshot.Validate(); err != nil {
+		return "", err
+	}
+	sel := HardwareSelection{
+		PanelID:    input.PanelID,
+		InverterID: input.InverterID,
+		BatteryID:  input.BatteryID,
+	}
+	if err := s.hardwareService.ValidateSelection(ctx, proposal.CompanyID, sel); err != nil {
+		return "", err
+	}
+	return label, nil
+}
+
+func compareOptions(proposalID int, options []*models.ProposalOption) *OptionComparison {
+	comparison := &OptionComparison{
+		ProposalID: proposalID,
+		Options:    make([]ComparedOption, 0, len(options)),
+		Rows:       make([]ComparisonRow, 0, len(comparisonMetrics)),
+	}
+	for _, option := range options {
+		comparison.Options = append(comparison.Options, ComparedOption{
+			ID:      option.ID,
+			Label:   option.Label,
+			Status:  option.Status,
+			Version: option.CurrentVersion,
+		})
+	}
+
+	for _, metric := range comparisonMetrics {
+		row := ComparisonRow{
+			Key:    metric.key,
+			Label:  metric.label,
+			Unit:   metric.unit,
+			Values: make([]*float64, len(options)),
+		}
+		for i, option := range options {
+			if option.Version == nil {
+				continue
+			}
+			if v := metric.value(&option.Version.ProposalOptionSnapshot); v != nil {
+				rounded := roundCents(*v)
+				row.Values[i] = &rounded
+			}
+		}
+		if metric.better != 0 {
+			row.Best = bestValue(row.Values, metric.better)
+		}
+		comparison.Rows = append(comparison.Rows, row)
+	}
+	return comparison
+}
+
+// bestValue returns the index of the best value, or nil when fewer than two
+// options have a value or they are all equal.
+func bestValue(values []*float64, better int) *int {
+	best, count, allEqual := -1, 0, true
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		count++
+		if best < 0 {
+			best = i
+			continue
+		}
+		if *v != *values[best] {
+			allEqual = false
+		}
+		if (better > 0 && *v > *values[best]) || (better < 0 && *v < *values[best]) {
+			best = i
+		}
+	}
+	if count < 2 || allEqual {
+		return nil
+	}
+	return &best
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
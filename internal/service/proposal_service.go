@@ -0,0 +1,521 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
+)
+
+const defaultUtilityRatePerKWh = 0.13
+
+type ProposalService struct {
+	proposalRepo *repo.ProposalRepo
+	optionRepo   *repo.ProposalOptionRepo
+	leadRepo     *repo.LeadRepo
+	companyRepo  *repo.CompanyRepo
+	quoteService *QuoteService
+	documents    *ProposalDocumentService
+	conversion   *ProposalConversionService
+}
+
+// PublicProposal is the homeowner-facing view of a proposal. It leaves out
+// internal IDs, notes and anything about commissions or profit.
+type PublicProposal struct {
+	Code                 string                 `json:"code" example:"PROP-7K3QX2M4N5P6R7S8T9V2W3X4Y5"`
+	Status               models.ProposalStatus  `json:"status" example:"viewed"`
+	CompanyName          string                 `json:"company_name" example:"Acme Solar"`
+	CompanyLogo          string                 `json:"company_logo,omitempty" example:"https://example.com/logo.png"`
+	Address              string                 `json:"address" example:"123 Solar St, San Francisco, CA 94102"`
+	SystemSize           float64                `json:"system_size" example:"10.5"`
+	PanelCount           int                    `json:"panel_count" example:"30"`
+	BatteryCount         int                    `json:"battery_count" example:"0"`
+	AnnualProduction     float64                `json:"annual_production" example:"13000"`
+	AnnualConsumption    float64                `json:"annual_consumption" example:"12000"`
+	SystemCost           float64                `json:"system_cost" example:"25000.00"`
+	Incentives           float64                `json:"incentives" example:"5000.00"`
+	NetCost              float64                `json:"net_cost" example:"20000.00"`
+	MonthlyPayment       float64                `json:"monthly_payment" example:"150.00"`
+	FinancingProvider    string                 `json:"financing_provider,omitempty" example:"SunPower Financial"`
+	CurrentUtilityBill   float64                `json:"current_utility_bill" example:"200.00"`
+	EstimatedUtilityBill float64                `json:"estimated_utility_bill" example:"50.00"`
+	DocumentURL          string                 `json:"document_url,omitempty" example:"https://docs.example.com/proposal.pdf"`
+	SentAt               *time.Time             `json:"sent_at" example:"2025-10-01T10:00:00Z"`
+	ExpiresAt            *time.Time             `json:"expires_at" example:"2025-10-30T23:59:59Z"`
+	AcceptedAt           *time.Time             `json:"accepted_at,omitempty" example:"2025-10-01T12:00:00Z"`
+	Options              []PublicProposalOption `json:"options,omitempty"`
+}
+
+// PublicProposalOption is one of the systems offered in a public proposal.
+type PublicProposalOption struct {
+	ID                   int                         `json:"id" example:"2"`
+	Label                string                      `json:"label" example:"Better"`
+	Status               models.ProposalOptionStatus `json:"status" example:"active"`
+	SystemSize           float64                     `json:"system_size" example:"10.5"`
+	PanelCount           int                         `json:"panel_count" example:"30"`
+	BatteryCount         int                         `json:"battery_count" example:"1"`
+	AnnualProduction     float64                     `json:"annual_production" example:"13000"`
+	SystemCost           float64                     `json:"system_cost" example:"25000.00"`
+	Incentives           float64                     `json:"incentives" example:"7500.00"`
+	NetCost              float64                     `json:"net_cost" example:"17500.00"`
+	MonthlyPayment       float64                     `json:"monthly_payment" example:"150.00"`
+	FinancingProvider    string                      `json:"financing_provider,omitempty" example:"SunPower Financial"`
+	FinancingTermMonths  int                         `json:"financing_term_months,omitempty" example:"300"`
+	FinancingAPR         float64                     `json:"financing_apr,omitempty" example:"0.0699"`
+	EstimatedUtilityBill float64                     `json:"estimated_utility_bill" example:"20.00"`
+}
+
+// CreateProposalInput creates a proposal from a lead. System and production
+// details are copied from the lead; the optional quote overrides feed the
+// financials calculated by QuoteService.
+type CreateProposalInput struct {
+	LeadID              int        `json:"lead_id" example:"1"`
+	ProjectID           int        `json:"project_id" example:"1"`
+	HomeownerID         int        `json:"homeowner_id" example:"2"`
+	SalesID             *int       `json:"sales_id,omitempty" example:"1"`
+	MonthlyElectricBill *float64   `json:"monthly_electric_bill,omitempty" example:"200.00"`
+	CostPerWatt         *float64   `json:"cost_per_watt,omitempty" example:"3.00"`
+	LoanInterestRate    *float64   `json:"loan_interest_rate,omitempty" example:"0.0699"`
+	LoanTermYears       *int       `json:"loan_term_years,omitempty" example:"25"`
+	FinancingOptionID   *int       `json:"financing_option_id,omitempty" example:"1"`
+	FinancingProvider   string     `json:"financing_provider,omitempty" example:"SunPower Financial"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty" example:"2025-10-30T23:59:59Z"`
+	Notes               string     `json:"notes,omitempty" example:"Custom proposal notes"`
+}
+
+func NewProposalService(proposalRepo *repo.ProposalRepo, optionRepo *repo.ProposalOptionRepo, leadRepo *repo.LeadRepo, companyRepo *repo.CompanyRepo, quoteService *QuoteService, documents *ProposalDocumentService, conversion *ProposalConversionService) *ProposalService {
+	return &ProposalService{
+		proposalRepo: proposalRepo,
+		optionRepo:   optionRepo,
+		leadRepo:     leadRepo,
+		companyRepo:  companyRepo,
+		quoteService: quoteService,
+		documents:    documents,
+		conversion:   conversion,
+	}
+}
+
+// CreateFromLead builds a draft proposal for a lead.
+func (s *ProposalService) CreateFromLead(ctx context.Context, input CreateProposalInput) (*models.Proposal, error) {
+	lead, err := s.leadRepo.GetByID(ctx, input.LeadID)
+	if err != nil {
+		return nil, err
+	}
+	if input.ProjectID == 0 {
+		return nil, fmt.Errorf("project ID is required")
+	}
+	if input.HomeownerID == 0 {
+		return nil, fmt.Errorf("homeowner ID is required")
+	}
+
+	salesID := lead.CreatorID
+	if input.SalesID != nil {
+		salesID = *input.SalesID
+	}
+
+	code, err := newProposalCode()
+	if err != nil {
+		return nil, err
+	}
+
+	leadID := lead.ID
+	proposal := &models.Proposal{
+		Code:              code,
+		Status:            models.ProposalStatusDraft,
+		ProjectID:         input.ProjectID,
+		LeadID:            &leadID,
+		CompanyID:         lead.CompanyID,
+		SalesID:           salesID,
+		HomeownerID:       input.HomeownerID,
+		SystemSize:        lead.SystemSize,
+		PanelCount:        lead.PanelCount,
+		PanelID:           lead.PanelID,
+		InverterID:        lead.InverterID,
+		BatteryCount:      lead.BatteryCount,
+		AnnualProduction:  lead.AnnualProduction,
+		AnnualConsumption: lead.KwhUsage,
+		FinancingOptionID: input.FinancingOptionID,
+		FinancingProvider: input.FinancingProvider,
+		UtilityID:         lead.UtilityID,
+		ExpiresAt:         input.ExpiresAt,
+		Notes:             input.Notes,
+		Address:           lead.Address,
+	}
+
+	quoteInput := proposalQuoteInput(lead, input)
+	if err := s.quoteService.ApplyLeadUsage(ctx, lead.ID, &quoteInput); err != nil {
+		return nil, err
+	}
+	if quoteInput.HourlyLoadKWh != nil {
+		proposal.MonthlyConsumption = usage.MonthlyTotals(quoteInput.HourlyLoadKWh)
+	}
+	quote, err := s.quoteService.CalculateQuote(ctx, &lead.CompanyID, quoteInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate proposal financials: %w", err)
+	}
+	proposal.SystemCost = quote.SystemCostBeforeIncentives
+	proposal.Incentives = math.Round((quote.SystemCostBeforeIncentives-quote.SystemCostAfterIncentives)*100) / 100
+	proposal.NetCost = quote.SystemCostAfterIncentives
+	proposal.MonthlyPayment = quote.EstimatedMonthlyPayment
+	proposal.LoanTermMonths = quote.LoanTermMonths
+	if quote.CashFlow != nil {
+		proposal.CashFlow = quote.CashFlow.Years
+	}
+	proposal.MonthlyProduction = usage.MonthlyTotals(quoteProduction(quoteInput))
+	proposal.CurrentUtilityBill = quote.CurrentMonthlyBill
+	proposal.EstimatedUtilityBill = quote.EstimatedNewMonthlyBill
+
+	if err := proposal.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.proposalRepo.Create(ctx, proposal); err != nil {
+		return nil, fmt.Errorf("failed to create proposal: %w", err)
+	}
+	s.refreshDocument(ctx, proposal)
+	return proposal, nil
+}
+
+func (s *ProposalService) GetByID(ctx context.Context, id int) (*models.Proposal, error) {
+	return s.proposalRepo.GetByID(ctx, id)
+}
+
+func (s *ProposalService) GetByCode(ctx context.Context, code string) (*models.Proposal, error) {
+	return s.proposalRepo.GetByCode(ctx, code)
+}
+
+func (s *ProposalService) List(ctx context.Context, filter repo.ProposalFilter, limit, offset int) ([]*models.Proposal, int64, error) {
+	return s.proposalRepo.Search(ctx, filter, limit, offset)
+}
+
+// publicByCode loads a proposal for its public link. Drafts are not public,
+// and open proposals past their expiry date are expired and refused.
+func (s *ProposalService) publicByCode(ctx context.Context, code string) (*models.Proposal, error) {
+	proposal, err := s.proposalRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status == models.ProposalStatusDraft {
+		return nil, models.ErrProposalNotFound
+	}
+	if proposal.Status == models.ProposalStatusExpired {
+		return nil, models.ErrProposalExpired
+	}
+	if proposal.IsOpen() && proposal.IsExpired() {
+		if err := s.proposalRepo.Expire(ctx, proposal.ID); err != nil {
+			return nil, fmt.Errorf("failed to expire proposal: %w", err)
+		}
+		return nil, models.ErrProposalExpired
+	}
+	return proposal, nil
+}
+
+// ViewByCode opens a proposal from its public link. Every open is recorded;
+// the first one moves a sent proposal to viewed. Drafts are not public and
+// open proposals past their expiry date are refused.
+func (s *ProposalService) ViewByCode(ctx context.Context, code, userAgent, ipAddress string) (*PublicProposal, error) {
+	proposal, err := s.publicByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.ProposalView{
+		ProposalID: proposal.ID,
+		ViewedAt:   time.Now(),
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+	}
+	if err := s.proposalRepo.CreateView(ctx, view); err != nil {
+		return nil, fmt.Errorf("failed to record proposal view: %w", err)
+	}
+	if proposal.Status == models.ProposalStatusSent {
+		if err := s.proposalRepo.MarkViewed(ctx, proposal.ID, view.ViewedAt); err != nil {
+			return nil, fmt.Errorf("failed to mark proposal viewed: %w", err)
+		}
+		proposal.MarkViewed()
+	}
+
+	public := &PublicProposal{
+		Code:                 proposal.Code,
+		Status:               proposal.Status,
+		Address:              proposal.Address,
+		SystemSize:           proposal.SystemSize,
+		PanelCount:           proposal.PanelCount,
+		BatteryCount:         proposal.BatteryCount,
+		AnnualProduction:     proposal.AnnualProduction,
+		AnnualConsumption:    proposal.AnnualConsumption,
+		SystemCost:           proposal.SystemCost,
+		Incentives:           proposal.Incentives,
+		NetCost:              proposal.NetCost,
+		MonthlyPayment:       proposal.MonthlyPayment,
+		FinancingProvider:    proposal.FinancingProvider,
+		CurrentUtilityBill:   proposal.CurrentUtilityBill,
+		EstimatedUtilityBill: proposal.EstimatedUtilityBill,
+		SentAt:               proposal.SentAt,
+		ExpiresAt:            proposal.ExpiresAt,
+		AcceptedAt:           proposal.AcceptedAt,
+	}
+	if proposal.DocumentURL != "" {
+		public.DocumentURL = "/p/" + proposal.Code + "/pdf"
+	}
+	if company, err := s.companyRepo.GetByID(ctx, proposal.CompanyID); err == nil {
+		public.CompanyName = company.Name
+		if company.DisplayName != "" {
+			public.CompanyName = company.DisplayName
+		}
+		if company.LogoPath != nil {
+			public.CompanyLogo = *company.LogoPath
+		}
+	}
+
+	options, err := s.optionRepo.List(ctx, proposal.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proposal options: %w", err)
+	}
+	for _, option := range options {
+		if option.Version == nil {
+			continue
+		}
+		v := option.Version
+		public.Options = append(public.Options, PublicProposalOption{
+			ID:                   option.ID,
+			Label:                option.Label,
+			Status:               option.Status,
+			SystemSize:           v.SystemSize,
+			PanelCount:           v.PanelCount,
+			BatteryCount:         v.BatteryCount,
+			AnnualProduction:     v.AnnualProduction,
+			SystemCost:           v.SystemCost,
+			Incentives:           v.Incentives,
+			NetCost:              v.NetCost,
+			MonthlyPayment:       v.MonthlyPayment,
+			FinancingProvider:    v.FinancingProvider,
+			FinancingTermMonths:  v.FinancingTermMonths,
+			FinancingAPR:         v.FinancingAPR,
+			EstimatedUtilityBill: v.EstimatedUtilityBill,
+		})
+	}
+	return public, nil
+}
+
+// ListViews returns every recorded open of a proposal, newest first.
+func (s *ProposalService) ListViews(ctx context.Context, id int) ([]*models.ProposalView, error) {
+	if _, err := s.proposalRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.proposalRepo.ListViews(ctx, id)
+}
+
+// Delete removes a proposal that has not been sent yet.
+func (s *ProposalService) Delete(ctx context.Context, id int) error {
+	proposal, err := s.proposalRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if proposal.Status != models.ProposalStatusDraft {
+		return models.ErrInvalidProposalTransition
+	}
+	return s.proposalRepo.Delete(ctx, id)
+}
+
+// Send marks a draft proposal as sent, starting its expiry clock.
+func (s *ProposalService) Send(ctx context.Context, id int) (*models.Proposal, error) {
+	proposal, err := s.proposalRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !proposal.CanTransitionTo(models.ProposalStatusSent) {
+		return nil, models.ErrInvalidProposalTransition
+	}
+	if proposal.IsExpired() {
+		return nil, models.ErrProposalExpired
+	}
+
+	proposal.MarkSent()
+	if err := s.proposalRepo.Update(ctx, proposal); err != nil {
+		return nil, fmt.Errorf("failed to send proposal: %w", err)
+	}
+	s.refreshDocument(ctx, proposal)
+	return proposal, nil
+}
+
+// Accept records the homeowner accepting an open proposal and creates its
+// deal. Proposals with options are accepted through AcceptOption instead.
+func (s *ProposalService) Accept(ctx context.Context, id int) (*AcceptedProposal, error) {
+	return s.accept(ctx, id, nil)
+}
+
+// AcceptOption records the homeowner accepting an open proposal with one of
+// its options and creates its deal.
+func (s *ProposalService) AcceptOption(ctx context.Context, id, optionID int) (*AcceptedProposal, error) {
+	return s.accept(ctx, id, &optionID)
+}
+
+func (s *ProposalService) accept(ctx context.Context, id int, optionID *int) (*AcceptedProposal, error) {
+	accepted, err := s.conversion.Accept(ctx, id, optionID)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshDocument(ctx, accepted.Proposal)
+	return accepted, nil
+}
+
+// Reject records the homeowner turning down an open proposal.
+func (s *ProposalService) Reject(ctx context.Context, id int, reason string) (*models.Proposal, error) {
+	proposal, err := s.openProposal(ctx, id, models.ProposalStatusRejected)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal.MarkRejected()
+	proposal.RejectionReason = strings.TrimSpace(reason)
+	if err := s.proposalRepo.Update(ctx, proposal); err != nil {
+		return nil, fmt.Errorf("failed to reject proposal: %w", err)
+	}
+	return proposal, nil
+}
+
+// openProposal loads a proposal that is about to be answered. A proposal
+// found past its expiry date is marked expired on the way out.
+func (s *ProposalService) openProposal(ctx context.Context, id int, next models.ProposalStatus) (*models.Proposal, error) {
+	proposal, err := s.proposalRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !proposal.CanTransitionTo(next) {
+		return nil, models.ErrInvalidProposalTransition
+	}
+	if proposal.IsExpired() {
+		if err := s.proposalRepo.Expire(ctx, proposal.ID); err != nil {
+			return nil, fmt.Errorf("failed to expire proposal: %w", err)
+		}
+		return nil, models.ErrProposalExpired
+	}
+	return proposal, nil
+}
+
+// Document returns the proposal PDF, rendering it first if the proposal
+// changed since it was last rendered. force renders it regardless.
+func (s *ProposalService) Document(ctx context.Context, id int, force bool) (*models.Proposal, io.ReadCloser, error) {
+	proposal, err := s.proposalRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := s.openDocument(ctx, proposal, force)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proposal, file, nil
+}
+
+// DocumentByCode returns the PDF of a proposal from its public link, under
+// the same rules as ViewByCode.
+func (s *ProposalService) DocumentByCode(ctx context.Context, code string) (*models.Proposal, io.ReadCloser, error) {
+	proposal, err := s.publicByCode(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := s.openDocument(ctx, proposal, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proposal, file, nil
+}
+
+// openDocument renders the proposal PDF when needed and opens it. A PDF
+// missing from the store is rendered again.
+func (s *ProposalService) openDocument(ctx context.Context, proposal *models.Proposal, force bool) (io.ReadCloser, error) {
+	var err error
+	if force {
+		err = s.documents.Regenerate(ctx, proposal)
+	} else {
+		_, err = s.documents.EnsureDocument(ctx, proposal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.documents.Open(ctx, proposal)
+	if errors.Is(err, fs.ErrNotExist) && !force {
+		if err := s.documents.Regenerate(ctx, proposal); err != nil {
+			return nil, err
+		}
+		file, err = s.documents.Open(ctx, proposal)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proposal PDF: %w", err)
+	}
+	return file, nil
+}
+
+// refreshDocument re-renders the proposal PDF if its inputs changed. A
+// failed render is logged rather than failing the change that triggered it;
+// the PDF is rendered again the next time it is requested.
+func (s *ProposalService) refreshDocument(ctx context.Context, proposal *models.Proposal) {
+	if s.documents == nil {
+		return
+	}
+	if _, err := s.documents.EnsureDocument(ctx, proposal); err != nil {
+		log.Printf("Warning: failed to render PDF for proposal %d: %v", proposal.ID, err)
+	}
+}
+
+// proposalQuoteInput derives the quote inputs from a lead. The monthly bill
+// falls back to the lead's pre-solar cost and then to its annual usage at an
+// average utility rate. The lead's additional incentive is quoted as a
+// rebate.
+func proposalQuoteInput(lead *models.Lead, input CreateProposalInput) models.QuoteInput {
+	monthlyBill := 0.0
+	switch {
+	case input.MonthlyElectricBill != nil:
+		monthlyBill = *input.MonthlyElectricBill
+	case lead.ElectricityCostPre != nil:
+		monthlyBill = float64(*lead.ElectricityCostPre)
+	default:
+		monthlyBill = lead.KwhUsage * defaultUtilityRatePerKWh / 12
+	}
+
+	additionalIncentive := 0.0
+	if lead.AdditionalIncentive != nil {
+		additionalIncentive = float64(*lead.AdditionalIncentive)
+	}
+
+	offset := 100.0
+	if lead.KwhUsage > 0 {
+		offset = math.Min(100, lead.AnnualProduction/lead.KwhUsage*100)
+	}
+
+	return models.QuoteInput{
+		SystemSizeKW:        lead.SystemSize,
+		AnnualProductionKWh: lead.AnnualProduction,
+		MonthlyElectricBill: math.Round(monthlyBill*100) / 100,
+		ElectricalOffsetPct: math.Round(offset*100) / 100,
+		PanelCount:          lead.PanelCount,
+		State:               stateFromAddress(lead.Address),
+		CostPerWatt:         input.CostPerWatt,
+		LoanInterestRate:    input.LoanInterestRate,
+		LoanTermYears:       input.LoanTermYears,
+		FinancingOptionID:   input.FinancingOptionID,
+		UtilityID:           lead.UtilityID,
+		AdditionalIncentive: additionalIncentive,
+	}
+}
+
+// newProposalCode returns a code carrying 128 random bits. The code is the
+// only credential on the public proposal link, so it must not be guessable.
+func newProposalCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate proposal code: %w", err)
+	}
+	return "PROP-" + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
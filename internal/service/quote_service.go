@@ -1,177 +1,386 @@
 package service
 
 import (
-	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
-	"math"
+	"context"
 	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/battery"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/tariff"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
 )
 
 type QuoteService struct {
-	quoteRepo *repo.QuoteRepo
+	quoteRepo        *repo.QuoteRepo
+	leadRepo         *repo.LeadRepo
+	financingService *FinancingService
+	incentiveService *IncentiveService
+	usageService     *UsageService
 }
 
-type QuoteInput struct {
-	SystemSizeKW          float64
-	AnnualProductionKWh   float64
-	MonthlyElectricBill   float64
-	ElectricalOffsetPct   float64
-	PanelCount            int
-	State                 string
-	CostPerWatt           *float64
-	UtilityRatePerKWh     *float64
-	AnnualUtilityIncrease *float64
-	FederalTaxCredit      *float64
-	LoanInterestRate      *float64
-	LoanTermYears         *int
+// defaultQuoteAssumptions fill in any rate a quote's input leaves out.
+// Saved quotes keep the values they were calculated with, so changing these
+// only affects new quotes and quotes recomputed with fresh defaults.
+var defaultQuoteAssumptions = models.QuoteAssumptions{
+	CostPerWatt:           3.00,
+	UtilityRatePerKWh:     defaultUtilityRatePerKWh,
+	AnnualUtilityIncrease: 0.03,
+	LoanInterestRate:      0.0699,
+	LoanTermYears:         25,
+	LoanTermMonths:        300,
+
+	AnnualDegradation:              0.005,
+	InflationRate:                  0.025,
+	DiscountRate:                   0.05,
+	OMCostPerKWYear:                15,
+	InverterReplacementYear:        12,
+	InverterReplacementCostPerWatt: 0.20,
+	TaxCreditYear:                  1,
 }
 
-type QuoteResult struct {
-	SystemCostBeforeIncentives float64 `json:"system_cost_before_incentives"`
-	FederalTaxCredit           float64 `json:"federal_tax_credit"`
-	SystemCostAfterIncentives  float64 `json:"system_cost_after_incentives"`
-	EstimatedMonthlyPayment    float64 `json:"estimated_monthly_payment"`
-	CurrentMonthlyBill         float64 `json:"current_monthly_bill"`
-	EstimatedNewMonthlyBill    float64 `json:"estimated_new_monthly_bill"`
-	MonthlySavings             float64 `json:"monthly_savings"`
-	FirstYearSavings           float64 `json:"first_year_savings"`
-	TwentyFiveYearSavings      float64 `json:"twenty_five_year_savings"`
-	SystemSizeKW               float64 `json:"system_size_kw"`
-	AnnualProductionKWh        float64 `json:"annual_production_kwh"`
-	PanelCount                 int     `json:"panel_count"`
-	ElectricalOffset           float64 `json:"electrical_offset_pct"`
-	CostPerWatt                float64 `json:"cost_per_watt"`
-	SimplePaybackYears         float64 `json:"simple_payback_years"`
-	BreakEvenYear              int     `json:"break_even_year"`
-	Summary                    string  `json:"summary"`
+// CreateQuoteInput saves a quote, optionally for a lead.
+type CreateQuoteInput struct {
+	LeadID    *int `json:"lead_id,omitempty" example:"1"`
+	CreatedBy *int `json:"created_by,omitempty" example:"1"`
+	models.QuoteInput
 }
 
+func NewQuoteService(quoteRepo *repo.QuoteRepo, leadRepo *repo.LeadRepo, financingService *FinancingService, incentiveService *IncentiveService, usageService *UsageService) *QuoteService {
+	return &QuoteService{
+		quoteRepo:        quoteRepo,
+		leadRepo:         leadRepo,
+		financingService: financingService,
+		incentiveService: incentiveService,
+		usageService:     usageService,
+	}
+}
 
-func NewQuoteService(quoteRepo *repo.QuoteRepo) *QuoteService {
-	return &QuoteService{quoteRepo: quoteRepo}
+// ApplyLeadUsage sets the input's hourly load to the lead's uploaded usage
+// when the input has none, so the quote bills the home's real load shape.
+func (s *QuoteService) ApplyLeadUsage(ctx context.Context, leadID int, input *models.QuoteInput) error {
+	if input.HourlyLoadKWh != nil {
+		return nil
+	}
+	load, err := s.usageService.LoadProfile(ctx, leadID)
+	if err != nil {
+		return fmt.Errorf("failed to load lead usage: %w", err)
+	}
+	input.HourlyLoadKWh = load
+	return nil
+}
+
+// CalculateQuote calculates a quote with the current default assumptions
+// without saving it. With a company, its financing catalog supplies the
+// loan terms and the products compared.
+func (s *QuoteService) CalculateQuote(ctx context.Context, companyID *int, input models.QuoteInput) (*models.QuoteResult, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	a, err := s.assumptions(ctx, companyID, input)
+	if err != nil {
+		return nil, err
+	}
+	return calculateQuote(input, a), nil
 }
 
+// Create calculates a quote and saves it with its inputs and assumptions.
+func (s *QuoteService) Create(ctx context.Context, input CreateQuoteInput) (*models.Quote, error) {
+	if err := input.QuoteInput.Validate(); err != nil {
+		return nil, err
+	}
 
-func (s *QuoteService) CalculateQuote(input QuoteInput) (*QuoteResult, error) {
-	if input.SystemSizeKW <= 0 {
-		return nil, fmt.Errorf("system size must be greater than 0")
+	quote := &models.Quote{
+		LeadID:    input.LeadID,
+		CreatedBy: input.CreatedBy,
+		Input:     input.QuoteInput,
 	}
-	if input.AnnualProductionKWh <= 0 {
-		return nil, fmt.Errorf("annual production must be greater than 0")
+	if input.LeadID != nil {
+		lead, err := s.leadRepo.GetByID(ctx, *input.LeadID)
+		if err != nil {
+			return nil, err
+		}
+		quote.CompanyID = &lead.CompanyID
+		if err := s.ApplyLeadUsage(ctx, lead.ID, &quote.Input); err != nil {
+			return nil, err
+		}
 	}
-	if input.MonthlyElectricBill <= 0 {
-		return nil, fmt.Errorf("monthly electric bill must be greater than 0")
+	assumptions, err := s.assumptions(ctx, quote.CompanyID, quote.Input)
+	if err != nil {
+		return nil, err
 	}
+	quote.Assumptions = assumptions
+	quote.Result = *calculateQuote(quote.Input, quote.Assumptions)
+	quote.CalculatedAt = time.Now()
 
-	// Set defaults
-	costPerWatt := 3.00
-	if input.CostPerWatt != nil {
-		costPerWatt = *input.CostPerWatt
+	if err := s.quoteRepo.Create(ctx, quote); err != nil {
+		return nil, fmt.Errorf("failed to save quote: %w", err)
 	}
+	return quote, nil
+}
 
-	utilityRate := 0.13
-	if input.UtilityRatePerKWh != nil {
-		utilityRate = *input.UtilityRatePerKWh
+func (s *QuoteService) GetByID(ctx context.Context, id int) (*models.Quote, error) {
+	return s.quoteRepo.GetByID(ctx, id)
+}
+
+func (s *QuoteService) List(ctx context.Context, filter repo.QuoteFilter, limit, offset int) ([]*models.Quote, int64, error) {
+	return s.quoteRepo.Search(ctx, filter, limit, offset)
+}
+
+// Recompute calculates a saved quote again. By default it keeps the
+// assumptions the quote was saved with, so only changes to the calculation
+// itself show up; with refreshDefaults, rates the input left out are taken
+// from the current defaults and financing catalog instead.
+func (s *QuoteService) Recompute(ctx context.Context, id int, refreshDefaults bool) (*models.Quote, error) {
+	quote, err := s.quoteRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := quote.Input.Validate(); err != nil {
+		return nil, err
 	}
 
-	annualIncrease := 0.03
-	if input.AnnualUtilityIncrease != nil {
-		annualIncrease = *input.AnnualUtilityIncrease
+	if refreshDefaults {
+		quote.Assumptions, err = s.assumptions(ctx, quote.CompanyID, quote.Input)
+		if err != nil {
+			return nil, err
+		}
 	}
+	quote.Result = *calculateQuote(quote.Input, quote.Assumptions)
+	quote.CalculatedAt = time.Now()
 
-	taxCredit := 0.26
-	if input.FederalTaxCredit != nil {
-		taxCredit = *input.FederalTaxCredit
+	if err := s.quoteRepo.Update(ctx, quote); err != nil {
+		return nil, fmt.Errorf("failed to save quote: %w", err)
 	}
+	return quote, nil
+}
 
-	interestRate := 0.0699
-	if input.LoanInterestRate != nil {
-		interestRate = *input.LoanInterestRate
+// assumptions resolves the input's assumptions and fills in the incentives
+// the system is eligible for. For a company, it also fills in its financing
+// catalog: every product it offers in the input's state is compared
+// alongside cash, and the loan terms come from the input's financing option
+// or else the first loan on offer.
+func (s *QuoteService) assumptions(ctx context.Context, companyID *int, input models.QuoteInput) (models.QuoteAssumptions, error) {
+	a := resolveQuoteAssumptions(input)
+	now := time.Now()
+
+	// Incentives are valued for a homeowner-owned system; third-party
+	// owners claim their own and price them into the lease or PPA.
+	incentives, err := s.incentiveService.Eligible(ctx, incentive.System{
+		State:     input.State,
+		UtilityID: input.UtilityID,
+		SizeKW:    input.SystemSizeKW,
+		Ownership: incentive.OwnershipOwned,
+	}, now)
+	if err != nil {
+		return a, fmt.Errorf("failed to load incentives: %w", err)
+	}
+	for _, i := range incentives {
+		program := i.Program()
+		if program.Type == incentive.TypeFederalITC && input.FederalTaxCredit == nil {
+			a.FederalTaxCredit = program.RateFor(a.InstallYear)
+		}
+		a.Incentives = append(a.Incentives, program)
 	}
 
-	loanTermYears := 25
+	if companyID == nil {
+		return a, nil
+	}
+
+	options, err := s.financingService.Available(ctx, *companyID, input.State, now)
+	if err != nil {
+		return a, fmt.Errorf("failed to load financing options: %w", err)
+	}
+
+	var loan *models.FinancingOption
+	if input.FinancingOptionID != nil {
+		loan, err = s.financingService.Resolve(ctx, *companyID, *input.FinancingOptionID, input.State, now)
+		if err != nil {
+			return a, err
+		}
+	} else {
+		for _, option := range options {
+			if option.Type == finance.ProductLoan {
+				loan = option
+				break
+			}
+		}
+	}
+	if loan != nil && loan.Type == finance.ProductLoan {
+		if input.LoanInterestRate == nil {
+			a.LoanInterestRate = loan.InterestRate
+		}
+		if input.LoanTermYears == nil {
+			a.LoanTermYears = loan.TermMonths / 12
+			a.LoanTermMonths = loan.TermMonths
+		}
+		a.LoanFee = loan.LoanFee
+		a.LoanFeeFixed = loan.LoanFeeFixed
+		a.LoanITCPaydownMonth = loan.ITCPaydownMonth
+	}
+
+	if len(options) > 0 {
+		a.FinancingProducts = []finance.ProductSpec{{Type: finance.ProductCash}}
+		for _, option := range options {
+			a.FinancingProducts = append(a.FinancingProducts, option.ProductSpec())
+		}
+	}
+	return a, nil
+}
+
+// resolveQuoteAssumptions takes each rate from the input when it has one
+// and from the defaults otherwise.
+func resolveQuoteAssumptions(input models.QuoteInput) models.QuoteAssumptions {
+	a := defaultQuoteAssumptions
+	if input.CostPerWatt != nil {
+		a.CostPerWatt = *input.CostPerWatt
+	}
+	if input.UtilityRatePerKWh != nil {
+		a.UtilityRatePerKWh = *input.UtilityRatePerKWh
+	}
+	if input.AnnualUtilityIncrease != nil {
+		a.AnnualUtilityIncrease = *input.AnnualUtilityIncrease
+	}
+	if input.LoanInterestRate != nil {
+		a.LoanInterestRate = *input.LoanInterestRate
+	}
 	if input.LoanTermYears != nil {
-		loanTermYears = *input.LoanTermYears
+		a.LoanTermYears = *input.LoanTermYears
+		a.LoanTermMonths = *input.LoanTermYears * 12
+	}
+	if input.AnnualDegradation != nil {
+		a.AnnualDegradation = *input.AnnualDegradation
+	}
+	if input.InflationRate != nil {
+		a.InflationRate = *input.InflationRate
+	}
+	if input.DiscountRate != nil {
+		a.DiscountRate = *input.DiscountRate
+	}
+	if input.OMCostPerKWYear != nil {
+		a.OMCostPerKWYear = *input.OMCostPerKWYear
+	}
+	if input.InverterReplacementYear != nil {
+		a.InverterReplacementYear = *input.InverterReplacementYear
+	}
+	if input.InverterReplacementCostPerWatt != nil {
+		a.InverterReplacementCostPerWatt = *input.InverterReplacementCostPerWatt
+	}
+	if input.TaxCreditYear != nil {
+		a.TaxCreditYear = *input.TaxCreditYear
+	}
+	a.InstallYear = time.Now().Year()
+	if input.InstallYear != nil {
+		a.InstallYear = *input.InstallYear
+	}
+	// The federal credit follows the enacted schedule unless the catalog
+	// has a federal ITC of its own.
+	a.FederalTaxCredit = incentive.FederalITC.RateFor(a.InstallYear)
+	if input.FederalTaxCredit != nil {
+		a.FederalTaxCredit = *input.FederalTaxCredit
 	}
+	return a
+}
+
+func calculateQuote(input models.QuoteInput, a models.QuoteAssumptions) *models.QuoteResult {
+	costPerWatt := a.CostPerWatt
+	annualIncrease := a.AnnualUtilityIncrease
+	taxCredit := a.FederalTaxCredit
 
 	// Calculate system costs
 	systemSizeWatts := input.SystemSizeKW * 1000
 	systemCostBeforeIncentives := systemSizeWatts * costPerWatt
 	federalTaxCreditAmount := systemCostBeforeIncentives * taxCredit
-	systemCostAfterIncentives := systemCostBeforeIncentives - federalTaxCreditAmount
 
-	// Calculate monthly loan payment (using simple amortization formula)
-	monthlyRate := interestRate / 12
-	numPayments := float64(loanTermYears * 12)
-	monthlyPayment := 0.0
-
-	if interestRate > 0 {
-		monthlyPayment = systemCostBeforeIncentives *
-			(monthlyRate * math.Pow(1+monthlyRate, numPayments)) /
-			(math.Pow(1+monthlyRate, numPayments) - 1)
-	} else {
-		monthlyPayment = systemCostBeforeIncentives / numPayments
-	}
-
-	// Calculate current and new electricity costs
-	annualCurrentBill := input.MonthlyElectricBill * 12
+	// The monthly payment is the headline loan's, on its contract price
+	// with the lender's dealer fee grossed up, as the financing comparison
+	// prices it.
+	loan := headlineLoan(a)
+	loanTerms := loan.Terms(finance.System{Price: systemCostBeforeIncentives, TaxCreditRate: taxCredit})
+	monthlyPayment := loanTerms.MonthlyPayment
 
 	// Calculate offset amount
 	offsetRatio := input.ElectricalOffsetPct / 100.0
-	annualSolarProduction := input.AnnualProductionKWh
-	annualSolarSavings := annualSolarProduction * utilityRate
 
 	// New bill calculation (remaining electricity needed)
 	remainingUsagePct := math.Max(0, 1.0-offsetRatio)
 	newMonthlyBill := input.MonthlyElectricBill * remainingUsagePct
 
+	// With a tariff, the hourly bill model replaces the offset percentage.
+	// The modeled bill ratio is applied to the homeowner's actual bill so
+	// estimates stay anchored to what they pay today.
+	bills := billQuote(input, a)
+	if bills != nil && bills.Before.AnnualTotal > 0 {
+		billRatio := bills.After.AnnualTotal / bills.Before.AnnualTotal
+		newMonthlyBill = input.MonthlyElectricBill * billRatio
+	}
+
 	// Calculate savings
 	monthlySavingsFromSolar := input.MonthlyElectricBill - newMonthlyBill
 	netMonthlySavings := monthlySavingsFromSolar - monthlyPayment
 	annualSavingsFromReducedBill := (input.MonthlyElectricBill - newMonthlyBill) * 12
-    firstYearSavings := annualSavingsFromReducedBill - (monthlyPayment * 12)
-
-	// Calculate 25-year savings with utility rate increase
-	twentyFiveYearSavings := 0.0
-	totalUtilityCostWithoutSolar := 0.0
-	totalCostWithSolar := 0.0
-
-	for year := 1; year <= 25; year++ {
-      yearlyBill := annualCurrentBill * math.Pow(1+annualIncrease, float64(year-1))
-      totalUtilityCostWithoutSolar += yearlyBill
-      yearlyNewBill := newMonthlyBill * 12 * math.Pow(1+annualIncrease, float64(year-1))
-      if year <= loanTermYears {
-          totalCostWithSolar += (monthlyPayment * 12) + yearlyNewBill
-      } else {
-          totalCostWithSolar += yearlyNewBill
-      }
-    }
-
-	twentyFiveYearSavings = totalUtilityCostWithoutSolar - totalCostWithSolar
-
-	simplePayback := systemCostAfterIncentives / annualSolarSavings
-
-	breakEvenYear := 0
-	cumulativeSavings := 0.0
-	for year := 1; year <= 30; year++ {
-		yearlyBill := annualCurrentBill * math.Pow(1+annualIncrease, float64(year-1))
-		yearlySolarSavings := yearlyBill * offsetRatio
-
-		if year <= loanTermYears {
-			cumulativeSavings += yearlySolarSavings - (monthlyPayment * 12)
-		} else {
-			cumulativeSavings += yearlySolarSavings
-		}
-
-		if cumulativeSavings >= systemCostBeforeIncentives && breakEvenYear == 0 {
-			breakEvenYear = year
-			break
+	firstYearSavings := annualSavingsFromReducedBill - (monthlyPayment * 12)
+
+	// Model 25 years of the headline loan: bill savings fall with panel
+	// degradation and rise with utility rates, the tax credit arrives when
+	// it is claimed, and O&M and the inverter replacement are paid along
+	// the way.
+	base := finance.CashFlowInput{
+		Years:                   25,
+		SystemSizeKW:            input.SystemSizeKW,
+		TaxCreditYear:           a.TaxCreditYear,
+		FirstYearProductionKWh:  input.AnnualProductionKWh,
+		FirstYearBillSavings:    annualSavingsFromReducedBill,
+		Degradation:             a.AnnualDegradation,
+		UtilityEscalation:       annualIncrease,
+		OMCostPerKW:             a.OMCostPerKWYear,
+		InverterReplacementYear: a.InverterReplacementYear,
+		InverterReplacementCost: systemSizeWatts * a.InverterReplacementCostPerWatt,
+		Inflation:               a.InflationRate,
+		DiscountRate:            a.DiscountRate,
+	}
+
+	// Value the other incentives the system gets. Rebates and state tax
+	// credits come off the net cost with the federal credit; all of them
+	// reach the cash flow in the years they are received.
+	incentives := quoteIncentives(input, a, base, systemCostBeforeIncentives)
+	base.Incentives = incentive.Yearly(incentives, base.Years)
+	systemCostAfterIncentives := systemCostBeforeIncentives - federalTaxCreditAmount
+	totalIncentives := federalTaxCreditAmount
+	for _, v := range incentives {
+		if v.Type == incentive.TypeRebate || v.Type == incentive.TypeStateTaxCredit {
+			systemCostAfterIncentives -= v.Amount
 		}
+		totalIncentives += v.Amount
+	}
+
+	loanCashFlow := base
+	loanCashFlow.UpfrontPayment = loanTerms.UpfrontPayment
+	loanCashFlow.AnnualPayments = loanTerms.AnnualPayments
+	loanCashFlow.TaxCredit = loanTerms.TaxCredit
+	cashFlow := finance.Model(loanCashFlow)
+	twentyFiveYearSavings := cashFlow.TotalNetSavings
+	breakEvenYear := cashFlow.PaybackYear
+
+	// Compare every financing product on offer over the same system.
+	financing := finance.Compare(base, systemCostBeforeIncentives, taxCredit, quoteProducts(input, a))
+
+	// Simple payback compares the net cost with the first year's bill
+	// savings, as if the system were bought outright.
+	simplePayback := 0.0
+	if annualSavingsFromReducedBill > 0 {
+		simplePayback = systemCostAfterIncentives / annualSavingsFromReducedBill
 	}
 
 	summary := fmt.Sprintf(
 		"This %0.2f kW solar system with %d panels will produce approximately %0.0f kWh annually, "+
 		"offsetting %0.0f%% of your electricity usage. "+
-		"The system costs $%0.2f before incentives ($%0.2f after federal tax credit). "+
+		"The system costs $%0.2f before incentives ($%0.2f after incentives). "+
 		"Your estimated monthly payment is $%0.2f, and you'll save approximately $%0.2f in the first year. "+
 		"Over 25 years, your total savings are estimated at $%0.2f.",
 		input.SystemSizeKW,
@@ -184,11 +393,12 @@ func (s *QuoteService) CalculateQuote(input QuoteInput) (*QuoteResult, error) {
 		firstYearSavings,
 		twentyFiveYearSavings,
 	)
-	return &QuoteResult{
+	return &models.QuoteResult{
 		SystemCostBeforeIncentives: math.Round(systemCostBeforeIncentives*100) / 100,
 		FederalTaxCredit:           math.Round(federalTaxCreditAmount*100) / 100,
 		SystemCostAfterIncentives:  math.Round(systemCostAfterIncentives*100) / 100,
 		EstimatedMonthlyPayment:    math.Round(monthlyPayment*100) / 100,
+		LoanTermMonths:             loan.TermMonths,
 		CurrentMonthlyBill:         input.MonthlyElectricBill,
 		EstimatedNewMonthlyBill:    math.Round(newMonthlyBill*100) / 100,
 		MonthlySavings:             math.Round(netMonthlySavings*100) / 100,
@@ -202,5 +412,203 @@ func (s *QuoteService) CalculateQuote(input QuoteInput) (*QuoteResult, error) {
 		SimplePaybackYears:         math.Round(simplePayback*100) / 100,
 		BreakEvenYear:              breakEvenYear,
 		Summary:                    summary,
-	}, nil
+		Bills:                      bills,
+		CashFlow:                   cashFlow,
+		Financing:                  financing,
+		Incentives:                 append([]incentive.Value{federalIncentive(a, base, federalTaxCreditAmount)}, incentives...),
+		TotalIncentives:            math.Round(totalIncentives*100) / 100,
+		Storage:                    storageQuote(input, a),
+	}
+}
+
+// quoteIncentives values the quote's incentives other than the federal tax
+// credit, which the assumed rate covers: the eligible catalog incentives,
+// those in the input, and the input's additional incentive as a rebate.
+func quoteIncentives(input models.QuoteInput, a models.QuoteAssumptions, base finance.CashFlowInput, cost float64) []incentive.Value {
+	programs := make([]incentive.Program, 0, len(a.Incentives)+len(input.Incentives)+1)
+	for _, p := range slices.Concat(a.Incentives, input.Incentives) {
+		if p.Type != incentive.TypeFederalITC {
+			programs = append(programs, p)
+		}
+	}
+	if input.AdditionalIncentive > 0 {
+		programs = append(programs, incentive.Program{
+			Name:   "Additional incentive",
+			Type:   incentive.TypeRebate,
+			Amount: input.AdditionalIncentive,
+		})
+	}
+	return incentive.Apply(programs, incentive.System{
+		State:                  input.State,
+		UtilityID:              input.UtilityID,
+		SizeKW:                 input.SystemSizeKW,
+		Cost:                   cost,
+		Ownership:              incentive.OwnershipOwned,
+		InstallYear:            a.InstallYear,
+		FirstYearProductionKWh: base.FirstYearProductionKWh,
+		Degradation:            base.Degradation,
+		Years:                  base.Years,
+		TaxCreditYear:          base.TaxCreditYear,
+	})
+}
+
+// federalIncentive lists the federal tax credit under the catalog's name
+// for it when there is one.
+func federalIncentive(a models.QuoteAssumptions, base finance.CashFlowInput, amount float64) incentive.Value {
+	v := incentive.Value{
+		Name:   incentive.FederalITC.Name,
+		Type:   incentive.TypeFederalITC,
+		Amount: math.Round(amount*100) / 100,
+		Yearly: make([]float64, base.Years),
+	}
+	for _, p := range a.Incentives {
+		if p.Type == incentive.TypeFederalITC {
+			v.Name = p.Name
+			break
+		}
+	}
+	if base.Years > 0 {
+		v.Yearly[min(max(base.TaxCreditYear, 1), base.Years)-1] = v.Amount
+	}
+	return v
+}
+
+// quoteProducts returns the financing products a quote compares: the ones
+// in its input, else the company catalog's, else cash and the headline loan.
+func quoteProducts(input models.QuoteInput, a models.QuoteAssumptions) []finance.Product {
+	specs := input.FinancingProducts
+	if len(specs) == 0 {
+		specs = a.FinancingProducts
+	}
+	if len(specs) == 0 {
+		return []finance.Product{
+			finance.Cash{},
+			headlineLoan(a),
+		}
+	}
+	products := make([]finance.Product, 0, len(specs))
+	for _, spec := range specs {
+		// Input specs are checked by QuoteInput.Validate and catalog
+		// options when they are saved.
+		if product, err := spec.Product(); err == nil {
+			products = append(products, product)
+		}
+	}
+	return products
+}
+
+// headlineLoan is the loan a quote's monthly payment and cash flow are
+// priced on: the assumed rate and term with the dealer fee and tax credit
+// paydown of the catalog loan it came from.
+func headlineLoan(a models.QuoteAssumptions) finance.Loan {
+	// Quotes saved before the term was kept in months only have the years.
+	months := a.LoanTermMonths
+	if months == 0 {
+		months = a.LoanTermYears * 12
+	}
+	return finance.Loan{
+		APR:             a.LoanInterestRate,
+		TermMonths:      months,
+		LoanFee:         a.LoanFee,
+		LoanFeeFixed:    a.LoanFeeFixed,
+		ITCPaydownMonth: a.LoanITCPaydownMonth,
+	}
+}
+
+// billQuote models the quote's bills before and after solar on its tariff,
+// or returns nil when it has none.
+func billQuote(input models.QuoteInput, a models.QuoteAssumptions) *tariff.Comparison {
+	if input.Tariff == nil {
+		return nil
+	}
+	load, production := quoteProfiles(input, a)
+	bills, err := input.Tariff.Compare(load, production)
+	if err != nil {
+		// Profiles are checked by QuoteInput.Validate.
+		return nil
+	}
+	return bills
+}
+
+// quoteProfiles returns the quote's hourly load and production. Missing
+// hourly load is synthesized from the annual consumption implied by the
+// offset, or by the bill and utility rate, or kept to the input's monthly
+// consumption when it has one; missing hourly production from
+// the annual production.
+func quoteProfiles(input models.QuoteInput, a models.QuoteAssumptions) (load, production []float64) {
+	load = input.HourlyLoadKWh
+	if load == nil {
+		annualKWh := 0.0
+		if a.UtilityRatePerKWh > 0 {
+			annualKWh = input.MonthlyElectricBill * 12 / a.UtilityRatePerKWh
+		}
+		if input.ElectricalOffsetPct > 0 {
+			annualKWh = input.AnnualProductionKWh / (input.ElectricalOffsetPct / 100)
+		}
+		load = tariff.Profile(annualKWh, defaultConsumptionProfile, tariff.ResidentialLoadShape)
+		if input.SynthesizesLoad() {
+			// The shape is checked by QuoteInput.Validate.
+			if profile, err := usage.Synthesize(input.LoadShape(annualKWh)); err == nil {
+				load = profile.Hourly
+			}
+		}
+	}
+	return load, quoteProduction(input)
+}
+
+// quoteProduction returns the input's hourly production, or a profile of
+// its annual production when it has none.
+func quoteProduction(input models.QuoteInput) []float64 {
+	if input.HourlyProductionKWh != nil {
+		return input.HourlyProductionKWh
+	}
+	return tariff.Profile(input.AnnualProductionKWh, defaultProductionProfile, tariff.SolarShape)
+}
+
+// storageQuote dispatches the quote's batteries over its hourly profiles
+// and bills the result against solar alone, or returns nil when it has no
+// storage. Without a tariff, bills are modeled on a flat rate with full net
+// metering, under which storage saves nothing.
+func storageQuote(input models.QuoteInput, a models.QuoteAssumptions) *models.QuoteStorageResult {
+	if input.Storage == nil {
+		return nil
+	}
+	storage := *input.Storage
+	result := &models.QuoteStorageResult{
+		RecommendedCount: battery.RecommendCount(storage.Config, storage.CriticalLoadKW, storage.BackupHours),
+	}
+	if storage.Count == 0 {
+		storage.Count = result.RecommendedCount
+	}
+	result.BatteryCount = storage.Count
+
+	t := input.Tariff
+	if t == nil {
+		t = tariff.Flat(a.UtilityRatePerKWh)
+	}
+	importRates, _ := t.HourlyRates()
+	load, production := quoteProfiles(input, a)
+
+	dispatch, err := battery.Dispatch(storage.Config, load, production, importRates)
+	if err != nil {
+		// Storage is checked by QuoteInput.Validate.
+		return nil
+	}
+	bills, err := t.CompareSupply(load, production, dispatch.Supply)
+	if err != nil {
+		return nil
+	}
+	result.Dispatch = dispatch
+	result.Bills = bills
+	result.AnnualBillSavings = bills.AnnualSavings
+
+	// Backup mode holds the whole battery for outages; the other modes
+	// only their reserve.
+	result.BackupHours = battery.BackupHours(dispatch.ReserveKWh, storage.CriticalLoadKW)
+	result.Cost = roundCents(storage.CostPerBattery * float64(storage.Count))
+	result.NetCost = roundCents(result.Cost * (1 - a.FederalTaxCredit))
+	if result.AnnualBillSavings > 0 {
+		result.SimplePaybackYears = roundCents(result.NetCost / result.AnnualBillSavings)
+	}
+	return result
 }
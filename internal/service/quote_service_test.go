@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/finance"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/incentive"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestCalculateQuoteGolden(t *testing.T) {
+	input := models.QuoteInput{
+		SystemSizeKW:        8.4,
+		AnnualProductionKWh: 11800,
+		MonthlyElectricBill: 180,
+		ElectricalOffsetPct: 95,
+		PanelCount:          21,
+		State:               "CA",
+	}
+	assumptions := func(edit func(a *models.QuoteAssumptions)) models.QuoteAssumptions {
+		a := defaultQuoteAssumptions
+		a.InstallYear = 2025
+		a.FederalTaxCredit = incentive.FederalITC.RateFor(2025)
+		edit(&a)
+		return a
+	}
+
+	tests := []struct {
+		name string
+		a    models.QuoteAssumptions
+	}{
+		{"quote_cash_and_loan", assumptions(func(a *models.QuoteAssumptions) {})},
+		{"quote_dealer_fee_tax_credit_year_2", assumptions(func(a *models.QuoteAssumptions) {
+			a.TaxCreditYear = 2
+			a.LoanFee = 0.25
+			a.LoanFeeFixed = 500
+			a.FinancingProducts = []finance.ProductSpec{
+				{Type: finance.ProductCash},
+				{Type: finance.ProductLoan, APR: 0.0599, TermMonths: 240, LoanFee: 0.25, LoanFeeFixed: 500, ITCPaydownMonth: 18},
+			}
+		})},
+		{"quote_early_inverter_replacement", assumptions(func(a *models.QuoteAssumptions) {
+			a.InverterReplacementYear = 8
+			a.InverterReplacementCostPerWatt = 0.35
+		})},
+		{"quote_itc_paydown", assumptions(func(a *models.QuoteAssumptions) {
+			a.LoanInterestRate = 0.0599
+			a.LoanTermYears = 20
+			a.LoanTermMonths = 240
+			a.LoanFee = 0.25
+			a.LoanITCPaydownMonth = 18
+		})},
+		{"quote_66_month_loan", assumptions(func(a *models.QuoteAssumptions) {
+			a.LoanTermYears = 5
+			a.LoanTermMonths = 66
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.name, calculateQuote(input, tt.a))
+		})
+	}
+}
+
+// The headline cash flow is the one the financing comparison gives the
+// same loan.
+func TestCalculateQuoteCashFlowMatchesLoanOffer(t *testing.T) {
+	a := defaultQuoteAssumptions
+	a.InstallYear = 2025
+	a.FederalTaxCredit = incentive.FederalITC.RateFor(2025)
+	a.LoanFee = 0.25
+	a.LoanFeeFixed = 500
+	a.LoanITCPaydownMonth = 18
+	result := calculateQuote(models.QuoteInput{
+		SystemSizeKW:        8.4,
+		AnnualProductionKWh: 11800,
+		MonthlyElectricBill: 180,
+		ElectricalOffsetPct: 95,
+	}, a)
+
+	for _, offer := range result.Financing {
+		if offer.Type != finance.ProductLoan {
+			continue
+		}
+		if offer.MonthlyPayment != result.EstimatedMonthlyPayment {
+			t.Errorf("monthly payment = %v, loan offer has %v", result.EstimatedMonthlyPayment, offer.MonthlyPayment)
+		}
+		got, _ := json.Marshal(result.CashFlow)
+		want, _ := json.Marshal(offer.CashFlow)
+		if !bytes.Equal(got, want) {
+			t.Errorf("cash flow differs from the loan offer's:\n got %s\nwant %s", got, want)
+		}
+		return
+	}
+	t.Fatal("no loan offer in the financing comparison")
+}
+
+// assertGolden compares got, as indented JSON, with testdata/name.golden.json,
+// rewriting the file instead when the tests run with -update.
+func assertGolden(t *testing.T, name string, got any) {
+	t.Helper()
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal %s: %v", name, err)
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s (run with -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(want, data) {
+		t.Errorf("%s differs from %s (run with -update to accept):\n%s", name, path, data)
+	}
+}
@@ -0,0 +1,1273 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/client"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/storage"
+	"github.com/go-pdf/fpdf"
+	"gorm.io/gorm"
+)
+
+const (
+	signatureRequestTTL     = 14 * 24 * time.Hour
+	signatureCodeTTL        = 10 * time.Minute
+	signatureVerifiedWindow = time.Hour
+	maxSignatureImageBytes  = 512 << 10
+	maxSignatureImagePixels = 4 << 20
+
+	// A signer may ask for maxSignatureCodeSends codes in each
+	// signatureCodeSendWindow and guess each one maxSignatureCodeAttempts
+	// times.
+	maxSignatureCodeSends    = 5
+	maxSignatureCodeAttempts = 3
+	signatureCodeSendWindow  = time.Hour
+)
+
+// signatureConsentText is what the signer agrees to before signing. It is
+// copied onto every request so later edits do not change what was agreed.
+const signatureConsentText = "I agree to use electronic records and signatures for this contract. " +
+	"I understand that my electronic signature is legally binding in the same way as a handwritten signature, " +
+	"that I may request a paper copy of the contract, and that I may withdraw this consent before I sign."
+
+var (
+	// ErrVerificationCodeNotSent means the signer's code could not be delivered.
+	ErrVerificationCodeNotSent = errors.New("verification code could not be sent")
+	// ErrTooManyVerificationCodes means the signer asked for more codes than
+	// the send window allows.
+	ErrTooManyVerificationCodes = errors.New("too many verification codes requested; try again later")
+)
+
+// SignatureService collects electronic signatures on deal contracts. The
+// signer proves who they are with a one-time code, consents to signing
+// electronically and signs by drawing or typing their name. Every step is
+// recorded in a hash-chained audit trail that ends in a completion
+// certificate.
+type SignatureService struct {
+	tx              *repo.TxManager
+	signatureRepo   *repo.SignatureRepo
+	dealRepo        *repo.DealRepo
+	proposalRepo    *repo.ProposalRepo
+	companyRepo     *repo.CompanyRepo
+	userRepo        *repo.UserRepo
+	hardwareService *HardwareService
+	store           storage.DocumentStore
+	email           *client.SendGridClient
+	sms             *client.TwilioClient
+	httpClient      *http.Client
+	publicURL       string
+}
+
+// CreateSignatureRequestInput names the signer of a deal's contract. Without
+// a signer name the deal's homeowner is asked to sign. The verification
+// channel defaults to email when there is an email address.
+type CreateSignatureRequestInput struct {
+	SignerName          string `json:"signer_name,omitempty" example:"Jane Homeowner"`
+	SignerEmail         string `json:"signer_email,omitempty" example:"jane@example.com"`
+	SignerPhone         string `json:"signer_phone,omitempty" example:"+14155550100"`
+	VerificationChannel string `json:"verification_channel,omitempty" example:"email"`
+}
+
+// SignInput is the signer's consent and signature. SignatureImage is a PNG
+// data URL of a drawn signature; TypedName is used when nothing was drawn.
+type SignInput struct {
+	Consent        bool   `json:"consent" example:"true"`
+	SignatureImage string `json:"signature_image,omitempty" example:"data:image/png;base64,iVBORw0KGgo..."`
+	TypedName      string `json:"typed_name,omitempty" example:"Jane Homeowner"`
+}
+
+// PublicSignatureRequest is what the signer sees on the signing link.
+type PublicSignatureRequest struct {
+	Token               string                 `json:"token" example:"SIGN-7K3QX2M4N5P6R7S8T9V2W3X4Y5"`
+	Status              models.SignatureStatus `json:"status" example:"pending"`
+	CompanyName         string                 `json:"company_name" example:"Acme Solar"`
+	SignerName          string                 `json:"signer_name" example:"Jane Homeowner"`
+	VerificationChannel string                 `json:"verification_channel" example:"email"`
+	CodeDestination     string                 `json:"code_destination" example:"j***@example.com"`
+	Verified            bool                   `json:"verified" example:"false"`
+	ConsentText         string                 `json:"consent_text"`
+	DocumentHash        string                 `json:"document_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Address             string                 `json:"address" example:"123 Solar Street, CA 90210"`
+	SystemSize          float64                `json:"system_size" example:"10.5"`
+	PanelCount          int                    `json:"panel_count" example:"30"`
+	TotalCost           float64                `json:"total_cost" example:"25000.00"`
+	ExpiresAt           time.Time              `json:"expires_at" example:"2025-10-15T10:00:00Z"`
+	SignedAt            *time.Time             `json:"signed_at,omitempty" example:"2025-10-01T10:06:00Z"`
+}
+
+// SignatureAudit is a signature request with its audit trail.
+type SignatureAudit struct {
+	Request *models.SignatureRequest `json:"request"`
+	Events  []*models.SignatureEvent `json:"events"`
+}
+
+// SignatureVerification reports whether a request's documents and audit
+// trail are unchanged since they were recorded.
+type SignatureVerification struct {
+	RequestID            int    `json:"request_id" example:"1"`
+	DocumentIntact       bool   `json:"document_intact" example:"true"`
+	SignedDocumentIntact *bool  `json:"signed_document_intact,omitempty" example:"true"`
+	ChainIntact          bool   `json:"chain_intact" example:"true"`
+	EventCount           int    `json:"event_count" example:"6"`
+	BrokenEventID        *int   `json:"broken_event_id,omitempty" example:"4"`
+	LastHash             string `json:"last_hash" example:"60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"`
+}
+
+// contractDocument is everything a contract PDF is rendered from.
+type contractDocument struct {
+	Request *models.SignatureRequest
+	Terms   models.ContractTerms
+
+	// Set on the signed copy only
+	SignatureImage []byte
+}
+
+// NewSignatureService creates the signature service. A nil email or SMS
+// client disables that verification channel.
+func NewSignatureService(tx *repo.TxManager, signatureRepo *repo.SignatureRepo, dealRepo *repo.DealRepo, proposalRepo *repo.ProposalRepo, companyRepo *repo.CompanyRepo, userRepo *repo.UserRepo, hardwareService *HardwareService, store storage.DocumentStore, email *client.SendGridClient, sms *client.TwilioClient, publicURL string) *SignatureService {
+	return &SignatureService{
+		tx:              tx,
+		signatureRepo:   signatureRepo,
+		dealRepo:        dealRepo,
+		proposalRepo:    proposalRepo,
+		companyRepo:     companyRepo,
+		userRepo:        userRepo,
+		hardwareService: hardwareService,
+		store:           store,
+		email:           email,
+		sms:             sms,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		publicURL:       strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// Create renders a deal's contract and opens a signature request for it.
+// The contract's terms are frozen on the request, and the SHA-256 of the
+// rendered contract anchors the request's audit trail.
+func (s *SignatureService) Create(ctx context.Context, dealID int, input CreateSignatureRequestInput, ipAddress, userAgent string) (*models.SignatureRequest, error) {
+	deal, err := s.getDeal(ctx, dealID)
+	if err != nil {
+		return nil, err
+	}
+	if deal.SignedAt != nil {
+		return nil, models.ErrDealAlreadySigned
+	}
+	if err := s.fillSigner(ctx, deal, &input); err != nil {
+		return nil, err
+	}
+
+	token, err := newSignatureToken()
+	if err != nil {
+		return nil, err
+	}
+	request := &models.SignatureRequest{
+		Token:               token,
+		DealID:              deal.ID,
+		CompanyID:           deal.CompanyID,
+		Status:              models.SignatureStatusPending,
+		SignerName:          input.SignerName,
+		SignerEmail:         input.SignerEmail,
+		SignerPhone:         input.SignerPhone,
+		VerificationChannel: input.VerificationChannel,
+		ConsentText:         signatureConsentText,
+		ExpiresAt:           time.Now().Add(signatureRequestTTL),
+	}
+
+	terms, err := s.contractTerms(ctx, deal)
+	if err != nil {
+		return nil, err
+	}
+	request.Contract = &terms
+	doc := &contractDocument{Request: request, Terms: terms}
+	hash, err := s.renderAndStore(ctx, contractDocumentName(token), func(w io.Writer) error {
+		return s.renderContractPDF(w, doc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render contract: %w", err)
+	}
+	request.DocumentHash = hash
+
+	if err := s.signatureRepo.Create(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to create signature request: %w", err)
+	}
+	request.DocumentURL = signatureDocumentURL(request.ID)
+	if err := s.signatureRepo.Update(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to create signature request: %w", err)
+	}
+	detail := fmt.Sprintf("Signature requested from %s; contract SHA-256 %s", request.SignerName, hash)
+	if err := s.record(ctx, s.signatureRepo, request, models.SignatureEventCreated, ipAddress, userAgent, detail); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// signatureDocumentURL is the route a request's contract is served from:
+// the signed copy once it is signed. Stored documents are private and only
+// ever served through it.
+func signatureDocumentURL(id int) string {
+	return fmt.Sprintf("/api/signature-requests/%d/document", id)
+}
+
+func signatureCertificateURL(id int) string {
+	return fmt.Sprintf("/api/signature-requests/%d/certificate", id)
+}
+
+// SigningURL is the link the signer opens to review and sign.
+func (s *SignatureService) SigningURL(request *models.SignatureRequest) string {
+	return s.publicURL + "/sign/" + request.Token
+}
+
+// Get returns a signature request with its audit trail.
+func (s *SignatureService) Get(ctx context.Context, id int) (*SignatureAudit, error) {
+	request, err := s.signatureRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.signatureRepo.ListEvents(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureAudit{Request: request, Events: events}, nil
+}
+
+func (s *SignatureService) ListByDeal(ctx context.Context, dealID int) ([]*models.SignatureRequest, error) {
+	if _, err := s.getDeal(ctx, dealID); err != nil {
+		return nil, err
+	}
+	return s.signatureRepo.ListByDeal(ctx, dealID)
+}
+
+// PublicView returns the signer's view of a request opened by its token,
+// with the terms frozen on the request when it has them.
+func (s *SignatureService) PublicView(ctx context.Context, token string) (*PublicSignatureRequest, error) {
+	request, err := s.signatureRepo.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if request.IsExpired() {
+		return nil, models.ErrSignatureRequestExpired
+	}
+	deal, err := s.getDeal(ctx, request.DealID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &PublicSignatureRequest{
+		Token:               request.Token,
+		Status:              request.Status,
+		SignerName:          request.SignerName,
+		VerificationChannel: request.VerificationChannel,
+		CodeDestination:     maskedDestination(request),
+		Verified:            s.isVerified(request),
+		ConsentText:         request.ConsentText,
+		DocumentHash:        request.DocumentHash,
+		Address:             deal.Address,
+		SystemSize:          deal.SystemSize,
+		PanelCount:          deal.PanelCount,
+		TotalCost:           deal.TotalCost,
+		ExpiresAt:           request.ExpiresAt,
+		SignedAt:            request.SignedAt,
+	}
+	if company, err := s.companyRepo.GetByID(ctx, request.CompanyID); err == nil {
+		view.CompanyName = companyDisplayName(company)
+	}
+	if terms := request.Contract; terms != nil {
+		view.CompanyName = terms.CompanyName
+		view.Address = terms.Address
+		view.SystemSize = terms.SystemSize
+		view.PanelCount = terms.PanelCount
+		view.TotalCost = terms.TotalCost
+	}
+	return view, nil
+}
+
+// OpenDocument returns the contract behind a signing link, the signed copy
+// once it is signed. Each open by the signer is recorded.
+func (s *SignatureService) OpenDocument(ctx context.Context, token, ipAddress, userAgent string) (io.ReadCloser, error) {
+	request, err := s.signatureRepo.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if request.IsExpired() {
+		return nil, models.ErrSignatureRequestExpired
+	}
+
+	name := contractDocumentName(request.Token)
+	if request.Status == models.SignatureStatusCompleted {
+		name = signedContractName(request.Token)
+	} else if err := s.record(ctx, s.signatureRepo, request, models.SignatureEventDocumentViewed, ipAddress, userAgent, "Contract opened by signer"); err != nil {
+		return nil, err
+	}
+	return s.store.Open(ctx, name)
+}
+
+// Document returns the signed contract of a completed request, or the
+// unsigned contract while it is still being signed.
+func (s *SignatureService) Document(ctx context.Context, id int) (*models.SignatureRequest, io.ReadCloser, error) {
+	request, err := s.signatureRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := contractDocumentName(request.Token)
+	if request.Status == models.SignatureStatusCompleted {
+		name = signedContractName(request.Token)
+	}
+	file, err := s.store.Open(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return request, file, nil
+}
+
+// SendCode sends the signer a one-time code over the request's verification
+// channel. A new code replaces any code sent before; codes are kept hashed
+// in the database so they survive restarts and work on every replica.
+func (s *SignatureService) SendCode(ctx context.Context, token, ipAddress, userAgent string) error {
+	request, err := s.openRequest(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	code, err := s.issueCode(ctx, request)
+	if err != nil {
+		return err
+	}
+	companyName := "SunReady"
+	if company, err := s.companyRepo.GetByID(ctx, request.CompanyID); err == nil {
+		companyName = companyDisplayName(company)
+	}
+
+	var sendErr error
+	switch request.VerificationChannel {
+	case models.NotificationChannelEmail:
+		if s.email == nil {
+			sendErr = errors.New("email delivery is not configured")
+			break
+		}
+		subject := "Your code to sign with " + companyName
+		body := fmt.Sprintf("Hi %s,\n\nYour verification code to sign your contract with %s is %s. It expires in %d minutes.\n\nIf you did not ask for this code, you can ignore this email.",
+			request.SignerName, companyName, code, int(signatureCodeTTL.Minutes()))
+		sendErr = s.email.SendEmail(request.SignerEmail, request.SignerName, subject, body)
+	case models.NotificationChannelSMS:
+		if s.sms == nil {
+			sendErr = errors.New("SMS delivery is not configured")
+			break
+		}
+		body := fmt.Sprintf("Your code to sign your contract with %s is %s. It expires in %d minutes.", companyName, code, int(signatureCodeTTL.Minutes()))
+		sendErr = s.sms.SendSMS(request.SignerPhone, body)
+	default:
+		sendErr = models.ErrInvalidSignerContact
+	}
+	if sendErr != nil {
+		if err := s.discardCode(ctx, request); err != nil {
+			log.Printf("Warning: failed to discard unsent code for signature request %d: %v", request.ID, err)
+		}
+		return fmt.Errorf("%w: %v", ErrVerificationCodeNotSent, sendErr)
+	}
+
+	detail := fmt.Sprintf("Code sent by %s to %s", request.VerificationChannel, maskedDestination(request))
+	return s.record(ctx, s.signatureRepo, request, models.SignatureEventCodeSent, ipAddress, userAgent, detail)
+}
+
+// VerifyCode checks the signer's one-time code. A correct code verifies the
+// signer for signatureVerifiedWindow.
+func (s *SignatureService) VerifyCode(ctx context.Context, token, code, ipAddress, userAgent string) (*models.SignatureRequest, error) {
+	request, err := s.openRequest(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err := s.checkCode(ctx, request, strings.TrimSpace(code))
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		if recErr := s.record(ctx, s.signatureRepo, request, models.SignatureEventCodeFailed, ipAddress, userAgent, reason); recErr != nil {
+			log.Printf("Warning: failed to record code failure for signature request %d: %v", request.ID, recErr)
+		}
+		return nil, fmt.Errorf("%w: %s", models.ErrInvalidVerificationCode, reason)
+	}
+
+	now := time.Now()
+	request.Status = models.SignatureStatusVerified
+	request.VerifiedAt = &now
+	if err := s.signatureRepo.Update(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to save verification: %w", err)
+	}
+	detail := fmt.Sprintf("Identity verified by %s code sent to %s", request.VerificationChannel, maskedDestination(request))
+	if err := s.record(ctx, s.signatureRepo, request, models.SignatureEventIdentityVerified, ipAddress, userAgent, detail); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Sign records the signer's consent and signature, renders the signed
+// contract and marks the deal signed. The signed copy is rendered from the
+// terms frozen on the request, and a deal that no longer matches them is
+// refused with ErrContractChanged, so the signer only ever signs what they
+// were shown. The signed files are written and the request, the deal and
+// the proposal's contract link updated in one transaction, under the
+// request's lock; the completion certificate is rendered afterwards.
+func (s *SignatureService) Sign(ctx context.Context, token string, input SignInput, ipAddress, userAgent string) (*models.SignatureRequest, error) {
+	request, err := s.openRequest(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !s.isVerified(request) {
+		return nil, models.ErrSignerNotVerified
+	}
+	if !input.Consent {
+		return nil, models.ErrConsentRequired
+	}
+	deal, err := s.getDeal(ctx, request.DealID)
+	if err != nil {
+		return nil, err
+	}
+	if deal.SignedAt != nil {
+		return nil, models.ErrDealAlreadySigned
+	}
+
+	terms, err := s.contractTerms(ctx, deal)
+	if err != nil {
+		return nil, err
+	}
+	// Requests created before terms were frozen are rendered from the deal.
+	if request.Contract != nil {
+		if !terms.SameDeal(*request.Contract) {
+			return nil, models.ErrContractChanged
+		}
+		terms = *request.Contract
+	}
+	doc := &contractDocument{Request: request, Terms: terms}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if strings.TrimSpace(input.SignatureImage) != "" {
+		image, err := decodeSignatureImage(input.SignatureImage)
+		if err != nil {
+			return nil, err
+		}
+		request.SignatureType = models.SignatureTypeDrawn
+		doc.SignatureImage = image
+	} else if typed := strings.Join(strings.Fields(input.TypedName), " "); typed != "" {
+		request.SignatureType = models.SignatureTypeTyped
+		request.TypedSignature = typed
+	} else {
+		return nil, models.ErrInvalidSignature
+	}
+	request.ConsentedAt = &now
+	request.SignedAt = &now
+	request.SignerIP = ipAddress
+	request.SignerUserAgent = userAgent
+
+	err = s.tx.Do(ctx, func(tx *gorm.DB) error {
+		signatures := s.signatureRepo.WithTx(tx)
+		current, err := signatures.GetByIDForUpdate(ctx, request.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status == models.SignatureStatusCompleted {
+			return models.ErrSignatureRequestCompleted
+		}
+
+		// The files are named after the request, so only the attempt
+		// holding the lock on a request still open may write them.
+		if doc.SignatureImage != nil {
+			url, err := s.store.Save(ctx, signatureImageName(request.Token), doc.SignatureImage)
+			if err != nil {
+				return fmt.Errorf("failed to store signature: %w", err)
+			}
+			request.SignatureImageURL = url
+		}
+		hash, err := s.renderAndStore(ctx, signedContractName(request.Token), func(w io.Writer) error {
+			return s.renderContractPDF(w, doc)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to render signed contract: %w", err)
+		}
+		request.SignedDocumentURL = signatureDocumentURL(request.ID)
+		request.SignedDocumentHash = hash
+		request.Status = models.SignatureStatusCompleted
+
+		if err := s.dealRepo.WithTx(tx).MarkSigned(ctx, deal.ID, now); err != nil {
+			return err
+		}
+		if err := signatures.Update(ctx, request); err != nil {
+			return fmt.Errorf("failed to save signature: %w", err)
+		}
+		if deal.ProposalID != nil {
+			proposals := s.proposalRepo.WithTx(tx)
+			proposal, err := proposals.GetByIDForUpdate(ctx, *deal.ProposalID)
+			if err != nil {
+				return err
+			}
+			proposal.ContractURL = request.SignedDocumentURL
+			if err := proposals.Update(ctx, proposal); err != nil {
+				return fmt.Errorf("failed to save contract on proposal: %w", err)
+			}
+		}
+
+		if err := s.recordAt(ctx, signatures, request, models.SignatureEventConsentGiven, now, ipAddress, userAgent, request.ConsentText); err != nil {
+			return err
+		}
+		detail := fmt.Sprintf("Signed with a %s signature; signed contract SHA-256 %s", request.SignatureType, request.SignedDocumentHash)
+		return s.recordAt(ctx, signatures, request, models.SignatureEventSigned, now, ipAddress, userAgent, detail)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.record(ctx, s.signatureRepo, request, models.SignatureEventCompleted, "", "", "Deal marked signed"); err != nil {
+		return nil, err
+	}
+	if err := s.renderCertificate(ctx, request); err != nil {
+		log.Printf("Warning: failed to render completion certificate for signature request %d: %v", request.ID, err)
+	}
+	return request, nil
+}
+
+// Certificate returns the completion certificate of a signed request,
+// rendering it first if it is missing.
+func (s *SignatureService) Certificate(ctx context.Context, id int) (*models.SignatureRequest, io.ReadCloser, error) {
+	request, err := s.signatureRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if request.Status != models.SignatureStatusCompleted {
+		return nil, nil, models.ErrSignatureRequestIncomplete
+	}
+	if request.CertificateURL == "" {
+		if err := s.renderCertificate(ctx, request); err != nil {
+			return nil, nil, err
+		}
+	}
+	file, err := s.store.Open(ctx, certificateName(request.Token))
+	if err != nil {
+		return nil, nil, err
+	}
+	return request, file, nil
+}
+
+// Verify recomputes the hashes of a request's stored documents and of every
+// event in its audit trail.
+func (s *SignatureService) Verify(ctx context.Context, id int) (*SignatureVerification, error) {
+	request, err := s.signatureRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.signatureRepo.ListEvents(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SignatureVerification{
+		RequestID:   request.ID,
+		ChainIntact: true,
+		EventCount:  len(events),
+		LastHash:    request.DocumentHash,
+	}
+
+	hash, err := s.storedHash(ctx, contractDocumentName(request.Token))
+	if err != nil {
+		return nil, err
+	}
+	result.DocumentIntact = hash == request.DocumentHash
+	if request.SignedDocumentHash != "" {
+		hash, err := s.storedHash(ctx, signedContractName(request.Token))
+		if err != nil {
+			return nil, err
+		}
+		intact := hash == request.SignedDocumentHash
+		result.SignedDocumentIntact = &intact
+	}
+
+	for _, event := range events {
+		if event.PrevHash != result.LastHash || event.ComputeHash() != event.Hash {
+			id := event.ID
+			result.ChainIntact = false
+			result.BrokenEventID = &id
+			break
+		}
+		result.LastHash = event.Hash
+	}
+	return result, nil
+}
+
+// issueCode generates a code for request and stores its hash, counting it
+// against the request's sends in the current window.
+func (s *SignatureService) issueCode(ctx context.Context, request *models.SignatureRequest) (string, error) {
+	code, err := client.GenerateOTP(6)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+
+	err = s.tx.Do(ctx, func(tx *gorm.DB) error {
+		signatures := s.signatureRepo.WithTx(tx)
+		// The request row serializes sends, including the first one.
+		if _, err := signatures.GetByIDForUpdate(ctx, request.ID); err != nil {
+			return err
+		}
+		current, err := signatures.GetCodeForUpdate(ctx, request.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			current = &models.SignatureCode{RequestID: request.ID}
+		}
+
+		now := time.Now()
+		if now.Sub(current.SendWindowStart) >= signatureCodeSendWindow {
+			current.SendWindowStart = now
+			current.Sends = 0
+		}
+		if current.Sends >= maxSignatureCodeSends {
+			return ErrTooManyVerificationCodes
+		}
+		current.Sends++
+		current.CodeHash = signatureCodeHash(request.Token, code)
+		current.ExpiresAt = now.Add(signatureCodeTTL)
+		current.Attempts = 0
+		return signatures.SaveCode(ctx, current)
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// discardCode forgets the code last issued for request, e.g. when it could
+// not be sent. It still counts against the request's sends.
+func (s *SignatureService) discardCode(ctx context.Context, request *models.SignatureRequest) error {
+	return s.tx.Do(ctx, func(tx *gorm.DB) error {
+		signatures := s.signatureRepo.WithTx(tx)
+		current, err := signatures.GetCodeForUpdate(ctx, request.ID)
+		if err != nil || current == nil {
+			return err
+		}
+		current.CodeHash = ""
+		return signatures.SaveCode(ctx, current)
+	})
+}
+
+// checkCode checks code against the one last issued for request and returns
+// why it was refused, or "" when it matched. A code is used up by a
+// successful check, by expiring or by too many wrong guesses.
+func (s *SignatureService) checkCode(ctx context.Context, request *models.SignatureRequest, code string) (string, error) {
+	var reason string
+	err := s.tx.Do(ctx, func(tx *gorm.DB) error {
+		signatures := s.signatureRepo.WithTx(tx)
+		current, err := signatures.GetCodeForUpdate(ctx, request.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.CodeHash == "" {
+			reason = "no code has been sent"
+			return nil
+		}
+
+		switch {
+		case time.Now().After(current.ExpiresAt):
+			reason = "code has expired"
+		case current.Attempts >= maxSignatureCodeAttempts:
+			reason = "maximum verification attempts exceeded"
+		case subtle.ConstantTimeCompare([]byte(current.CodeHash), []byte(signatureCodeHash(request.Token, code))) != 1:
+			current.Attempts++
+			reason = fmt.Sprintf("invalid code (attempt %d/%d)", current.Attempts, maxSignatureCodeAttempts)
+			return signatures.SaveCode(ctx, current)
+		}
+		current.CodeHash = ""
+		return signatures.SaveCode(ctx, current)
+	})
+	return reason, err
+}
+
+// signatureCodeHash hashes a code with the request's token, which is never
+// stored alongside it in the code's row.
+func signatureCodeHash(token, code string) string {
+	sum := sha256.Sum256([]byte(token + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// CleanupCodes deletes codes whose code and send window have both run out.
+func (s *SignatureService) CleanupCodes(ctx context.Context, now time.Time) (int64, error) {
+	return s.signatureRepo.DeleteCodesBefore(ctx, now.Add(-max(signatureCodeTTL, signatureCodeSendWindow)))
+}
+
+// RunCodeCleanup calls CleanupCodes every interval until ctx is done.
+func (s *SignatureService) RunCodeCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.CleanupCodes(ctx, time.Now()); err != nil {
+			log.Printf("Warning: signature code cleanup failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SignatureService) openRequest(ctx context.Context, token string) (*models.SignatureRequest, error) {
+	request, err := s.signatureRepo.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status == models.SignatureStatusCompleted {
+		return nil, models.ErrSignatureRequestCompleted
+	}
+	if request.IsExpired() {
+		return nil, models.ErrSignatureRequestExpired
+	}
+	return request, nil
+}
+
+func (s *SignatureService) isVerified(request *models.SignatureRequest) bool {
+	if request.Status == models.SignatureStatusCompleted {
+		return true
+	}
+	return request.Status == models.SignatureStatusVerified &&
+		request.VerifiedAt != nil &&
+		time.Since(*request.VerifiedAt) < signatureVerifiedWindow
+}
+
+func (s *SignatureService) getDeal(ctx context.Context, id int) (*models.Deal, error) {
+	deal, err := s.dealRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrDealNotFound
+		}
+		return nil, err
+	}
+	return deal, nil
+}
+
+// fillSigner defaults the signer to the deal's homeowner and checks that
+// the chosen verification channel has somewhere to send the code.
+func (s *SignatureService) fillSigner(ctx context.Context, deal *models.Deal, input *CreateSignatureRequestInput) error {
+	input.SignerName = strings.TrimSpace(input.SignerName)
+	input.SignerEmail = strings.TrimSpace(input.SignerEmail)
+	input.SignerPhone = strings.TrimSpace(input.SignerPhone)
+	input.VerificationChannel = strings.ToLower(strings.TrimSpace(input.VerificationChannel))
+
+	if input.SignerName == "" {
+		homeowner, err := s.userRepo.GetByID(ctx, deal.HomeownerID)
+		if err != nil {
+			return models.ErrInvalidSignerName
+		}
+		input.SignerName = fullName(homeowner)
+		if input.SignerEmail == "" {
+			input.SignerEmail = homeowner.Email
+		}
+		if input.SignerPhone == "" && homeowner.PhoneNumber != nil {
+			input.SignerPhone = *homeowner.PhoneNumber
+		}
+	}
+	if input.SignerName == "" {
+		return models.ErrInvalidSignerName
+	}
+
+	if input.VerificationChannel == "" {
+		input.VerificationChannel = models.NotificationChannelEmail
+		if input.SignerEmail == "" {
+			input.VerificationChannel = models.NotificationChannelSMS
+		}
+	}
+	switch input.VerificationChannel {
+	case models.NotificationChannelEmail:
+		if !strings.Contains(input.SignerEmail, "@") {
+			return models.ErrInvalidSignerContact
+		}
+	case models.NotificationChannelSMS:
+		if input.SignerPhone == "" {
+			return models.ErrInvalidSignerContact
+		}
+	default:
+		return models.ErrInvalidSignerContact
+	}
+	return nil
+}
+
+func (s *SignatureService) record(ctx context.Context, signatures *repo.SignatureRepo, request *models.SignatureRequest, eventType, ipAddress, userAgent, detail string) error {
+	return s.recordAt(ctx, signatures, request, eventType, time.Now(), ipAddress, userAgent, detail)
+}
+
+// recordAt appends an event to the request's audit trail. Times are stored
+// in UTC at microsecond precision, which is what the database keeps, so the
+// hash can be recomputed from the stored row.
+func (s *SignatureService) recordAt(ctx context.Context, signatures *repo.SignatureRepo, request *models.SignatureRequest, eventType string, at time.Time, ipAddress, userAgent, detail string) error {
+	event := &models.SignatureEvent{
+		RequestID:  request.ID,
+		Type:       eventType,
+		OccurredAt: at.UTC().Truncate(time.Microsecond),
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		Detail:     detail,
+	}
+	return signatures.AppendEvent(ctx, event)
+}
+
+// contractTerms reads the terms a deal's contract shows from the deal, its
+// company and its catalog hardware.
+func (s *SignatureService) contractTerms(ctx context.Context, deal *models.Deal) (models.ContractTerms, error) {
+	terms := models.ContractTerms{
+		Address:           deal.Address,
+		SystemSize:        deal.SystemSize,
+		PanelCount:        deal.PanelCount,
+		ProductionKWH:     deal.ProductionKWH,
+		FinancingProvider: deal.FinancingProvider,
+		TotalCost:         deal.TotalCost,
+	}
+
+	company, err := s.companyRepo.GetByID(ctx, deal.CompanyID)
+	if err != nil {
+		return terms, models.ErrCompanyNotFound
+	}
+	terms.CompanyName = companyDisplayName(company)
+	if company.LogoPath != nil {
+		terms.LogoPath = *company.LogoPath
+	}
+
+	if deal.PanelID != nil {
+		if panel, err := s.hardwareService.GetPanel(ctx, *deal.PanelID); err == nil {
+			terms.PanelName = panel.DisplayName
+			if terms.PanelName == "" {
+				terms.PanelName = panel.Manufacturer + " " + panel.Model
+			}
+		}
+	}
+	if deal.InverterID != nil {
+		if inverter, err := s.hardwareService.GetInverter(ctx, *deal.InverterID); err == nil {
+			terms.InverterName = inverter.Name
+			if terms.InverterName == "" {
+				terms.InverterName = inverter.Manufacturer + " " + inverter.Model
+			}
+		}
+	}
+	return terms, nil
+}
+
+// renderAndStore renders a document, stores it under name and returns its
+// SHA-256.
+func (s *SignatureService) renderAndStore(ctx context.Context, name string, render func(w io.Writer) error) (string, error) {
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		return "", err
+	}
+	if _, err := s.store.Save(ctx, name, buf.Bytes()); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *SignatureService) storedHash(ctx context.Context, name string) (string, error) {
+	file, err := s.store.Open(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *SignatureService) renderCertificate(ctx context.Context, request *models.SignatureRequest) error {
+	events, err := s.signatureRepo.ListEvents(ctx, request.ID)
+	if err != nil {
+		return err
+	}
+	companyName := ""
+	if company, err := s.companyRepo.GetByID(ctx, request.CompanyID); err == nil {
+		companyName = companyDisplayName(company)
+	}
+
+	var image []byte
+	if request.SignatureType == models.SignatureTypeDrawn {
+		file, err := s.store.Open(ctx, signatureImageName(request.Token))
+		if err != nil {
+			return fmt.Errorf("failed to open signature: %w", err)
+		}
+		image, err = io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read signature: %w", err)
+		}
+	}
+
+	if _, err := s.renderAndStore(ctx, certificateName(request.Token), func(w io.Writer) error {
+		return renderCertificatePDF(w, request, events, companyName, image)
+	}); err != nil {
+		return fmt.Errorf("failed to render certificate: %w", err)
+	}
+	request.CertificateURL = signatureCertificateURL(request.ID)
+	if err := s.signatureRepo.Update(ctx, request); err != nil {
+		return fmt.Errorf("failed to save certificate: %w", err)
+	}
+	return nil
+}
+
+func (s *SignatureService) renderContractPDF(w io.Writer, doc *contractDocument) error {
+	req, terms := doc.Request, doc.Terms
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetTitle("Solar Installation Agreement", true)
+	pdf.SetAuthor(terms.CompanyName, true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 30
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(130, 130, 130)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s  |  %s  |  Page %d", tr(terms.CompanyName), req.Token, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	logoDrawn := false
+	if terms.LogoPath != "" {
+		if err := drawLogo(pdf, s.httpClient, terms.LogoPath); err != nil {
+			log.Printf("Warning: failed to add company logo to contract %s: %v", req.Token, err)
+		} else {
+			logoDrawn = true
+		}
+	}
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(33, 37, 41)
+	if !logoDrawn {
+		pdf.CellFormat(contentWidth, 10, tr(terms.CompanyName), "", 1, "L", false, 0, "")
+	} else {
+		pdf.SetY(37)
+	}
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetTextColor(230, 126, 34)
+	pdf.CellFormat(contentWidth, 12, "Solar Installation Agreement", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.CellFormat(contentWidth, 6, "Agreement "+req.Token, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Parties")
+	parties := [][2]string{
+		{"Installer", terms.CompanyName},
+		{"Customer", req.SignerName},
+		{"Installation address", terms.Address},
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(33, 37, 41)
+	for i, row := range parties {
+		fill := i%2 == 0
+		pdf.SetFillColor(245, 245, 245)
+		pdf.CellFormat(contentWidth/2, 7, row[0], "", 0, "L", fill, 0, "")
+		pdf.CellFormat(contentWidth/2, 7, tr(row[1]), "", 1, "R", fill, 0, "")
+	}
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "System and Price")
+	specs := [][2]string{
+		{"System size", fmt.Sprintf("%.2f kW", terms.SystemSize)},
+		{"Panels", fmt.Sprintf("%d", terms.PanelCount)},
+	}
+	if terms.PanelName != "" {
+		specs = append(specs, [2]string{"Panel model", terms.PanelName})
+	}
+	if terms.InverterName != "" {
+		specs = append(specs, [2]string{"Inverter", terms.InverterName})
+	}
+	if terms.ProductionKWH > 0 {
+		specs = append(specs, [2]string{"Estimated annual production", fmt.Sprintf("%s kWh", formatNumber(float64(terms.ProductionKWH), 0))})
+	}
+	if terms.FinancingProvider != "" {
+		specs = append(specs, [2]string{"Financing", terms.FinancingProvider})
+	}
+	specs = append(specs, [2]string{"Contract price", formatMoney(terms.TotalCost)})
+	for i, spec := range specs {
+		fill := i%2 == 0
+		pdf.SetFillColor(245, 245, 245)
+		pdf.CellFormat(contentWidth/2, 7, spec[0], "", 0, "L", fill, 0, "")
+		pdf.CellFormat(contentWidth/2, 7, tr(spec[1]), "", 1, "R", fill, 0, "")
+	}
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Terms")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(33, 37, 41)
+	clauses := []string{
+		"1. The installer will design, permit and install the system described above at the installation address for the contract price.",
+		"2. Production figures are estimates. Actual production depends on weather, shading and utility conditions.",
+		"3. The customer may cancel this agreement without penalty within three business days of signing by notifying the installer in writing.",
+		"4. Incentives, rebates and tax credits are not guaranteed and are the customer's responsibility to claim unless agreed otherwise in writing.",
+		"5. This agreement is signed electronically. Electronic signatures and records have the same effect as handwritten signatures and paper records.",
+	}
+	for _, clause := range clauses {
+		pdf.MultiCell(contentWidth, 5, tr(clause), "", "L", false)
+		pdf.Ln(1)
+	}
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Customer Signature")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(33, 37, 41)
+	if req.SignedAt == nil {
+		pdf.Ln(14)
+		pdf.SetDrawColor(33, 37, 41)
+		y := pdf.GetY()
+		pdf.Line(15, y, 15+contentWidth/2, y)
+		pdf.Ln(2)
+		pdf.CellFormat(contentWidth, 6, tr(req.SignerName), "", 1, "L", false, 0, "")
+		return pdf.Output(w)
+	}
+
+	if len(doc.SignatureImage) > 0 {
+		opts := fpdf.ImageOptions{ImageType: "png", ReadDpi: false}
+		pdf.RegisterImageOptionsReader("signature", opts, bytes.NewReader(doc.SignatureImage))
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("invalid signature image: %w", err)
+		}
+		pdf.ImageOptions("signature", 15, pdf.GetY(), 0, 20, true, opts, 0, "")
+	} else {
+		pdf.SetFont("Times", "BI", 24)
+		pdf.CellFormat(contentWidth, 14, tr(req.TypedSignature), "", 1, "L", false, 0, "")
+	}
+	y := pdf.GetY() + 1
+	pdf.SetDrawColor(33, 37, 41)
+	pdf.Line(15, y, 15+contentWidth/2, y)
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(contentWidth, 6, tr(req.SignerName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.CellFormat(contentWidth, 4, fmt.Sprintf("Signed electronically %s from IP %s", req.SignedAt.UTC().Format("January 2, 2006 15:04:05 MST"), req.SignerIP), "", 1, "L", false, 0, "")
+	pdf.CellFormat(contentWidth, 4, "Identity verified by "+req.VerificationChannel+" one-time code", "", 1, "L", false, 0, "")
+	pdf.CellFormat(contentWidth, 4, "Unsigned agreement SHA-256: "+req.DocumentHash, "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func renderCertificatePDF(w io.Writer, req *models.SignatureRequest, events []*models.SignatureEvent, companyName string, signatureImage []byte) error {
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetTitle("Certificate of Completion "+req.Token, true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 30
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(130, 130, 130)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s  |  Page %d", req.Token, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetTextColor(230, 126, 34)
+	pdf.CellFormat(contentWidth, 12, "Certificate of Completion", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.CellFormat(contentWidth, 6, tr(companyName)+"  |  Solar Installation Agreement "+req.Token, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	summary := [][2]string{
+		{"Status", string(req.Status)},
+		{"Signer", req.SignerName},
+	}
+	if req.SignerEmail != "" {
+		summary = append(summary, [2]string{"Email", req.SignerEmail})
+	}
+	if req.SignerPhone != "" {
+		summary = append(summary, [2]string{"Phone", req.SignerPhone})
+	}
+	summary = append(summary,
+		[2]string{"Identity verification", req.VerificationChannel + " one-time code"},
+		[2]string{"Signature type", req.SignatureType},
+		[2]string{"Signer IP address", req.SignerIP},
+		[2]string{"Requested", req.CreatedAt.UTC().Format(time.RFC3339)},
+	)
+	if req.VerifiedAt != nil {
+		summary = append(summary, [2]string{"Identity verified", req.VerifiedAt.UTC().Format(time.RFC3339)})
+	}
+	if req.ConsentedAt != nil {
+		summary = append(summary, [2]string{"Consent given", req.ConsentedAt.UTC().Format(time.RFC3339)})
+	}
+	if req.SignedAt != nil {
+		summary = append(summary, [2]string{"Signed", req.SignedAt.UTC().Format(time.RFC3339)})
+	}
+
+	sectionTitle(pdf, contentWidth, "Summary")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(33, 37, 41)
+	for i, row := range summary {
+		fill := i%2 == 0
+		pdf.SetFillColor(245, 245, 245)
+		pdf.CellFormat(contentWidth*0.4, 7, row[0], "", 0, "L", fill, 0, "")
+		pdf.CellFormat(contentWidth*0.6, 7, tr(row[1]), "", 1, "R", fill, 0, "")
+	}
+	if req.SignerUserAgent != "" {
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.MultiCell(contentWidth, 4, tr("Browser: "+req.SignerUserAgent), "", "L", false)
+	}
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Signature")
+	if len(signatureImage) > 0 {
+		opts := fpdf.ImageOptions{ImageType: "png"}
+		pdf.RegisterImageOptionsReader("signature", opts, bytes.NewReader(signatureImage))
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("invalid signature image: %w", err)
+		}
+		pdf.ImageOptions("signature", 15, pdf.GetY(), 0, 20, true, opts, 0, "")
+	} else {
+		pdf.SetFont("Times", "BI", 24)
+		pdf.SetTextColor(33, 37, 41)
+		pdf.CellFormat(contentWidth, 14, tr(req.TypedSignature), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Consent")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.MultiCell(contentWidth, 5, tr(req.ConsentText), "", "L", false)
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Document Fingerprints (SHA-256)")
+	pdf.SetFont("Courier", "", 8)
+	pdf.CellFormat(contentWidth, 5, "Unsigned: "+req.DocumentHash, "", 1, "L", false, 0, "")
+	pdf.CellFormat(contentWidth, 5, "Signed:   "+req.SignedDocumentHash, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	sectionTitle(pdf, contentWidth, "Audit Trail")
+	widths := []float64{contentWidth * 0.2, contentWidth * 0.27, contentWidth * 0.2, contentWidth * 0.33}
+	tableHeader(pdf, widths, []string{"Event", "Time (UTC)", "IP address", "Hash"})
+	pdf.SetTextColor(33, 37, 41)
+	for i, event := range events {
+		fill := i%2 == 1
+		pdf.SetFillColor(245, 245, 245)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(widths[0], 6, strings.ReplaceAll(event.Type, "_", " "), "", 0, "L", fill, 0, "")
+		pdf.CellFormat(widths[1], 6, event.OccurredAt.UTC().Format("2006-01-02 15:04:05.000"), "", 0, "R", fill, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(event.IPAddress), "", 0, "R", fill, 0, "")
+		pdf.SetFont("Courier", "", 7)
+		pdf.CellFormat(widths[3], 6, event.Hash[:24]+"...", "", 1, "R", fill, 0, "")
+	}
+	if len(events) > 0 {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.MultiCell(contentWidth, 4, "Each event's hash covers the event and the hash before it, starting from the unsigned document's fingerprint. Final hash: "+events[len(events)-1].Hash, "", "L", false)
+	}
+
+	return pdf.Output(w)
+}
+
+// decodeSignatureImage decodes a drawn signature sent as a PNG data URL or
+// bare base64. The image is decoded in full and encoded again, so only a
+// well-formed PNG of bounded size is ever stored or drawn into a PDF.
+func decodeSignatureImage(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "data:") {
+		header, data, ok := strings.Cut(value, ",")
+		if !ok || header != "data:image/png;base64" {
+			return nil, models.ErrInvalidSignature
+		}
+		value = data
+	}
+	if base64.StdEncoding.DecodedLen(len(value)) > maxSignatureImageBytes {
+		return nil, models.ErrInvalidSignature
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, models.ErrInvalidSignature
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxSignatureImagePixels {
+		return nil, models.ErrInvalidSignature
+	}
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, models.ErrInvalidSignature
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, models.ErrInvalidSignature
+	}
+	return buf.Bytes(), nil
+}
+
+// maskedDestination shows where a code went without revealing the full
+// address, e.g. j***@example.com or ***0100.
+func maskedDestination(request *models.SignatureRequest) string {
+	if request.VerificationChannel == models.NotificationChannelSMS {
+		phone := request.SignerPhone
+		if len(phone) <= 4 {
+			return "***"
+		}
+		return "***" + phone[len(phone)-4:]
+	}
+	local, domain, ok := strings.Cut(request.SignerEmail, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	return local[:1] + "***@" + domain
+}
+
+func companyDisplayName(company *models.Company) string {
+	if company.DisplayName != "" {
+		return company.DisplayName
+	}
+	return company.Name
+}
+
+// newSignatureToken returns a token carrying 128 random bits. Like proposal
+// codes, the token is the only credential on the signing link.
+func newSignatureToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate signature token: %w", err)
+	}
+	return "SIGN-" + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+func contractDocumentName(token string) string {
+	return "contracts/" + token + ".pdf"
+}
+
+func signedContractName(token string) string {
+	return "contracts/" + token + "-signed.pdf"
+}
+
+func certificateName(token string) string {
+	return "contracts/" + token + "-certificate.pdf"
+}
+
+func signatureImageName(token string) string {
+	return "signatures/" + token + ".png"
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/sizing"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
+)
+
+// Where a sizing's yield came from.
+const (
+	YieldSourceInput    = "input"
+	YieldSourceLead     = "lead"
+	YieldSourceEstimate = "estimate"
+)
+
+// SizingService sizes systems locally to a target offset, the way
+// LightFusion does when a 3D project is created with TargetSolarOffset.
+type SizingService struct {
+	leadRepo          *repo.LeadRepo
+	hardwareService   *HardwareService
+	productionService *ProductionService
+	usageService      *UsageService
+}
+
+// SizeSystemInput takes consumption in the same shape as a 3D project
+// request. With a lead, anything left out is taken from the lead: its
+// uploaded usage or else annual usage, hardware, manual kWh/kW and
+// location. Without a yield, one is
+// estimated from the weather at the location for the given tilt and
+// azimuth.
+type SizeSystemInput struct {
+	LeadID            *int      `json:"lead_id,omitempty" example:"1"`
+	Consumption       []float64 `json:"consumption,omitempty" example:"800,850,900,950,1000,1050,1100,1150,1200,1250,1300,1350"`
+	Period            string    `json:"period,omitempty" example:"month"`
+	Unit              string    `json:"unit,omitempty" example:"kwh"`
+	RatePerKWh        float64   `json:"rate_per_kwh,omitempty" example:"0.13"`
+	TargetSolarOffset float64   `json:"target_solar_offset,omitempty" example:"100"`
+	Mode              *string   `json:"mode,omitempty" example:"offset"`
+	PanelID           *int      `json:"panel_id,omitempty" example:"1"`
+	InverterID        *int      `json:"inverter_id,omitempty" example:"1"`
+	YieldKWhPerKW     float64   `json:"yield_kwh_per_kw,omitempty" example:"1450"`
+	Latitude          *float64  `json:"latitude,omitempty" example:"37.7749"`
+	Longitude         *float64  `json:"longitude,omitempty" example:"-122.4194"`
+	TiltDeg           *float64  `json:"tilt_deg,omitempty" example:"20"`
+	AzimuthDeg        *float64  `json:"azimuth_deg,omitempty" example:"180"`
+	DCACRatio         float64   `json:"dc_ac_ratio,omitempty" example:"1.2"`
+	MaxPanels         int       `json:"max_panels,omitempty" example:"32"`
+	MaxSystemKW       float64   `json:"max_system_kw,omitempty" example:"0"`
+}
+
+// SystemSizing is a sized system with the hardware and yield it was sized
+// with.
+type SystemSizing struct {
+	*sizing.Result
+	PanelID       int     `json:"panel_id" example:"1"`
+	InverterID    *int    `json:"inverter_id,omitempty" example:"1"`
+	YieldKWhPerKW float64 `json:"yield_kwh_per_kw" example:"1450"`
+	YieldSource   string  `json:"yield_source" example:"estimate"`
+}
+
+func NewSizingService(leadRepo *repo.LeadRepo, hardwareService *HardwareService, productionService *ProductionService, usageService *UsageService) *SizingService {
+	return &SizingService{leadRepo: leadRepo, hardwareService: hardwareService, productionService: productionService, usageService: usageService}
+}
+
+// Size sizes a system to the target offset, or to the caps in max mode.
+func (s *SizingService) Size(ctx context.Context, input SizeSystemInput) (*SystemSizing, error) {
+	yieldSource := YieldSourceInput
+	if input.LeadID != nil {
+		lead, err := s.leadRepo.GetByID(ctx, *input.LeadID)
+		if err != nil {
+			return nil, err
+		}
+		if len(input.Consumption) == 0 {
+			load, err := s.usageService.LoadProfile(ctx, lead.ID)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case load != nil:
+				input.Consumption = usage.MonthlyTotals(load)
+				input.Period, input.Unit = sizing.PeriodMonth, sizing.UnitKWh
+			case lead.KwhUsage > 0:
+				input.Consumption = []float64{lead.KwhUsage}
+				input.Period, input.Unit = sizing.PeriodYear, sizing.UnitKWh
+			}
+		}
+		if input.PanelID == nil {
+			input.PanelID = lead.PanelID
+		}
+		if input.InverterID == nil {
+			input.InverterID = lead.InverterID
+		}
+		if input.YieldKWhPerKW == 0 && lead.KwhPerKwManual > 0 {
+			input.YieldKWhPerKW = float64(lead.KwhPerKwManual)
+			yieldSource = YieldSourceLead
+		}
+		if input.Latitude == nil || input.Longitude == nil {
+			input.Latitude, input.Longitude = &lead.Latitude, &lead.Longitude
+		}
+	}
+	if input.PanelID == nil {
+		return nil, fmt.Errorf("%w: a panel or lead with a panel is required", sizing.ErrInvalidSizing)
+	}
+
+	mode := sizing.ModeOffset
+	if input.Mode != nil && *input.Mode != "" {
+		mode = *input.Mode
+	}
+	var annualKWh float64
+	if mode != sizing.ModeMax || len(input.Consumption) > 0 {
+		consumption := sizing.Consumption{
+			Values:     input.Consumption,
+			Period:     input.Period,
+			Unit:       input.Unit,
+			RatePerKWh: input.RatePerKWh,
+		}
+		if consumption.RatePerKWh == 0 {
+			consumption.RatePerKWh = defaultUtilityRatePerKWh
+		}
+		var err error
+		if annualKWh, err = consumption.AnnualKWh(); err != nil {
+			return nil, err
+		}
+	}
+
+	panel, err := s.hardwareService.GetPanel(ctx, *input.PanelID)
+	if err != nil {
+		return nil, err
+	}
+	sizingInput := sizing.Input{
+		AnnualKWh:     annualKWh,
+		TargetOffset:  input.TargetSolarOffset,
+		Mode:          mode,
+		PanelWatts:    panel.Power,
+		YieldKWhPerKW: input.YieldKWhPerKW,
+		DCACRatio:     input.DCACRatio,
+		MaxPanels:     input.MaxPanels,
+		MaxSystemKW:   input.MaxSystemKW,
+	}
+	if input.InverterID != nil {
+		inverter, err := s.hardwareService.GetInverter(ctx, *input.InverterID)
+		if err != nil {
+			return nil, err
+		}
+		sizingInput.InverterWatts = inverter.Capacity
+		sizingInput.InverterMaxDCWatts = inverter.MaxDCPower
+		sizingInput.Micro = inverter.IsMicro()
+	}
+
+	if sizingInput.YieldKWhPerKW == 0 {
+		if input.Latitude == nil || input.Longitude == nil {
+			return nil, fmt.Errorf("%w: a yield, location or lead is required", sizing.ErrInvalidSizing)
+		}
+		// Yield per kW doesn't depend on size, so estimate a 1 kW array
+		// with the chosen panel and the default inverter loading.
+		estimate, err := s.productionService.Estimate(ctx, EstimateProductionInput{
+			Latitude:     input.Latitude,
+			Longitude:    input.Longitude,
+			SystemSizeKW: 1,
+			TiltDeg:      input.TiltDeg,
+			AzimuthDeg:   input.AzimuthDeg,
+			PanelID:      input.PanelID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sizingInput.YieldKWhPerKW = estimate.KWhPerKW
+		yieldSource = YieldSourceEstimate
+	}
+
+	result, err := sizing.Size(sizingInput)
+	if err != nil {
+		return nil, err
+	}
+	return &SystemSizing{
+		Result:        result,
+		PanelID:       panel.ID,
+		InverterID:    input.InverterID,
+		YieldKWhPerKW: sizingInput.YieldKWhPerKW,
+		YieldSource:   yieldSource,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/Bilal-Cplusoft/sun_ready/internal/models"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/repo"
+	"github.com/Bilal-Cplusoft/sun_ready/internal/usage"
+)
+
+// UsageService stores leads' measured usage. Quotes, proposals and sizing
+// for a lead with usage use its real load shape instead of a typical one.
+type UsageService struct {
+	usageRepo *repo.LeadUsageRepo
+	leadRepo  *repo.LeadRepo
+}
+
+func NewUsageService(usageRepo *repo.LeadUsageRepo, leadRepo *repo.LeadRepo) *UsageService {
+	return &UsageService{usageRepo: usageRepo, leadRepo: leadRepo}
+}
+
+// Import parses an uploaded usage file, normalizes it to a year of hourly
+// usage and saves it for the lead, replacing any earlier upload. The lead's
+// annual usage is set from it. An empty format is detected from the file.
+func (s *UsageService) Import(ctx context.Context, leadID int, format, fileName string, r io.Reader) (*models.LeadUsage, error) {
+	lead, err := s.leadRepo.GetByID(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	intervals, format, err := usage.Parse(r, format)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := usage.Normalize(intervals)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.LeadUsage{
+		LeadID:          lead.ID,
+		Format:          format,
+		FileName:        fileName,
+		Start:           profile.Start,
+		End:             profile.End,
+		IntervalMinutes: profile.IntervalMinutes,
+		ReadingHours:    profile.ReadingHours,
+		FilledHours:     profile.FilledHours,
+		AnnualKWh:       profile.AnnualKWh,
+		MonthlyKWh:      profile.Monthly[:],
+		HourlyKWh:       profile.Hourly,
+	}
+	if err := s.usageRepo.Save(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save usage: %w", err)
+	}
+
+	lead.KwhUsage = math.Round(profile.AnnualKWh)
+	if err := s.leadRepo.Update(ctx, lead); err != nil {
+		return nil, fmt.Errorf("failed to update lead usage: %w", err)
+	}
+	return record, nil
+}
+
+func (s *UsageService) Get(ctx context.Context, leadID int) (*models.LeadUsage, error) {
+	return s.usageRepo.GetByLeadID(ctx, leadID)
+}
+
+func (s *UsageService) Delete(ctx context.Context, leadID int) error {
+	return s.usageRepo.DeleteByLeadID(ctx, leadID)
+}
+
+// LoadProfile returns the lead's hourly usage, or nil when it has none.
+func (s *UsageService) LoadProfile(ctx context.Context, leadID int) ([]float64, error) {
+	record, err := s.usageRepo.GetByLeadID(ctx, leadID)
+	if errors.Is(err, models.ErrLeadUsageNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record.HourlyKWh, nil
+}
+
+// SynthesizeLoadInput describes a home for a synthetic load profile. With a
+// LeadID, the lead's annual usage is used when no consumption is given and
+// the state is read from its address; State sets the climate zone when
+// none is given.
+type SynthesizeLoadInput struct {
+	usage.SyntheticInput
+	LeadID *int   `json:"lead_id,omitempty" example:"1"`
+	State  string `json:"state,omitempty" example:"CA"`
+}
+
+// Synthesize expands monthly or annual consumption into a year of hourly
+// load shaped like a reference home in the same climate.
+func (s *UsageService) Synthesize(ctx context.Context, input SynthesizeLoadInput) (*usage.SyntheticProfile, error) {
+	in := input.SyntheticInput
+	state := input.State
+	if input.LeadID != nil {
+		lead, err := s.leadRepo.GetByID(ctx, *input.LeadID)
+		if err != nil {
+			return nil, err
+		}
+		if len(in.Monthly) == 0 && in.AnnualKWh == 0 {
+			in.AnnualKWh = lead.KwhUsage
+		}
+		if state == "" {
+			state = stateFromAddress(lead.Address)
+		}
+	}
+	if in.Climate == "" {
+		in.Climate = usage.ClimateForState(state)
+	}
+	return usage.Synthesize(in)
+}
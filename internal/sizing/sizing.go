@@ -0,0 +1,210 @@
+// Package sizing picks a panel count, DC and AC size and inverter count
+// that cover a target share of a home's consumption.
+package sizing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidSizing = errors.New("invalid sizing")
+
+// Consumption periods and units, as sent to LightFusion with a 3D project.
+const (
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+
+	UnitKWh     = "kwh"
+	UnitDollars = "dollars"
+)
+
+// Sizing modes. ModeOffset sizes to the target offset; ModeMax fills the
+// roof whatever the consumption.
+const (
+	ModeOffset = "offset"
+	ModeMax    = "max"
+)
+
+const DefaultDCACRatio = 1.2
+
+// Consumption is a home's usage as entered: twelve monthly values or one
+// yearly value, in kWh or in dollars billed at RatePerKWh.
+type Consumption struct {
+	Values     []float64 `json:"values" example:"800,850,900,950,1000,1050,1100,1150,1200,1250,1300,1350"`
+	Period     string    `json:"period" example:"month"`
+	Unit       string    `json:"unit" example:"kwh"`
+	RatePerKWh float64   `json:"rate_per_kwh,omitempty" example:"0.13"`
+}
+
+// AnnualKWh returns the year's consumption in kWh.
+func (c Consumption) AnnualKWh() (float64, error) {
+	switch c.Period {
+	case PeriodMonth, "":
+		if len(c.Values) != 12 {
+			return 0, fmt.Errorf("%w: monthly consumption needs 12 values", ErrInvalidSizing)
+		}
+	case PeriodYear:
+		if len(c.Values) != 1 {
+			return 0, fmt.Errorf("%w: yearly consumption needs 1 value", ErrInvalidSizing)
+		}
+	default:
+		return 0, fmt.Errorf("%w: unknown period %q", ErrInvalidSizing, c.Period)
+	}
+
+	var total float64
+	for _, v := range c.Values {
+		if v < 0 {
+			return 0, fmt.Errorf("%w: consumption cannot be negative", ErrInvalidSizing)
+		}
+		total += v
+	}
+	switch c.Unit {
+	case UnitKWh, "":
+		return total, nil
+	case UnitDollars:
+		if c.RatePerKWh <= 0 {
+			return 0, fmt.Errorf("%w: a rate is required for consumption in dollars", ErrInvalidSizing)
+		}
+		return total / c.RatePerKWh, nil
+	default:
+		return 0, fmt.Errorf("%w: unknown unit %q", ErrInvalidSizing, c.Unit)
+	}
+}
+
+// Input is what a system is sized from. Yield is the expected first-year
+// production per kW DC at the site.
+type Input struct {
+	AnnualKWh     float64 `json:"annual_kwh" example:"13800"`
+	TargetOffset  float64 `json:"target_offset" example:"100"`
+	Mode          string  `json:"mode" example:"offset"`
+	PanelWatts    float64 `json:"panel_watts" example:"400"`
+	YieldKWhPerKW float64 `json:"yield_kwh_per_kw" example:"1450"`
+	// InverterWatts is the AC capacity of one inverter; zero leaves the
+	// inverter count out and sizes AC from the DC/AC ratio.
+	InverterWatts float64 `json:"inverter_watts,omitempty" example:"7600"`
+	// InverterMaxDCWatts is the most DC one inverter takes; zero counts
+	// inverters from the DC/AC ratio instead.
+	InverterMaxDCWatts float64 `json:"inverter_max_dc_watts,omitempty" example:"11800"`
+	// Micro installs one inverter per panel.
+	Micro bool `json:"micro,omitempty" example:"false"`
+	// DCACRatio is the DC per AC inverters are loaded to when their max DC
+	// isn't known; zero means 1.2.
+	DCACRatio float64 `json:"dc_ac_ratio,omitempty" example:"1.2"`
+	// MaxPanels and MaxSystemKW cap the system to what the roof or the
+	// utility allows; zero means no cap.
+	MaxPanels   int     `json:"max_panels,omitempty" example:"32"`
+	MaxSystemKW float64 `json:"max_system_kw,omitempty" example:"0"`
+}
+
+// Result is a sized system. ExactPanels and ExactOffset are before rounding
+// to whole panels (and after any roof cap); PanelCount and Offset after.
+type Result struct {
+	AnnualConsumptionKWh float64 `json:"annual_consumption_kwh" example:"13800"`
+	TargetOffset         float64 `json:"target_offset" example:"100"`
+	ExactPanels          float64 `json:"exact_panels" example:"23.79"`
+	ExactOffset          float64 `json:"exact_offset" example:"100"`
+	PanelCount           int     `json:"panel_count" example:"24"`
+	Offset               float64 `json:"offset" example:"100.87"`
+	DCSizeKW             float64 `json:"dc_size_kw" example:"9.6"`
+	ACSizeKW             float64 `json:"ac_size_kw" example:"7.6"`
+	InverterCount        int     `json:"inverter_count" example:"1"`
+	DCACRatio            float64 `json:"dc_ac_ratio" example:"1.26"`
+	AnnualProductionKWh  float64 `json:"annual_production_kwh" example:"13920"`
+	// Capped is set when the roof or system cap kept the system below the
+	// target.
+	Capped bool `json:"capped" example:"false"`
+}
+
+func (in *Input) Validate() error {
+	if in.PanelWatts <= 0 || in.YieldKWhPerKW <= 0 {
+		return fmt.Errorf("%w: panel watts and yield must be greater than 0", ErrInvalidSizing)
+	}
+	if in.AnnualKWh < 0 || in.TargetOffset < 0 || in.InverterWatts < 0 || in.InverterMaxDCWatts < 0 || in.DCACRatio < 0 ||
+		in.MaxPanels < 0 || in.MaxSystemKW < 0 {
+		return fmt.Errorf("%w: values cannot be negative", ErrInvalidSizing)
+	}
+	switch in.Mode {
+	case ModeOffset, "":
+		if in.AnnualKWh == 0 || in.TargetOffset == 0 {
+			return fmt.Errorf("%w: consumption and a target offset are required", ErrInvalidSizing)
+		}
+	case ModeMax:
+		if in.MaxPanels == 0 && in.MaxSystemKW == 0 {
+			return fmt.Errorf("%w: max mode needs a panel or system cap", ErrInvalidSizing)
+		}
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidSizing, in.Mode)
+	}
+	return nil
+}
+
+// Size sizes the system. In offset mode it takes the fewest whole panels
+// that meet the target, within the caps.
+func Size(in Input) (*Result, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	ratio := in.DCACRatio
+	if ratio == 0 {
+		ratio = DefaultDCACRatio
+	}
+	panelKW := in.PanelWatts / 1000
+
+	limit := math.Inf(1)
+	if in.MaxPanels > 0 {
+		limit = float64(in.MaxPanels)
+	}
+	if in.MaxSystemKW > 0 {
+		limit = math.Min(limit, math.Floor(in.MaxSystemKW/panelKW+1e-9))
+	}
+
+	var exact float64
+	if in.Mode == ModeMax {
+		exact = limit
+	} else {
+		exact = in.AnnualKWh * in.TargetOffset / 100 / (panelKW * in.YieldKWhPerKW)
+	}
+	r := &Result{AnnualConsumptionKWh: round2(in.AnnualKWh), TargetOffset: in.TargetOffset}
+	if exact > limit {
+		exact = limit
+		r.Capped = in.Mode != ModeMax
+	}
+	// Ignore float noise so an exact fit doesn't take an extra panel.
+	r.PanelCount = int(math.Ceil(exact - 1e-9))
+	if float64(r.PanelCount) > limit {
+		r.PanelCount = int(limit)
+	}
+	r.ExactPanels = round2(exact)
+
+	dc := float64(r.PanelCount) * panelKW
+	r.DCSizeKW = round2(dc)
+	switch {
+	case r.PanelCount == 0:
+	case in.Micro && in.InverterWatts > 0:
+		r.InverterCount = r.PanelCount
+		r.ACSizeKW = round2(float64(r.InverterCount) * in.InverterWatts / 1000)
+	case in.InverterWatts > 0 && in.InverterMaxDCWatts > 0:
+		r.InverterCount = int(math.Ceil(dc/(in.InverterMaxDCWatts/1000) - 1e-9))
+		r.ACSizeKW = round2(float64(r.InverterCount) * in.InverterWatts / 1000)
+	case in.InverterWatts > 0:
+		r.InverterCount = max(1, int(math.Round(dc/ratio/(in.InverterWatts/1000))))
+		r.ACSizeKW = round2(float64(r.InverterCount) * in.InverterWatts / 1000)
+	default:
+		r.ACSizeKW = round2(dc / ratio)
+	}
+	if r.ACSizeKW > 0 {
+		r.DCACRatio = round2(dc / r.ACSizeKW)
+	}
+
+	r.AnnualProductionKWh = round2(dc * in.YieldKWhPerKW)
+	if in.AnnualKWh > 0 {
+		r.ExactOffset = round2(exact * panelKW * in.YieldKWhPerKW / in.AnnualKWh * 100)
+		r.Offset = round2(dc * in.YieldKWhPerKW / in.AnnualKWh * 100)
+	}
+	return r, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
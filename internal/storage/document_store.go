@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DocumentStore keeps generated documents such as proposal PDFs. Documents are
+// private: they are served through handlers that check access, never by a
+// file server. Save returns where the document was stored.
+type DocumentStore interface {
+	Save(ctx context.Context, name string, data []byte) (string, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// LocalDocumentStore writes documents to a directory on disk, such as
+// ./data/documents. Save returns the document's name under baseURL.
+type LocalDocumentStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalDocumentStore(dir, baseURL string) (*LocalDocumentStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create document directory: %w", err)
+	}
+	return &LocalDocumentStore{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+// Save writes the document atomically so readers never see a partial file.
+func (s *LocalDocumentStore) Save(ctx context.Context, name string, data []byte) (string, error) {
+	target, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create document directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create document: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write document: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write document: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write document: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("failed to store document: %w", err)
+	}
+
+	return s.baseURL + "/" + path.Clean(filepath.ToSlash(name)), nil
+}
+
+func (s *LocalDocumentStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	target, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(target)
+}
+
+func (s *LocalDocumentStore) Delete(ctx context.Context, name string) error {
+	target, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path resolves name inside the store directory and refuses names that would
+// escape it.
+func (s *LocalDocumentStore) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if clean == "." || filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("invalid document name %q", name)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
@@ -0,0 +1,202 @@
+package tariff
+
+import (
+	"math"
+	"time"
+)
+
+// HoursPerYear is the length of an hourly profile. Profiles follow a
+// non-leap calendar year starting on a Sunday, so weekends and months line
+// up with referenceYear.
+const HoursPerYear = 8760
+
+var referenceYear = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// MonthlyBill is one billing month. Energy charges, credits and totals are
+// in dollars; Total is what the customer pays for the month after credits.
+type MonthlyBill struct {
+	Month               int     `json:"month" example:"1"`
+	LoadKWh             float64 `json:"load_kwh" example:"950"`
+	ProductionKWh       float64 `json:"production_kwh" example:"620"`
+	ImportKWh           float64 `json:"import_kwh" example:"610"`
+	ExportKWh           float64 `json:"export_kwh" example:"280"`
+	EnergyCharge        float64 `json:"energy_charge" example:"231.40"`
+	FixedCharge         float64 `json:"fixed_charge" example:"15.00"`
+	NonBypassableCharge float64 `json:"non_bypassable_charge" example:"18.30"`
+	ExportCredit        float64 `json:"export_credit" example:"14.00"`
+	CreditApplied       float64 `json:"credit_applied" example:"14.00"`
+	CreditCarried       float64 `json:"credit_carried" example:"0"`
+	CreditPaidOut       float64 `json:"credit_paid_out" example:"0"`
+	Total               float64 `json:"total" example:"250.70"`
+}
+
+// Bill is a year of monthly bills followed by the annual true-up.
+type Bill struct {
+	Months []MonthlyBill `json:"months"`
+	// TrueUpPayment is paid for the year's net surplus kWh at the net
+	// surplus rate; ForfeitedCredit is credit left at true-up and lost.
+	TrueUpPayment   float64 `json:"true_up_payment" example:"0"`
+	ForfeitedCredit float64 `json:"forfeited_credit" example:"0"`
+	// AnnualTotal is the sum of the monthly totals less the true-up payment
+	// and any credit paid out.
+	AnnualTotal float64 `json:"annual_total" example:"2780.55"`
+}
+
+// Comparison is the same load billed without and with solar, or with two
+// different supplies.
+type Comparison struct {
+	Tariff         string      `json:"tariff" example:"E-TOU-C"`
+	Before         *Bill       `json:"before"`
+	After          *Bill       `json:"after"`
+	MonthlySavings [12]float64 `json:"monthly_savings"`
+	AnnualSavings  float64     `json:"annual_savings" example:"2140.10"`
+}
+
+// Compare bills load without solar and load less production with solar.
+func (t *Tariff) Compare(load, production []float64) (*Comparison, error) {
+	return t.CompareSupply(load, nil, production)
+}
+
+// CompareSupply bills the same load against two hourly supplies, e.g. solar
+// alone and solar with a battery.
+func (t *Tariff) CompareSupply(load, before, after []float64) (*Comparison, error) {
+	beforeBill, err := t.Bill(load, before)
+	if err != nil {
+		return nil, err
+	}
+	afterBill, err := t.Bill(load, after)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Comparison{Tariff: t.Name, Before: beforeBill, After: afterBill}
+	for m := range c.MonthlySavings {
+		c.MonthlySavings[m] = round2(beforeBill.Months[m].Total - afterBill.Months[m].Total)
+	}
+	c.AnnualSavings = round2(beforeBill.AnnualTotal - afterBill.AnnualTotal)
+	return c, nil
+}
+
+// Bill computes a year of bills for an hourly load and, optionally, hourly
+// solar production. Load and production are netted hour by hour; surplus
+// production in an hour is exported.
+func (t *Tariff) Bill(load, production []float64) (*Bill, error) {
+	if len(load) != HoursPerYear || (production != nil && len(production) != HoursPerYear) {
+		return nil, ErrInvalidProfile
+	}
+
+	type monthTotals struct {
+		MonthlyBill
+		netKWh float64
+	}
+	months := make([]monthTotals, 12)
+
+	ts := referenceYear
+	for h := 0; h < HoursPerYear; h, ts = h+1, ts.Add(time.Hour) {
+		m := int(ts.Month()) - 1
+		weekend := ts.Weekday() == time.Saturday || ts.Weekday() == time.Sunday
+		p := t.period(m, ts.Hour(), weekend)
+
+		solar := 0.0
+		if production != nil {
+			solar = production[h]
+		}
+		net := load[h] - solar
+		mt := &months[m]
+		mt.LoadKWh += load[h]
+		mt.ProductionKWh += solar
+		mt.netKWh += net
+
+		rate := t.Periods[p].Rate
+		if net >= 0 {
+			mt.ImportKWh += net
+			mt.EnergyCharge += net * rate
+		} else {
+			mt.ExportKWh += -net
+			mt.ExportCredit += -net * t.exportRate(h, p)
+		}
+	}
+
+	bill := &Bill{Months: make([]MonthlyBill, 12)}
+	carried := 0.0
+	annualNet := 0.0
+	for m := range months {
+		mt := &months[m]
+		mt.Month = m + 1
+		annualNet += mt.netKWh
+
+		tierKWh := mt.ImportKWh
+		if t.isNEM() {
+			tierKWh = math.Max(0, mt.netKWh)
+		}
+		mt.EnergyCharge += t.tierCharge(tierKWh)
+		mt.FixedCharge = t.FixedMonthlyCharge
+		if t.Export.Type == ExportNEM2 {
+			mt.NonBypassableCharge = mt.ImportKWh * t.Export.NonBypassableCharge
+		}
+
+		available := mt.ExportCredit + carried
+		mt.CreditApplied = math.Min(available, mt.EnergyCharge)
+		left := available - mt.CreditApplied
+		if t.Export.Type == ExportAvoidedCost {
+			mt.CreditPaidOut = left
+			carried = 0
+		} else {
+			carried = left
+		}
+		mt.CreditCarried = carried
+
+		total := mt.FixedCharge + mt.NonBypassableCharge + mt.EnergyCharge - mt.CreditApplied
+		mt.Total = math.Max(total, t.MinimumBill)
+
+		bill.Months[m] = roundMonth(mt.MonthlyBill)
+		bill.AnnualTotal += mt.Total - mt.CreditPaidOut
+	}
+
+	if annualNet < 0 && t.Export.NetSurplusRate > 0 {
+		bill.TrueUpPayment = -annualNet * t.Export.NetSurplusRate
+	}
+	bill.ForfeitedCredit = carried
+	bill.AnnualTotal -= bill.TrueUpPayment
+
+	bill.TrueUpPayment = round2(bill.TrueUpPayment)
+	bill.ForfeitedCredit = round2(bill.ForfeitedCredit)
+	bill.AnnualTotal = round2(bill.AnnualTotal)
+	return bill, nil
+}
+
+// tierCharge returns the tier adders owed on a month's consumption.
+func (t *Tariff) tierCharge(kwh float64) float64 {
+	charge, floor := 0.0, 0.0
+	for _, tier := range t.Tiers {
+		ceiling := math.Inf(1)
+		if tier.MaxKWh != nil {
+			ceiling = *tier.MaxKWh
+		}
+		if kwh > floor {
+			charge += (math.Min(kwh, ceiling) - floor) * tier.RateAdder
+		}
+		floor = ceiling
+	}
+	return charge
+}
+
+func roundMonth(b MonthlyBill) MonthlyBill {
+	b.LoadKWh = round2(b.LoadKWh)
+	b.ProductionKWh = round2(b.ProductionKWh)
+	b.ImportKWh = round2(b.ImportKWh)
+	b.ExportKWh = round2(b.ExportKWh)
+	b.EnergyCharge = round2(b.EnergyCharge)
+	b.FixedCharge = round2(b.FixedCharge)
+	b.NonBypassableCharge = round2(b.NonBypassableCharge)
+	b.ExportCredit = round2(b.ExportCredit)
+	b.CreditApplied = round2(b.CreditApplied)
+	b.CreditCarried = round2(b.CreditCarried)
+	b.CreditPaidOut = round2(b.CreditPaidOut)
+	b.Total = round2(b.Total)
+	return b
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,54 @@
+package tariff
+
+import "time"
+
+// Typical hourly shapes, used when a quote has monthly or annual figures
+// but no measured interval data. Each is normalized when a profile is built.
+var (
+	// ResidentialLoadShape has a small morning peak and a larger evening
+	// peak, as in most home load research profiles.
+	ResidentialLoadShape = [24]float64{
+		0.030, 0.027, 0.026, 0.025, 0.026, 0.030, 0.038, 0.045,
+		0.043, 0.038, 0.036, 0.036, 0.036, 0.037, 0.039, 0.043,
+		0.050, 0.059, 0.064, 0.063, 0.059, 0.053, 0.044, 0.036,
+	}
+	// SolarShape is a clear-sky production curve centred on solar noon.
+	SolarShape = [24]float64{
+		0, 0, 0, 0, 0, 0, 0.010, 0.035,
+		0.065, 0.095, 0.118, 0.130, 0.133, 0.127, 0.112, 0.089,
+		0.058, 0.024, 0.004, 0, 0, 0, 0, 0,
+	}
+)
+
+// Profile spreads annualKWh over the hours of the year: across months by
+// monthly, then evenly over the days of each month, then over the hours of
+// each day by daily.
+func Profile(annualKWh float64, monthly [12]float64, daily [24]float64) []float64 {
+	monthTotal, dayTotal := sum(monthly[:]), sum(daily[:])
+	profile := make([]float64, HoursPerYear)
+	if monthTotal == 0 || dayTotal == 0 {
+		return profile
+	}
+
+	ts := referenceYear
+	for h := range profile {
+		m := ts.Month()
+		days := float64(daysIn(m))
+		monthKWh := annualKWh * monthly[m-1] / monthTotal
+		profile[h] = monthKWh / days * daily[ts.Hour()] / dayTotal
+		ts = ts.Add(time.Hour)
+	}
+	return profile
+}
+
+func daysIn(m time.Month) int {
+	return time.Date(referenceYear.Year(), m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
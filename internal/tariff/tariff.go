@@ -0,0 +1,247 @@
+// Package tariff models utility rate plans and computes hourly (8760) bills
+// with and without solar. Tariffs are plain JSON so they can be kept in
+// files and checked without a database or a rate API.
+package tariff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidTariff  = errors.New("invalid tariff")
+	ErrInvalidProfile = errors.New("hourly profiles must have 8760 values")
+)
+
+// Export compensation rules.
+const (
+	// ExportNEM1 credits every exported kWh at the retail rate of the hour
+	// it was exported in.
+	ExportNEM1 = "nem1"
+	// ExportNEM2 is NEM1 plus non-bypassable charges on every imported kWh
+	// that export credits cannot offset.
+	ExportNEM2 = "nem2"
+	// ExportNetBilling credits exports at a separate export rate, usually
+	// far below retail (e.g. California NEM 3.0).
+	ExportNetBilling = "net_billing"
+	// ExportAvoidedCost credits exports at a flat avoided-cost rate. Credit
+	// left after the month's energy charges is paid out instead of carried.
+	ExportAvoidedCost = "avoided_cost"
+	// ExportNone gives no credit for exports.
+	ExportNone = "none"
+)
+
+// Tariff is a residential rate plan. Each hour of the year falls in one
+// time-of-use period, picked from the weekday or weekend schedule by month
+// and hour. A tariff with no schedules is a flat rate: every hour is in
+// period 0.
+type Tariff struct {
+	Name               string  `json:"name" example:"E-TOU-C"`
+	Utility            string  `json:"utility" example:"PG&E"`
+	FixedMonthlyCharge float64 `json:"fixed_monthly_charge" example:"0"`
+	// MinimumBill is the least a month can cost, charges and credits
+	// included.
+	MinimumBill float64 `json:"minimum_bill" example:"10.00"`
+
+	Periods []Period `json:"periods"`
+	// WeekdaySchedule and WeekendSchedule give the period index for each
+	// month (0-11) and hour (0-23).
+	WeekdaySchedule [][]int `json:"weekday_schedule,omitempty"`
+	WeekendSchedule [][]int `json:"weekend_schedule,omitempty"`
+
+	// Tiers add to the period rate once monthly consumption passes each
+	// tier's limit. They apply to net consumption under NEM and to imports
+	// otherwise.
+	Tiers []Tier `json:"tiers,omitempty"`
+
+	Export ExportRule `json:"export"`
+}
+
+// Period is one time-of-use period.
+type Period struct {
+	Name string  `json:"name" example:"peak"`
+	Rate float64 `json:"rate" example:"0.45"`
+}
+
+// Tier is a block of monthly consumption. MaxKWh is the upper limit of the
+// block; nil means no limit and is only valid for the last tier.
+type Tier struct {
+	MaxKWh    *float64 `json:"max_kwh,omitempty" example:"300"`
+	RateAdder float64  `json:"rate_adder" example:"0.05"`
+}
+
+// ExportRule says how exported energy is credited.
+type ExportRule struct {
+	Type string `json:"type" example:"net_billing"`
+	// Rate is the flat export rate for net billing and avoided cost.
+	Rate float64 `json:"rate,omitempty" example:"0.05"`
+	// PeriodRates, when set, overrides Rate per time-of-use period for net
+	// billing. It must have one value per period.
+	PeriodRates []float64 `json:"period_rates,omitempty"`
+	// HourlyRates, when set, overrides both for net billing with one value
+	// per hour of the year, e.g. the avoided cost calculator values.
+	HourlyRates []float64 `json:"hourly_rates,omitempty"`
+	// NonBypassableCharge is charged per imported kWh under NEM 2 and
+	// cannot be offset by export credits.
+	NonBypassableCharge float64 `json:"non_bypassable_charge,omitempty" example:"0.03"`
+	// NetSurplusRate pays for the kWh exported beyond what was imported
+	// over the year, at the annual true-up. Credit left at true-up is
+	// otherwise lost.
+	NetSurplusRate float64 `json:"net_surplus_rate,omitempty" example:"0.04"`
+}
+
+// Parse reads a tariff from JSON and validates it.
+func Parse(r io.Reader) (*Tariff, error) {
+	var t Tariff
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTariff, err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// LoadFile reads a tariff from a JSON file.
+func LoadFile(path string) (*Tariff, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Flat returns a single-period tariff with full retail net metering, the
+// model quotes used before tariffs were supported.
+func Flat(rate float64) *Tariff {
+	return &Tariff{
+		Name:    "Flat rate",
+		Periods: []Period{{Name: "all", Rate: rate}},
+		Export:  ExportRule{Type: ExportNEM1},
+	}
+}
+
+// Validate checks that the tariff can be billed.
+func (t *Tariff) Validate() error {
+	if len(t.Periods) == 0 {
+		return fmt.Errorf("%w: at least one period is required", ErrInvalidTariff)
+	}
+	for _, p := range t.Periods {
+		if p.Rate < 0 {
+			return fmt.Errorf("%w: period %q has a negative rate", ErrInvalidTariff, p.Name)
+		}
+	}
+	if t.FixedMonthlyCharge < 0 || t.MinimumBill < 0 {
+		return fmt.Errorf("%w: fixed charge and minimum bill must not be negative", ErrInvalidTariff)
+	}
+	for name, schedule := range map[string][][]int{"weekday": t.WeekdaySchedule, "weekend": t.WeekendSchedule} {
+		if schedule == nil {
+			continue
+		}
+		if len(schedule) != 12 {
+			return fmt.Errorf("%w: %s schedule must have 12 months", ErrInvalidTariff, name)
+		}
+		for m, hours := range schedule {
+			if len(hours) != 24 {
+				return fmt.Errorf("%w: %s schedule month %d must have 24 hours", ErrInvalidTariff, name, m+1)
+			}
+			for _, p := range hours {
+				if p < 0 || p >= len(t.Periods) {
+					return fmt.Errorf("%w: %s schedule month %d uses unknown period %d", ErrInvalidTariff, name, m+1, p)
+				}
+			}
+		}
+	}
+	last := 0.0
+	for i, tier := range t.Tiers {
+		if tier.MaxKWh == nil {
+			if i != len(t.Tiers)-1 {
+				return fmt.Errorf("%w: only the last tier may be unlimited", ErrInvalidTariff)
+			}
+			continue
+		}
+		if *tier.MaxKWh <= last {
+			return fmt.Errorf("%w: tier limits must increase", ErrInvalidTariff)
+		}
+		last = *tier.MaxKWh
+	}
+
+	e := t.Export
+	switch e.Type {
+	case ExportNEM1, ExportNEM2, ExportNetBilling, ExportAvoidedCost, ExportNone:
+	case "":
+		return fmt.Errorf("%w: export type is required", ErrInvalidTariff)
+	default:
+		return fmt.Errorf("%w: unknown export type %q", ErrInvalidTariff, e.Type)
+	}
+	if e.Rate < 0 || e.NonBypassableCharge < 0 || e.NetSurplusRate < 0 {
+		return fmt.Errorf("%w: export rates must not be negative", ErrInvalidTariff)
+	}
+	if e.PeriodRates != nil && len(e.PeriodRates) != len(t.Periods) {
+		return fmt.Errorf("%w: export period rates must have one value per period", ErrInvalidTariff)
+	}
+	if e.HourlyRates != nil && len(e.HourlyRates) != HoursPerYear {
+		return fmt.Errorf("%w: export hourly rates must have %d values", ErrInvalidTariff, HoursPerYear)
+	}
+	return nil
+}
+
+// period returns the time-of-use period of an hour.
+func (t *Tariff) period(month, hour int, weekend bool) int {
+	schedule := t.WeekdaySchedule
+	if weekend && t.WeekendSchedule != nil {
+		schedule = t.WeekendSchedule
+	}
+	if schedule == nil {
+		return 0
+	}
+	return schedule[month][hour]
+}
+
+// exportRate returns what one kWh exported in hour h earns.
+func (t *Tariff) exportRate(h, period int) float64 {
+	switch t.Export.Type {
+	case ExportNEM1, ExportNEM2:
+		return t.Periods[period].Rate
+	case ExportNetBilling:
+		if t.Export.HourlyRates != nil {
+			return t.Export.HourlyRates[h]
+		}
+		if t.Export.PeriodRates != nil {
+			return t.Export.PeriodRates[period]
+		}
+		return t.Export.Rate
+	case ExportAvoidedCost:
+		return t.Export.Rate
+	default:
+		return 0
+	}
+}
+
+// isNEM reports whether the tariff nets exports against imports kWh for kWh.
+func (t *Tariff) isNEM() bool {
+	return t.Export.Type == ExportNEM1 || t.Export.Type == ExportNEM2
+}
+
+// HourlyRates returns the import rate and the export credit of every hour
+// of the year, before tiers.
+func (t *Tariff) HourlyRates() (imports, exports []float64) {
+	imports = make([]float64, HoursPerYear)
+	exports = make([]float64, HoursPerYear)
+	ts := referenceYear
+	for h := range imports {
+		weekend := ts.Weekday() == time.Saturday || ts.Weekday() == time.Sunday
+		p := t.period(int(ts.Month())-1, ts.Hour(), weekend)
+		imports[h] = t.Periods[p].Rate
+		exports[h] = t.exportRate(h, p)
+		ts = ts.Add(time.Hour)
+	}
+	return imports, exports
+}
@@ -0,0 +1,326 @@
+package tariff
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// constant is an hourly profile of kwh every hour.
+func constant(kwh float64) []float64 {
+	profile := make([]float64, HoursPerYear)
+	for h := range profile {
+		profile[h] = kwh
+	}
+	return profile
+}
+
+// midday is an hourly profile of kwh from 10:00 to 14:00 every day.
+func midday(kwh float64) []float64 {
+	profile := make([]float64, HoursPerYear)
+	for h := range profile {
+		if hour := h % 24; hour >= 10 && hour < 14 {
+			profile[h] = kwh
+		}
+	}
+	return profile
+}
+
+func limit(kwh float64) *float64 { return &kwh }
+
+func TestBillExportRules(t *testing.T) {
+	// A load of 1 kWh an hour and 2 kWh of solar for four hours a day
+	// imports 20 kWh and exports 4 kWh a day: 620 and 124 in January.
+	tests := []struct {
+		name       string
+		export     ExportRule
+		production []float64
+		want       MonthlyBill
+	}{
+		{
+			name:   "no solar",
+			export: ExportRule{Type: ExportNEM1},
+			want:   MonthlyBill{ImportKWh: 744, EnergyCharge: 223.2, Total: 223.2},
+		},
+		{
+			name:       "NEM 1 credits exports at retail",
+			export:     ExportRule{Type: ExportNEM1},
+			production: midday(2),
+			want:       MonthlyBill{ImportKWh: 620, ExportKWh: 124, EnergyCharge: 186, ExportCredit: 37.2, CreditApplied: 37.2, Total: 148.8},
+		},
+		{
+			name:       "NEM 2 adds non-bypassable charges on imports",
+			export:     ExportRule{Type: ExportNEM2, NonBypassableCharge: 0.03},
+			production: midday(2),
+			want:       MonthlyBill{ImportKWh: 620, ExportKWh: 124, EnergyCharge: 186, NonBypassableCharge: 18.6, ExportCredit: 37.2, CreditApplied: 37.2, Total: 167.4},
+		},
+		{
+			name:       "net billing credits exports at the export rate",
+			export:     ExportRule{Type: ExportNetBilling, Rate: 0.05},
+			production: midday(2),
+			want:       MonthlyBill{ImportKWh: 620, ExportKWh: 124, EnergyCharge: 186, ExportCredit: 6.2, CreditApplied: 6.2, Total: 179.8},
+		},
+		{
+			name:       "avoided cost",
+			export:     ExportRule{Type: ExportAvoidedCost, Rate: 0.05},
+			production: midday(2),
+			want:       MonthlyBill{ImportKWh: 620, ExportKWh: 124, EnergyCharge: 186, ExportCredit: 6.2, CreditApplied: 6.2, Total: 179.8},
+		},
+		{
+			name:       "no export credit",
+			export:     ExportRule{Type: ExportNone},
+			production: midday(2),
+			want:       MonthlyBill{ImportKWh: 620, ExportKWh: 124, EnergyCharge: 186, Total: 186},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tariff := Flat(0.30)
+			tariff.Export = tt.export
+			bill, err := tariff.Bill(constant(1), tt.production)
+			if err != nil {
+				t.Fatalf("Bill: %v", err)
+			}
+			want := tt.want
+			want.Month = 1
+			want.LoadKWh = 744
+			want.ProductionKWh = want.ExportKWh + 744 - want.ImportKWh
+			if got := bill.Months[0]; got != want {
+				t.Errorf("January = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestBillCreditsAndTrueUp(t *testing.T) {
+	// 10 kWh of solar for four hours a day exports 36 kWh and imports 20
+	// a day, earning more credit than the month's charges.
+	tests := []struct {
+		name          string
+		export        ExportRule
+		wantCarried   float64
+		wantPaidOut   float64
+		wantTrueUp    float64
+		wantForfeited float64
+		wantAnnual    float64
+	}{
+		{
+			name:          "NEM carries credit to true-up and forfeits it",
+			export:        ExportRule{Type: ExportNEM1},
+			wantCarried:   31 * 4.8,
+			wantForfeited: 365 * 4.8,
+			wantAnnual:    120,
+		},
+		{
+			name:          "net surplus is paid at true-up",
+			export:        ExportRule{Type: ExportNEM1, NetSurplusRate: 0.04},
+			wantCarried:   31 * 4.8,
+			wantTrueUp:    365 * 16 * 0.04,
+			wantForfeited: 365 * 4.8,
+			wantAnnual:    120 - 365*16*0.04,
+		},
+		{
+			name:        "avoided cost pays out credit monthly",
+			export:      ExportRule{Type: ExportAvoidedCost, Rate: 0.30},
+			wantPaidOut: 31 * 4.8,
+			wantAnnual:  120 - 365*4.8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tariff := Flat(0.30)
+			tariff.MinimumBill = 10
+			tariff.Export = tt.export
+			bill, err := tariff.Bill(constant(1), midday(10))
+			if err != nil {
+				t.Fatalf("Bill: %v", err)
+			}
+			jan := bill.Months[0]
+			if jan.Total != 10 || jan.CreditApplied != 186 {
+				t.Errorf("January total %v after %v credit, want the 10 minimum after 186", jan.Total, jan.CreditApplied)
+			}
+			if !near(jan.CreditCarried, tt.wantCarried) || !near(jan.CreditPaidOut, tt.wantPaidOut) {
+				t.Errorf("January carried %v and paid out %v, want %v and %v", jan.CreditCarried, jan.CreditPaidOut, tt.wantCarried, tt.wantPaidOut)
+			}
+			if !near(bill.TrueUpPayment, tt.wantTrueUp) || !near(bill.ForfeitedCredit, tt.wantForfeited) {
+				t.Errorf("true-up %v and forfeited %v, want %v and %v", bill.TrueUpPayment, bill.ForfeitedCredit, tt.wantTrueUp, tt.wantForfeited)
+			}
+			if !near(bill.AnnualTotal, tt.wantAnnual) {
+				t.Errorf("annual = %v, want %v", bill.AnnualTotal, tt.wantAnnual)
+			}
+		})
+	}
+}
+
+func TestBillTiers(t *testing.T) {
+	// 0-300 kWh adds nothing, 300-600 adds 5c and above 600 adds 10c.
+	tiers := []Tier{{MaxKWh: limit(300)}, {MaxKWh: limit(600), RateAdder: 0.05}, {RateAdder: 0.10}}
+	tests := []struct {
+		name       string
+		export     string
+		production []float64
+		want       float64
+	}{
+		// 744 kWh: 300 at 5c and 144 at 10c.
+		{"no solar", ExportNEM1, nil, 223.2 + 29.4},
+		// NEM tiers the 496 kWh net.
+		{"NEM tiers net use", ExportNEM1, midday(2), 186 + 9.8},
+		// Net billing tiers the 620 kWh imported.
+		{"net billing tiers imports", ExportNetBilling, midday(2), 186 + 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tariff := Flat(0.30)
+			tariff.Tiers = tiers
+			tariff.Export = ExportRule{Type: tt.export}
+			bill, err := tariff.Bill(constant(1), tt.production)
+			if err != nil {
+				t.Fatalf("Bill: %v", err)
+			}
+			if got := bill.Months[0].EnergyCharge; !near(got, tt.want) {
+				t.Errorf("January energy charge = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// touTariff charges 50c from 16:00 to 21:00 on weekdays and 20c otherwise.
+func touTariff() *Tariff {
+	weekday, weekend := make([][]int, 12), make([][]int, 12)
+	for m := range weekday {
+		weekday[m], weekend[m] = make([]int, 24), make([]int, 24)
+		for h := 16; h < 21; h++ {
+			weekday[m][h] = 1
+		}
+	}
+	return &Tariff{
+		Name:            "TOU",
+		Periods:         []Period{{Name: "off-peak", Rate: 0.20}, {Name: "peak", Rate: 0.50}},
+		WeekdaySchedule: weekday,
+		WeekendSchedule: weekend,
+		Export:          ExportRule{Type: ExportNetBilling, PeriodRates: []float64{0.04, 0.10}},
+	}
+}
+
+func TestBillTimeOfUse(t *testing.T) {
+	bill, err := touTariff().Bill(constant(1), nil)
+	if err != nil {
+		t.Fatalf("Bill: %v", err)
+	}
+	// January has 22 weekdays: 110 peak kWh at 50c and 634 at 20c.
+	if got := bill.Months[0].EnergyCharge; !near(got, 55+126.8) {
+		t.Errorf("January energy charge = %v, want %v", got, 55+126.8)
+	}
+}
+
+func TestHourlyRates(t *testing.T) {
+	imports, exports := touTariff().HourlyRates()
+	tests := []struct {
+		name     string
+		hour     int
+		imp, exp float64
+	}{
+		{"Sunday evening", 17, 0.20, 0.04},
+		{"Monday evening", 24 + 17, 0.50, 0.10},
+		{"Monday morning", 24 + 9, 0.20, 0.04},
+	}
+	for _, tt := range tests {
+		if imports[tt.hour] != tt.imp || exports[tt.hour] != tt.exp {
+			t.Errorf("%s: rates %v and %v, want %v and %v", tt.name, imports[tt.hour], exports[tt.hour], tt.imp, tt.exp)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c, err := Flat(0.30).Compare(constant(1), midday(2))
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	// NEM saves retail on all 248 kWh solar makes in January.
+	if c.MonthlySavings[0] != 74.4 || !near(c.AnnualSavings, 365*4*2*0.30) {
+		t.Errorf("savings = %v in January and %v a year", c.MonthlySavings[0], c.AnnualSavings)
+	}
+	if _, err := Flat(0.30).Compare(constant(1)[:24], nil); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("short load: err = %v, want ErrInvalidProfile", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Tariff)
+	}{
+		{"no periods", func(t *Tariff) { t.Periods = nil }},
+		{"negative rate", func(t *Tariff) { t.Periods[0].Rate = -0.1 }},
+		{"negative fixed charge", func(t *Tariff) { t.FixedMonthlyCharge = -1 }},
+		{"short schedule", func(t *Tariff) { t.WeekdaySchedule = t.WeekdaySchedule[:11] }},
+		{"short month", func(t *Tariff) { t.WeekendSchedule[3] = t.WeekendSchedule[3][:23] }},
+		{"unknown period", func(t *Tariff) { t.WeekdaySchedule[0][0] = 2 }},
+		{"unlimited tier first", func(t *Tariff) { t.Tiers = []Tier{{}, {MaxKWh: limit(100)}} }},
+		{"falling tiers", func(t *Tariff) { t.Tiers = []Tier{{MaxKWh: limit(300)}, {MaxKWh: limit(200)}} }},
+		{"no export type", func(t *Tariff) { t.Export.Type = "" }},
+		{"unknown export type", func(t *Tariff) { t.Export.Type = "nem4" }},
+		{"negative export rate", func(t *Tariff) { t.Export.Rate = -1 }},
+		{"period rates per period", func(t *Tariff) { t.Export.PeriodRates = []float64{0.04} }},
+		{"hourly rates per hour", func(t *Tariff) { t.Export.HourlyRates = make([]float64, 24) }},
+	}
+	if err := touTariff().Validate(); err != nil {
+		t.Fatalf("valid tariff: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tariff := touTariff()
+			tt.edit(tariff)
+			if err := tariff.Validate(); !errors.Is(err, ErrInvalidTariff) {
+				t.Errorf("err = %v, want ErrInvalidTariff", err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"flat", `{"name":"Flat","periods":[{"name":"all","rate":0.3}],"export":{"type":"nem1"}}`, false},
+		{"unknown field", `{"name":"Flat","periods":[{"name":"all","rate":0.3}],"export":{"type":"nem1"},"demand":1}`, true},
+		{"invalid", `{"name":"Flat","periods":[],"export":{"type":"nem1"}}`, true},
+		{"malformed", `{"name":`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.json))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTariff) {
+				t.Errorf("err = %v, want ErrInvalidTariff", err)
+			}
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	monthly := [12]float64{2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	profile := Profile(13000, monthly, SolarShape)
+	if len(profile) != HoursPerYear {
+		t.Fatalf("%d hours, want %d", len(profile), HoursPerYear)
+	}
+	january := sum(profile[:31*24])
+	if !near(sum(profile), 13000) || !near(january, 2000) {
+		t.Errorf("profile sums to %v with %v in January, want 13000 with 2000", sum(profile), january)
+	}
+	if profile[0] != 0 || profile[12] <= profile[9] {
+		t.Errorf("solar profile peaks at %v at noon and starts at %v at midnight", profile[12], profile[0])
+	}
+	if empty := Profile(1000, [12]float64{}, ResidentialLoadShape); sum(empty) != 0 {
+		t.Errorf("profile with no monthly weights sums to %v, want 0", sum(empty))
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
@@ -0,0 +1,322 @@
+// Package usage reads utility interval data, from Green Button downloads
+// or utility CSV exports, and normalizes it into a year of hourly usage.
+package usage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidUsage      = errors.New("invalid usage data")
+	ErrInsufficientUsage = errors.New("not enough usage data")
+)
+
+// byteOrderMark starts files saved as UTF-8 by some spreadsheet tools.
+const byteOrderMark = "\uFEFF"
+
+// Upload formats.
+const (
+	FormatGreenButton = "green_button"
+	FormatCSV         = "csv"
+)
+
+// Interval is energy used over a period starting at Start. Start is the
+// local clock time, carried in UTC so it compares without zones.
+type Interval struct {
+	Start    time.Time
+	Duration time.Duration
+	KWh      float64
+}
+
+// Parse reads intervals in the given format, or in the format detected
+// from the content when format is empty, and returns the format read.
+func Parse(r io.Reader, format string) ([]Interval, string, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		format = FormatCSV
+		if head, _ := br.Peek(512); bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(head, []byte(byteOrderMark))), []byte("<")) {
+			format = FormatGreenButton
+		}
+	}
+	var intervals []Interval
+	var err error
+	switch format {
+	case FormatGreenButton:
+		intervals, err = ParseGreenButton(br)
+	case FormatCSV:
+		intervals, err = ParseCSV(br)
+	default:
+		err = fmt.Errorf("%w: unknown format %q", ErrInvalidUsage, format)
+	}
+	return intervals, format, err
+}
+
+// ESPI codes used when reading Green Button data.
+const (
+	espiUnitWh         = 72
+	espiFlowReceived   = 19
+	secondsPerHour     = 3600
+	maxIntervalSeconds = 31 * 24 * secondsPerHour
+)
+
+// ParseGreenButton reads a Green Button (NAESB ESPI) XML download, either
+// an Atom feed or a bare IntervalBlock. Readings follow the ReadingType
+// that precedes them; readings of energy sent to the grid are skipped.
+// Times are shifted from UTC to local standard time by the feed's
+// LocalTimeParameters.
+func ParseGreenButton(r io.Reader) ([]Interval, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		intervals []Interval
+		scale     = 1.0 / 1000
+		received  bool
+		tzOffset  int64
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUsage, err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "ReadingType":
+			var rt struct {
+				PowerOfTenMultiplier int `xml:"powerOfTenMultiplier"`
+				UOM                  int `xml:"uom"`
+				FlowDirection        int `xml:"flowDirection"`
+			}
+			if err := dec.DecodeElement(&rt, &se); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidUsage, err)
+			}
+			if rt.UOM != 0 && rt.UOM != espiUnitWh {
+				return nil, fmt.Errorf("%w: unsupported unit of measure %d", ErrInvalidUsage, rt.UOM)
+			}
+			scale = math.Pow10(rt.PowerOfTenMultiplier) / 1000
+			received = rt.FlowDirection == espiFlowReceived
+		case "LocalTimeParameters":
+			var ltp struct {
+				TzOffset int64 `xml:"tzOffset"`
+			}
+			if err := dec.DecodeElement(&ltp, &se); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidUsage, err)
+			}
+			tzOffset = ltp.TzOffset
+		case "IntervalReading":
+			var ir struct {
+				TimePeriod struct {
+					Duration int64 `xml:"duration"`
+					Start    int64 `xml:"start"`
+				} `xml:"timePeriod"`
+				Value float64 `xml:"value"`
+			}
+			if err := dec.DecodeElement(&ir, &se); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidUsage, err)
+			}
+			if received {
+				continue
+			}
+			if ir.TimePeriod.Duration <= 0 || ir.TimePeriod.Duration > maxIntervalSeconds {
+				return nil, fmt.Errorf("%w: interval of %d seconds", ErrInvalidUsage, ir.TimePeriod.Duration)
+			}
+			intervals = append(intervals, Interval{
+				Start:    time.Unix(ir.TimePeriod.Start+tzOffset, 0).UTC(),
+				Duration: time.Duration(ir.TimePeriod.Duration) * time.Second,
+				KWh:      ir.Value * scale,
+			})
+		}
+	}
+	if len(intervals) == 0 {
+		return nil, fmt.Errorf("%w: no interval readings", ErrInvalidUsage)
+	}
+	return intervals, nil
+}
+
+// Column names utilities use in interval CSV exports, lower-cased.
+var (
+	csvDateColumns  = []string{"date", "read date", "usage date"}
+	csvStartColumns = []string{"start time", "start", "interval start", "start date", "start_time", "interval_start", "start datetime", "timestamp", "datetime", "date/time", "date time", "time"}
+	csvEndColumns   = []string{"end time", "end", "interval end", "end date", "end_time", "interval_end", "end datetime"}
+	csvUnitColumns  = []string{"units", "unit", "uom"}
+	// Usage columns are matched by prefix, so "usage (kwh)" matches.
+	csvUsagePrefixes = []string{"usage", "kwh", "consumption", "import", "energy", "value", "wh"}
+)
+
+var csvTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 3:04 PM",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	"2006-01-02",
+	"1/2/2006",
+}
+
+// ParseCSV reads a utility interval CSV export. Account details above the
+// header row are skipped; the header needs a start time, or a date and a
+// start time, and a usage column. Without an end time, each interval runs
+// to the next one's start. Usage is in kWh unless a units column or the
+// usage header says Wh.
+func ParseCSV(r io.Reader) ([]Interval, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUsage, err)
+	}
+
+	var (
+		header                    = -1
+		dateCol, startCol, endCol int
+		usageCol, unitCol         int
+		usageInWh                 bool
+	)
+	for i, row := range rows {
+		names := make([]string, len(row))
+		for j, name := range row {
+			names[j] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, byteOrderMark)))
+		}
+		dateCol, startCol, endCol = findColumn(names, csvDateColumns), findColumn(names, csvStartColumns), findColumn(names, csvEndColumns)
+		unitCol = findColumn(names, csvUnitColumns)
+		usageCol = -1
+		for j, name := range names {
+			for _, prefix := range csvUsagePrefixes {
+				if strings.HasPrefix(name, prefix) {
+					usageCol = j
+					usageInWh = strings.Contains(name, "(wh)") || name == "wh"
+					break
+				}
+			}
+			if usageCol >= 0 {
+				break
+			}
+		}
+		if usageCol >= 0 && (startCol >= 0 || dateCol >= 0) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("%w: no header with a start time and usage column", ErrInvalidUsage)
+	}
+
+	type row struct {
+		start, end time.Time
+		kwh        float64
+	}
+	var parsed []row
+	for i, record := range rows[header+1:] {
+		line := header + i + 2
+		if usageCol >= len(record) || strings.TrimSpace(record[usageCol]) == "" {
+			continue
+		}
+		start, err := csvTime(record, dateCol, startCol)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidUsage, line, err)
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(record[usageCol]), ",", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: usage %q", ErrInvalidUsage, line, record[usageCol])
+		}
+		wh := usageInWh
+		if unitCol >= 0 && unitCol < len(record) {
+			wh = strings.EqualFold(strings.TrimSpace(record[unitCol]), "wh")
+		}
+		if wh {
+			value /= 1000
+		}
+		r := row{start: start, kwh: value}
+		if endCol >= 0 && endCol < len(record) && strings.TrimSpace(record[endCol]) != "" {
+			// An end time alone is on the start's date.
+			if r.end, err = csvTime(record, dateCol, endCol); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidUsage, line, err)
+			}
+			// Intervals ending at midnight are written with the start's date.
+			if !r.end.After(r.start) {
+				r.end = r.end.AddDate(0, 0, 1)
+			}
+		}
+		parsed = append(parsed, r)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("%w: no interval readings", ErrInvalidUsage)
+	}
+
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].start.Before(parsed[j].start) })
+	intervals := make([]Interval, len(parsed))
+	for i, p := range parsed {
+		duration := p.end.Sub(p.start)
+		if p.end.IsZero() {
+			switch {
+			case i+1 < len(parsed):
+				duration = parsed[i+1].start.Sub(p.start)
+			case i > 0:
+				duration = intervals[i-1].Duration
+			default:
+				duration = time.Hour
+			}
+		}
+		if duration <= 0 || duration > maxIntervalSeconds*time.Second {
+			return nil, fmt.Errorf("%w: interval at %s of %s", ErrInvalidUsage, p.start.Format(time.DateTime), duration)
+		}
+		intervals[i] = Interval{Start: p.start, Duration: duration, KWh: p.kwh}
+	}
+	return intervals, nil
+}
+
+func findColumn(names, candidates []string) int {
+	for _, c := range candidates {
+		for i, name := range names {
+			if name == c {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// csvTime reads the time in column col, joined to the date in dateCol when
+// the column holds only a time of day. Times are kept as local clock time.
+func csvTime(record []string, dateCol, col int) (time.Time, error) {
+	var value string
+	if col >= 0 && col < len(record) {
+		value = strings.TrimSpace(record[col])
+	}
+	if dateCol >= 0 && dateCol < len(record) && dateCol != col {
+		date := strings.TrimSpace(record[dateCol])
+		if value == "" {
+			value = date
+		} else if !strings.ContainsAny(value, "-/") {
+			value = date + " " + value
+		}
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
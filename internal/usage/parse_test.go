@@ -0,0 +1,165 @@
+package usage
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGreenButton(t *testing.T) {
+	// Two hours used in Pacific standard time, then an hour sent to the
+	// grid, which is skipped.
+	const feed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:espi="http://naesb.org/espi">
+  <entry><content><espi:LocalTimeParameters><espi:tzOffset>-28800</espi:tzOffset></espi:LocalTimeParameters></content></entry>
+  <entry><content><espi:ReadingType><espi:powerOfTenMultiplier>0</espi:powerOfTenMultiplier><espi:uom>72</espi:uom><espi:flowDirection>1</espi:flowDirection></espi:ReadingType></content></entry>
+  <entry><content><espi:IntervalBlock>
+    <espi:IntervalReading><espi:timePeriod><espi:duration>3600</espi:duration><espi:start>1735718400</espi:start></espi:timePeriod><espi:value>1500</espi:value></espi:IntervalReading>
+    <espi:IntervalReading><espi:timePeriod><espi:duration>3600</espi:duration><espi:start>1735722000</espi:start></espi:timePeriod><espi:value>2250</espi:value></espi:IntervalReading>
+  </espi:IntervalBlock></content></entry>
+  <entry><content><espi:ReadingType><espi:uom>72</espi:uom><espi:flowDirection>19</espi:flowDirection></espi:ReadingType></content></entry>
+  <entry><content><espi:IntervalBlock>
+    <espi:IntervalReading><espi:timePeriod><espi:duration>3600</espi:duration><espi:start>1735725600</espi:start></espi:timePeriod><espi:value>900</espi:value></espi:IntervalReading>
+  </espi:IntervalBlock></content></entry>
+</feed>`
+
+	got, err := ParseGreenButton(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("ParseGreenButton: %v", err)
+	}
+	// 2025-01-01 08:00 UTC is midnight Pacific standard time.
+	want := []Interval{
+		{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Duration: time.Hour, KWh: 1.5},
+		{Start: time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC), Duration: time.Hour, KWh: 2.25},
+	}
+	assertIntervals(t, got, want)
+}
+
+func TestParseGreenButtonErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"no readings", `<feed xmlns="http://www.w3.org/2005/Atom"></feed>`},
+		{"unit is not Wh", `<IntervalBlock><ReadingType><uom>38</uom></ReadingType></IntervalBlock>`},
+		{"zero duration", `<IntervalBlock><IntervalReading><timePeriod><duration>0</duration><start>0</start></timePeriod><value>1</value></IntervalReading></IntervalBlock>`},
+		{"malformed", `<IntervalBlock><IntervalReading>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseGreenButton(strings.NewReader(tt.xml)); !errors.Is(err, ErrInvalidUsage) {
+				t.Errorf("err = %v, want ErrInvalidUsage", err)
+			}
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	jan1 := func(hour, min int) time.Time { return time.Date(2025, 1, 1, hour, min, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		csv  string
+		want []Interval
+	}{
+		{
+			name: "start and end",
+			csv: "Start Time,End Time,Usage (kWh)\n" +
+				"2025-01-01 00:00,2025-01-01 00:15,0.25\n" +
+				"2025-01-01 00:15,2025-01-01 00:30,0.5\n",
+			want: []Interval{
+				{Start: jan1(0, 0), Duration: 15 * time.Minute, KWh: 0.25},
+				{Start: jan1(0, 15), Duration: 15 * time.Minute, KWh: 0.5},
+			},
+		},
+		{
+			name: "account details above the header",
+			csv: "Name,Jane Homeowner\nAccount,12345\n\n" +
+				byteOrderMark + "Date,Start Time,End Time,Usage,Units\n" +
+				"1/1/2025,11:00 PM,12:00 AM,1200,Wh\n",
+			want: []Interval{{Start: jan1(23, 0), Duration: time.Hour, KWh: 1.2}},
+		},
+		{
+			name: "no end time runs to the next start",
+			csv: "timestamp,kwh\n" +
+				"2025-01-01T01:00:00,2\n" +
+				"2025-01-01T00:00:00,1\n" +
+				"2025-01-01T00:30:00,\n",
+			want: []Interval{
+				{Start: jan1(0, 0), Duration: time.Hour, KWh: 1},
+				{Start: jan1(1, 0), Duration: time.Hour, KWh: 2},
+			},
+		},
+		{
+			name: "Wh header and thousands separators",
+			csv:  "Interval Start,Consumption (Wh)\n2025-01-01 00:00,\"1,500\"\n",
+			want: []Interval{{Start: jan1(0, 0), Duration: time.Hour, KWh: 1.5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("ParseCSV: %v", err)
+			}
+			assertIntervals(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"no header", "when,amount\n2025-01-01,1\n"},
+		{"no readings", "Start Time,Usage\n"},
+		{"bad time", "Start Time,Usage\nyesterday,1\n"},
+		{"bad usage", "Start Time,Usage\n2025-01-01 00:00,lots\n"},
+		{"end before start", "Start Time,End Time,Usage\n2025-01-01 00:00,2024-12-01 00:00,1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCSV(strings.NewReader(tt.csv)); !errors.Is(err, ErrInvalidUsage) {
+				t.Errorf("err = %v, want ErrInvalidUsage", err)
+			}
+		})
+	}
+}
+
+func TestParseDetectsFormat(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"xml", byteOrderMark + "  <IntervalBlock><IntervalReading><timePeriod><duration>3600</duration><start>0</start></timePeriod><value>1000</value></IntervalReading></IntervalBlock>", FormatGreenButton},
+		{"csv", "Start Time,Usage\n2025-01-01 00:00,1\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intervals, format, err := Parse(strings.NewReader(tt.data), "")
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if format != tt.want || len(intervals) != 1 || intervals[0].KWh != 1 {
+				t.Errorf("Parse = %v, %q, want one 1 kWh interval as %q", intervals, format, tt.want)
+			}
+		})
+	}
+}
+
+func assertIntervals(t *testing.T, got, want []Interval) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d intervals %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || got[i].Duration != want[i].Duration || !near(got[i].KWh, want[i].KWh) {
+			t.Errorf("interval %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,191 @@
+package usage
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// HoursPerYear is the length of a normalized profile, the same non-leap
+// year tariff and production profiles use.
+const HoursPerYear = 8760
+
+// MinReadingHours is the least data a profile is built from.
+const MinReadingHours = 7 * 24
+
+// gapWindowDays is how far either side of a missing hour readings at the
+// same time of day are averaged to fill it.
+const gapWindowDays = 7
+
+// Profile is a year of hourly usage. Hourly starts at midnight on January
+// 1; hours come from the last 365 days of readings, placed by their date,
+// and hours with no reading are filled from readings at the same time of
+// day nearby, or else across the year.
+type Profile struct {
+	Hourly    []float64   `json:"hourly_kwh,omitempty"`
+	Monthly   [12]float64 `json:"monthly_kwh"`
+	AnnualKWh float64     `json:"annual_kwh" example:"10840.25"`
+	// Start and End bound the readings used.
+	Start           time.Time `json:"start" example:"2024-06-01T00:00:00Z"`
+	End             time.Time `json:"end" example:"2025-06-01T00:00:00Z"`
+	IntervalMinutes int       `json:"interval_minutes" example:"15"`
+	ReadingHours    int       `json:"reading_hours" example:"8712"`
+	FilledHours     int       `json:"filled_hours" example:"48"`
+}
+
+// Normalize builds a profile from intervals. Negative (net export)
+// readings count as no use.
+func Normalize(intervals []Interval) (*Profile, error) {
+	if len(intervals) == 0 {
+		return nil, ErrInsufficientUsage
+	}
+	sorted := make([]Interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	end := sorted[0].Start
+	for _, in := range sorted {
+		if e := in.Start.Add(in.Duration); e.After(end) {
+			end = e
+		}
+	}
+	windowStart := end.AddDate(0, 0, -365)
+
+	p := &Profile{Hourly: make([]float64, HoursPerYear), IntervalMinutes: intervalMinutes(sorted)}
+	have := make([]bool, HoursPerYear)
+	for _, in := range sorted {
+		start, stop := in.Start, in.Start.Add(in.Duration)
+		if !stop.After(windowStart) {
+			continue
+		}
+		if p.Start.IsZero() || start.Before(p.Start) {
+			p.Start = maxTime(start, windowStart)
+		}
+		kwh := math.Max(0, in.KWh)
+		// Spread the interval over the clock hours it covers.
+		for t := start; t.Before(stop); {
+			next := t.Truncate(time.Hour).Add(time.Hour)
+			if next.After(stop) {
+				next = stop
+			}
+			if !t.Before(windowStart) {
+				if h, ok := HourOfYear(t); ok {
+					p.Hourly[h] += kwh * float64(next.Sub(t)) / float64(in.Duration)
+					have[h] = true
+				}
+			}
+			t = next
+		}
+	}
+	p.End = end
+
+	for _, ok := range have {
+		if ok {
+			p.ReadingHours++
+		}
+	}
+	if p.ReadingHours < MinReadingHours {
+		return nil, ErrInsufficientUsage
+	}
+	p.FilledHours = fillGaps(p.Hourly, have)
+
+	copy(p.Monthly[:], MonthlyTotals(p.Hourly))
+	for _, kwh := range p.Hourly {
+		p.AnnualKWh += kwh
+	}
+	p.AnnualKWh = round2(p.AnnualKWh)
+	return p, nil
+}
+
+// fillGaps fills each missing hour with the average at the same hour of
+// day over the days around it, or across the year when those are missing
+// too, and returns how many hours it filled.
+func fillGaps(hourly []float64, have []bool) int {
+	var yearSum [24]float64
+	var yearCount [24]int
+	for h, ok := range have {
+		if ok {
+			yearSum[h%24] += hourly[h]
+			yearCount[h%24]++
+		}
+	}
+
+	filled := 0
+	days := HoursPerYear / 24
+	for h, ok := range have {
+		if ok {
+			continue
+		}
+		day, hour := h/24, h%24
+		var sum float64
+		var count int
+		for d := -gapWindowDays; d <= gapWindowDays; d++ {
+			i := ((day+d+days)%days)*24 + hour
+			if have[i] {
+				sum += hourly[i]
+				count++
+			}
+		}
+		switch {
+		case count > 0:
+			hourly[h] = sum / float64(count)
+		case yearCount[hour] > 0:
+			hourly[h] = yearSum[hour] / float64(yearCount[hour])
+		}
+		filled++
+	}
+	return filled
+}
+
+// intervalMinutes returns the most common interval length.
+func intervalMinutes(intervals []Interval) int {
+	counts := map[time.Duration]int{}
+	var best time.Duration
+	for _, in := range intervals {
+		counts[in.Duration]++
+		if counts[in.Duration] > counts[best] {
+			best = in.Duration
+		}
+	}
+	return int(best / time.Minute)
+}
+
+var referenceYear = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// HourOfYear returns the hour of a non-leap year a time falls in. February
+// 29 has none.
+func HourOfYear(t time.Time) (int, bool) {
+	if t.Month() == time.February && t.Day() == 29 {
+		return 0, false
+	}
+	day := time.Date(referenceYear.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).YearDay() - 1
+	return day*24 + t.Hour(), true
+}
+
+// MonthOf returns the month (0-11) of an hour of the year.
+func MonthOf(hourOfYear int) int {
+	return int(referenceYear.Add(time.Duration(hourOfYear)*time.Hour).Month()) - 1
+}
+
+// MonthlyTotals sums an hourly profile by month.
+func MonthlyTotals(hourly []float64) []float64 {
+	monthly := make([]float64, 12)
+	for h, kwh := range hourly {
+		monthly[MonthOf(h)] += kwh
+	}
+	for m := range monthly {
+		monthly[m] = round2(monthly[m])
+	}
+	return monthly
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
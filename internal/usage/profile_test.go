@@ -0,0 +1,111 @@
+package usage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// hourlyYear returns a reading for every hour of 2025 using kwh(hour of
+// day), leaving out the hours skip reports.
+func hourlyYear(kwh func(hour int) float64, skip func(t time.Time) bool) []Interval {
+	var intervals []Interval
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for t := start; t.Year() == 2025; t = t.Add(time.Hour) {
+		if skip != nil && skip(t) {
+			continue
+		}
+		intervals = append(intervals, Interval{Start: t, Duration: time.Hour, KWh: kwh(t.Hour())})
+	}
+	return intervals
+}
+
+func TestNormalizeFillsGaps(t *testing.T) {
+	byHour := func(hour int) float64 { return float64(hour) / 10 }
+	march := func(day int) time.Time { return time.Date(2025, 3, day, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		skip       func(time.Time) bool
+		wantFilled int
+	}{
+		{"complete", nil, 0},
+		{"a missing day", func(t time.Time) bool {
+			return t.Month() == time.March && t.Day() == 10
+		}, 24},
+		{"a missing month", func(t time.Time) bool {
+			return !t.Before(march(1)) && t.Before(march(1).AddDate(0, 1, 0))
+		}, 31 * 24},
+		{"afternoons missing", func(t time.Time) bool {
+			return t.Hour() >= 12 && t.Hour() < 18 && t.YearDay()%2 == 0
+		}, 182 * 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Normalize(hourlyYear(byHour, tt.skip))
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if p.FilledHours != tt.wantFilled || p.ReadingHours != HoursPerYear-tt.wantFilled {
+				t.Errorf("filled %d and read %d hours, want %d filled", p.FilledHours, p.ReadingHours, tt.wantFilled)
+			}
+			// Every day has the same shape, so a filled hour matches its
+			// time of day.
+			for h, kwh := range p.Hourly {
+				if !near(kwh, byHour(h%24)) {
+					t.Fatalf("hour %d = %v, want %v", h, kwh, byHour(h%24))
+				}
+			}
+			if want := 365 * 27.6; !near(p.AnnualKWh, want) {
+				t.Errorf("annual = %v, want %v", p.AnnualKWh, want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSpreadsIntervalsOverHours(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var intervals []Interval
+	// A week of 15-minute readings, then one 2-hour reading from 00:30
+	// on the next day.
+	for i := 0; i < 7*24*4; i++ {
+		intervals = append(intervals, Interval{Start: start.Add(time.Duration(i) * 15 * time.Minute), Duration: 15 * time.Minute, KWh: 0.25})
+	}
+	intervals = append(intervals, Interval{Start: start.AddDate(0, 0, 7).Add(30 * time.Minute), Duration: 2 * time.Hour, KWh: 4})
+
+	p, err := Normalize(intervals)
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if p.IntervalMinutes != 15 {
+		t.Errorf("interval = %d minutes, want 15", p.IntervalMinutes)
+	}
+	h, _ := HourOfYear(start)
+	if !near(p.Hourly[h], 1) {
+		t.Errorf("first hour = %v, want 1", p.Hourly[h])
+	}
+	h, _ = HourOfYear(start.AddDate(0, 0, 7))
+	for i, want := range []float64{1, 2, 1} {
+		if !near(p.Hourly[h+i], want) {
+			t.Errorf("hour %d of the 2-hour reading = %v, want %v", i, p.Hourly[h+i], want)
+		}
+	}
+}
+
+func TestNormalizeInsufficient(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		intervals []Interval
+	}{
+		{"none", nil},
+		{"under a week", []Interval{{Start: start, Duration: 6 * 24 * time.Hour, KWh: 100}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Normalize(tt.intervals); !errors.Is(err, ErrInsufficientUsage) {
+				t.Errorf("err = %v, want ErrInsufficientUsage", err)
+			}
+		})
+	}
+}
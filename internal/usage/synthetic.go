@@ -0,0 +1,329 @@
+package usage
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Climate zones, grouped from the IECC zones by what drives a home's
+// electric load: cooling in the south, heating in the north.
+const (
+	ClimateHot      = "hot"       // IECC 1-2
+	ClimateWarm     = "warm"      // IECC 3
+	ClimateMixed    = "mixed"     // IECC 4
+	ClimateCold     = "cold"      // IECC 5-6
+	ClimateVeryCold = "very_cold" // IECC 7-8
+)
+
+// DefaultBuildingAreaSqFt is the floor area assumed when it is not known,
+// about that of a typical single-family home.
+const DefaultBuildingAreaSqFt = 1800
+
+// stateClimates is the zone most of each state's homes are in.
+var stateClimates = map[string]string{
+	"AL": ClimateWarm, "AK": ClimateVeryCold, "AZ": ClimateHot, "AR": ClimateWarm,
+	"CA": ClimateWarm, "CO": ClimateCold, "CT": ClimateCold, "DE": ClimateMixed,
+	"DC": ClimateMixed, "FL": ClimateHot, "GA": ClimateWarm, "HI": ClimateHot,
+	"ID": ClimateCold, "IL": ClimateCold, "IN": ClimateCold, "IA": ClimateCold,
+	"KS": ClimateMixed, "KY": ClimateMixed, "LA": ClimateHot, "ME": ClimateCold,
+	"MD": ClimateMixed, "MA": ClimateCold, "MI": ClimateCold, "MN": ClimateCold,
+	"MS": ClimateWarm, "MO": ClimateMixed, "MT": ClimateCold, "NE": ClimateCold,
+	"NV": ClimateWarm, "NH": ClimateCold, "NJ": ClimateMixed, "NM": ClimateMixed,
+	"NY": ClimateCold, "NC": ClimateMixed, "ND": ClimateCold, "OH": ClimateCold,
+	"OK": ClimateWarm, "OR": ClimateMixed, "PA": ClimateCold, "RI": ClimateCold,
+	"SC": ClimateWarm, "SD": ClimateCold, "TN": ClimateMixed, "TX": ClimateHot,
+	"UT": ClimateCold, "VT": ClimateCold, "VA": ClimateMixed, "WA": ClimateMixed,
+	"WV": ClimateMixed, "WI": ClimateCold, "WY": ClimateCold, "PR": ClimateHot,
+}
+
+// ClimateForState returns the climate zone of a state by its postal code,
+// or ClimateMixed when it is not known.
+func ClimateForState(state string) string {
+	if zone, ok := stateClimates[strings.ToUpper(strings.TrimSpace(state))]; ok {
+		return zone
+	}
+	return ClimateMixed
+}
+
+// climateShape is a zone's reference building: the kWh per square foot a
+// year its end uses draw, and the months they fall in.
+type climateShape struct {
+	coolingPerSqFt  float64
+	heatingPerSqFt  float64 // fans and resistance backup with fuel heat
+	heatPumpPerSqFt float64 // heating with an electric heat pump
+	coolingMonthly  [12]float64
+	heatingMonthly  [12]float64
+}
+
+var climateShapes = map[string]climateShape{
+	ClimateHot: {
+		coolingPerSqFt: 2.6, heatingPerSqFt: 0.05, heatPumpPerSqFt: 0.6,
+		coolingMonthly: [12]float64{0.03, 0.03, 0.05, 0.07, 0.10, 0.12, 0.13, 0.13, 0.12, 0.10, 0.07, 0.05},
+		heatingMonthly: [12]float64{0.30, 0.22, 0.10, 0.02, 0, 0, 0, 0, 0, 0.02, 0.10, 0.24},
+	},
+	ClimateWarm: {
+		coolingPerSqFt: 1.8, heatingPerSqFt: 0.10, heatPumpPerSqFt: 1.3,
+		coolingMonthly: [12]float64{0.01, 0.01, 0.03, 0.05, 0.10, 0.16, 0.20, 0.20, 0.14, 0.07, 0.02, 0.01},
+		heatingMonthly: [12]float64{0.24, 0.19, 0.12, 0.05, 0.01, 0, 0, 0, 0, 0.04, 0.13, 0.22},
+	},
+	ClimateMixed: {
+		coolingPerSqFt: 1.1, heatingPerSqFt: 0.15, heatPumpPerSqFt: 2.4,
+		coolingMonthly: [12]float64{0, 0, 0.01, 0.03, 0.09, 0.19, 0.25, 0.23, 0.14, 0.05, 0.01, 0},
+		heatingMonthly: [12]float64{0.21, 0.17, 0.13, 0.07, 0.02, 0, 0, 0, 0.01, 0.06, 0.13, 0.20},
+	},
+	ClimateCold: {
+		coolingPerSqFt: 0.6, heatingPerSqFt: 0.20, heatPumpPerSqFt: 3.8,
+		coolingMonthly: [12]float64{0, 0, 0, 0.01, 0.07, 0.21, 0.30, 0.26, 0.12, 0.03, 0, 0},
+		heatingMonthly: [12]float64{0.19, 0.16, 0.13, 0.08, 0.03, 0.005, 0, 0, 0.015, 0.07, 0.13, 0.19},
+	},
+	ClimateVeryCold: {
+		coolingPerSqFt: 0.1, heatingPerSqFt: 0.25, heatPumpPerSqFt: 5.0,
+		coolingMonthly: [12]float64{0, 0, 0, 0, 0.05, 0.25, 0.40, 0.25, 0.05, 0, 0, 0},
+		heatingMonthly: [12]float64{0.16, 0.14, 0.12, 0.09, 0.05, 0.02, 0.01, 0.02, 0.04, 0.08, 0.12, 0.15},
+	},
+}
+
+// Base load: appliances, lighting and plug loads, the same in every zone.
+const (
+	baseKWh        = 3000
+	basePerSqFt    = 1.6
+	evKWh          = 3200 // about 11,000 miles a year
+	weekendBaseDay = 1.08 // weekend days use more than weekdays at home
+)
+
+var (
+	baseMonthly = [12]float64{0.090, 0.080, 0.083, 0.078, 0.080, 0.082, 0.088, 0.088, 0.080, 0.080, 0.083, 0.088}
+	// Daily shapes by hour from midnight.
+	baseWeekday = [24]float64{
+		0.030, 0.027, 0.026, 0.025, 0.026, 0.030, 0.038, 0.045,
+		0.043, 0.038, 0.036, 0.036, 0.036, 0.037, 0.039, 0.043,
+		0.050, 0.059, 0.064, 0.063, 0.059, 0.053, 0.044, 0.036,
+	}
+	baseWeekend = [24]float64{
+		0.031, 0.028, 0.026, 0.025, 0.025, 0.027, 0.030, 0.036,
+		0.043, 0.046, 0.046, 0.045, 0.044, 0.043, 0.043, 0.044,
+		0.047, 0.053, 0.057, 0.057, 0.054, 0.050, 0.043, 0.036,
+	}
+	coolingDaily = [24]float64{
+		0.020, 0.015, 0.012, 0.010, 0.010, 0.010, 0.012, 0.018,
+		0.025, 0.035, 0.045, 0.055, 0.065, 0.075, 0.083, 0.088,
+		0.090, 0.088, 0.080, 0.068, 0.055, 0.045, 0.035, 0.027,
+	}
+	heatingDaily = [24]float64{
+		0.045, 0.045, 0.047, 0.050, 0.055, 0.062, 0.068, 0.065,
+		0.055, 0.042, 0.033, 0.028, 0.025, 0.024, 0.025, 0.028,
+		0.035, 0.045, 0.050, 0.050, 0.048, 0.046, 0.045, 0.044,
+	}
+	// EVs charge overnight after the evening commute.
+	evDaily = [24]float64{
+		0.12, 0.12, 0.11, 0.09, 0.06, 0.03, 0.01, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0.01, 0.03, 0.05, 0.06, 0.09, 0.11, 0.12,
+	}
+)
+
+// SyntheticInput is what is known of a home's consumption: twelve monthly
+// kWh figures from January, or a single annual figure, or neither, when
+// the annual is estimated from the building alone.
+type SyntheticInput struct {
+	Monthly          []float64 `json:"monthly_kwh,omitempty"`
+	AnnualKWh        float64   `json:"annual_kwh,omitempty" example:"10800"`
+	Climate          string    `json:"climate_zone,omitempty" example:"mixed"`
+	BuildingAreaSqFt float64   `json:"building_area_sqft,omitempty" example:"2000"`
+	EV               bool      `json:"ev"`
+	HeatPump         bool      `json:"heat_pump"`
+}
+
+func (in *SyntheticInput) Validate() error {
+	if len(in.Monthly) != 0 && len(in.Monthly) != 12 {
+		return fmt.Errorf("%w: monthly kWh needs 12 values, got %d", ErrInvalidUsage, len(in.Monthly))
+	}
+	for m, kwh := range in.Monthly {
+		if kwh < 0 || math.IsNaN(kwh) || math.IsInf(kwh, 0) {
+			return fmt.Errorf("%w: monthly kWh for month %d is %g", ErrInvalidUsage, m+1, kwh)
+		}
+	}
+	if in.AnnualKWh < 0 {
+		return fmt.Errorf("%w: annual kWh must not be negative", ErrInvalidUsage)
+	}
+	if in.BuildingAreaSqFt < 0 {
+		return fmt.Errorf("%w: building area must not be negative", ErrInvalidUsage)
+	}
+	if _, ok := climateShapes[in.Climate]; in.Climate != "" && !ok {
+		return fmt.Errorf("%w: unknown climate zone %q", ErrInvalidUsage, in.Climate)
+	}
+	return nil
+}
+
+// EndUses splits annual kWh by what uses it.
+type EndUses struct {
+	BaseKWh    float64 `json:"base_kwh" example:"6200"`
+	CoolingKWh float64 `json:"cooling_kwh" example:"2200"`
+	HeatingKWh float64 `json:"heating_kwh" example:"300"`
+	EVKWh      float64 `json:"ev_kwh" example:"0"`
+}
+
+// SyntheticProfile is a year of hourly load built from a reference shape.
+// Hourly starts at midnight on January 1 and sums to Monthly month by month.
+type SyntheticProfile struct {
+	Hourly           []float64   `json:"hourly_kwh,omitempty"`
+	Monthly          [12]float64 `json:"monthly_kwh"`
+	AnnualKWh        float64     `json:"annual_kwh" example:"10800"`
+	Climate          string      `json:"climate_zone" example:"mixed"`
+	BuildingAreaSqFt float64     `json:"building_area_sqft" example:"2000"`
+	EV               bool        `json:"ev"`
+	HeatPump         bool        `json:"heat_pump"`
+	// Estimated is set when the annual came from the building, not bills.
+	Estimated bool    `json:"estimated"`
+	EndUses   EndUses `json:"end_uses"`
+}
+
+// Synthesize expands monthly or annual consumption into hourly load. The
+// reference building for the climate zone, area and electrification sets
+// the shape: how load falls across the months of an annual figure, and
+// across the hours of each month. Monthly figures are kept exactly.
+func Synthesize(in SyntheticInput) (*SyntheticProfile, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	if in.Climate == "" {
+		in.Climate = ClimateMixed
+	}
+	if in.BuildingAreaSqFt == 0 {
+		in.BuildingAreaSqFt = DefaultBuildingAreaSqFt
+	}
+	zone := climateShapes[in.Climate]
+
+	uses := EndUses{
+		BaseKWh:    baseKWh + basePerSqFt*in.BuildingAreaSqFt,
+		CoolingKWh: zone.coolingPerSqFt * in.BuildingAreaSqFt,
+		HeatingKWh: zone.heatingPerSqFt * in.BuildingAreaSqFt,
+	}
+	if in.HeatPump {
+		uses.HeatingKWh = zone.heatPumpPerSqFt * in.BuildingAreaSqFt
+	}
+	if in.EV {
+		uses.EVKWh = evKWh
+	}
+	shape := referenceLoad(uses, zone)
+	shapeMonthly := monthSums(shape)
+
+	p := &SyntheticProfile{
+		Hourly:           make([]float64, HoursPerYear),
+		Climate:          in.Climate,
+		BuildingAreaSqFt: in.BuildingAreaSqFt,
+		EV:               in.EV,
+		HeatPump:         in.HeatPump,
+	}
+	shapeAnnual := uses.BaseKWh + uses.CoolingKWh + uses.HeatingKWh + uses.EVKWh
+	switch {
+	case len(in.Monthly) == 12:
+		copy(p.Monthly[:], in.Monthly)
+	case in.AnnualKWh > 0:
+		// December takes the rounding so the months add up to the annual.
+		rest := in.AnnualKWh
+		for m := range 11 {
+			p.Monthly[m] = round2(in.AnnualKWh * shapeMonthly[m] / shapeAnnual)
+			rest -= p.Monthly[m]
+		}
+		p.Monthly[11] = round2(rest)
+	default:
+		for m := range p.Monthly {
+			p.Monthly[m] = round2(shapeMonthly[m])
+		}
+		p.Estimated = true
+	}
+
+	// Scale each month of the shape to its total, leaving rounding on the
+	// month's largest hour so hours add up to it exactly.
+	largest := [12]int{}
+	sums := [12]float64{}
+	for h := range shape {
+		m := MonthOf(h)
+		p.Hourly[h] = shape[h] * p.Monthly[m] / shapeMonthly[m]
+		sums[m] += p.Hourly[h]
+		if p.Hourly[h] > p.Hourly[largest[m]] || MonthOf(largest[m]) != m {
+			largest[m] = h
+		}
+	}
+	for m := range p.Monthly {
+		p.Hourly[largest[m]] += p.Monthly[m] - sums[m]
+		p.AnnualKWh += p.Monthly[m]
+	}
+
+	scale := p.AnnualKWh / shapeAnnual
+	p.EndUses = EndUses{
+		BaseKWh:    round2(uses.BaseKWh * scale),
+		CoolingKWh: round2(uses.CoolingKWh * scale),
+		HeatingKWh: round2(uses.HeatingKWh * scale),
+		EVKWh:      round2(uses.EVKWh * scale),
+	}
+	p.AnnualKWh = round2(p.AnnualKWh)
+	return p, nil
+}
+
+// referenceLoad spreads each end use's annual kWh over the months by its
+// monthly weights, over the days of each month, and over the hours of each
+// day by its daily shape.
+func referenceLoad(uses EndUses, zone climateShape) []float64 {
+	load := make([]float64, HoursPerYear)
+	addEndUse(load, uses.BaseKWh, baseMonthly, baseWeekday, baseWeekend, weekendBaseDay)
+	addEndUse(load, uses.CoolingKWh, zone.coolingMonthly, coolingDaily, coolingDaily, 1)
+	addEndUse(load, uses.HeatingKWh, zone.heatingMonthly, heatingDaily, heatingDaily, 1)
+	addEndUse(load, uses.EVKWh, [12]float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, evDaily, evDaily, 1)
+	return load
+}
+
+func addEndUse(load []float64, annualKWh float64, monthly [12]float64, weekday, weekend [24]float64, weekendDay float64) {
+	if annualKWh == 0 {
+		return
+	}
+	monthTotal := 0.0
+	for _, w := range monthly {
+		monthTotal += w
+	}
+	weekdayTotal, weekendTotal := 0.0, 0.0
+	for hour := range weekday {
+		weekdayTotal += weekday[hour]
+		weekendTotal += weekend[hour]
+	}
+
+	// Weight of each day, so a month's days share its kWh.
+	var dayWeights [HoursPerYear / 24]float64
+	var monthWeights [12]float64
+	for day := range dayWeights {
+		date := referenceYear.AddDate(0, 0, day)
+		dayWeights[day] = 1
+		if isWeekend(date) {
+			dayWeights[day] = weekendDay
+		}
+		monthWeights[date.Month()-1] += dayWeights[day]
+	}
+
+	for day, weight := range dayWeights {
+		date := referenceYear.AddDate(0, 0, day)
+		m := date.Month() - 1
+		dayKWh := annualKWh * monthly[m] / monthTotal * weight / monthWeights[m]
+		shape, total := weekday, weekdayTotal
+		if isWeekend(date) {
+			shape, total = weekend, weekendTotal
+		}
+		for hour, w := range shape {
+			load[day*24+hour] += dayKWh * w / total
+		}
+	}
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func monthSums(hourly []float64) [12]float64 {
+	var monthly [12]float64
+	for h, kwh := range hourly {
+		monthly[MonthOf(h)] += kwh
+	}
+	return monthly
+}
@@ -0,0 +1,156 @@
+package usage
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSynthesize(t *testing.T) {
+	bills := []float64{1200, 1000, 850, 700, 800, 1100, 1400, 1450, 1150, 800, 850, 1150}
+	tests := []struct {
+		name          string
+		in            SyntheticInput
+		wantMonthly   []float64
+		wantAnnual    float64
+		wantEstimated bool
+	}{
+		{
+			name:        "monthly bills are kept",
+			in:          SyntheticInput{Monthly: bills, Climate: ClimateHot},
+			wantMonthly: bills,
+			wantAnnual:  12450,
+		},
+		{
+			name:       "annual is spread by the shape",
+			in:         SyntheticInput{AnnualKWh: 10800, Climate: ClimateCold, HeatPump: true},
+			wantAnnual: 10800,
+		},
+		{
+			name:          "estimated from the default building",
+			in:            SyntheticInput{},
+			wantAnnual:    baseKWh + DefaultBuildingAreaSqFt*(basePerSqFt+1.1+0.15),
+			wantEstimated: true,
+		},
+		{
+			name:          "estimated with an EV",
+			in:            SyntheticInput{Climate: ClimateWarm, BuildingAreaSqFt: 2500, EV: true},
+			wantAnnual:    baseKWh + evKWh + 2500*(basePerSqFt+1.8+0.10),
+			wantEstimated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Synthesize(tt.in)
+			if err != nil {
+				t.Fatalf("Synthesize: %v", err)
+			}
+			if len(p.Hourly) != HoursPerYear {
+				t.Fatalf("%d hours, want %d", len(p.Hourly), HoursPerYear)
+			}
+			if p.Estimated != tt.wantEstimated {
+				t.Errorf("estimated = %v, want %v", p.Estimated, tt.wantEstimated)
+			}
+			if math.Abs(p.AnnualKWh-tt.wantAnnual) > 0.01*12 {
+				t.Errorf("annual = %v, want %v", p.AnnualKWh, tt.wantAnnual)
+			}
+			for m, kwh := range tt.wantMonthly {
+				if p.Monthly[m] != kwh {
+					t.Errorf("month %d = %v, want %v", m+1, p.Monthly[m], kwh)
+				}
+			}
+
+			// Hours add up to each month, and the months to the annual.
+			sums := monthSums(p.Hourly)
+			total := 0.0
+			for m := range sums {
+				if math.Abs(sums[m]-p.Monthly[m]) > 1e-6 {
+					t.Errorf("month %d hours sum to %v, want %v", m+1, sums[m], p.Monthly[m])
+				}
+				total += p.Monthly[m]
+			}
+			if math.Abs(round2(total)-p.AnnualKWh) > 1e-9 {
+				t.Errorf("months sum to %v, annual is %v", total, p.AnnualKWh)
+			}
+			for h, kwh := range p.Hourly {
+				if kwh < 0 {
+					t.Fatalf("hour %d = %v, want no negative load", h, kwh)
+				}
+			}
+
+			uses := p.EndUses.BaseKWh + p.EndUses.CoolingKWh + p.EndUses.HeatingKWh + p.EndUses.EVKWh
+			if math.Abs(uses-p.AnnualKWh) > 0.05 {
+				t.Errorf("end uses sum to %v, annual is %v", uses, p.AnnualKWh)
+			}
+			if (p.EndUses.EVKWh > 0) != tt.in.EV {
+				t.Errorf("EV use = %v with EV %v", p.EndUses.EVKWh, tt.in.EV)
+			}
+		})
+	}
+}
+
+func TestSynthesizeShapesByClimate(t *testing.T) {
+	// January is 0, July is 6.
+	tests := []struct {
+		climate  string
+		heatPump bool
+		peak     int
+	}{
+		{ClimateHot, false, 6},
+		{ClimateMixed, false, 6},
+		{ClimateVeryCold, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.climate, func(t *testing.T) {
+			p, err := Synthesize(SyntheticInput{AnnualKWh: 12000, Climate: tt.climate, HeatPump: tt.heatPump})
+			if err != nil {
+				t.Fatalf("Synthesize: %v", err)
+			}
+			peak := 0
+			for m, kwh := range p.Monthly {
+				if kwh > p.Monthly[peak] {
+					peak = m
+				}
+			}
+			if peak != tt.peak {
+				t.Errorf("peak month = %d, want %d: %v", peak+1, tt.peak+1, p.Monthly)
+			}
+		})
+	}
+}
+
+func TestSynthesizeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   SyntheticInput
+	}{
+		{"eleven months", SyntheticInput{Monthly: make([]float64, 11)}},
+		{"negative month", SyntheticInput{Monthly: []float64{1, 1, 1, 1, 1, -1, 1, 1, 1, 1, 1, 1}}},
+		{"NaN month", SyntheticInput{Monthly: []float64{1, 1, 1, 1, 1, math.NaN(), 1, 1, 1, 1, 1, 1}}},
+		{"negative annual", SyntheticInput{AnnualKWh: -1}},
+		{"negative area", SyntheticInput{BuildingAreaSqFt: -1}},
+		{"unknown climate", SyntheticInput{Climate: "arctic"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Synthesize(tt.in); !errors.Is(err, ErrInvalidUsage) {
+				t.Errorf("err = %v, want ErrInvalidUsage", err)
+			}
+		})
+	}
+}
+
+func TestClimateForState(t *testing.T) {
+	tests := []struct{ state, want string }{
+		{"FL", ClimateHot},
+		{" ca ", ClimateWarm},
+		{"AK", ClimateVeryCold},
+		{"", ClimateMixed},
+		{"ZZ", ClimateMixed},
+	}
+	for _, tt := range tests {
+		if got := ClimateForState(tt.state); got != tt.want {
+			t.Errorf("ClimateForState(%q) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}